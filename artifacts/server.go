@@ -0,0 +1,354 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+const (
+	ListArtifactsRoute    = "ListArtifacts"
+	DownloadArtifactRoute = "DownloadArtifact"
+
+	// Latest names the most recently modified version of an artifact.
+	Latest = "latest"
+)
+
+var Routes = rata.Routes{
+	{Path: "/artifacts", Method: "GET", Name: ListArtifactsRoute},
+	{Path: "/artifacts/:name/:version", Method: "GET", Name: DownloadArtifactRoute},
+}
+
+var (
+	ErrNotFound = errors.New("artifact not found")
+
+	extensions = []string{".tgz", ".tar.gz"}
+
+	// Names and versions start with a letter or digit, which keeps "." and
+	// ".." from reaching outside of the directory.
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Artifact is a version of a tarball hosted by the server. Checksum is the
+// hex SHA-256 of the tarball.
+type Artifact struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Checksum  string    `json:"checksum"`
+	SizeBytes int64     `json:"size_bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	path string
+}
+
+type checksum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// Server hosts the tarballs of a directory laid out as name/version.tgz, so
+// Diego cells can download dropbear, the TEA and teapot itself without
+// reaching outside of the deployment. Tarballs are served with their checksum
+// as ETag.
+type Server struct {
+	dir     string
+	handler http.Handler
+	logger  lager.Logger
+
+	mutex     sync.Mutex
+	checksums map[string]checksum
+}
+
+func NewServer(dir string, logger lager.Logger) *Server {
+	server := &Server{
+		dir:       dir,
+		logger:    logger.Session("artifacts"),
+		checksums: map[string]checksum{},
+	}
+
+	handler, err := rata.NewRouter(Routes, rata.Handlers{
+		ListArtifactsRoute:    http.HandlerFunc(server.list),
+		DownloadArtifactRoute: http.HandlerFunc(server.download),
+	})
+	if err != nil {
+		panic("unable to create router: " + err.Error())
+	}
+	server.handler = handler
+
+	return server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// List returns every version of every artifact in the directory.
+func (s *Server) List() ([]Artifact, error) {
+	names, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := []Artifact{}
+	for _, name := range names {
+		if !name.IsDir() || !namePattern.MatchString(name.Name()) {
+			continue
+		}
+
+		versions, err := s.versions(name.Name())
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, versions...)
+	}
+
+	return artifacts, nil
+}
+
+// Find returns the given version of the named artifact, Latest being the most
+// recently modified one.
+func (s *Server) Find(name, version string) (Artifact, error) {
+	if !namePattern.MatchString(name) || !namePattern.MatchString(version) {
+		return Artifact{}, ErrNotFound
+	}
+
+	versions, err := s.versions(name)
+	if os.IsNotExist(err) {
+		return Artifact{}, ErrNotFound
+	}
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	var found *Artifact
+	for i, artifact := range versions {
+		if version == Latest && (found == nil || artifact.UpdatedAt.After(found.UpdatedAt)) {
+			found = &versions[i]
+		} else if artifact.Version == version {
+			found = &versions[i]
+			break
+		}
+	}
+
+	if found == nil {
+		return Artifact{}, ErrNotFound
+	}
+	return *found, nil
+}
+
+// Localize points the bootstrap at the artifacts hosted by the server, which
+// is reachable from the cells at url. The public dropbear and TEA are replaced
+// by the latest hosted versions, if any, and artifacts already under url are
+// pinned to a version and given their checksum.
+func (s *Server) Localize(bootstrap models.Bootstrap, url string) (models.Bootstrap, error) {
+	prefix := strings.TrimRight(url, "/") + "/artifacts/"
+	defaults := models.DefaultBootstrap()
+
+	localize := func(artifact *models.Artifact, name string) error {
+		if artifact.URL == defaults.Dropbear.URL || artifact.URL == defaults.Tea.URL {
+			if _, err := s.Find(name, Latest); err != nil {
+				return nil
+			}
+			artifact.URL = prefix + name + "/" + Latest
+		}
+
+		if !strings.HasPrefix(artifact.URL, prefix) {
+			return nil
+		}
+
+		if artifact.Checksum != "" {
+			return fmt.Errorf("%s is hosted by teapot and cannot have a checksum", artifact.URL)
+		}
+
+		parts := strings.Split(strings.TrimPrefix(artifact.URL, prefix), "/")
+		if len(parts) != 2 {
+			return fmt.Errorf("%s is not an artifact", artifact.URL)
+		}
+
+		hosted, err := s.Find(parts[0], parts[1])
+		if err != nil {
+			return fmt.Errorf("%s: %s", artifact.URL, err)
+		}
+
+		artifact.URL = prefix + hosted.Name + "/" + hosted.Version
+		artifact.Checksum = hosted.Checksum
+		return nil
+	}
+
+	localizeSteps := func(steps []models.BootstrapStep) ([]models.BootstrapStep, error) {
+		localized := make([]models.BootstrapStep, len(steps))
+		for i, step := range steps {
+			if step.Download != nil {
+				download := *step.Download
+				if err := localize(&download, ""); err != nil {
+					return nil, err
+				}
+				step.Download = &download
+			}
+			localized[i] = step
+		}
+		return localized, nil
+	}
+
+	if err := localize(&bootstrap.Dropbear, "dropbear"); err != nil {
+		return bootstrap, err
+	}
+
+	if err := localize(&bootstrap.Tea, "tea"); err != nil {
+		return bootstrap, err
+	}
+
+	var err error
+	if bootstrap.Steps, err = localizeSteps(bootstrap.Steps); err != nil {
+		return bootstrap, err
+	}
+
+	templates := map[string]models.BootstrapOverride{}
+	for template, override := range bootstrap.Templates {
+		for _, artifact := range []**models.Artifact{&override.Dropbear, &override.Tea} {
+			if *artifact == nil {
+				continue
+			}
+			localized := **artifact
+			if err := localize(&localized, ""); err != nil {
+				return bootstrap, err
+			}
+			*artifact = &localized
+		}
+
+		if override.Steps, err = localizeSteps(override.Steps); err != nil {
+			return bootstrap, err
+		}
+		templates[template] = override
+	}
+	if bootstrap.Templates != nil {
+		bootstrap.Templates = templates
+	}
+
+	return bootstrap, nil
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	artifacts, err := s.List()
+	if err != nil {
+		s.logger.Error("failed-to-list-artifacts", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artifacts)
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	artifact, err := s.Find(r.FormValue(":name"), r.FormValue(":version"))
+	if err == ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed-to-find-artifact", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(artifact.path)
+	if err != nil {
+		s.logger.Error("failed-to-open-artifact", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("ETag", `"`+artifact.Checksum+`"`)
+	w.Header().Set("X-Checksum-Sha256", artifact.Checksum)
+	http.ServeContent(w, r, "", artifact.UpdatedAt, file)
+}
+
+func (s *Server) versions(name string) ([]Artifact, error) {
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := []Artifact{}
+	for _, file := range files {
+		version := tarballVersion(file.Name())
+		if file.IsDir() || version == "" {
+			continue
+		}
+
+		path := filepath.Join(s.dir, name, file.Name())
+		sum, err := s.checksum(path, file)
+		if err != nil {
+			return nil, err
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Name:      name,
+			Version:   version,
+			Checksum:  sum,
+			SizeBytes: file.Size(),
+			UpdatedAt: file.ModTime().UTC(),
+			path:      path,
+		})
+	}
+
+	return artifacts, nil
+}
+
+// checksum hashes the tarball at path, reusing the last hash for as long as
+// the file's size and modification time don't change.
+func (s *Server) checksum(path string, info os.FileInfo) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cached, ok := s.checksums[path]
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.sum, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	s.checksums[path] = checksum{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	return sum, nil
+}
+
+func tarballVersion(filename string) string {
+	for _, extension := range extensions {
+		if strings.HasSuffix(filename, extension) {
+			version := strings.TrimSuffix(filename, extension)
+			if namePattern.MatchString(version) && version != Latest {
+				return version
+			}
+		}
+	}
+	return ""
+}
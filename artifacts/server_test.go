@@ -0,0 +1,174 @@
+package artifacts_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/luan/teapot/artifacts"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func sha(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("Server", func() {
+	var (
+		dir    string
+		server *Server
+	)
+
+	writeArtifact := func(name, filename, contents string, modTime time.Time) {
+		Expect(os.MkdirAll(filepath.Join(dir, name), 0755)).To(Succeed())
+		path := filepath.Join(dir, name, filename)
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+	}
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "artifacts")
+		Expect(err).NotTo(HaveOccurred())
+
+		yesterday := time.Now().Add(-24 * time.Hour)
+		writeArtifact("tea", "1.0.tgz", "tea 1.0", yesterday)
+		writeArtifact("tea", "1.1.tar.gz", "tea 1.1", time.Now())
+		writeArtifact("tea", "README", "not a tarball", time.Now())
+		writeArtifact("dropbear", "2015.67.tgz", "dropbear", yesterday)
+
+		server = NewServer(dir, lager.NewLogger("test"))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("GET /artifacts", func() {
+		It("lists the tarballs with their checksums", func() {
+			response := get("/artifacts", nil)
+			Expect(response.Code).To(Equal(http.StatusOK))
+
+			var artifacts []Artifact
+			Expect(json.Unmarshal(response.Body.Bytes(), &artifacts)).To(Succeed())
+			Expect(artifacts).To(HaveLen(3))
+			Expect(artifacts[0].Name).To(Equal("dropbear"))
+			Expect(artifacts[0].Version).To(Equal("2015.67"))
+			Expect(artifacts[0].Checksum).To(Equal(sha("dropbear")))
+			Expect(artifacts[0].SizeBytes).To(Equal(int64(len("dropbear"))))
+		})
+	})
+
+	Describe("GET /artifacts/:name/:version", func() {
+		It("serves the tarball with its checksum as ETag", func() {
+			response := get("/artifacts/tea/1.0", nil)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Body.String()).To(Equal("tea 1.0"))
+			Expect(response.Header().Get("ETag")).To(Equal(`"` + sha("tea 1.0") + `"`))
+			Expect(response.Header().Get("X-Checksum-Sha256")).To(Equal(sha("tea 1.0")))
+		})
+
+		It("serves the most recent version as latest", func() {
+			response := get("/artifacts/tea/latest", nil)
+			Expect(response.Body.String()).To(Equal("tea 1.1"))
+		})
+
+		It("responds with 304 NOT MODIFIED when the ETag matches", func() {
+			response := get("/artifacts/tea/1.0", http.Header{"If-None-Match": {`"` + sha("tea 1.0") + `"`}})
+			Expect(response.Code).To(Equal(http.StatusNotModified))
+		})
+
+		It("responds with 404 NOT FOUND for unknown artifacts", func() {
+			Expect(get("/artifacts/tea/2.0", nil).Code).To(Equal(http.StatusNotFound))
+			Expect(get("/artifacts/nope/latest", nil).Code).To(Equal(http.StatusNotFound))
+			Expect(get("/artifacts/tea/README", nil).Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Find", func() {
+		It("doesn't look outside of the artifact directories", func() {
+			writeArtifact(".", "1.0.tgz", "top level", time.Now())
+			writeArtifact("tea", "...tgz", "dots", time.Now())
+
+			_, err := server.Find(".", "1.0")
+			Expect(err).To(Equal(ErrNotFound))
+			_, err = server.Find("..", "latest")
+			Expect(err).To(Equal(ErrNotFound))
+			_, err = server.Find("tea", "..")
+			Expect(err).To(Equal(ErrNotFound))
+		})
+	})
+
+	Describe("Localize", func() {
+		const url = "http://10.0.0.1:8081"
+
+		It("points the public dropbear and TEA at the latest hosted versions", func() {
+			bootstrap, err := server.Localize(models.DefaultBootstrap(), url)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bootstrap.Dropbear).To(Equal(models.Artifact{
+				URL:      url + "/artifacts/dropbear/2015.67",
+				CacheKey: "dropbear",
+				Checksum: sha("dropbear"),
+			}))
+			Expect(bootstrap.Tea.URL).To(Equal(url + "/artifacts/tea/1.1"))
+			Expect(bootstrap.Tea.Checksum).To(Equal(sha("tea 1.1")))
+		})
+
+		It("leaves the public artifacts that aren't hosted alone", func() {
+			os.RemoveAll(filepath.Join(dir, "dropbear"))
+			bootstrap, err := server.Localize(models.DefaultBootstrap(), url)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bootstrap.Dropbear).To(Equal(models.DefaultBootstrap().Dropbear))
+		})
+
+		It("pins hosted artifacts used by steps and templates", func() {
+			bootstrap := models.DefaultBootstrap()
+			bootstrap.Steps = []models.BootstrapStep{{Download: &models.Artifact{URL: url + "/artifacts/tea/1.0"}, To: "/opt"}}
+			bootstrap.Templates = map[string]models.BootstrapOverride{
+				"t": {Tea: &models.Artifact{URL: url + "/artifacts/tea/latest"}},
+			}
+
+			localized, err := server.Localize(bootstrap, url)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(localized.Steps[0].Download.Checksum).To(Equal(sha("tea 1.0")))
+			Expect(localized.Templates["t"].Tea.URL).To(Equal(url + "/artifacts/tea/1.1"))
+			Expect(bootstrap.Steps[0].Download.Checksum).To(BeEmpty())
+		})
+
+		It("fails when a hosted artifact doesn't exist", func() {
+			bootstrap := models.DefaultBootstrap()
+			bootstrap.Tea = models.Artifact{URL: url + "/artifacts/tea/2.0"}
+			_, err := server.Localize(bootstrap, url)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when a hosted artifact has a checksum", func() {
+			bootstrap := models.DefaultBootstrap()
+			bootstrap.Tea = models.Artifact{URL: url + "/artifacts/tea/1.0", Checksum: sha("tea 1.0")}
+			_, err := server.Localize(bootstrap, url)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
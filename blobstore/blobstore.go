@@ -0,0 +1,177 @@
+package blobstore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+//go:generate counterfeiter -o fakes/fake_blobstore.go . Blobstore
+type Blobstore interface {
+	Put(key string, content io.Reader, size int64) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+type s3Blobstore struct {
+	endpoint   *url.URL
+	bucket     string
+	region     string
+	accessKey  string
+	secretKey  string
+	httpClient *http.Client
+}
+
+// NewS3 returns a Blobstore backed by a bucket of an S3-compatible object
+// store. Objects are addressed path-style, so stores other than Amazon's
+// don't need wildcard DNS.
+func NewS3(endpoint, bucket, region, accessKey, secretKey string) (Blobstore, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint: %s", endpoint)
+	}
+
+	return &s3Blobstore{
+		endpoint:   u,
+		bucket:     bucket,
+		region:     region,
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+func (s *s3Blobstore) Put(key string, content io.Reader, size int64) error {
+	res, err := s.do("PUT", key, content, size)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *s3Blobstore) Get(key string) (io.ReadCloser, error) {
+	res, err := s.do("GET", key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *s3Blobstore) Delete(key string) error {
+	res, err := s.do("DELETE", key, nil, 0)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *s3Blobstore) do(method, key string, body io.Reader, size int64) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = "/" + s.bucket + "/" + strings.TrimLeft(key, "/")
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s %s failed with status %d", method, key, res.StatusCode)
+	}
+
+	return res, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req. Payloads
+// are streamed, so they are left out of the signature.
+func (s *s3Blobstore) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.region, "s3", "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": "UNSIGNED-PAYLOAD",
+		"x-amz-date":           amzDate,
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	signingKey := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
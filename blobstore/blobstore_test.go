@@ -0,0 +1,100 @@
+package blobstore_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/luan/teapot/blobstore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("S3 Blobstore", func() {
+	var (
+		server    *ghttp.Server
+		blobstore Blobstore
+	)
+
+	verifySignature := func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		Expect(authorization).To(HavePrefix("AWS4-HMAC-SHA256 Credential=access-key/"))
+		Expect(authorization).To(ContainSubstring("/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+		Expect(r.Header.Get("X-Amz-Content-Sha256")).To(Equal("UNSIGNED-PAYLOAD"))
+		Expect(r.Header.Get("X-Amz-Date")).NotTo(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		var err error
+		blobstore, err = NewS3(server.URL(), "backups", "us-east-1", "access-key", "secret-key")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("rejects invalid endpoints", func() {
+		_, err := NewS3("not a url", "backups", "us-east-1", "access-key", "secret-key")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("Put", func() {
+		It("uploads the content to the bucket with a signed request", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/backups/snapshots/s1.tar"),
+				verifySignature,
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.ContentLength).To(Equal(int64(5)))
+					body, _ := ioutil.ReadAll(r.Body)
+					Expect(string(body)).To(Equal("hello"))
+				},
+			))
+
+			Expect(blobstore.Put("snapshots/s1.tar", strings.NewReader("hello"), 5)).To(Succeed())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("fails when the store rejects the upload", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusForbidden, ""))
+			Expect(blobstore.Put("snapshots/s1.tar", strings.NewReader("hello"), 5)).NotTo(Succeed())
+		})
+	})
+
+	Describe("Get", func() {
+		It("streams the content from the bucket", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/backups/snapshots/s1.tar"),
+				verifySignature,
+				ghttp.RespondWith(http.StatusOK, "hello"),
+			))
+
+			content, err := blobstore.Get("snapshots/s1.tar")
+			Expect(err).NotTo(HaveOccurred())
+			defer content.Close()
+			Expect(ioutil.ReadAll(content)).To(Equal([]byte("hello")))
+		})
+
+		It("returns ErrNotFound for missing objects", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+			_, err := blobstore.Get("snapshots/nope.tar")
+			Expect(err).To(Equal(ErrNotFound))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes the object", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/backups/snapshots/s1.tar"),
+				verifySignature,
+				ghttp.RespondWith(http.StatusNoContent, ""),
+			))
+
+			Expect(blobstore.Delete("snapshots/s1.tar")).To(Succeed())
+		})
+	})
+})
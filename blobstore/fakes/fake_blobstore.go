@@ -0,0 +1,140 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"io"
+	"sync"
+
+	"github.com/luan/teapot/blobstore"
+)
+
+type FakeBlobstore struct {
+	PutStub        func(key string, content io.Reader, size int64) error
+	putMutex       sync.RWMutex
+	putArgsForCall []struct {
+		key     string
+		content io.Reader
+		size    int64
+	}
+	putReturns struct {
+		result1 error
+	}
+	GetStub        func(key string) (io.ReadCloser, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		key string
+	}
+	getReturns struct {
+		result1 io.ReadCloser
+		result2 error
+	}
+	DeleteStub        func(key string) error
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		key string
+	}
+	deleteReturns struct {
+		result1 error
+	}
+}
+
+func (fake *FakeBlobstore) Put(key string, content io.Reader, size int64) error {
+	fake.putMutex.Lock()
+	fake.putArgsForCall = append(fake.putArgsForCall, struct {
+		key     string
+		content io.Reader
+		size    int64
+	}{key, content, size})
+	fake.putMutex.Unlock()
+	if fake.PutStub != nil {
+		return fake.PutStub(key, content, size)
+	} else {
+		return fake.putReturns.result1
+	}
+}
+
+func (fake *FakeBlobstore) PutCallCount() int {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	return len(fake.putArgsForCall)
+}
+
+func (fake *FakeBlobstore) PutArgsForCall(i int) (string, io.Reader, int64) {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	return fake.putArgsForCall[i].key, fake.putArgsForCall[i].content, fake.putArgsForCall[i].size
+}
+
+func (fake *FakeBlobstore) PutReturns(result1 error) {
+	fake.PutStub = nil
+	fake.putReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeBlobstore) Get(key string) (io.ReadCloser, error) {
+	fake.getMutex.Lock()
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		key string
+	}{key})
+	fake.getMutex.Unlock()
+	if fake.GetStub != nil {
+		return fake.GetStub(key)
+	} else {
+		return fake.getReturns.result1, fake.getReturns.result2
+	}
+}
+
+func (fake *FakeBlobstore) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *FakeBlobstore) GetArgsForCall(i int) string {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return fake.getArgsForCall[i].key
+}
+
+func (fake *FakeBlobstore) GetReturns(result1 io.ReadCloser, result2 error) {
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 io.ReadCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBlobstore) Delete(key string) error {
+	fake.deleteMutex.Lock()
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		key string
+	}{key})
+	fake.deleteMutex.Unlock()
+	if fake.DeleteStub != nil {
+		return fake.DeleteStub(key)
+	} else {
+		return fake.deleteReturns.result1
+	}
+}
+
+func (fake *FakeBlobstore) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+func (fake *FakeBlobstore) DeleteArgsForCall(i int) string {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return fake.deleteArgsForCall[i].key
+}
+
+func (fake *FakeBlobstore) DeleteReturns(result1 error) {
+	fake.DeleteStub = nil
+	fake.deleteReturns = struct {
+		result1 error
+	}{result1}
+}
+
+var _ blobstore.Blobstore = new(FakeBlobstore)
@@ -5,22 +5,92 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/tedsuo/rata"
 )
 
+const workstationPollInterval = time.Second
+
 type Client interface {
-	CreateWorkstation(request WorkstationCreateRequest) error
+	CreateWorkstation(request WorkstationCreateRequest) (OperationResponse, error)
+	GetWorkstation(name string) (WorkstationResponse, error)
+	WaitForWorkstation(name string, timeout time.Duration) (WorkstationResponse, error)
 	DeleteWorkstation(name string) error
+	CloneWorkstation(name string, request WorkstationCloneRequest) error
+	ApplyWorkstations(manifest WorkstationManifest, dryRun bool) ([]WorkstationChange, error)
+	UpdateWorkstation(name string, request WorkstationUpdateRequest) (OperationResponse, error)
+	ApprovePrivilege(name string) error
+	RestartWorkstation(name string) error
+	GetOperation(id string) (OperationResponse, error)
 	AttachWorkstation(name string) (*websocket.Conn, error)
+	StreamLogs(name, source string, follow bool) (*websocket.Conn, error)
 	ListWorkstations() ([]WorkstationResponse, error)
 	AddKeyToWorkstation(name, key string) error
+	ListWorkstationKeys(name string) ([]SSHKeyResponse, error)
+	GetWorkstationKey(name, fingerprint string) (SSHKeyResponse, error)
+	RemoveKeyFromWorkstation(name, fingerprint string) error
+	ExecWorkstation(name, command string) (JobResponse, error)
+	Forward(name string, remotePort uint16, localListener net.Listener, onError func(error)) error
+
+	ListSchedules(name string) ([]ScheduleResponse, error)
+	CreateSchedule(name string, request ScheduleCreateRequest) (ScheduleResponse, error)
+	UpdateSchedule(name, id string, request ScheduleCreateRequest) (ScheduleResponse, error)
+	DeleteSchedule(name, id string) error
+
+	CopyTo(name, path string, content io.Reader, archive bool) error
+	CopyFrom(name, path string, archive bool) (io.ReadCloser, error)
+
+	ListRoutes(name string) ([]RouteResponse, error)
+	CreateRoute(name string, request RouteCreateRequest) (RouteResponse, error)
+	DeleteRoute(name, hostname string) error
+
+	ListSnapshots(name string) ([]SnapshotResponse, error)
+	CreateSnapshot(name string) (SnapshotResponse, error)
+	GetSnapshot(id string) (SnapshotResponse, error)
+	DeleteSnapshot(id string) error
+
+	ListTemplates() ([]TemplateResponse, error)
+	GetTemplate(name string) (TemplateResponse, error)
+	SaveTemplate(name string, request TemplateRequest) (TemplateResponse, error)
+	DeleteTemplate(name string) error
+
+	ListEgressPolicies() ([]EgressPolicyResponse, error)
+	GetWorkstationEgress(name string) (EgressPolicyResponse, error)
+
+	GetUserQuotas() ([]QuotaResponse, error)
+
+	GetCapacity() ([]CapacityResponse, error)
+
+	GetUsage(groupBy string, from, to time.Time) ([]UsageRecordResponse, error)
+
+	ListUserKeys() ([]SSHKeyResponse, error)
+	AddUserKey(key string) (SSHKeyResponse, error)
+	RemoveUserKey(fingerprint string) error
+	ListSecrets() ([]SecretResponse, error)
+	SaveSecret(name, value string) (SecretResponse, error)
+	DeleteSecret(name string) error
+
+	CreateWebhook(request WebhookRequest) (WebhookResponse, error)
+	ListWebhooks() ([]WebhookResponse, error)
+	GetWebhook(id string) (WebhookResponse, error)
+	DeleteWebhook(id string) error
+	ListWebhookDeliveries(id string) ([]DeliveryResponse, error)
+	ListDeadLetters() ([]DeliveryResponse, error)
+	Redeliver(id string) (DeliveryResponse, error)
+
+	CreateJob(request JobCreateRequest) (JobResponse, error)
+	GetJob(id string) (JobResponse, error)
+	CancelJob(id string) error
 }
 
 type client struct {
@@ -35,28 +105,439 @@ func NewClient(url string) Client {
 	}
 }
 
-func (c *client) CreateWorkstation(request WorkstationCreateRequest) error {
-	return c.doRequest(CreateWorkstationRoute, nil, nil, request, nil, nil)
+func (c *client) CreateWorkstation(request WorkstationCreateRequest) (OperationResponse, error) {
+	var operation OperationResponse
+	err := c.doRequest(CreateWorkstationRoute, nil, nil, request, &operation, nil)
+	return operation, err
+}
+
+func (c *client) GetWorkstation(name string) (WorkstationResponse, error) {
+	var workstation WorkstationResponse
+	err := c.doRequest(GetWorkstationRoute, rata.Params{"name": name}, nil, nil, &workstation, nil)
+	return workstation, err
+}
+
+// WaitForWorkstation polls the workstation until it's RUNNING, failing as soon
+// as it crashes, can't be placed or is gone, or once timeout has passed.
+func (c *client) WaitForWorkstation(name string, timeout time.Duration) (WorkstationResponse, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		workstation, err := c.GetWorkstation(name)
+		if err != nil {
+			return workstation, err
+		}
+
+		switch {
+		case workstation.State == "RUNNING":
+			return workstation, nil
+		case workstation.State == "CRASHED":
+			return workstation, fmt.Errorf("workstation '%s' crashed", name)
+		case workstation.PlacementError != "":
+			return workstation, fmt.Errorf("workstation '%s' could not be placed: %s", name, workstation.PlacementError)
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return workstation, fmt.Errorf("workstation '%s' was not RUNNING after %s", name, timeout)
+		}
+		if remaining > workstationPollInterval {
+			remaining = workstationPollInterval
+		}
+		time.Sleep(remaining)
+	}
 }
 
 func (c *client) DeleteWorkstation(name string) error {
 	return c.doRequest(DeleteWorkstationRoute, rata.Params{"name": name}, nil, nil, nil, nil)
 }
 
+func (c *client) CloneWorkstation(name string, request WorkstationCloneRequest) error {
+	return c.doRequest(CloneWorkstationRoute, rata.Params{"name": name}, nil, request, nil, nil)
+}
+
+func (c *client) UpdateWorkstation(name string, request WorkstationUpdateRequest) (OperationResponse, error) {
+	var operation OperationResponse
+	err := c.doRequest(UpdateWorkstationRoute, rata.Params{"name": name}, nil, request, &operation, nil)
+	return operation, err
+}
+
+func (c *client) ApprovePrivilege(name string) error {
+	return c.doRequest(ApprovePrivilegeRoute, rata.Params{"name": name}, nil, nil, nil, nil)
+}
+
+func (c *client) RestartWorkstation(name string) error {
+	return c.doRequest(RestartWorkstationRoute, rata.Params{"name": name}, nil, nil, nil, nil)
+}
+
+func (c *client) GetOperation(id string) (OperationResponse, error) {
+	var operation OperationResponse
+	err := c.doRequest(GetOperationRoute, rata.Params{"id": id}, nil, nil, &operation, nil)
+	return operation, err
+}
+
+func (c *client) ApplyWorkstations(manifest WorkstationManifest, dryRun bool) ([]WorkstationChange, error) {
+	var changes []WorkstationChange
+	query := url.Values{"dry_run": {strconv.FormatBool(dryRun)}}
+	err := c.doRequest(ApplyWorkstationsRoute, nil, query, manifest, &changes, nil)
+	return changes, err
+}
+
 func (c *client) AddKeyToWorkstation(name, key string) error {
 	return c.doRequest(AddKeyToWorkstationRoute, rata.Params{"name": name}, nil, nil, nil, []byte(key))
 }
 
+func (c *client) ListWorkstationKeys(name string) ([]SSHKeyResponse, error) {
+	var keys []SSHKeyResponse
+	err := c.doRequest(ListKeysRoute, rata.Params{"name": name}, nil, nil, &keys, nil)
+	return keys, err
+}
+
+func (c *client) GetWorkstationKey(name, fingerprint string) (SSHKeyResponse, error) {
+	var key SSHKeyResponse
+	err := c.doRequest(GetKeyRoute, rata.Params{"name": name, "fingerprint": fingerprint}, nil, nil, &key, nil)
+	return key, err
+}
+
+func (c *client) RemoveKeyFromWorkstation(name, fingerprint string) error {
+	return c.doRequest(RemoveKeyRoute, rata.Params{"name": name, "fingerprint": fingerprint}, nil, nil, nil, nil)
+}
+
 func (c *client) AttachWorkstation(name string) (*websocket.Conn, error) {
 	return c.wsRequest(AttachWorkstationRoute, rata.Params{"name": name}, nil, nil)
 }
 
+// StreamLogs opens a websocket the workstation's log lines are sent over as
+// LogLineResponse JSON messages, only those of source unless it's empty.
+func (c *client) StreamLogs(name, source string, follow bool) (*websocket.Conn, error) {
+	query := url.Values{"follow": {strconv.FormatBool(follow)}}
+	if source != "" {
+		query.Set("source", source)
+	}
+	return c.wsRequest(StreamLogsRoute, rata.Params{"name": name}, query, nil)
+}
+
 func (c *client) ListWorkstations() ([]WorkstationResponse, error) {
 	var workstations []WorkstationResponse
 	err := c.doRequest(ListWorkstationsRoute, rata.Params{}, nil, nil, &workstations, nil)
 	return workstations, err
 }
 
+func (c *client) ExecWorkstation(name, command string) (JobResponse, error) {
+	var job JobResponse
+	err := c.doRequest(ExecWorkstationRoute, rata.Params{"name": name}, nil, WorkstationExecRequest{Command: command}, &job, nil)
+	return job, err
+}
+
+// Forward accepts connections on localListener and tunnels each of them to
+// remotePort inside the workstation, until the listener is closed. A
+// connection whose tunnel can't be opened is closed without stopping the
+// others, and the error passed to onError unless it is nil.
+func (c *client) Forward(name string, remotePort uint16, localListener net.Listener, onError func(error)) error {
+	params := rata.Params{"name": name, "port": strconv.Itoa(int(remotePort))}
+
+	for {
+		conn, err := localListener.Accept()
+		if err != nil {
+			return err
+		}
+
+		ws, err := c.wsRequest(ForwardWorkstationRoute, params, nil, nil)
+		if err != nil {
+			conn.Close()
+			if onError != nil {
+				onError(fmt.Errorf("forwarding %s to %s:%d failed: %s", conn.RemoteAddr(), name, remotePort, err))
+			}
+			continue
+		}
+
+		go pipeWebsocket(ws, conn)
+	}
+}
+
+func pipeWebsocket(ws *websocket.Conn, conn net.Conn) {
+	defer ws.Close()
+	defer conn.Close()
+
+	go func() {
+		buffer := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				ws.Close()
+				return
+			}
+			ws.WriteMessage(websocket.BinaryMessage, buffer[:n])
+		}
+	}()
+
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(message); err != nil {
+			return
+		}
+	}
+}
+
+func (c *client) ListSchedules(name string) ([]ScheduleResponse, error) {
+	var schedules []ScheduleResponse
+	err := c.doRequest(ListSchedulesRoute, rata.Params{"name": name}, nil, nil, &schedules, nil)
+	return schedules, err
+}
+
+func (c *client) CreateSchedule(name string, request ScheduleCreateRequest) (ScheduleResponse, error) {
+	var schedule ScheduleResponse
+	err := c.doRequest(CreateScheduleRoute, rata.Params{"name": name}, nil, request, &schedule, nil)
+	return schedule, err
+}
+
+func (c *client) UpdateSchedule(name, id string, request ScheduleCreateRequest) (ScheduleResponse, error) {
+	var schedule ScheduleResponse
+	err := c.doRequest(UpdateScheduleRoute, rata.Params{"name": name, "id": id}, nil, request, &schedule, nil)
+	return schedule, err
+}
+
+func (c *client) DeleteSchedule(name, id string) error {
+	return c.doRequest(DeleteScheduleRoute, rata.Params{"name": name, "id": id}, nil, nil, nil, nil)
+}
+
+// CopyTo streams content to path inside the workstation. When archive is set
+// content must be a tarball, which is extracted into the directory at path.
+func (c *client) CopyTo(name, path string, content io.Reader, archive bool) error {
+	res, err := c.fileRequest(UploadFileRoute, name, path, archive, content)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// CopyFrom streams the file at path inside the workstation. When archive is
+// set path may also be a directory, which is streamed as a tarball.
+func (c *client) CopyFrom(name, path string, archive bool) (io.ReadCloser, error) {
+	res, err := c.fileRequest(DownloadFileRoute, name, path, archive, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (c *client) fileRequest(requestName, name, path string, archive bool, body io.Reader) (*http.Response, error) {
+	req, err := c.reqGen.CreateRequest(requestName, rata.Params{"name": name}, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.URL.Path += strings.TrimLeft(path, "/")
+	if archive {
+		req.URL.RawQuery = url.Values{"tar": {"true"}}.Encode()
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode > 299 {
+		defer res.Body.Close()
+		errResponse := Error{}
+		json.NewDecoder(res.Body).Decode(&errResponse)
+		return nil, errResponse
+	}
+
+	return res, nil
+}
+
+func (c *client) ListRoutes(name string) ([]RouteResponse, error) {
+	var routes []RouteResponse
+	err := c.doRequest(ListRoutesRoute, rata.Params{"name": name}, nil, nil, &routes, nil)
+	return routes, err
+}
+
+func (c *client) CreateRoute(name string, request RouteCreateRequest) (RouteResponse, error) {
+	var route RouteResponse
+	err := c.doRequest(CreateRouteRoute, rata.Params{"name": name}, nil, request, &route, nil)
+	return route, err
+}
+
+func (c *client) DeleteRoute(name, hostname string) error {
+	return c.doRequest(DeleteRouteRoute, rata.Params{"name": name, "hostname": hostname}, nil, nil, nil, nil)
+}
+
+func (c *client) ListSnapshots(name string) ([]SnapshotResponse, error) {
+	var snapshots []SnapshotResponse
+	err := c.doRequest(ListSnapshotsRoute, rata.Params{"name": name}, nil, nil, &snapshots, nil)
+	return snapshots, err
+}
+
+func (c *client) CreateSnapshot(name string) (SnapshotResponse, error) {
+	var snapshot SnapshotResponse
+	err := c.doRequest(CreateSnapshotRoute, rata.Params{"name": name}, nil, nil, &snapshot, nil)
+	return snapshot, err
+}
+
+func (c *client) GetSnapshot(id string) (SnapshotResponse, error) {
+	var snapshot SnapshotResponse
+	err := c.doRequest(GetSnapshotRoute, rata.Params{"id": id}, nil, nil, &snapshot, nil)
+	return snapshot, err
+}
+
+func (c *client) DeleteSnapshot(id string) error {
+	return c.doRequest(DeleteSnapshotRoute, rata.Params{"id": id}, nil, nil, nil, nil)
+}
+
+func (c *client) ListTemplates() ([]TemplateResponse, error) {
+	var templates []TemplateResponse
+	err := c.doRequest(ListTemplatesRoute, nil, nil, nil, &templates, nil)
+	return templates, err
+}
+
+func (c *client) GetTemplate(name string) (TemplateResponse, error) {
+	var template TemplateResponse
+	err := c.doRequest(GetTemplateRoute, rata.Params{"name": name}, nil, nil, &template, nil)
+	return template, err
+}
+
+func (c *client) SaveTemplate(name string, request TemplateRequest) (TemplateResponse, error) {
+	var template TemplateResponse
+	err := c.doRequest(SaveTemplateRoute, rata.Params{"name": name}, nil, request, &template, nil)
+	return template, err
+}
+
+func (c *client) DeleteTemplate(name string) error {
+	return c.doRequest(DeleteTemplateRoute, rata.Params{"name": name}, nil, nil, nil, nil)
+}
+
+func (c *client) ListEgressPolicies() ([]EgressPolicyResponse, error) {
+	var policies []EgressPolicyResponse
+	err := c.doRequest(ListEgressPoliciesRoute, nil, nil, nil, &policies, nil)
+	return policies, err
+}
+
+func (c *client) GetWorkstationEgress(name string) (EgressPolicyResponse, error) {
+	var egress EgressPolicyResponse
+	err := c.doRequest(GetWorkstationEgressRoute, rata.Params{"name": name}, nil, nil, &egress, nil)
+	return egress, err
+}
+
+func (c *client) GetUserQuotas() ([]QuotaResponse, error) {
+	var quotas []QuotaResponse
+	err := c.doRequest(GetUserQuotasRoute, nil, nil, nil, &quotas, nil)
+	return quotas, err
+}
+
+func (c *client) GetCapacity() ([]CapacityResponse, error) {
+	var capacity []CapacityResponse
+	err := c.doRequest(GetCapacityRoute, nil, nil, nil, &capacity, nil)
+	return capacity, err
+}
+
+// GetUsage reports the daily usage from the day of from to the day of to,
+// both included, grouped by user, team or image.
+func (c *client) GetUsage(groupBy string, from, to time.Time) ([]UsageRecordResponse, error) {
+	query := url.Values{
+		"group_by": {groupBy},
+		"from":     {from.UTC().Format("2006-01-02")},
+		"to":       {to.UTC().Format("2006-01-02")},
+	}
+
+	var records []UsageRecordResponse
+	err := c.doRequest(GetUsageRoute, nil, query, nil, &records, nil)
+	return records, err
+}
+
+func (c *client) ListUserKeys() ([]SSHKeyResponse, error) {
+	var keys []SSHKeyResponse
+	err := c.doRequest(ListUserKeysRoute, nil, nil, nil, &keys, nil)
+	return keys, err
+}
+
+func (c *client) AddUserKey(key string) (SSHKeyResponse, error) {
+	var sshKey SSHKeyResponse
+	err := c.doRequest(AddUserKeyRoute, nil, nil, UserKeyCreateRequest{Key: key}, &sshKey, nil)
+	return sshKey, err
+}
+
+func (c *client) RemoveUserKey(fingerprint string) error {
+	return c.doRequest(RemoveUserKeyRoute, rata.Params{"fingerprint": fingerprint}, nil, nil, nil, nil)
+}
+
+func (c *client) ListSecrets() ([]SecretResponse, error) {
+	var secrets []SecretResponse
+	err := c.doRequest(ListSecretsRoute, nil, nil, nil, &secrets, nil)
+	return secrets, err
+}
+
+func (c *client) SaveSecret(name, value string) (SecretResponse, error) {
+	var secret SecretResponse
+	err := c.doRequest(SaveSecretRoute, rata.Params{"name": name}, nil, SecretRequest{Value: value}, &secret, nil)
+	return secret, err
+}
+
+func (c *client) DeleteSecret(name string) error {
+	return c.doRequest(DeleteSecretRoute, rata.Params{"name": name}, nil, nil, nil, nil)
+}
+
+func (c *client) CreateWebhook(request WebhookRequest) (WebhookResponse, error) {
+	var webhook WebhookResponse
+	err := c.doRequest(CreateWebhookRoute, nil, nil, request, &webhook, nil)
+	return webhook, err
+}
+
+func (c *client) ListWebhooks() ([]WebhookResponse, error) {
+	var webhooks []WebhookResponse
+	err := c.doRequest(ListWebhooksRoute, nil, nil, nil, &webhooks, nil)
+	return webhooks, err
+}
+
+func (c *client) GetWebhook(id string) (WebhookResponse, error) {
+	var webhook WebhookResponse
+	err := c.doRequest(GetWebhookRoute, rata.Params{"id": id}, nil, nil, &webhook, nil)
+	return webhook, err
+}
+
+func (c *client) DeleteWebhook(id string) error {
+	return c.doRequest(DeleteWebhookRoute, rata.Params{"id": id}, nil, nil, nil, nil)
+}
+
+func (c *client) ListWebhookDeliveries(id string) ([]DeliveryResponse, error) {
+	var deliveries []DeliveryResponse
+	err := c.doRequest(ListWebhookDeliveriesRoute, rata.Params{"id": id}, nil, nil, &deliveries, nil)
+	return deliveries, err
+}
+
+func (c *client) ListDeadLetters() ([]DeliveryResponse, error) {
+	var deliveries []DeliveryResponse
+	err := c.doRequest(ListDeadLettersRoute, nil, nil, nil, &deliveries, nil)
+	return deliveries, err
+}
+
+// Redeliver queues a dead-lettered delivery again.
+func (c *client) Redeliver(id string) (DeliveryResponse, error) {
+	var delivery DeliveryResponse
+	err := c.doRequest(RedeliverRoute, rata.Params{"id": id}, nil, nil, &delivery, nil)
+	return delivery, err
+}
+
+func (c *client) CreateJob(request JobCreateRequest) (JobResponse, error) {
+	var job JobResponse
+	err := c.doRequest(CreateJobRoute, nil, nil, request, &job, nil)
+	return job, err
+}
+
+func (c *client) GetJob(id string) (JobResponse, error) {
+	var job JobResponse
+	err := c.doRequest(GetJobRoute, rata.Params{"id": id}, nil, nil, &job, nil)
+	return job, err
+}
+
+func (c *client) CancelJob(id string) error {
+	return c.doRequest(CancelJobRoute, rata.Params{"id": id}, nil, nil, nil, nil)
+}
+
 func (c *client) doRequest(requestName string, params rata.Params, queryParams url.Values, request, response interface{}, rawBody []byte) error {
 	if rawBody == nil {
 		var err error
@@ -119,7 +600,7 @@ func (c *client) wsRequest(requestName string, params rata.Params, queryParams u
 	}
 
 	ws, res, err := websocket.NewClient(conn, req.URL, req.Header, 1024, 1024)
-	if res.StatusCode > 299 {
+	if res != nil && res.StatusCode > 299 {
 		errResponse := Error{}
 		json.NewDecoder(res.Body).Decode(&errResponse)
 		return nil, errResponse
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/luan/teapot"
+)
+
+// apply runs `teapot apply`, which shows the changes a manifest makes to a
+// running teapot and then applies them.
+func apply(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	manifestFile := flags.String("f", "", "JSON or YAML manifest to apply, - reads it from stdin")
+	target := flags.String("target", os.Getenv("TEAPOT"), "url of the teapot, with the basic auth credentials if needed, defaults to $TEAPOT")
+	dryRun := flags.Bool("dry-run", false, "only show the changes")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *manifestFile == "" || *target == "" {
+		fmt.Fprintln(stderr, "Usage: teapot apply -f manifest.json|manifest.yml [-target url] [-dry-run]")
+		return 2
+	}
+
+	var contents []byte
+	var err error
+	if *manifestFile == "-" {
+		contents, err = ioutil.ReadAll(stdin)
+	} else {
+		contents, err = ioutil.ReadFile(*manifestFile)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read the manifest: %s\n", err)
+		return 1
+	}
+
+	manifest, err := teapot.ParseWorkstationManifest(contents)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid manifest: %s\n", err)
+		return 1
+	}
+
+	client := teapot.NewClient(*target)
+
+	changes, err := client.ApplyWorkstations(manifest, true)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to plan: %s\n", err)
+		return 1
+	}
+
+	printChanges(stdout, changes)
+	if *dryRun || len(changes) == 0 {
+		return 0
+	}
+
+	_, err = client.ApplyWorkstations(manifest, false)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to apply: %s\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Applied %d changes.\n", len(changes))
+	return 0
+}
+
+func printChanges(w io.Writer, changes []teapot.WorkstationChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+
+	symbols := map[string]string{
+		"create":  "+",
+		"update":  "~",
+		"replace": "-/+",
+		"delete":  "-",
+	}
+
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s %s (%s)\n", symbols[change.Action], change.Workstation, change.Action)
+		for _, field := range change.Fields {
+			fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, field.From, field.To)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/cmd/teapot/testrunner"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("teapot apply", func() {
+	const manifest = `{
+		"name": "lab",
+		"workstations": [
+			{"name": "lab-01", "owner": "alice"},
+			{"name": "lab-02", "owner": "bob"}
+		]
+	}`
+
+	BeforeEach(func() {
+		teapotArgs.Admins = username
+		teapotRunner = testrunner.New(teapotBinPath, teapotArgs)
+		teapotProcess = ginkgomon.Invoke(teapotRunner)
+
+		desiredLRPsRoute, _ := receptor.Routes.FindRouteByName(receptor.DesiredLRPsRoute)
+		receptorServer.RouteToHandler(desiredLRPsRoute.Method, desiredLRPsRoute.Path, ghttp.CombineHandlers(
+			ghttp.VerifyRequest(desiredLRPsRoute.Method, desiredLRPsRoute.Path, "domain=tiego"),
+			ghttp.RespondWithJSONEncoded(http.StatusOK, []receptor.DesiredLRPResponse{
+				{ProcessGuid: "lab-02", RootFSPath: "docker:///ubuntu#trusty", Annotation: `{"owner":"alice","manifest":"lab"}`},
+				{ProcessGuid: "lab-03", RootFSPath: "docker:///ubuntu#trusty", Annotation: `{"manifest":"lab"}`},
+				{ProcessGuid: "other", RootFSPath: "docker:///ubuntu#trusty"},
+			}),
+		))
+	})
+
+	AfterEach(func() {
+		ginkgomon.Kill(teapotProcess)
+	})
+
+	It("shows the changes the manifest makes", func() {
+		target := fmt.Sprintf("http://%s:%s@%s", username, password, teapotAddress)
+		command := exec.Command(teapotBinPath, "apply", "-f", "-", "-target", target, "-dry-run")
+		command.Stdin = strings.NewReader(manifest)
+
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`\+ lab-01 \(create\)`))
+		Expect(session.Out).To(gbytes.Say(`~ lab-02 \(update\)`))
+		Expect(session.Out).To(gbytes.Say(`owner: "alice" -> "bob"`))
+		Expect(session.Out).To(gbytes.Say(`- lab-03 \(delete\)`))
+		Expect(session.Out).NotTo(gbytes.Say("other"))
+	})
+
+	It("reads YAML manifests", func() {
+		target := fmt.Sprintf("http://%s:%s@%s", username, password, teapotAddress)
+		command := exec.Command(teapotBinPath, "apply", "-f", "-", "-target", target, "-dry-run")
+		command.Stdin = strings.NewReader(`
+name: lab
+workstations:
+- name: lab-01
+  owner: alice
+- name: lab-02
+  owner: bob
+`)
+
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`\+ lab-01 \(create\)`))
+		Expect(session.Out).To(gbytes.Say(`~ lab-02 \(update\)`))
+		Expect(session.Out).To(gbytes.Say(`- lab-03 \(delete\)`))
+	})
+})
@@ -0,0 +1,94 @@
+package main_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/cmd/teapot/testrunner"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Artifact server", func() {
+	var (
+		artifactsDir     string
+		artifactsAddress string
+	)
+
+	checksum := func(contents string) string {
+		sum := sha256.Sum256([]byte(contents))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		var err error
+		artifactsDir, err = ioutil.TempDir("", "artifacts")
+		Expect(err).NotTo(HaveOccurred())
+
+		for name, contents := range map[string]string{"dropbear": "dropbear tarball", "tea": "tea tarball"} {
+			Expect(os.Mkdir(filepath.Join(artifactsDir, name), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(artifactsDir, name, "1.0.tgz"), []byte(contents), 0644)).To(Succeed())
+		}
+
+		artifactsAddress = fmt.Sprintf("127.0.0.1:%d", 6800+GinkgoParallelNode())
+		args := teapotArgs
+		args.ArtifactsDir = artifactsDir
+		args.ArtifactsAddress = artifactsAddress
+		teapotProcess = ginkgomon.Invoke(testrunner.New(teapotBinPath, args))
+	})
+
+	AfterEach(func() {
+		ginkgomon.Kill(teapotProcess)
+		os.RemoveAll(artifactsDir)
+	})
+
+	It("serves the artifacts without authentication", func() {
+		response, err := http.Get("http://" + artifactsAddress + "/artifacts/tea/latest")
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("ETag")).To(Equal(`"` + checksum("tea tarball") + `"`))
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("tea tarball"))
+	})
+
+	It("bootstraps workstations from the artifact server", func() {
+		var createBody []byte
+
+		createDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.CreateDesiredLRPRoute)
+		receptorServer.AppendHandlers(
+			ghttp.RespondWith(http.StatusNotFound, ""),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest(createDesiredLRPRoute.Method, createDesiredLRPRoute.Path),
+				func(w http.ResponseWriter, r *http.Request) {
+					createBody, _ = ioutil.ReadAll(r.Body)
+				},
+				ghttp.RespondWith(http.StatusCreated, ""),
+			),
+		)
+
+		_, err := client.CreateWorkstation(newValidWorkstationCreateRequest())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(createBody)).To(ContainSubstring(fmt.Sprintf(
+			`"from":"http://%s/artifacts/dropbear/1.0","to":"/tmp","cache_key":"dropbear-%s"`,
+			artifactsAddress, checksum("dropbear tarball"),
+		)))
+		Expect(string(createBody)).To(ContainSubstring(fmt.Sprintf(
+			`"from":"http://%s/artifacts/tea/1.0","to":"/tmp","cache_key":"tea-%s"`,
+			artifactsAddress, checksum("tea tarball"),
+		)))
+		Expect(string(createBody)).NotTo(ContainSubstring("amazonaws.com"))
+	})
+})
@@ -1,7 +1,9 @@
 package main_test
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
 
 	"github.com/luan/teapot/cmd/teapot/testrunner"
 	"github.com/tedsuo/ifrit/ginkgomon"
@@ -35,6 +37,55 @@ var _ = Describe("Basic Auth", func() {
 			})
 		})
 
+		Context("when a users file has been given", func() {
+			BeforeEach(func() {
+				usersFile, err := ioutil.TempFile("", "users")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = usersFile.WriteString(`{"alice":"wonderland","bob":"builder"}`)
+				Expect(err).NotTo(HaveOccurred())
+				usersFile.Close()
+
+				teapotArgs.UsersFile = usersFile.Name()
+				teapotRunner = testrunner.New(teapotBinPath, teapotArgs)
+			})
+
+			AfterEach(func() {
+				os.Remove(teapotArgs.UsersFile)
+			})
+
+			It("returns 401 for all requests", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("lets each user in with their own password only", func() {
+				req, err := http.NewRequest("GET", "http://"+teapotAddress, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				req.SetBasicAuth("bob", "builder")
+				res, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+				req.SetBasicAuth("bob", "wonderland")
+				res, err = http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("still lets the -username user in", func() {
+				req, err := http.NewRequest("GET", "http://"+teapotAddress, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				req.SetBasicAuth(username, password)
+				res, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
 		Context("and the username and password have not been set", func() {
 			BeforeEach(func() {
 				teapotArgs.Username = ""
@@ -1,21 +1,35 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	cf_lager "github.com/cloudfoundry-incubator/cf-lager"
 	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/artifacts"
+	"github.com/luan/teapot/blobstore"
+	"github.com/luan/teapot/gateway"
 	"github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/logs"
 	"github.com/luan/teapot/managers"
 	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/scheduler"
+	"github.com/luan/teapot/store"
+	"github.com/luan/teapot/watcher"
+	"github.com/luan/teapot/webhooks"
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
 	"github.com/tedsuo/ifrit/http_server"
 	"github.com/tedsuo/ifrit/sigmon"
+	"golang.org/x/crypto/ssh"
 )
 
 var serverAddress = flag.String(
@@ -48,12 +62,222 @@ var password = flag.String(
 	"password for basic auth",
 )
 
+var usersFile = flag.String(
+	"usersFile",
+	"",
+	"JSON file mapping usernames to passwords for basic auth, enables basic auth if set",
+)
+
 var teaSecret = flag.String(
 	"teaSecret",
 	"",
 	"secret for accessing the TEA API",
 )
 
+var dataFile = flag.String(
+	"dataFile",
+	"",
+	"JSON file where teapot keeps its state, kept in memory if not set",
+)
+
+var scheduleInterval = flag.Duration(
+	"scheduleInterval",
+	15*time.Second,
+	"how often workstation schedules are checked",
+)
+
+var sshAddress = flag.String(
+	"sshAddress",
+	"",
+	"The host:port that the SSH gateway is bound to, disabled if not set.",
+)
+
+var sshHostKey = flag.String(
+	"sshHostKey",
+	"",
+	"PEM encoded private key the SSH gateway uses as its host key and to log into workstations",
+)
+
+var sshHandshakeTimeout = flag.Duration(
+	"sshHandshakeTimeout",
+	30*time.Second,
+	"how long clients of the SSH gateway have to complete the handshake and log in",
+)
+
+var backupEndpoint = flag.String(
+	"backupEndpoint",
+	"",
+	"The url of the S3-compatible object store snapshots are kept in, backups are disabled if not set.",
+)
+
+var backupBucket = flag.String(
+	"backupBucket",
+	"",
+	"bucket snapshots are kept in",
+)
+
+var backupRegion = flag.String(
+	"backupRegion",
+	"us-east-1",
+	"region of the backup bucket",
+)
+
+var backupAccessKey = flag.String(
+	"backupAccessKey",
+	"",
+	"access key for the backup object store",
+)
+
+var backupSecretKey = flag.String(
+	"backupSecretKey",
+	"",
+	"secret key for the backup object store",
+)
+
+var templatesFile = flag.String(
+	"templatesFile",
+	"",
+	"JSON file with the workstation templates, edits made through the API are written back to it",
+)
+
+var resizeTimeout = flag.Duration(
+	"resizeTimeout",
+	5*time.Minute,
+	"how long a resized workstation has to be RUNNING again before the resize is marked as failed",
+)
+
+var createTimeout = flag.Duration(
+	"createTimeout",
+	10*time.Minute,
+	"how long a created workstation has to be ready to attach to before its creation is marked as failed",
+)
+
+var secretsKey = flag.String(
+	"secretsKey",
+	"",
+	"hex encoded 16, 24 or 32 byte AES key secrets are encrypted with, secrets are disabled if not set",
+)
+
+var bootstrapFile = flag.String(
+	"bootstrapFile",
+	"",
+	"JSON file with the workstation bootstrap spec (artifact URLs, checksums, extra steps, dotfiles), the public artifacts are used if not set",
+)
+
+var artifactsDir = flag.String(
+	"artifactsDir",
+	"",
+	"directory of name/version.tgz tarballs served to workstations, the artifact server is disabled if not set",
+)
+
+var artifactsAddress = flag.String(
+	"artifactsAddress",
+	"",
+	"The host:port that the artifact server is bound to.",
+)
+
+var artifactsURL = flag.String(
+	"artifactsURL",
+	"",
+	"The url Diego cells reach the artifact server at, http://<artifactsAddress> if not set.",
+)
+
+var artifactsVerifyTimeout = flag.Duration(
+	"artifactsVerifyTimeout",
+	5*time.Minute,
+	"how long downloading each checksummed bootstrap artifact may take when teapot starts",
+)
+
+var egressPoliciesFile = flag.String(
+	"egressPoliciesFile",
+	"",
+	"JSON file with the egress policies admins can choose for workstations and templates",
+)
+
+var defaultEgressPolicy = flag.String(
+	"defaultEgressPolicy",
+	models.OpenEgressPolicy,
+	"egress policy of workstations and jobs that don't choose one",
+)
+
+var quotasFile = flag.String(
+	"quotasFile",
+	"",
+	"JSON file with the default, per-user and per-team workstation quotas, no limits if not set",
+)
+
+var capacityAdmission = flag.Bool(
+	"capacityAdmission",
+	false,
+	"refuse to create or start workstations no Diego cell has room for",
+)
+
+var dopplerAddress = flag.String(
+	"dopplerAddress",
+	"",
+	"websocket address of the Doppler workstation logs are read from, e.g. wss://doppler.example.com:443, logs are disabled if not set",
+)
+
+var dopplerAuthToken = flag.String(
+	"dopplerAuthToken",
+	"",
+	"Authorization header sent to the Doppler, if it needs one; UAA tokens expire, so prefer -dopplerAuthTokenFile for long-running teapots",
+)
+
+var dopplerAuthTokenFile = flag.String(
+	"dopplerAuthTokenFile",
+	"",
+	"file holding the Authorization header sent to the Doppler, read again on every connection so it can be refreshed while teapot runs",
+)
+
+var dopplerTimeout = flag.Duration(
+	"dopplerTimeout",
+	10*time.Second,
+	"how long to wait for the Doppler to accept a connection and to send the recent log lines",
+)
+
+var dopplerSkipSSLValidation = flag.Bool(
+	"dopplerSkipSSLValidation",
+	false,
+	"don't verify the Doppler's certificate",
+)
+
+var webhookInterval = flag.Duration(
+	"webhookInterval",
+	time.Second,
+	"how often due webhook deliveries are sent",
+)
+
+var webhookTimeout = flag.Duration(
+	"webhookTimeout",
+	10*time.Second,
+	"how long a webhook has to answer a delivery",
+)
+
+var webhookRetryInterval = flag.Duration(
+	"webhookRetryInterval",
+	30*time.Second,
+	"how long until a failed webhook delivery is retried, doubled after every further failure",
+)
+
+var webhookMaxAttempts = flag.Int(
+	"webhookMaxAttempts",
+	8,
+	"how many times a webhook delivery is attempted before it is dead-lettered",
+)
+
+var privilegePolicy = flag.String(
+	"privilegePolicy",
+	models.PrivilegedPolicy,
+	"whether workstations and jobs run privileged: privileged, unprivileged or admin-approved, templates can override it",
+)
+
+var admins = flag.String(
+	"admins",
+	"",
+	"comma separated users allowed to edit templates and approve privileged workstations",
+)
+
 func PrintUsageAndExit() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 	flag.PrintDefaults()
@@ -61,6 +285,10 @@ func PrintUsageAndExit() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		os.Exit(apply(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	cf_lager.AddFlags(flag.CommandLine)
 	flag.Parse()
 
@@ -75,6 +303,18 @@ func main() {
 		problems = append(problems, "-address")
 	}
 
+	if len(*sshAddress) > 0 && len(*sshHostKey) == 0 {
+		problems = append(problems, "-sshHostKey")
+	}
+
+	if len(*artifactsDir) > 0 && len(*artifactsAddress) == 0 {
+		problems = append(problems, "-artifactsAddress")
+	}
+
+	if len(*backupEndpoint) > 0 && len(*backupBucket) == 0 {
+		problems = append(problems, "-backupBucket")
+	}
+
 	if len(problems) > 0 {
 		fmt.Fprintf(os.Stderr, "Missing arguments: %s\n\n", strings.Join(problems, ", "))
 		PrintUsageAndExit()
@@ -86,13 +326,155 @@ func main() {
 		"receptor_address": *receptorAddress,
 		"apps_domain":      *appsDomain,
 	})
+	dataStore, err := store.NewStore(*dataFile)
+	if err != nil {
+		logger.Fatal("failed-to-open-data-file", err)
+	}
+
+	bootstrap, err := managers.LoadBootstrap(*bootstrapFile)
+	if err != nil {
+		logger.Fatal("failed-to-load-bootstrap", err)
+	}
+
+	// Hosted artifacts are checked against the directory they're served from,
+	// the others are downloaded to be checked.
+	remoteArtifacts := bootstrap
+	var artifactServer *artifacts.Server
+	if len(*artifactsDir) > 0 {
+		if len(*artifactsURL) == 0 {
+			*artifactsURL = "http://" + *artifactsAddress
+		}
+		artifactServer = artifacts.NewServer(*artifactsDir, logger)
+		bootstrap, err = artifactServer.Localize(bootstrap, *artifactsURL)
+		if err != nil {
+			logger.Fatal("invalid-bootstrap-artifact", err)
+		}
+	}
+	err = managers.VerifyArtifacts(remoteArtifacts, &http.Client{Timeout: *artifactsVerifyTimeout})
+	if err != nil {
+		logger.Fatal("invalid-bootstrap-artifact", err)
+	}
+
+	if !models.ValidPrivilegePolicy(*privilegePolicy) {
+		logger.Fatal("invalid-privilege-policy", fmt.Errorf("unknown privilege policy %s", *privilegePolicy))
+	}
+
+	egressPolicyManager, err := managers.NewEgressPolicyManager(*egressPoliciesFile, *defaultEgressPolicy)
+	if err != nil {
+		logger.Fatal("failed-to-load-egress-policies", err)
+	}
+
+	quotaManager, err := managers.NewQuotaManager(*quotasFile)
+	if err != nil {
+		logger.Fatal("failed-to-load-quotas", err)
+	}
+
 	receptorClient := receptor.NewClient(*receptorAddress)
+	capacityManager := managers.NewCapacityManager(receptorClient, *capacityAdmission)
 	routeProvider := models.NewRouteProvider(*appsDomain)
-	workstationManager := managers.NewWorkstationManager(receptorClient, routeProvider, *teaSecret, logger)
-	handler := handlers.New(workstationManager, logger, *username, *password)
+	userManager := managers.NewUserManager(dataStore)
+	workstationManager := managers.NewWorkstationManager(receptorClient, routeProvider, userManager, bootstrap, egressPolicyManager, quotaManager, capacityManager, *teaSecret, logger)
+	jobManager := managers.NewJobManager(receptorClient, bootstrap, egressPolicyManager, logger)
+
+	var backupStore blobstore.Blobstore
+	if len(*backupEndpoint) > 0 {
+		backupStore, err = blobstore.NewS3(*backupEndpoint, *backupBucket, *backupRegion, *backupAccessKey, *backupSecretKey)
+		if err != nil {
+			logger.Fatal("invalid-backup-endpoint", err)
+		}
+	}
+	backupManager := managers.NewBackupManager(workstationManager, backupStore, dataStore, logger)
+
+	templateManager, err := managers.NewTemplateManager(*templatesFile)
+	if err != nil {
+		logger.Fatal("failed-to-load-templates", err)
+	}
+
+	key, err := hex.DecodeString(*secretsKey)
+	if err != nil {
+		logger.Fatal("invalid-secrets-key", err)
+	}
+	secretManager, err := managers.NewSecretManager(key, dataStore)
+	if err != nil {
+		logger.Fatal("invalid-secrets-key", err)
+	}
+
+	operationManager := managers.NewOperationManager(workstationManager, backupManager, dataStore, *resizeTimeout, *createTimeout, logger)
+	if err := operationManager.FailInterrupted(); err != nil {
+		logger.Fatal("failed-to-fail-interrupted-operations", err)
+	}
+	crashManager := managers.NewCrashManager(dataStore)
+	webhookManager := managers.NewWebhookManager(dataStore, *webhookRetryInterval, *webhookMaxAttempts)
+	usageManager := managers.NewUsageManager(dataStore, quotaManager)
+
+	var logSource logs.LogSource
+	if len(*dopplerAddress) > 0 {
+		authToken := logs.StaticToken(*dopplerAuthToken)
+		if len(*dopplerAuthTokenFile) > 0 {
+			authToken = logs.TokenFile(*dopplerAuthTokenFile)
+		}
+
+		logSource, err = logs.NewDoppler(*dopplerAddress, authToken, *dopplerSkipSSLValidation, *dopplerTimeout)
+		if err != nil {
+			logger.Fatal("invalid-doppler-address", err)
+		}
+	}
+
+	users, err := loadUsers(*usersFile)
+	if err != nil {
+		logger.Fatal("failed-to-load-users", err)
+	}
+	if len(*username) > 0 {
+		users[*username] = *password
+	}
+
+	handler := handlers.New(handlers.Dependencies{
+		WorkstationManager:  workstationManager,
+		JobManager:          jobManager,
+		UserManager:         userManager,
+		BackupManager:       backupManager,
+		TemplateManager:     templateManager,
+		OperationManager:    operationManager,
+		SecretManager:       secretManager,
+		CrashManager:        crashManager,
+		EgressPolicyManager: egressPolicyManager,
+		QuotaManager:        quotaManager,
+		CapacityManager:     capacityManager,
+		WebhookManager:      webhookManager,
+		UsageManager:        usageManager,
+		LogSource:           logSource,
+		PrivilegePolicy:     *privilegePolicy,
+		Admins:              adminList(*admins),
+		Users:               users,
+	}, logger)
 
 	members := grouper.Members{
 		{"server", http_server.New(*serverAddress, handler)},
+		{"scheduler", scheduler.New(workstationManager, jobManager, backupManager, *scheduleInterval, logger)},
+		{"watcher", watcher.New(receptorClient, time.Second, logger,
+			watcher.NewSnapshotRestorer(workstationManager, backupManager, logger),
+			watcher.NewKeyRestorer(workstationManager, logger),
+			watcher.NewCrashRecorder(workstationManager, crashManager, logger),
+			watcher.NewEventPublisher(workstationManager, webhookManager, logger),
+			watcher.NewUsageMeter(receptorClient, workstationManager, usageManager, logger),
+		)},
+		{"webhooks", webhooks.New(webhookManager, *webhookInterval, *webhookTimeout, logger)},
+	}
+
+	if artifactServer != nil {
+		members = append(members, grouper.Member{
+			"artifacts", http_server.New(*artifactsAddress, handlers.LogWrap(artifactServer, logger)),
+		})
+	}
+
+	if len(*sshAddress) > 0 {
+		hostKey, err := loadHostKey(*sshHostKey)
+		if err != nil {
+			logger.Fatal("failed-to-load-ssh-host-key", err)
+		}
+		members = append(members, grouper.Member{
+			"gateway", gateway.New(*sshAddress, hostKey, workstationManager, userManager, adminList(*admins), *sshHandshakeTimeout, logger),
+		})
 	}
 
 	group := grouper.NewOrdered(os.Interrupt, members)
@@ -101,10 +483,53 @@ func main() {
 
 	logger.Info("started")
 
-	err := <-monitor.Wait()
+	err = <-monitor.Wait()
 	if err != nil {
 		logger.Error("exited-with-failure", err)
 		os.Exit(1)
 	}
 	logger.Info("exited")
 }
+
+func loadHostKey(path string) (ssh.Signer, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(pem)
+}
+
+// loadUsers reads the usernames and passwords allowed to use the API from the
+// JSON file at path.
+func loadUsers(path string) (map[string]string, error) {
+	users := map[string]string{}
+	if path == "" {
+		return users, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(contents, &users); err != nil {
+		return nil, err
+	}
+
+	for user := range users {
+		if user == "" {
+			return nil, fmt.Errorf("%s: empty username", path)
+		}
+	}
+	return users, nil
+}
+
+func adminList(admins string) []string {
+	var list []string
+	for _, admin := range strings.Split(admins, ",") {
+		if admin = strings.TrimSpace(admin); admin != "" {
+			list = append(list, admin)
+		}
+	}
+	return list
+}
@@ -2,8 +2,10 @@ package main_test
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 
+	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/luan/teapot"
 	"github.com/luan/teapot/cmd/teapot/testrunner"
 	. "github.com/onsi/ginkgo"
@@ -13,6 +15,7 @@ import (
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/rata"
 
 	"testing"
 	"time"
@@ -60,6 +63,8 @@ var _ = SynchronizedAfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	receptorServer = ghttp.NewServer()
+	eventStreamRoute, _ := receptor.Routes.FindRouteByName(receptor.EventStream)
+	receptorServer.RouteToHandler(eventStreamRoute.Method, eventStreamRoute.Path, ghttp.RespondWith(http.StatusNotFound, ""))
 	logger = lager.NewLogger("test")
 
 	teapotAddress = fmt.Sprintf("127.0.0.1:%d", 6700+GinkgoParallelNode())
@@ -86,3 +91,32 @@ var _ = BeforeEach(func() {
 var _ = AfterEach(func() {
 	receptorServer.Close()
 })
+
+// receptorRequests returns the requests made to the receptor, excluding the
+// event stream subscriptions made in the background by the watcher.
+func receptorRequests() []*http.Request {
+	eventStreamRoute, _ := receptor.Routes.FindRouteByName(receptor.EventStream)
+
+	requests := []*http.Request{}
+	for _, request := range receptorServer.ReceivedRequests() {
+		if request.URL.Path != eventStreamRoute.Path {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// routeWorkstation answers the lookups teapot makes for a workstation owned
+// by the test user, reporting the given instances as its actual LRPs.
+func routeWorkstation(name string, actualLRPs ...receptor.ActualLRPResponse) {
+	getDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.GetDesiredLRPRoute)
+	getDesiredLRPPath, _ := getDesiredLRPRoute.CreatePath(rata.Params{"process_guid": name})
+	receptorServer.RouteToHandler(getDesiredLRPRoute.Method, getDesiredLRPPath, ghttp.RespondWithJSONEncoded(http.StatusOK, receptor.DesiredLRPResponse{
+		ProcessGuid: name,
+		Annotation:  fmt.Sprintf(`{"owner":%q}`, username),
+	}))
+
+	actualLRPsRoute, _ := receptor.Routes.FindRouteByName(receptor.ActualLRPsByProcessGuidRoute)
+	actualLRPsPath, _ := actualLRPsRoute.CreatePath(rata.Params{"process_guid": name})
+	receptorServer.RouteToHandler(actualLRPsRoute.Method, actualLRPsPath, ghttp.RespondWithJSONEncoded(http.StatusOK, append([]receptor.ActualLRPResponse{}, actualLRPs...)))
+}
@@ -11,8 +11,13 @@ type Args struct {
 	ReceptorAddress string
 	Username        string
 	Password        string
+	UsersFile       string
+	Admins          string
 	AppsDomain      string
 	TEASecret       string
+
+	ArtifactsDir     string
+	ArtifactsAddress string
 }
 
 func (args Args) ArgSlice() []string {
@@ -21,8 +26,12 @@ func (args Args) ArgSlice() []string {
 		"-receptorAddress", args.ReceptorAddress,
 		"-username", args.Username,
 		"-password", args.Password,
+		"-usersFile", args.UsersFile,
+		"-admins", args.Admins,
 		"-appsDomain", args.AppsDomain,
 		"-teaSecret", args.TEASecret,
+		"-artifactsDir", args.ArtifactsDir,
+		"-artifactsAddress", args.ArtifactsAddress,
 	}
 }
 
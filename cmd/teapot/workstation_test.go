@@ -1,10 +1,14 @@
 package main_test
 
 import (
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
@@ -30,6 +34,7 @@ var _ = Describe("Workstation API", func() {
 
 	Describe("POST /workstatations/", func() {
 		var workstationToCreate teapot.WorkstationCreateRequest
+		var operation teapot.OperationResponse
 		var createErr error
 
 		BeforeEach(func() {
@@ -121,19 +126,72 @@ var _ = Describe("Workstation API", func() {
 							},
 						},
 						EgressRules: openRules,
+						Annotation:  `{"owner":"username","egress_policy":"open","privilege":"privileged"}`,
 					}),
 				),
 			)
 
-			createErr = client.CreateWorkstation(workstationToCreate)
+			operation, createErr = client.CreateWorkstation(workstationToCreate)
 		})
 
-		It("responds without an error", func() {
+		It("responds with the create operation", func() {
 			Expect(createErr).NotTo(HaveOccurred())
+			Expect(operation.Type).To(Equal("create"))
+			Expect(operation.Workstation).To(Equal("my-workstation"))
+			Expect(operation.Step).To(Equal("requesting-lrp"))
 		})
 
 		It("requests an LRP from the receptor", func() {
-			Expect(receptorServer.ReceivedRequests()).To(HaveLen(2))
+			Expect(receptorRequests()).To(HaveLen(2))
+		})
+	})
+
+	Describe("WaitForWorkstation", func() {
+		var (
+			workstation teapot.WorkstationResponse
+			waitErr     error
+			states      []receptor.ActualLRPState
+		)
+
+		BeforeEach(func() {
+			states = []receptor.ActualLRPState{receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning}
+		})
+
+		JustBeforeEach(func() {
+			getDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.GetDesiredLRPRoute)
+			getDesiredLRPPath, _ := getDesiredLRPRoute.CreatePath(rata.Params{"process_guid": "w1"})
+			actualLRPsRoute, _ := receptor.Routes.FindRouteByName(receptor.ActualLRPsByProcessGuidRoute)
+			actualLRPsPath, _ := actualLRPsRoute.CreatePath(rata.Params{"process_guid": "w1"})
+			for _, state := range states {
+				receptorServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(getDesiredLRPRoute.Method, getDesiredLRPPath),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, receptor.DesiredLRPResponse{ProcessGuid: "w1", Instances: 1}),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest(actualLRPsRoute.Method, actualLRPsPath),
+						ghttp.RespondWithJSONEncoded(http.StatusOK, []receptor.ActualLRPResponse{{ProcessGuid: "w1", State: state}}),
+					),
+				)
+			}
+
+			workstation, waitErr = client.WaitForWorkstation("w1", 5*time.Second)
+		})
+
+		It("blocks until the workstation is RUNNING", func() {
+			Expect(waitErr).NotTo(HaveOccurred())
+			Expect(workstation.State).To(Equal("RUNNING"))
+			Expect(receptorRequests()).To(HaveLen(4))
+		})
+
+		Context("when the workstation crashes", func() {
+			BeforeEach(func() {
+				states = []receptor.ActualLRPState{receptor.ActualLRPStateCrashed}
+			})
+
+			It("fails right away", func() {
+				Expect(waitErr).To(MatchError("workstation 'w1' crashed"))
+			})
 		})
 	})
 
@@ -164,7 +222,7 @@ var _ = Describe("Workstation API", func() {
 		})
 
 		It("requests the LRPs from the receptor", func() {
-			Expect(receptorServer.ReceivedRequests()).To(HaveLen(2))
+			Expect(receptorRequests()).To(HaveLen(2))
 		})
 	})
 
@@ -173,6 +231,7 @@ var _ = Describe("Workstation API", func() {
 
 		BeforeEach(func() {
 			workstationToDelete := "w1"
+			routeWorkstation(workstationToDelete)
 			deleteDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.DeleteDesiredLRPRoute)
 			deleteDesiredLRPPath, _ := deleteDesiredLRPRoute.CreatePath(rata.Params{"process_guid": workstationToDelete})
 			receptorServer.AppendHandlers(
@@ -190,7 +249,78 @@ var _ = Describe("Workstation API", func() {
 		})
 
 		It("requests an LRP from the receptor", func() {
-			Expect(receptorServer.ReceivedRequests()).To(HaveLen(1))
+			Expect(receptorRequests()).To(HaveLen(3))
+		})
+	})
+
+	Describe("PATCH /workstations/:name", func() {
+		var (
+			operation teapot.OperationResponse
+			updateErr error
+		)
+
+		BeforeEach(func() {
+			desiredLRP := receptor.DesiredLRPResponse{
+				ProcessGuid: "w1",
+				Domain:      "tiego",
+				RootFSPath:  "docker:///ubuntu#trusty",
+				MemoryMB:    256,
+				Ports:       []uint16{8080, 3000},
+				Annotation:  `{"owner":"username"}`,
+			}
+			getDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.GetDesiredLRPRoute)
+			getDesiredLRPPath, _ := getDesiredLRPRoute.CreatePath(rata.Params{"process_guid": "w1"})
+			actualLRPsRoute, _ := receptor.Routes.FindRouteByName(receptor.ActualLRPsByProcessGuidRoute)
+			actualLRPsPath, _ := actualLRPsRoute.CreatePath(rata.Params{"process_guid": "w1"})
+			deleteDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.DeleteDesiredLRPRoute)
+			deleteDesiredLRPPath, _ := deleteDesiredLRPRoute.CreatePath(rata.Params{"process_guid": "w1"})
+			createDesiredLRPRoute, _ := receptor.Routes.FindRouteByName(receptor.CreateDesiredLRPRoute)
+			receptorServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(getDesiredLRPRoute.Method, getDesiredLRPPath),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, desiredLRP),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(actualLRPsRoute.Method, actualLRPsPath),
+					ghttp.RespondWith(http.StatusOK, "[]"),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(getDesiredLRPRoute.Method, getDesiredLRPPath),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, desiredLRP),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(actualLRPsRoute.Method, actualLRPsPath),
+					ghttp.RespondWith(http.StatusOK, "[]"),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(getDesiredLRPRoute.Method, getDesiredLRPPath),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, desiredLRP),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(deleteDesiredLRPRoute.Method, deleteDesiredLRPPath),
+					ghttp.RespondWith(http.StatusNoContent, ""),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(createDesiredLRPRoute.Method, createDesiredLRPRoute.Path),
+					ghttp.RespondWith(http.StatusCreated, ""),
+				),
+			)
+
+			operation, updateErr = client.UpdateWorkstation("w1", teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+		})
+
+		It("responds with the resize operation", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(operation.Type).To(Equal("resize"))
+			Expect(operation.Workstation).To(Equal("w1"))
+		})
+
+		It("replaces the workstation and tracks it in GET /operations/:id", func() {
+			Eventually(func() string {
+				operation, _ = client.GetOperation(operation.ID)
+				return operation.State
+			}).Should(Equal("SUCCEEDED"))
+			Expect(receptorRequests()).To(HaveLen(7))
 		})
 	})
 
@@ -209,20 +339,11 @@ var _ = Describe("Workstation API", func() {
 			teaPort, _ := strconv.Atoi(teaHostPort[1])
 
 			workstationToAttach := "w1"
-			actualLRPsByProcessGuidRoute, _ := receptor.Routes.FindRouteByName(receptor.ActualLRPsByProcessGuidRoute)
-			actualLRPsByProcessGuidPath, _ := actualLRPsByProcessGuidRoute.CreatePath(rata.Params{"process_guid": workstationToAttach})
-			receptorServer.AppendHandlers(
-				ghttp.CombineHandlers(
-					ghttp.VerifyRequest(actualLRPsByProcessGuidRoute.Method, actualLRPsByProcessGuidPath),
-					ghttp.RespondWithJSONEncoded(http.StatusOK, []receptor.ActualLRPResponse{
-						{
-							Address: teaHost,
-							Ports:   []receptor.PortMapping{{HostPort: uint16(teaPort)}},
-							State:   receptor.ActualLRPStateRunning,
-						},
-					}),
-				),
-			)
+			routeWorkstation(workstationToAttach, receptor.ActualLRPResponse{
+				Address: teaHost,
+				Ports:   []receptor.PortMapping{{HostPort: uint16(teaPort)}},
+				State:   receptor.ActualLRPStateRunning,
+			})
 			teaServer.AppendHandlers(
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/shell"),
@@ -235,8 +356,12 @@ var _ = Describe("Workstation API", func() {
 							panic(err)
 						}
 						defer ws.Close()
+						// Only the proxying spec sends a message, the others
+						// tear teapot down while this is still waiting for one.
 						_, m, err := ws.ReadMessage()
-						Expect(err).NotTo(HaveOccurred())
+						if err != nil {
+							return
+						}
 						Expect(string(m)).To(Equal("hello"))
 						ws.WriteMessage(websocket.TextMessage, []byte("world"))
 					},
@@ -263,7 +388,147 @@ var _ = Describe("Workstation API", func() {
 		})
 
 		It("requests an actual LRP from the receptor", func() {
-			Expect(receptorServer.ReceivedRequests()).To(HaveLen(1))
+			Expect(receptorRequests()).To(HaveLen(3))
+		})
+	})
+
+	Describe("GET /workstations/:name/forward/:port", func() {
+		var (
+			teaServer     *ghttp.Server
+			listener      net.Listener
+			running       receptor.ActualLRPResponse
+			forwardErrors chan error
+		)
+
+		BeforeEach(func() {
+			teaServer = ghttp.NewServer()
+			teaURL, _ := url.Parse(teaServer.URL())
+			teaHostPort := strings.Split(teaURL.Host, ":")
+			teaPort, _ := strconv.Atoi(teaHostPort[1])
+
+			running = receptor.ActualLRPResponse{
+				Address: teaHostPort[0],
+				Ports:   []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(teaPort)}},
+				State:   receptor.ActualLRPStateRunning,
+			}
+			routeWorkstation("w1", running)
+			teaServer.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/tunnel/"+teaSecret+"/4000"),
+					func(w http.ResponseWriter, r *http.Request) {
+						upgrader := websocket.Upgrader{}
+						ws, err := upgrader.Upgrade(w, r, nil)
+						if err != nil {
+							panic(err)
+						}
+						defer ws.Close()
+						for {
+							messageType, m, err := ws.ReadMessage()
+							if err != nil {
+								return
+							}
+							ws.WriteMessage(messageType, m)
+						}
+					},
+				),
+			)
+
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			forwardErrors = make(chan error, 1)
+			go client.Forward("w1", 4000, listener, func(err error) { forwardErrors <- err })
+		})
+
+		AfterEach(func() {
+			listener.Close()
+			teaServer.Close()
+		})
+
+		It("tunnels local connections to the port inside the workstation", func() {
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("ping"))
+			Expect(err).NotTo(HaveOccurred())
+
+			response := make([]byte, 4)
+			_, err = io.ReadFull(conn, response)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(response)).To(Equal("ping"))
+		})
+
+		It("keeps accepting connections after a tunnel fails to open", func() {
+			routeWorkstation("w1")
+
+			failed, err := net.Dial("tcp", listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer failed.Close()
+			_, err = failed.Read(make([]byte, 1))
+			Expect(err).To(HaveOccurred())
+			Eventually(forwardErrors).Should(Receive(MatchError(ContainSubstring("forwarding"))))
+
+			routeWorkstation("w1", running)
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("ping"))
+			Expect(err).NotTo(HaveOccurred())
+
+			response := make([]byte, 4)
+			_, err = io.ReadFull(conn, response)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(response)).To(Equal("ping"))
+		})
+	})
+
+	Describe("/workstations/:name/files/*path", func() {
+		var teaServer *ghttp.Server
+
+		BeforeEach(func() {
+			teaServer = ghttp.NewServer()
+			teaURL, _ := url.Parse(teaServer.URL())
+			teaHostPort := strings.Split(teaURL.Host, ":")
+			teaPort, _ := strconv.Atoi(teaHostPort[1])
+
+			routeWorkstation("w1", receptor.ActualLRPResponse{
+				Address: teaHostPort[0],
+				Ports:   []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(teaPort)}},
+				State:   receptor.ActualLRPStateRunning,
+			})
+		})
+
+		AfterEach(func() {
+			teaServer.Close()
+		})
+
+		It("uploads files with CopyTo", func() {
+			teaServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/files/"+teaSecret+"/home/vcap/config.yml"),
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					Expect(string(body)).To(Equal("debug: true\n"))
+				},
+			))
+
+			err := client.CopyTo("w1", "/home/vcap/config.yml", strings.NewReader("debug: true\n"), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(teaServer.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("downloads directories as tarballs with CopyFrom", func() {
+			teaServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/files/"+teaSecret+"/home/vcap/project", "tar=true"),
+				ghttp.RespondWith(http.StatusOK, "tarball"),
+			))
+
+			content, err := client.CopyFrom("w1", "/home/vcap/project", true)
+			Expect(err).NotTo(HaveOccurred())
+			defer content.Close()
+
+			Expect(ioutil.ReadAll(content)).To(Equal([]byte("tarball")))
 		})
 	})
 })
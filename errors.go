@@ -13,6 +13,47 @@ const (
 	WorkstationNotFound  = "WorkstationNotFound"
 	InvalidWorkstation   = "InvalidWorkstation"
 	DuplicateWorkstation = "DuplicateWorkstation"
+	InvalidPort          = "InvalidPort"
+	InvalidManifest      = "InvalidManifest"
+	InsufficientCapacity = "InsufficientCapacity"
+
+	OperationNotFound   = "OperationNotFound"
+	OperationInProgress = "OperationInProgress"
+
+	KeyNotFound = "KeyNotFound"
+	InvalidKey  = "InvalidKey"
+
+	JobNotFound = "JobNotFound"
+	InvalidJob  = "InvalidJob"
+
+	ScheduleNotFound = "ScheduleNotFound"
+	InvalidSchedule  = "InvalidSchedule"
+
+	FileNotFound = "FileNotFound"
+	InvalidPath  = "InvalidPath"
+
+	RouteNotFound = "RouteNotFound"
+	InvalidRoute  = "InvalidRoute"
+
+	SnapshotNotFound     = "SnapshotNotFound"
+	BackupsNotConfigured = "BackupsNotConfigured"
+
+	TemplateNotFound = "TemplateNotFound"
+	InvalidTemplate  = "InvalidTemplate"
+
+	SecretNotFound       = "SecretNotFound"
+	InvalidSecret        = "InvalidSecret"
+	SecretsNotConfigured = "SecretsNotConfigured"
+
+	Forbidden     = "Forbidden"
+	QuotaExceeded = "QuotaExceeded"
+
+	LogsNotConfigured = "LogsNotConfigured"
+	InvalidUsageQuery = "InvalidUsageQuery"
+
+	WebhookNotFound    = "WebhookNotFound"
+	InvalidWebhook     = "InvalidWebhook"
+	DeadLetterNotFound = "DeadLetterNotFound"
 
 	InvalidJSON = "InvalidJSON"
 
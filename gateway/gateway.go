@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	dropbearPort    = 22000
+	workstationUser = "vcap"
+)
+
+var (
+	ErrInvalidLogin = errors.New("login must be user+workstation")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Gateway is an SSH server that lets users reach their workstations through a
+// single port. Users log in as user+workstation with any key from their
+// profile or from the workstation itself, and the session is proxied to the
+// workstation's dropbear. It is meant to be run as an ifrit member.
+type Gateway struct {
+	address            string
+	hostKey            ssh.Signer
+	workstationManager managers.WorkstationManager
+	userManager        managers.UserManager
+	admins             map[string]bool
+	handshakeTimeout   time.Duration
+	logger             lager.Logger
+}
+
+// New returns a Gateway listening on address. Workstations without an owner
+// can only be reached by the given admins. Clients that haven't logged in
+// within handshakeTimeout are disconnected.
+func New(address string, hostKey ssh.Signer, workstationManager managers.WorkstationManager, userManager managers.UserManager, admins []string, handshakeTimeout time.Duration, logger lager.Logger) *Gateway {
+	adminSet := map[string]bool{}
+	for _, admin := range admins {
+		adminSet[admin] = true
+	}
+
+	return &Gateway{
+		address:            address,
+		hostKey:            hostKey,
+		workstationManager: workstationManager,
+		userManager:        userManager,
+		admins:             adminSet,
+		handshakeTimeout:   handshakeTimeout,
+		logger:             logger.Session("gateway"),
+	}
+}
+
+// ParseLogin splits a user+workstation login. The workstation name cannot
+// contain a plus sign, so the last one separates both parts.
+func ParseLogin(login string) (user, workstation string, err error) {
+	i := strings.LastIndex(login, "+")
+	if i <= 0 || i == len(login)-1 {
+		return "", "", ErrInvalidLogin
+	}
+	return login[:i], login[i+1:], nil
+}
+
+func (g *Gateway) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	listener, err := net.Listen("tcp", g.address)
+	if err != nil {
+		return err
+	}
+
+	g.logger.Info("listening", lager.Data{"address": listener.Addr().String()})
+	close(ready)
+
+	go g.serve(listener)
+
+	<-signals
+	return listener.Close()
+}
+
+func (g *Gateway) serve(listener net.Listener) {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: g.authenticate,
+	}
+	config.AddHostKey(g.hostKey)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go g.handle(conn, config)
+	}
+}
+
+// authenticate accepts a key from the user's profile or from the
+// workstation's keys, as long as the workstation is owned by the user. Anyone
+// could have created a workstation without an owner, so only admins are let
+// into those.
+func (g *Gateway) authenticate(meta ssh.ConnMetadata, publicKey ssh.PublicKey) (*ssh.Permissions, error) {
+	user, name, err := ParseLogin(meta.User())
+	if err != nil {
+		return nil, err
+	}
+
+	log := g.logger.Session("authenticate", lager.Data{"user": user, "workstation_name": name, "remote": meta.RemoteAddr().String()})
+
+	key, err := models.ParseSSHKey(string(ssh.MarshalAuthorizedKey(publicKey)))
+	if err != nil {
+		return nil, err
+	}
+
+	workstation, err := g.workstationManager.Get(name)
+	if err != nil {
+		log.Info("workstation-not-found")
+		return nil, ErrUnauthorized
+	}
+
+	if workstation.Owner == "" && !g.admins[user] {
+		log.Info("ownerless-not-admin")
+		return nil, ErrUnauthorized
+	}
+
+	if workstation.Owner != "" && workstation.Owner != user {
+		log.Info("not-owner", lager.Data{"owner": workstation.Owner})
+		return nil, ErrUnauthorized
+	}
+
+	userKeys, err := g.userManager.ListKeys(user)
+	if err != nil {
+		log.Error("list-user-keys-failed", err)
+		return nil, ErrUnauthorized
+	}
+
+	workstationKeys, err := g.workstationManager.ListKeys(name)
+	if err != nil {
+		log.Error("list-workstation-keys-failed", err)
+		return nil, ErrUnauthorized
+	}
+
+	for _, authorized := range append(userKeys, workstationKeys...) {
+		if authorized.Fingerprint == key.Fingerprint {
+			log.Info("authenticated", lager.Data{"fingerprint": key.Fingerprint})
+			return &ssh.Permissions{}, nil
+		}
+	}
+
+	log.Info("unknown-key", lager.Data{"fingerprint": key.Fingerprint})
+	return nil, ErrUnauthorized
+}
+
+func (g *Gateway) handle(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(g.handshakeTimeout))
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		g.logger.Info("handshake-failed", lager.Data{"remote": conn.RemoteAddr().String(), "error": err.Error()})
+		return
+	}
+	defer serverConn.Close()
+	conn.SetDeadline(time.Time{})
+
+	_, name, _ := ParseLogin(serverConn.User())
+	log := g.logger.Session("session", lager.Data{"login": serverConn.User(), "remote": conn.RemoteAddr().String()})
+
+	clientConn, err := g.dial(name)
+	if err != nil {
+		log.Error("dial-failed", err)
+		return
+	}
+	defer clientConn.Close()
+
+	log.Info("connected")
+
+	go proxyRequests(requests, clientConn)
+	go proxyRequests(clientConn.requests, serverConn)
+	go proxyChannels(clientConn.channels, serverConn)
+	proxyChannels(channels, clientConn)
+
+	log.Info("disconnected")
+}
+
+type clientConn struct {
+	ssh.Conn
+	channels <-chan ssh.NewChannel
+	requests <-chan *ssh.Request
+}
+
+// dial connects to the workstation's dropbear as the gateway itself, after
+// authorizing the gateway's key on the workstation.
+func (g *Gateway) dial(name string) (*clientConn, error) {
+	gatewayKey, err := models.ParseSSHKey(string(ssh.MarshalAuthorizedKey(g.hostKey.PublicKey())))
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.workstationManager.PushKey(name, gatewayKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel, err := g.workstationManager.Tunnel(name, dropbearPort)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, channels, requests, err := ssh.NewClientConn(tunnel, name, &ssh.ClientConfig{
+		User:            workstationUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(g.hostKey)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+
+	return &clientConn{Conn: conn, channels: channels, requests: requests}, nil
+}
@@ -0,0 +1,343 @@
+package gateway_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/gorilla/websocket"
+	. "github.com/luan/teapot/gateway"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func newSigner() ssh.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	signer, err := ssh.NewSignerFromKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return signer
+}
+
+func authorizedKey(signer ssh.Signer) []byte {
+	return ssh.MarshalAuthorizedKey(signer.PublicKey())
+}
+
+func fingerprint(signer ssh.Signer) string {
+	key, err := models.ParseSSHKey(string(authorizedKey(signer)))
+	Expect(err).NotTo(HaveOccurred())
+	return key.Fingerprint
+}
+
+// serveDropbear stands in for the workstation's dropbear: it only lets the
+// gateway in and answers every exec request with the command it was given.
+func serveDropbear(listener net.Listener, gatewayKey ssh.PublicKey) {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() != "vcap" || string(key.Marshal()) != string(gatewayKey.Marshal()) {
+				return nil, ErrUnauthorized
+			}
+			return &ssh.Permissions{}, nil
+		},
+	}
+	config.AddHostKey(newSigner())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			_, channels, requests, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(requests)
+
+			for newChannel := range channels {
+				channel, channelRequests, _ := newChannel.Accept()
+				for request := range channelRequests {
+					if request.Type != "exec" {
+						request.Reply(false, nil)
+						continue
+					}
+					request.Reply(true, nil)
+					channel.Write([]byte("ran " + string(request.Payload[4:])))
+					channel.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+// tunnelTo relays a TEA tunnel websocket to address.
+func tunnelTo(address string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		conn, err := net.Dial("tcp", address)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			buffer := make([]byte, 4096)
+			for {
+				n, err := conn.Read(buffer)
+				if err != nil {
+					wsConn.Close()
+					return
+				}
+				wsConn.WriteMessage(websocket.BinaryMessage, buffer[:n])
+			}
+		}()
+
+		for {
+			_, message, err := wsConn.ReadMessage()
+			if err != nil {
+				conn.Close()
+				return
+			}
+			conn.Write(message)
+		}
+	}
+}
+
+var _ = Describe("Gateway", func() {
+	var (
+		teaServer          *ghttp.Server
+		dropbear           net.Listener
+		fakeReceptorClient *fake_receptor.FakeClient
+		userManager        managers.UserManager
+		hostKey            ssh.Signer
+		aliceKey           ssh.Signer
+		pushedKeys         chan string
+		address            string
+		process            ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+
+		hostKey = newSigner()
+		aliceKey = newSigner()
+
+		var err error
+		dropbear, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go serveDropbear(dropbear, hostKey.PublicKey())
+
+		teaServer = ghttp.NewServer()
+		teaServer.AllowUnhandledRequests = true
+		pushedKeys = make(chan string, 10)
+		teaServer.RouteToHandler("POST", "/add-key/s3cret", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			pushedKeys <- string(body)
+			w.WriteHeader(http.StatusCreated)
+		})
+		teaServer.RouteToHandler("GET", "/tunnel/s3cret/22000", tunnelTo(dropbear.Addr().String()))
+
+		teaURL, err := url.Parse(teaServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		_, teaPort, _ := net.SplitHostPort(teaURL.Host)
+		hostPort, _ := strconv.Atoi(teaPort)
+
+		fakeRouteProvider := &model_fakes.FakeRouteProvider{}
+		fakeRouteProvider.SSHRouteReturns(teaServer.URL())
+
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			Annotation:  `{"owner":"alice"}`,
+		}, nil)
+		fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+			ProcessGuid: "w1",
+			State:       receptor.ActualLRPStateRunning,
+			Address:     "127.0.0.1",
+			Ports:       []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(hostPort)}},
+		}}, nil)
+
+		dataStore, _ := store.NewStore("")
+		userManager = managers.NewUserManager(dataStore)
+		_, err = userManager.AddKey("alice", authorizedKey(aliceKey))
+		Expect(err).NotTo(HaveOccurred())
+
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+
+		freePort, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		address = freePort.Addr().String()
+		freePort.Close()
+
+		process = ifrit.Invoke(New(address, hostKey, workstationManager, userManager, []string{"admin"}, time.Second, logger))
+	})
+
+	AfterEach(func() {
+		ginkgomon.Interrupt(process)
+		dropbear.Close()
+		teaServer.Close()
+	})
+
+	dial := func(login string, key ssh.Signer) (*ssh.Client, error) {
+		return ssh.Dial("tcp", address, &ssh.ClientConfig{
+			User:            login,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(key)},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+	}
+
+	Describe("ParseLogin", func() {
+		It("splits the user from the workstation", func() {
+			user, workstation, err := ParseLogin("alice+w1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user).To(Equal("alice"))
+			Expect(workstation).To(Equal("w1"))
+		})
+
+		It("allows plus signs in the user name", func() {
+			user, workstation, _ := ParseLogin("alice+ssh+w1")
+			Expect(user).To(Equal("alice+ssh"))
+			Expect(workstation).To(Equal("w1"))
+		})
+
+		It("rejects logins without a workstation", func() {
+			_, _, err := ParseLogin("alice")
+			Expect(err).To(Equal(ErrInvalidLogin))
+			_, _, err = ParseLogin("alice+")
+			Expect(err).To(Equal(ErrInvalidLogin))
+			_, _, err = ParseLogin("+w1")
+			Expect(err).To(Equal(ErrInvalidLogin))
+		})
+	})
+
+	Context("when the owner logs in with a profile key", func() {
+		It("proxies the session to the workstation's dropbear", func() {
+			client, err := dial("alice+w1", aliceKey)
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			session, err := client.NewSession()
+			Expect(err).NotTo(HaveOccurred())
+			defer session.Close()
+
+			output, err := session.Output("whoami")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("ran whoami"))
+		})
+
+		It("authorizes the gateway's key on the workstation", func() {
+			client, err := dial("alice+w1", aliceKey)
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+
+			Eventually(pushedKeys).Should(Receive(Equal(strings.TrimSpace(string(authorizedKey(hostKey))))))
+		})
+	})
+
+	Context("when a workstation key is used", func() {
+		It("lets the owner in", func() {
+			workstationKey := newSigner()
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+				ProcessGuid: "w1",
+				Annotation:  `{"owner":"alice","keys":[{"fingerprint":"` + fingerprint(workstationKey) + `"}]}`,
+			}, nil)
+
+			client, err := dial("alice+w1", workstationKey)
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+		})
+	})
+
+	Context("when the key is unknown", func() {
+		It("rejects the connection", func() {
+			_, err := dial("alice+w1", newSigner())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the user does not own the workstation", func() {
+		It("rejects the connection, even with a registered key", func() {
+			bobKey := newSigner()
+			userManager.AddKey("bob", authorizedKey(bobKey))
+
+			_, err := dial("bob+w1", bobKey)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the workstation has no owner", func() {
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "w1"}, nil)
+		})
+
+		It("rejects users, even with a registered key", func() {
+			bobKey := newSigner()
+			userManager.AddKey("bob", authorizedKey(bobKey))
+
+			_, err := dial("bob+w1", bobKey)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("lets admins in with their profile keys", func() {
+			adminKey := newSigner()
+			userManager.AddKey("admin", authorizedKey(adminKey))
+
+			client, err := dial("admin+w1", adminKey)
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+		})
+	})
+
+	Context("when the workstation doesn't exist", func() {
+		It("rejects the connection", func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, nil)
+
+			_, err := dial("alice+w1", aliceKey)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the client doesn't complete the handshake", func() {
+		It("disconnects it", func() {
+			conn, err := net.Dial("tcp", address)
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, err = ioutil.ReadAll(conn)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the login has no workstation", func() {
+		It("rejects the connection", func() {
+			_, err := dial("alice", aliceKey)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
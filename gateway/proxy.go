@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func proxyRequests(requests <-chan *ssh.Request, conn ssh.Conn) {
+	for request := range requests {
+		ok, payload, err := conn.SendRequest(request.Type, request.WantReply, request.Payload)
+		if request.WantReply {
+			request.Reply(ok && err == nil, payload)
+		}
+	}
+}
+
+func proxyChannels(channels <-chan ssh.NewChannel, conn ssh.Conn) {
+	for newChannel := range channels {
+		go proxyChannel(newChannel, conn)
+	}
+}
+
+// proxyChannel opens the same channel on conn and copies data and requests
+// both ways until either side closes it.
+func proxyChannel(newChannel ssh.NewChannel, conn ssh.Conn) {
+	target, targetRequests, err := conn.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
+	if err != nil {
+		if openErr, ok := err.(*ssh.OpenChannelError); ok {
+			newChannel.Reject(openErr.Reason, openErr.Message)
+		} else {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+
+	source, sourceRequests, err := newChannel.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(target, source)
+		target.CloseWrite()
+	}()
+	go io.Copy(target.Stderr(), source.Stderr())
+	go func() {
+		proxyChannelRequests(sourceRequests, target)
+		target.Close()
+	}()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		io.Copy(source, target)
+		source.CloseWrite()
+		wg.Done()
+	}()
+	go func() {
+		io.Copy(source.Stderr(), target.Stderr())
+		wg.Done()
+	}()
+
+	proxyChannelRequests(targetRequests, source)
+	wg.Wait()
+	source.Close()
+}
+
+func proxyChannelRequests(requests <-chan *ssh.Request, channel ssh.Channel) {
+	for request := range requests {
+		ok, err := channel.SendRequest(request.Type, request.WantReply, request.Payload)
+		if request.WantReply {
+			request.Reply(ok && err == nil, nil)
+		}
+	}
+}
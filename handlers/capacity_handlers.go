@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+type CapacityHandler struct {
+	manager managers.CapacityManager
+	logger  lager.Logger
+}
+
+func NewCapacityHandler(manager managers.CapacityManager, logger lager.Logger) *CapacityHandler {
+	return &CapacityHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// Get responds with the free capacity of the Diego cells of each stack.
+func (h *CapacityHandler) Get(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("get-capacity")
+
+	stacks, err := h.manager.Stacks()
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, stacks)
+}
+
+func writeInsufficientCapacityResponse(w http.ResponseWriter, err models.ErrInsufficientCapacity) {
+	writeJSONResponse(w, http.StatusServiceUnavailable, teapot.Error{
+		Type:    teapot.InsufficientCapacity,
+		Message: err.Error(),
+	})
+}
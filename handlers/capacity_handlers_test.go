@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CapacityHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *CapacityHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		handler = NewCapacityHandler(managers.NewCapacityManager(fakeReceptorClient, false), logger)
+
+		fakeReceptorClient.CellsReturns([]receptor.CellResponse{
+			{CellID: "cell-2", Stack: "lucid64", Capacity: receptor.CellCapacity{MemoryMB: 8192, DiskMB: 16384, Containers: 100}},
+			{CellID: "cell-1", Stack: "lucid64", Capacity: receptor.CellCapacity{MemoryMB: 8192, DiskMB: 16384, Containers: 100}},
+			{CellID: "cell-3", Stack: "trusty64", Capacity: receptor.CellCapacity{MemoryMB: 4096, DiskMB: 8192, Containers: 50}},
+		}, nil)
+		fakeReceptorClient.DesiredLRPsReturns([]receptor.DesiredLRPResponse{
+			{ProcessGuid: "w1", MemoryMB: 4096, DiskMB: 8192},
+			{ProcessGuid: "w2", MemoryMB: 1024, DiskMB: 1024},
+		}, nil)
+		fakeReceptorClient.ActualLRPsReturns([]receptor.ActualLRPResponse{
+			{ProcessGuid: "w1", CellID: "cell-1", State: receptor.ActualLRPStateRunning},
+			{ProcessGuid: "w2", CellID: "cell-1", State: receptor.ActualLRPStateClaimed},
+			{ProcessGuid: "w2", State: receptor.ActualLRPStateUnclaimed},
+		}, nil)
+		fakeReceptorClient.TasksReturns([]receptor.TaskResponse{
+			{TaskGuid: "t1", CellID: "cell-3", State: receptor.TaskStateRunning, MemoryMB: 256, DiskMB: 512},
+			{TaskGuid: "t2", CellID: "cell-3", State: receptor.TaskStateCompleted, MemoryMB: 256, DiskMB: 512},
+		}, nil)
+	})
+
+	Describe("Get", func() {
+		JustBeforeEach(func() {
+			handler.Get(responseRecorder, newTestRequest(""))
+		})
+
+		It("responds with the free capacity of each stack", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var capacity []teapot.CapacityResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &capacity)
+			Expect(capacity).To(Equal([]teapot.CapacityResponse{
+				{
+					Stack:       "lucid64",
+					Cells:       2,
+					Free:        teapot.CellResources{MemoryMB: 11264, DiskMB: 23552, Containers: 198},
+					LargestFree: teapot.CellResources{MemoryMB: 8192, DiskMB: 16384, Containers: 100},
+				},
+				{
+					Stack:       "trusty64",
+					Cells:       1,
+					Free:        teapot.CellResources{MemoryMB: 3840, DiskMB: 7680, Containers: 49},
+					LargestFree: teapot.CellResources{MemoryMB: 3840, DiskMB: 7680, Containers: 49},
+				},
+			}))
+		})
+
+		Context("when the cells can't be fetched", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.CellsReturns(nil, errors.New("boom"))
+			})
+
+			It("fails with a 500 INTERNAL SERVER ERROR", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
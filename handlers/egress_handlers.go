@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type EgressHandler struct {
+	policyManager      managers.EgressPolicyManager
+	workstationManager managers.WorkstationManager
+	logger             lager.Logger
+}
+
+func NewEgressHandler(policyManager managers.EgressPolicyManager, workstationManager managers.WorkstationManager, logger lager.Logger) *EgressHandler {
+	return &EgressHandler{
+		policyManager:      policyManager,
+		workstationManager: workstationManager,
+		logger:             logger,
+	}
+}
+
+func (h *EgressHandler) List(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("list-egress-policies")
+
+	policies, err := h.policyManager.List()
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, policies)
+}
+
+// Get responds with the egress rules the workstation was desired with, which
+// are the ones enforced even if its policy changed since.
+func (h *EgressHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("get-workstation-egress", lager.Data{
+		"Name": name,
+	})
+
+	workstation, err := h.workstationManager.Get(name)
+	if err != nil {
+		if _, ok := err.(models.ErrNotFound); ok {
+			log.Info("not-found")
+			writeWorkstationNotFoundResponse(w, name)
+			return
+		}
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, teapot.EgressPolicyResponse{
+		Name:  workstation.EgressPolicy,
+		Rules: workstation.EgressRules,
+	})
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const egressPoliciesJSON = `[{
+	"name": "internet-only",
+	"rules": [{"protocol": "tcp", "destinations": ["0.0.0.0-9.255.255.255", "11.0.0.0-255.255.255.255"], "ports": [80, 443]}]
+}]`
+
+var _ = Describe("EgressHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *EgressHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		policiesFile       string
+		req                *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+
+		file, err := ioutil.TempFile("", "egress-policies")
+		Expect(err).NotTo(HaveOccurred())
+		file.WriteString(egressPoliciesJSON)
+		file.Close()
+		policiesFile = file.Name()
+
+		egressPolicyManager, err := managers.NewEgressPolicyManager(policiesFile, "internet-only")
+		Expect(err).NotTo(HaveOccurred())
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		dataStore, _ := store.NewStore("")
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewEgressHandler(egressPolicyManager, workstationManager, logger)
+	})
+
+	AfterEach(func() {
+		os.Remove(policiesFile)
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			handler.List(responseRecorder, newTestRequest(""))
+		})
+
+		It("responds with the configured policies and the open one", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var policies []teapot.EgressPolicyResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &policies)
+			Expect(policies).To(HaveLen(2))
+			Expect(policies[0].Name).To(Equal("internet-only"))
+			Expect(policies[0].Rules[0].Ports).To(Equal([]uint16{80, 443}))
+			Expect(policies[1].Name).To(Equal(models.OpenEgressPolicy))
+		})
+	})
+
+	Describe("Get", func() {
+		Context("when the workstation exists", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+					ProcessGuid: "w1",
+					Annotation:  `{"egress_policy":"internet-only"}`,
+					EgressRules: []diego_models.SecurityGroupRule{
+						{Protocol: diego_models.TCPProtocol, Destinations: []string{"10.0.0.0/8"}, Ports: []uint16{5432}},
+					},
+				}, nil)
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=w1"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("responds with the rules the workstation was desired with", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var egress teapot.EgressPolicyResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &egress)
+				Expect(egress).To(Equal(teapot.EgressPolicyResponse{
+					Name: "internet-only",
+					Rules: []teapot.EgressRule{
+						{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}, Ports: []uint16{5432}},
+					},
+				}))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, errors.New("not found"))
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=nope"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,6 @@
+package handlers
+
+// WithUser authenticates a request as user, as BasicAuthWrap would.
+var WithUser = withUser
+
+var RequestUser = requestUser
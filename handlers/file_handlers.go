@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type FileHandler struct {
+	manager managers.WorkstationManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+func NewFileHandler(manager managers.WorkstationManager, admins []string, logger lager.Logger) *FileHandler {
+	return &FileHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	path := filePath(r, name)
+	archive := r.URL.Query().Get("tar") == "true"
+	log := h.logger.Session("download", lager.Data{
+		"Name":    name,
+		"Path":    path,
+		"Archive": archive,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	content, err := h.manager.Download(name, path, archive)
+	if err != nil {
+		h.writeFileError(w, log, err)
+		return
+	}
+	defer content.Close()
+
+	if archive {
+		w.Header().Set("Content-Type", "application/x-tar")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	n, err := io.Copy(w, content)
+	if err != nil {
+		log.Error("stream-failed", err, lager.Data{"bytes": n})
+		return
+	}
+
+	log.Info("downloaded", lager.Data{"bytes": n})
+}
+
+func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	path := filePath(r, name)
+	archive := r.URL.Query().Get("tar") == "true"
+	log := h.logger.Session("upload", lager.Data{
+		"Name":    name,
+		"Path":    path,
+		"Archive": archive,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	err := h.manager.Upload(name, path, archive, r.Body, r.ContentLength)
+	if err != nil {
+		h.writeFileError(w, log, err)
+		return
+	}
+
+	log.Info("uploaded")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// filePath returns the path inside the workstation, which is whatever follows
+// the files route.
+func filePath(r *http.Request, name string) string {
+	prefix := "/workstations/" + name + "/files"
+	return strings.TrimPrefix(r.URL.Path, prefix)
+}
+
+func (h *FileHandler) writeFileError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Error("invalid-path", err)
+		writeBadRequestResponse(w, teapot.InvalidPath, err)
+	case models.ErrNotRunning:
+		log.Info("not-running")
+		writeNotRunningResponse(w, t.Name)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+		if t.Resource == "workstation" {
+			writeWorkstationNotFoundResponse(w, t.Name)
+		} else {
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.FileNotFound,
+				Message: fmt.Sprintf("File '%s' not found", t.Name),
+			})
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("FileHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *FileHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		teaServer          *ghttp.Server
+		req                *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		dataStore, _ := store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewFileHandler(manager, []string{"admin"}, logger)
+
+		teaServer = ghttp.NewServer()
+		teaURL, _ := url.Parse(teaServer.URL())
+		teaHostPort := strings.Split(teaURL.Host, ":")
+		teaPort, _ := strconv.Atoi(teaHostPort[1])
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "w1", Annotation: `{"owner":"alice"}`}, nil)
+		fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+			ProcessGuid: "w1",
+			Address:     teaHostPort[0],
+			Ports:       []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(teaPort)}},
+			State:       receptor.ActualLRPStateRunning,
+		}}, nil)
+	})
+
+	AfterEach(func() {
+		teaServer.Close()
+	})
+
+	newFileRequest := func(path string, body string) *http.Request {
+		req := newTestRequest(body)
+		req.URL, _ = url.Parse("/workstations/w1/files" + path)
+		req.URL.RawQuery += "&:name=w1"
+		return req
+	}
+
+	Describe("Download", func() {
+		Context("when the file exists", func() {
+			BeforeEach(func() {
+				teaServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/files/something/etc/hosts"),
+					ghttp.RespondWith(http.StatusOK, "127.0.0.1 localhost\n"),
+				))
+				handler.Download(responseRecorder, newFileRequest("/etc/hosts", ""))
+			})
+
+			It("streams the file from the TEA", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				Expect(responseRecorder.Header().Get("Content-Type")).To(Equal("application/octet-stream"))
+				Expect(responseRecorder.Body.String()).To(Equal("127.0.0.1 localhost\n"))
+			})
+		})
+
+		Context("when a tarball is requested", func() {
+			BeforeEach(func() {
+				teaServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/files/something/home/vcap/project", "tar=true"),
+					ghttp.RespondWith(http.StatusOK, "tarball"),
+				))
+				req = newFileRequest("/home/vcap/project?tar=true", "")
+				handler.Download(responseRecorder, req)
+			})
+
+			It("asks the TEA for a tarball", func() {
+				Expect(teaServer.ReceivedRequests()).To(HaveLen(1))
+				Expect(responseRecorder.Header().Get("Content-Type")).To(Equal("application/x-tar"))
+			})
+		})
+
+		Context("when the file doesn't exist", func() {
+			BeforeEach(func() {
+				teaServer.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+				handler.Download(responseRecorder, newFileRequest("/nope", ""))
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.FileNotFound))
+			})
+		})
+
+		Context("when the workstation is not RUNNING", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "w1",
+					State:       receptor.ActualLRPStateClaimed,
+				}}, nil)
+				handler.Download(responseRecorder, newFileRequest("/etc/hosts", ""))
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(teaServer.ReceivedRequests()).To(BeEmpty())
+			})
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				handler.Download(responseRecorder, WithUser(newFileRequest("/etc/hosts", ""), "bob"))
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(teaServer.ReceivedRequests()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Upload", func() {
+		BeforeEach(func() {
+			teaServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/files/something/home/vcap/data.csv"),
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					Expect(string(body)).To(Equal("a,b\n1,2\n"))
+					w.WriteHeader(http.StatusCreated)
+				},
+			))
+			handler.Upload(responseRecorder, newFileRequest("/home/vcap/data.csv", "a,b\n1,2\n"))
+		})
+
+		It("streams the content to the TEA", func() {
+			Expect(teaServer.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("responds with 204 NO CONTENT", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+		})
+	})
+})
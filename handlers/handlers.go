@@ -1,33 +1,155 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/bmizerany/pat"
 	"github.com/luan/teapot"
+	"github.com/luan/teapot/logs"
 	"github.com/luan/teapot/managers"
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/rata"
 )
 
-func New(workstationManager managers.WorkstationManager, logger lager.Logger, username, password string) http.Handler {
-	workstationHandler := NewWorkstationHandler(workstationManager, logger)
+// Dependencies are the managers and settings the API's handlers are built
+// from. Users maps the names allowed in through basic auth to their
+// passwords, leaving the API open when empty.
+type Dependencies struct {
+	WorkstationManager  managers.WorkstationManager
+	JobManager          managers.JobManager
+	UserManager         managers.UserManager
+	BackupManager       managers.BackupManager
+	TemplateManager     managers.TemplateManager
+	OperationManager    managers.OperationManager
+	SecretManager       managers.SecretManager
+	CrashManager        managers.CrashManager
+	EgressPolicyManager managers.EgressPolicyManager
+	QuotaManager        managers.QuotaManager
+	CapacityManager     managers.CapacityManager
+	WebhookManager      managers.WebhookManager
+	UsageManager        managers.UsageManager
+	LogSource           logs.LogSource
+
+	PrivilegePolicy string
+	Admins          []string
+	Users           map[string]string
+}
+
+func New(d Dependencies, logger lager.Logger) http.Handler {
+	workstationHandler := NewWorkstationHandler(d.WorkstationManager, d.BackupManager, d.TemplateManager, d.OperationManager, d.SecretManager, d.CrashManager, d.PrivilegePolicy, d.Admins, logger)
+	jobHandler := NewJobHandler(d.JobManager, d.WorkstationManager, d.PrivilegePolicy, d.Admins, logger)
+	scheduleHandler := NewScheduleHandler(d.WorkstationManager, d.Admins, logger)
+	fileHandler := NewFileHandler(d.WorkstationManager, d.Admins, logger)
+	routeHandler := NewRouteHandler(d.WorkstationManager, d.Admins, logger)
+	snapshotHandler := NewSnapshotHandler(d.BackupManager, d.WorkstationManager, d.Admins, logger)
+	templateHandler := NewTemplateHandler(d.TemplateManager, d.Admins, logger)
+	userHandler := NewUserHandler(d.UserManager, logger)
+	operationHandler := NewOperationHandler(d.OperationManager, logger)
+	secretHandler := NewSecretHandler(d.SecretManager, logger)
+	egressHandler := NewEgressHandler(d.EgressPolicyManager, d.WorkstationManager, logger)
+	quotaHandler := NewQuotaHandler(d.QuotaManager, d.WorkstationManager, logger)
+	capacityHandler := NewCapacityHandler(d.CapacityManager, logger)
+	logHandler := NewLogHandler(d.LogSource, d.WorkstationManager, d.Admins, logger)
+	webhookHandler := NewWebhookHandler(d.WebhookManager, d.Admins, logger)
+	usageHandler := NewUsageHandler(d.UsageManager, d.Admins, logger)
 
 	actions := rata.Handlers{
 		// Workstations
 		teapot.CreateWorkstationRoute:   route(workstationHandler.Create),
+		teapot.GetWorkstationRoute:      route(workstationHandler.Get),
 		teapot.DeleteWorkstationRoute:   route(workstationHandler.Delete),
 		teapot.AttachWorkstationRoute:   route(workstationHandler.Attach),
 		teapot.ListWorkstationsRoute:    route(workstationHandler.List),
 		teapot.AddKeyToWorkstationRoute: route(workstationHandler.AddKey),
+		teapot.ExecWorkstationRoute:     route(jobHandler.Exec),
+		teapot.ForwardWorkstationRoute:  route(workstationHandler.Forward),
+		teapot.CloneWorkstationRoute:    route(workstationHandler.Clone),
+		teapot.ApplyWorkstationsRoute:   route(workstationHandler.Apply),
+		teapot.UpdateWorkstationRoute:   route(workstationHandler.Update),
+		teapot.ApprovePrivilegeRoute:    route(workstationHandler.ApprovePrivilege),
+		teapot.RestartWorkstationRoute:  route(workstationHandler.Restart),
+		teapot.StreamLogsRoute:          route(logHandler.Stream),
+
+		// Operations
+		teapot.GetOperationRoute: route(operationHandler.Get),
+
+		// Keys
+		teapot.ListKeysRoute:  route(workstationHandler.ListKeys),
+		teapot.GetKeyRoute:    route(workstationHandler.GetKey),
+		teapot.RemoveKeyRoute: route(workstationHandler.RemoveKey),
+
+		// Schedules
+		teapot.ListSchedulesRoute:  route(scheduleHandler.List),
+		teapot.CreateScheduleRoute: route(scheduleHandler.Create),
+		teapot.UpdateScheduleRoute: route(scheduleHandler.Update),
+		teapot.DeleteScheduleRoute: route(scheduleHandler.Delete),
+
+		// Files
+		teapot.DownloadFileRoute: route(fileHandler.Download),
+		teapot.UploadFileRoute:   route(fileHandler.Upload),
+
+		// Routes
+		teapot.ListRoutesRoute:  route(routeHandler.List),
+		teapot.CreateRouteRoute: route(routeHandler.Create),
+		teapot.DeleteRouteRoute: route(routeHandler.Delete),
+
+		// Snapshots
+		teapot.ListSnapshotsRoute:  route(snapshotHandler.List),
+		teapot.CreateSnapshotRoute: route(snapshotHandler.Create),
+		teapot.GetSnapshotRoute:    route(snapshotHandler.Get),
+		teapot.DeleteSnapshotRoute: route(snapshotHandler.Delete),
+
+		// Templates
+		teapot.ListTemplatesRoute:  route(templateHandler.List),
+		teapot.GetTemplateRoute:    route(templateHandler.Get),
+		teapot.SaveTemplateRoute:   route(templateHandler.Save),
+		teapot.DeleteTemplateRoute: route(templateHandler.Delete),
+
+		// Egress policies
+		teapot.ListEgressPoliciesRoute:   route(egressHandler.List),
+		teapot.GetWorkstationEgressRoute: route(egressHandler.Get),
+
+		// Quotas
+		teapot.GetUserQuotasRoute: route(quotaHandler.Get),
+
+		// Capacity
+		teapot.GetCapacityRoute: route(capacityHandler.Get),
+
+		// Usage
+		teapot.GetUsageRoute: route(usageHandler.Get),
+
+		// Users
+		teapot.ListUserKeysRoute:  route(userHandler.ListKeys),
+		teapot.AddUserKeyRoute:    route(userHandler.AddKey),
+		teapot.RemoveUserKeyRoute: route(userHandler.RemoveKey),
+		teapot.ListSecretsRoute:   route(secretHandler.List),
+		teapot.SaveSecretRoute:    route(secretHandler.Save),
+		teapot.DeleteSecretRoute:  route(secretHandler.Delete),
+
+		// Webhooks
+		teapot.CreateWebhookRoute:         route(webhookHandler.Create),
+		teapot.ListWebhooksRoute:          route(webhookHandler.List),
+		teapot.GetWebhookRoute:            route(webhookHandler.Get),
+		teapot.DeleteWebhookRoute:         route(webhookHandler.Delete),
+		teapot.ListWebhookDeliveriesRoute: route(webhookHandler.Deliveries),
+		teapot.ListDeadLettersRoute:       route(webhookHandler.DeadLetters),
+		teapot.RedeliverRoute:             route(webhookHandler.Redeliver),
+
+		// Jobs
+		teapot.CreateJobRoute: route(jobHandler.Create),
+		teapot.GetJobRoute:    route(jobHandler.Get),
+		teapot.CancelJobRoute: route(jobHandler.Cancel),
 	}
 
-	handler, err := rata.NewRouter(teapot.Routes, actions)
+	handler, err := newRouter(teapot.Routes, actions)
 	if err != nil {
 		panic("unable to create router: " + err.Error())
 	}
 
-	if username != "" {
-		handler = BasicAuthWrap(handler, username, password)
+	if len(d.Users) > 0 {
+		handler = BasicAuthWrap(handler, d.Users)
 	}
 
 	handler = LogWrap(handler, logger)
@@ -35,6 +157,38 @@ func New(workstationManager managers.WorkstationManager, logger lager.Logger, us
 	return handler
 }
 
+// newRouter works like rata.NewRouter, except that PATCH routes, which this
+// version of rata rejects, are routed by pat directly.
+func newRouter(routes rata.Routes, actions rata.Handlers) (http.Handler, error) {
+	var rataRoutes rata.Routes
+	patches := pat.New()
+	for _, r := range routes {
+		if strings.ToUpper(r.Method) != "PATCH" {
+			rataRoutes = append(rataRoutes, r)
+			continue
+		}
+
+		handler, ok := actions[r.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing handler %s", r.Name)
+		}
+		patches.Add("PATCH", r.Path, handler)
+	}
+
+	router, err := rata.NewRouter(rataRoutes, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			patches.ServeHTTP(w, r)
+		} else {
+			router.ServeHTTP(w, r)
+		}
+	}), nil
+}
+
 func route(f func(w http.ResponseWriter, r *http.Request)) http.Handler {
 	return http.HandlerFunc(f)
 }
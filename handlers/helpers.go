@@ -6,8 +6,52 @@ import (
 	"strconv"
 
 	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
 )
 
+// requestUser returns the user the request authenticated as, or an empty
+// string when authentication is disabled, in which case per-user actions are
+// refused since anybody could claim to be anyone.
+func requestUser(r *http.Request) string {
+	user, _ := r.Context().Value(userKey{}).(string)
+	return user
+}
+
+// canAccess tells whether user may use or change the workstation: admins may
+// use any, other users only the ones they own. Anybody may when
+// authentication is disabled, since there are no users to tell apart then.
+func canAccess(user string, admins map[string]bool, workstation models.Workstation) bool {
+	return user == "" || admins[user] || (workstation.Owner != "" && workstation.Owner == user)
+}
+
+// authorizeWorkstation responds with 404 NOT FOUND or 403 FORBIDDEN and
+// returns false unless the workstation exists and the request's user may
+// access it.
+func authorizeWorkstation(w http.ResponseWriter, r *http.Request, log lager.Logger, manager managers.WorkstationManager, admins map[string]bool, name string) bool {
+	workstation, err := manager.Get(name)
+	if err != nil {
+		if _, ok := err.(models.ErrNotFound); ok {
+			log.Info("not-found", lager.Data{"workstation_name": name})
+			writeWorkstationNotFoundResponse(w, name)
+			return false
+		}
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return false
+	}
+
+	user := requestUser(r)
+	if !canAccess(user, admins, workstation) {
+		log.Info("forbidden", lager.Data{"user": user, "workstation_name": name})
+		writeForbiddenResponse(w)
+		return false
+	}
+
+	return true
+}
+
 func writeUnknownErrorResponse(w http.ResponseWriter, err error) {
 	writeJSONResponse(w, http.StatusInternalServerError, teapot.Error{
 		Type:    teapot.UnknownError,
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type JobHandler struct {
+	manager            managers.JobManager
+	workstationManager managers.WorkstationManager
+	privilegePolicy    string
+	admins             map[string]bool
+	logger             lager.Logger
+}
+
+// NewJobHandler returns a JobHandler that runs jobs privileged as the
+// privilegePolicy allows workstations to be. Jobs can't wait for approval, so
+// under the admin-approved policy only admins run privileged ones. Commands
+// are only run in a workstation for admins and its owner.
+func NewJobHandler(manager managers.JobManager, workstationManager managers.WorkstationManager, privilegePolicy string, admins []string, logger lager.Logger) *JobHandler {
+	return &JobHandler{
+		manager:            manager,
+		workstationManager: workstationManager,
+		privilegePolicy:    privilegePolicy,
+		admins:             adminSet(admins),
+		logger:             logger,
+	}
+}
+
+func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("create-job")
+	jobRequest := teapot.JobCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&jobRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	job := models.NewJob(jobRequest)
+	mode, err := models.PrivilegeMode(h.privilegePolicy, job.Privileged, h.admins[requestUser(r)])
+	if err != nil {
+		h.writeJobError(w, log, err)
+		return
+	}
+	if mode == models.PendingApprovalMode {
+		log.Info("forbidden", lager.Data{"user": requestUser(r), "privileged": true})
+		writeForbiddenResponse(w)
+		return
+	}
+	job.Privileged = mode == models.PrivilegedMode
+
+	job, err = h.manager.Create(job)
+	if err != nil {
+		h.writeJobError(w, log, err)
+		return
+	}
+
+	log.Info("created", lager.Data{"job_id": job.ID})
+
+	writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+func (h *JobHandler) Exec(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("exec", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.workstationManager, h.admins, name) {
+		return
+	}
+	execRequest := teapot.WorkstationExecRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&execRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	job, err := h.manager.Exec(name, execRequest.Command)
+	if err != nil {
+		h.writeJobError(w, log, err)
+		return
+	}
+
+	log.Info("created", lager.Data{"workstation_name": name, "job_id": job.ID})
+
+	writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("get-job", lager.Data{
+		"ID": id,
+	})
+
+	job, err := h.manager.Get(id)
+	if err != nil {
+		h.writeJobError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, job)
+}
+
+func (h *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("cancel-job", lager.Data{
+		"ID": id,
+	})
+
+	err := h.manager.Cancel(id)
+	if err != nil {
+		h.writeJobError(w, log, err)
+		return
+	}
+
+	log.Info("cancelled", lager.Data{"job_id": id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *JobHandler) writeJobError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Error("invalid-job", err)
+		writeBadRequestResponse(w, teapot.InvalidJob, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+		if t.Resource == "workstation" {
+			writeWorkstationNotFoundResponse(w, t.Name)
+		} else {
+			writeJobNotFoundResponse(w, t.Name)
+		}
+	}
+}
+
+func writeJobNotFoundResponse(w http.ResponseWriter, id string) {
+	writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+		Type:    teapot.JobNotFound,
+		Message: fmt.Sprintf("Job with id '%s' not found", id),
+	})
+}
@@ -0,0 +1,296 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *JobHandler
+		jobManager         managers.JobManager
+		workstationManager managers.WorkstationManager
+		fakeReceptorClient *fake_receptor.FakeClient
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		jobManager = managers.NewJobManager(fakeReceptorClient, models.DefaultBootstrap(), egressPolicyManager, logger)
+		dataStore, _ := store.NewStore("")
+		quotaManager, _ := managers.NewQuotaManager("")
+		workstationManager = managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, managers.NewCapacityManager(fakeReceptorClient, false), "secret", logger)
+		handler = NewJobHandler(jobManager, workstationManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+	})
+
+	Describe("Create", func() {
+		Context("when everything succeeds", func() {
+			BeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(teapot.JobCreateRequest{
+					DockerImage: "docker:///golang",
+					Command:     "go test ./...",
+					MemoryMB:    512,
+				}))
+			})
+
+			It("responds with 202 ACCEPTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+			})
+
+			It("creates a task with the requested image and resources", func() {
+				Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(1))
+				task := fakeReceptorClient.CreateTaskArgsForCall(0)
+				Expect(task.RootFSPath).To(Equal("docker:///golang"))
+				Expect(task.MemoryMB).To(Equal(512))
+				Expect(task.Domain).To(Equal("tiego"))
+				Expect(task.Privileged).To(BeTrue())
+
+				action := task.Action.(*diego_models.RunAction)
+				Expect(action.Args[len(action.Args)-1]).To(Equal("go test ./..."))
+			})
+
+			It("responds with the job id", func() {
+				var job teapot.JobResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &job)
+				Expect(job.ID).To(Equal(fakeReceptorClient.CreateTaskArgsForCall(0).TaskGuid))
+				Expect(job.State).To(Equal(receptor.TaskStatePending))
+			})
+		})
+
+		Context("when the privilege policy is unprivileged", func() {
+			BeforeEach(func() {
+				handler = NewJobHandler(jobManager, workstationManager, models.UnprivilegedPolicy, []string{"admin"}, logger)
+			})
+
+			It("runs jobs unprivileged", func() {
+				handler.Create(responseRecorder, newTestRequest(teapot.JobCreateRequest{Command: "make"}))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(fakeReceptorClient.CreateTaskArgsForCall(0).Privileged).To(BeFalse())
+			})
+
+			It("fails privileged jobs with a 400 BAD REQUEST", func() {
+				handler.Create(responseRecorder, newTestRequest(teapot.JobCreateRequest{Command: "make", Privileged: true}))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when privileged jobs need an admin's approval", func() {
+			BeforeEach(func() {
+				handler = NewJobHandler(jobManager, workstationManager, models.AdminApprovedPolicy, []string{"admin"}, logger)
+			})
+
+			It("runs unprivileged jobs", func() {
+				handler.Create(responseRecorder, WithUser(newTestRequest(teapot.JobCreateRequest{Command: "make"}), "alice"))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(fakeReceptorClient.CreateTaskArgsForCall(0).Privileged).To(BeFalse())
+			})
+
+			It("runs privileged jobs for admins", func() {
+				handler.Create(responseRecorder, WithUser(newTestRequest(teapot.JobCreateRequest{Command: "make", Privileged: true}), "admin"))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(fakeReceptorClient.CreateTaskArgsForCall(0).Privileged).To(BeTrue())
+			})
+
+			It("fails privileged jobs for anyone else with a 403 FORBIDDEN", func() {
+				handler.Create(responseRecorder, WithUser(newTestRequest(teapot.JobCreateRequest{Command: "make", Privileged: true}), "alice"))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the command is missing", func() {
+			BeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(teapot.JobCreateRequest{}))
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("does not create a task", func() {
+				Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Exec", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = newTestRequest(teapot.WorkstationExecRequest{Command: "make"})
+			req.URL.RawQuery = ":name=workstation-name"
+		})
+
+		Context("when the workstation exists", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+					ProcessGuid: "workstation-name",
+					RootFSPath:  "docker:///debian#wheezy",
+					CPUWeight:   2,
+					DiskMB:      1024,
+					MemoryMB:    256,
+					Privileged:  true,
+					EgressRules: []diego_models.SecurityGroupRule{
+						{Protocol: diego_models.TCPProtocol, Destinations: []string{"8.8.8.8"}, Ports: []uint16{443}},
+					},
+				}, nil)
+				handler.Exec(responseRecorder, req)
+			})
+
+			It("responds with 202 ACCEPTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+			})
+
+			It("runs the task with the workstation's image and limits", func() {
+				task := fakeReceptorClient.CreateTaskArgsForCall(0)
+				Expect(task.RootFSPath).To(Equal("docker:///debian#wheezy"))
+				Expect(task.CPUWeight).To(Equal(uint(2)))
+				Expect(task.DiskMB).To(Equal(1024))
+				Expect(task.MemoryMB).To(Equal(256))
+				Expect(task.Privileged).To(BeTrue())
+			})
+
+			It("gives the task the workstation's egress rules", func() {
+				task := fakeReceptorClient.CreateTaskArgsForCall(0)
+				Expect(task.EgressRules).To(Equal([]diego_models.SecurityGroupRule{
+					{Protocol: diego_models.TCPProtocol, Destinations: []string{"8.8.8.8"}, Ports: []uint16{443}},
+				}))
+			})
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+					ProcessGuid: "workstation-name",
+					Annotation:  `{"owner":"bob"}`,
+				}, nil)
+				handler.Exec(responseRecorder, WithUser(req, "alice"))
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			})
+
+			It("does not create a task", func() {
+				Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, errors.New("not found"))
+				handler.Exec(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Get", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":id=job-id"
+		})
+
+		Context("when the job has completed", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetTaskReturns(receptor.TaskResponse{
+					TaskGuid:   "job-id",
+					Domain:     "tiego",
+					State:      receptor.TaskStateCompleted,
+					Annotation: `{"workstation":"w1","command":"make"}`,
+					Result:     "2\nmake: *** No targets.\n",
+				}, nil)
+				handler.Get(responseRecorder, req)
+			})
+
+			It("responds with 200 OK", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+
+			It("responds with the exit code and output", func() {
+				var job models.Job
+				json.Unmarshal(responseRecorder.Body.Bytes(), &job)
+				Expect(job.Workstation).To(Equal("w1"))
+				Expect(job.Command).To(Equal("make"))
+				Expect(*job.ExitCode).To(Equal(2))
+				Expect(job.Output).To(Equal("make: *** No targets.\n"))
+			})
+		})
+
+		Context("when the job doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetTaskReturns(receptor.TaskResponse{}, receptor.Error{Type: receptor.TaskNotFound})
+				handler.Get(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("returns a JobNotFound error", func() {
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError).To(Equal(teapot.Error{
+					Type:    teapot.JobNotFound,
+					Message: "Job with id 'job-id' not found",
+				}))
+			})
+		})
+	})
+
+	Describe("Cancel", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":id=job-id"
+			fakeReceptorClient.GetTaskReturns(receptor.TaskResponse{
+				TaskGuid: "job-id",
+				Domain:   "tiego",
+				State:    receptor.TaskStateRunning,
+			}, nil)
+			handler.Cancel(responseRecorder, req)
+		})
+
+		It("responds with 204 NO CONTENT", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("cancels the task", func() {
+			Expect(fakeReceptorClient.CancelTaskCallCount()).To(Equal(1))
+			Expect(fakeReceptorClient.CancelTaskArgsForCall(0)).To(Equal("job-id"))
+		})
+	})
+})
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/logs"
+	"github.com/luan/teapot/managers"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+// logHeartbeatInterval is how often an idle event stream gets a comment, so
+// clients that went away are noticed.
+const logHeartbeatInterval = 15 * time.Second
+
+type LogHandler struct {
+	logSource          logs.LogSource
+	workstationManager managers.WorkstationManager
+	admins             map[string]bool
+	logger             lager.Logger
+}
+
+// NewLogHandler returns a LogHandler reading from logSource, logs are
+// disabled when it's nil. Only admins and its owner read a workstation's
+// logs, which may carry its secrets.
+func NewLogHandler(logSource logs.LogSource, workstationManager managers.WorkstationManager, admins []string, logger lager.Logger) *LogHandler {
+	return &LogHandler{
+		logSource:          logSource,
+		workstationManager: workstationManager,
+		admins:             adminSet(admins),
+		logger:             logger,
+	}
+}
+
+// Stream sends the workstation's log lines, only those of the given source if
+// any, as websocket messages when the request is a websocket upgrade and as
+// server-sent events otherwise. With follow=true new lines keep being sent
+// until the client goes away.
+func (h *LogHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	follow := r.URL.Query().Get("follow") == "true"
+	source := r.URL.Query().Get("source")
+	log := h.logger.Session("stream-logs", lager.Data{
+		"Name":   name,
+		"Follow": follow,
+		"Source": source,
+	})
+
+	if h.logSource == nil {
+		log.Info("logs-disabled")
+		writeLogsNotConfiguredResponse(w)
+		return
+	}
+
+	if !authorizeWorkstation(w, r, log, h.workstationManager, h.admins, name) {
+		return
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Workstations are desired with their name as LogGuid.
+	lines, err := h.logSource.Stream(name, follow, stop)
+	if err != nil {
+		log.Error("stream-failed", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.streamWebsocket(log, w, r, lines, source)
+	} else {
+		h.streamEvents(log, w, r, lines, source)
+	}
+}
+
+func (h *LogHandler) streamWebsocket(log lager.Logger, w http.ResponseWriter, r *http.Request, lines <-chan logs.Line, source string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Error("upgrade-failed", err)
+		return
+	}
+	defer conn.Close()
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if !matchesSource(line, source) {
+				continue
+			}
+
+			payload, _ := json.Marshal(line)
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				log.Info("client-gone")
+				return
+			}
+		case <-gone:
+			log.Info("client-gone")
+			return
+		}
+	}
+}
+
+func (h *LogHandler) streamEvents(log lager.Logger, w http.ResponseWriter, r *http.Request, lines <-chan logs.Line, source string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flush := func() {
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	heartbeat := time.NewTicker(logHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var gone <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		gone = notifier.CloseNotify()
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if !matchesSource(line, source) {
+				continue
+			}
+
+			payload, _ := json.Marshal(line)
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+				log.Info("client-gone")
+				return
+			}
+			flush()
+		case <-gone:
+			log.Info("client-gone")
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				log.Info("client-gone")
+				return
+			}
+			flush()
+		}
+	}
+}
+
+func matchesSource(line logs.Line, source string) bool {
+	return source == "" || strings.EqualFold(line.Source, source)
+}
+
+func writeLogsNotConfiguredResponse(w http.ResponseWriter) {
+	writeJSONResponse(w, http.StatusNotImplemented, teapot.Error{
+		Type:    teapot.LogsNotConfigured,
+		Message: "Logs are not configured on this teapot",
+	})
+}
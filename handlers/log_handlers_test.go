@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/gorilla/websocket"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/logs"
+	log_fakes "github.com/luan/teapot/logs/fakes"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *LogHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		fakeLogSource      *log_fakes.FakeLogSource
+		workstationManager managers.WorkstationManager
+		req                *http.Request
+	)
+
+	sshdLine := logs.Line{Timestamp: time.Unix(1, 0).UTC(), Source: "SSHD", Stream: "stdout", Message: "listening"}
+	setupLine := logs.Line{Timestamp: time.Unix(2, 0).UTC(), Source: "SETUP", Stream: "stderr", Message: "downloading"}
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeLogSource = &log_fakes.FakeLogSource{}
+		dataStore, _ := store.NewStore("")
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		workstationManager = managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, managers.NewCapacityManager(fakeReceptorClient, false), "secret", logger)
+		handler = NewLogHandler(fakeLogSource, workstationManager, []string{"admin"}, logger)
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "ws", Annotation: `{"owner":"alice"}`}, nil)
+		fakeLogSource.Log("ws", sshdLine)
+		fakeLogSource.Log("ws", setupLine)
+		fakeLogSource.Log("other", logs.Line{Source: "SSHD", Message: "not mine"})
+
+		req = newTestRequest("")
+		req.URL.RawQuery = ":name=ws"
+	})
+
+	Describe("Stream", func() {
+		JustBeforeEach(func() {
+			handler.Stream(responseRecorder, req)
+		})
+
+		It("sends the workstation's lines as server-sent events", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(responseRecorder.Header().Get("Content-Type")).To(Equal("text/event-stream"))
+
+			sshd, _ := json.Marshal(sshdLine)
+			setup, _ := json.Marshal(setupLine)
+			Expect(responseRecorder.Body.String()).To(Equal("data: " + string(sshd) + "\n\ndata: " + string(setup) + "\n\n"))
+		})
+
+		Context("with a source", func() {
+			BeforeEach(func() {
+				req.URL.RawQuery += "&source=setup"
+			})
+
+			It("only sends the lines of that source", func() {
+				setup, _ := json.Marshal(setupLine)
+				Expect(responseRecorder.Body.String()).To(Equal("data: " + string(setup) + "\n\n"))
+			})
+		})
+
+		Context("when the user owns the workstation", func() {
+			BeforeEach(func() {
+				req = WithUser(req, "alice")
+			})
+
+			It("sends its lines", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				req = WithUser(req, "bob")
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("listening"))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, errors.New("not found"))
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the log source fails", func() {
+			BeforeEach(func() {
+				fakeLogSource.StreamError = errors.New("doppler is down")
+			})
+
+			It("fails with a 500 INTERNAL SERVER ERROR", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("when logs are not configured", func() {
+			BeforeEach(func() {
+				handler = NewLogHandler(nil, workstationManager, []string{"admin"}, logger)
+			})
+
+			It("fails with a 501 NOT IMPLEMENTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotImplemented))
+
+				var response teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response.Type).To(Equal(teapot.LogsNotConfigured))
+			})
+		})
+	})
+
+	Context("when following", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.URL.RawQuery = ":name=ws&" + r.URL.RawQuery
+				handler.Stream(w, r)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("keeps sending new lines as events", func() {
+			resp, err := http.Get(server.URL + "/?follow=true")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			reader := bufio.NewReader(resp.Body)
+			readLine := func() logs.Line {
+				data, err := reader.ReadString('\n')
+				Expect(err).NotTo(HaveOccurred())
+				reader.ReadString('\n')
+
+				var line logs.Line
+				Expect(json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &line)).To(Succeed())
+				return line
+			}
+
+			Expect(readLine()).To(Equal(sshdLine))
+			Expect(readLine()).To(Equal(setupLine))
+
+			Eventually(func() int { return fakeLogSource.Followers("ws") }).Should(Equal(1))
+			fakeLogSource.Log("ws", logs.Line{Source: "TEA", Message: "attached"})
+			Expect(readLine().Message).To(Equal("attached"))
+		})
+
+		It("sends lines as websocket messages when asked to upgrade", func() {
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/?follow=true&source=tea", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			Eventually(func() int { return fakeLogSource.Followers("ws") }).Should(Equal(1))
+			fakeLogSource.Log("ws", logs.Line{Source: "TEA", Message: "attached"})
+
+			var line teapot.LogLineResponse
+			Expect(conn.ReadJSON(&line)).To(Succeed())
+			Expect(line.Source).To(Equal("TEA"))
+			Expect(line.Message).To(Equal("attached"))
+
+			conn.Close()
+			Eventually(func() int { return fakeLogSource.Followers("ws") }).Should(Equal(0))
+		})
+	})
+})
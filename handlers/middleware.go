@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
 
 	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/cloudfoundry/dropsonde"
-	"github.com/goji/httpauth"
 	"github.com/pivotal-golang/lager"
 )
 
+type userKey struct{}
+
 func LogWrap(handler http.Handler, logger lager.Logger) http.HandlerFunc {
 	handler = dropsonde.InstrumentedHandler(handler)
 
@@ -24,14 +27,25 @@ func LogWrap(handler http.Handler, logger lager.Logger) http.HandlerFunc {
 	}
 }
 
-func BasicAuthWrap(handler http.Handler, username, password string) http.Handler {
-	opts := httpauth.AuthOptions{
-		Realm:               "API Authentication",
-		User:                username,
-		Password:            password,
-		UnauthorizedHandler: http.HandlerFunc(unauthorized),
-	}
-	return httpauth.BasicAuth(opts)(handler)
+// BasicAuthWrap only lets through requests authenticating as one of users,
+// which maps usernames to passwords, and records who made them for
+// requestUser.
+func BasicAuthWrap(handler http.Handler, users map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		expected, known := users[user]
+		if !ok || !known || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="API Authentication"`)
+			unauthorized(w, r)
+			return
+		}
+
+		handler.ServeHTTP(w, withUser(r, user))
+	})
+}
+
+func withUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
 }
 
 func unauthorized(w http.ResponseWriter, r *http.Request) {
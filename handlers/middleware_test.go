@@ -0,0 +1,60 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/luan/teapot/handlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BasicAuthWrap", func() {
+	var (
+		handler          http.Handler
+		responseRecorder *httptest.ResponseRecorder
+		seenUser         string
+	)
+
+	BeforeEach(func() {
+		seenUser = ""
+		responseRecorder = httptest.NewRecorder()
+		handler = BasicAuthWrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seenUser = RequestUser(r)
+		}), map[string]string{"alice": "wonderland", "bob": "builder"})
+	})
+
+	It("lets each user in with their own password", func() {
+		req := newTestRequest("")
+		req.SetBasicAuth("bob", "builder")
+		handler.ServeHTTP(responseRecorder, req)
+
+		Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+		Expect(seenUser).To(Equal("bob"))
+	})
+
+	It("rejects another user's password", func() {
+		req := newTestRequest("")
+		req.SetBasicAuth("bob", "wonderland")
+		handler.ServeHTTP(responseRecorder, req)
+
+		Expect(responseRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(seenUser).To(BeEmpty())
+	})
+
+	It("rejects unknown users", func() {
+		req := newTestRequest("")
+		req.SetBasicAuth("mallory", "")
+		handler.ServeHTTP(responseRecorder, req)
+
+		Expect(responseRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects requests without credentials", func() {
+		handler.ServeHTTP(responseRecorder, newTestRequest(""))
+
+		Expect(responseRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(responseRecorder.Header().Get("WWW-Authenticate")).To(ContainSubstring("Basic"))
+	})
+})
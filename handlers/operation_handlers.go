@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type OperationHandler struct {
+	manager managers.OperationManager
+	logger  lager.Logger
+}
+
+func NewOperationHandler(manager managers.OperationManager, logger lager.Logger) *OperationHandler {
+	return &OperationHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+func (h *OperationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("get-operation", lager.Data{
+		"ID": id,
+	})
+
+	operation, err := h.manager.Get(id)
+	if err != nil {
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"operation_id": id})
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.OperationNotFound,
+				Message: fmt.Sprintf("Operation with id '%s' not found", id),
+			})
+		}
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, operation)
+}
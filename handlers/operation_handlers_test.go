@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OperationHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *OperationHandler
+		operationManager managers.OperationManager
+		dataStore        store.Store
+		req              *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		dataStore, _ = store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		fakeReceptorClient := new(fake_receptor.FakeClient)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		backupManager := managers.NewBackupManager(workstationManager, nil, dataStore, logger)
+		operationManager = managers.NewOperationManager(workstationManager, backupManager, dataStore, 0, 0, logger)
+		handler = NewOperationHandler(operationManager, logger)
+
+		dataStore.Put("operations", "op1", models.Operation{
+			ID:          "op1",
+			Type:        models.OperationResize,
+			Workstation: "w1",
+			State:       models.OperationInProgressState,
+			Step:        models.OperationReplacingStep,
+		})
+	})
+
+	Describe("Get", func() {
+		Context("when the operation exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=op1"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("responds with the operation", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var operation models.Operation
+				json.Unmarshal(responseRecorder.Body.Bytes(), &operation)
+				Expect(operation.Workstation).To(Equal("w1"))
+				Expect(operation.Step).To(Equal(models.OperationReplacingStep))
+			})
+		})
+
+		Context("when teapot restarted while the operation was in progress", func() {
+			BeforeEach(func() {
+				Expect(operationManager.FailInterrupted()).To(Succeed())
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=op1"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("responds with the operation FAILED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var operation models.Operation
+				json.Unmarshal(responseRecorder.Body.Bytes(), &operation)
+				Expect(operation.State).To(Equal(models.OperationFailedState))
+				Expect(operation.FailureReason).To(ContainSubstring("restart"))
+			})
+		})
+
+		Context("when the operation finished long ago", func() {
+			BeforeEach(func() {
+				dataStore.Put("operations", "old", models.Operation{
+					ID:          "old",
+					Type:        models.OperationResize,
+					Workstation: "w1",
+					State:       models.OperationSucceededState,
+					Step:        models.OperationDoneStep,
+					UpdatedAt:   time.Now().Add(-48 * time.Hour),
+				})
+
+				_, err := operationManager.Create(models.NewWorkstation(teapot.WorkstationCreateRequest{Name: "w2"}))
+				Expect(err).NotTo(HaveOccurred())
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=old"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("expires it once another operation starts", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the operation doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=nope"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.OperationNotFound))
+			})
+		})
+	})
+})
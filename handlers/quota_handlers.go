@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+type QuotaHandler struct {
+	quotaManager       managers.QuotaManager
+	workstationManager managers.WorkstationManager
+	logger             lager.Logger
+}
+
+func NewQuotaHandler(quotaManager managers.QuotaManager, workstationManager managers.WorkstationManager, logger lager.Logger) *QuotaHandler {
+	return &QuotaHandler{
+		quotaManager:       quotaManager,
+		workstationManager: workstationManager,
+		logger:             logger,
+	}
+}
+
+// Get responds with the user's own quota followed by those of their teams,
+// each with the usage of the workstations it covers.
+func (h *QuotaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	log := h.logger.Session("get-quotas", lager.Data{
+		"User": user,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	usage, err := h.workstationManager.Usage([]string{user})
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	quotas := []teapot.QuotaResponse{quotaResponse("user", user, h.quotaManager.UserQuota(user), usage)}
+	for _, team := range h.quotaManager.Teams(user) {
+		usage, err := h.workstationManager.Usage(team.Members)
+		if err != nil {
+			log.Error("unknown-error", err)
+			writeUnknownErrorResponse(w, err)
+			return
+		}
+		quotas = append(quotas, quotaResponse("team", team.Name, team.Quota, usage))
+	}
+
+	writeJSONResponse(w, http.StatusOK, quotas)
+}
+
+func quotaResponse(scope, name string, quota models.Quota, usage models.Usage) teapot.QuotaResponse {
+	return teapot.QuotaResponse{
+		Scope: scope,
+		Name:  name,
+		Limits: teapot.QuotaLimits{
+			MaxWorkstations: quota.MaxWorkstations,
+			MemoryMB:        quota.MemoryMB,
+			DiskMB:          quota.DiskMB,
+			MaxPrivileged:   quota.MaxPrivileged,
+		},
+		Usage: teapot.QuotaUsage{
+			Workstations: usage.Workstations,
+			MemoryMB:     usage.MemoryMB,
+			DiskMB:       usage.DiskMB,
+			Privileged:   usage.Privileged,
+		},
+	}
+}
+
+func writeQuotaExceededResponse(w http.ResponseWriter, err models.ErrQuotaExceeded) {
+	writeJSONResponse(w, http.StatusForbidden, teapot.Error{
+		Type:    teapot.QuotaExceeded,
+		Message: err.Error(),
+	})
+}
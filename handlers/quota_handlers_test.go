@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const quotasJSON = `{
+	"default": {"max_workstations": 2, "memory_mb": 4096},
+	"users": {"bob": {"max_workstations": 5}},
+	"teams": [{"name": "platform", "members": ["alice", "bob"], "quota": {"max_privileged": 1}}]
+}`
+
+var _ = Describe("QuotaHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *QuotaHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		quotasFile         string
+		user               string
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{
+			{ProcessGuid: "w1", Instances: 1, MemoryMB: 1024, DiskMB: 2048, Privileged: true, Annotation: `{"owner":"alice"}`},
+			{ProcessGuid: "w2", Instances: 0, MemoryMB: 1024, DiskMB: 2048, Annotation: `{"owner":"alice"}`},
+			{ProcessGuid: "w3", Instances: 1, MemoryMB: 512, DiskMB: 1024, Annotation: `{"owner":"bob"}`},
+		}, nil)
+
+		file, err := ioutil.TempFile("", "quotas")
+		Expect(err).NotTo(HaveOccurred())
+		file.WriteString(quotasJSON)
+		file.Close()
+		quotasFile = file.Name()
+
+		quotaManager, err := managers.NewQuotaManager(quotasFile)
+		Expect(err).NotTo(HaveOccurred())
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		dataStore, _ := store.NewStore("")
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewQuotaHandler(quotaManager, workstationManager, logger)
+		user = "alice"
+	})
+
+	AfterEach(func() {
+		os.Remove(quotasFile)
+	})
+
+	JustBeforeEach(func() {
+		req := newTestRequest("")
+		req = WithUser(req, user)
+		handler.Get(responseRecorder, req)
+	})
+
+	It("responds with the user's quota and their teams', along with the usage", func() {
+		Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+		var quotas []teapot.QuotaResponse
+		json.Unmarshal(responseRecorder.Body.Bytes(), &quotas)
+		Expect(quotas).To(Equal([]teapot.QuotaResponse{
+			{
+				Scope:  "user",
+				Name:   "alice",
+				Limits: teapot.QuotaLimits{MaxWorkstations: 2, MemoryMB: 4096},
+				Usage:  teapot.QuotaUsage{Workstations: 2, MemoryMB: 1024, DiskMB: 2048, Privileged: 1},
+			},
+			{
+				Scope:  "team",
+				Name:   "platform",
+				Limits: teapot.QuotaLimits{MaxPrivileged: 1},
+				Usage:  teapot.QuotaUsage{Workstations: 3, MemoryMB: 1536, DiskMB: 3072, Privileged: 1},
+			},
+		}))
+	})
+
+	Context("when the user has a quota of their own", func() {
+		BeforeEach(func() {
+			user = "bob"
+		})
+
+		It("responds with it instead of the default", func() {
+			var quotas []teapot.QuotaResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &quotas)
+			Expect(quotas[0].Limits).To(Equal(teapot.QuotaLimits{MaxWorkstations: 5}))
+			Expect(quotas[0].Usage).To(Equal(teapot.QuotaUsage{Workstations: 1, MemoryMB: 512, DiskMB: 1024}))
+		})
+	})
+
+	Context("when the user isn't in a team", func() {
+		BeforeEach(func() {
+			user = "carol"
+		})
+
+		It("only responds with the user's quota", func() {
+			var quotas []teapot.QuotaResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &quotas)
+			Expect(quotas).To(HaveLen(1))
+			Expect(quotas[0].Usage).To(Equal(teapot.QuotaUsage{}))
+		})
+	})
+})
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type RouteHandler struct {
+	manager managers.WorkstationManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+func NewRouteHandler(manager managers.WorkstationManager, admins []string, logger lager.Logger) *RouteHandler {
+	return &RouteHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+func (h *RouteHandler) List(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("list-routes", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	routes, err := h.manager.ListRoutes(name)
+	if err != nil {
+		h.writeRouteError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, routes)
+}
+
+func (h *RouteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("create-route", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+	routeRequest := teapot.RouteCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&routeRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	route, err := h.manager.AddRoute(name, models.NewRoute(routeRequest))
+	if err != nil {
+		h.writeRouteError(w, log, err)
+		return
+	}
+
+	log.Info("created", lager.Data{"workstation_name": name, "hostname": route.Hostname, "port": route.Port})
+
+	writeJSONResponse(w, http.StatusCreated, route)
+}
+
+func (h *RouteHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	hostname := rata.Param(r, "hostname")
+	log := h.logger.Session("delete-route", lager.Data{
+		"Name":     name,
+		"Hostname": hostname,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	err := h.manager.RemoveRoute(name, hostname)
+	if err != nil {
+		h.writeRouteError(w, log, err)
+		return
+	}
+
+	log.Info("deleted", lager.Data{"workstation_name": name, "hostname": hostname})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RouteHandler) writeRouteError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Error("invalid-route", err)
+		writeBadRequestResponse(w, teapot.InvalidRoute, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+		if t.Resource == "workstation" {
+			writeWorkstationNotFoundResponse(w, t.Name)
+		} else {
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.RouteNotFound,
+				Message: fmt.Sprintf("Route with hostname '%s' not found", t.Name),
+			})
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *RouteHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		fakeRouteProvider  *model_fakes.FakeRouteProvider
+		req                *http.Request
+	)
+
+	updatedRoutes := func() cfroutes.CFRoutes {
+		Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(1))
+		_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+		routes, err := cfroutes.CFRoutesFromRoutingInfo(update.Routes)
+		Expect(err).NotTo(HaveOccurred())
+		return routes
+	}
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeRouteProvider = &model_fakes.FakeRouteProvider{}
+		fakeRouteProvider.AppsDomainReturns("example.com")
+		fakeRouteProvider.TiegoRouteStub = func(name string) string { return "tiego-" + name + ".example.com" }
+		fakeRouteProvider.SSHRouteStub = func(name string) string { return "ssh-" + name + ".example.com" }
+		fakeRouteProvider.PortRouteReturns("4000-w1.example.com")
+		dataStore, _ := store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewRouteHandler(manager, []string{"admin"}, logger)
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			Ports:       []uint16{8080, 3000, 4000},
+			Routes: cfroutes.CFRoutes{
+				{Hostnames: []string{"tiego-w1.example.com"}, Port: 3000},
+				{Hostnames: []string{"ssh-w1.example.com"}, Port: 8080},
+				{Hostnames: []string{"preview.example.com"}, Port: 4000},
+			}.RoutingInfo(),
+		}, nil)
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=w1"
+			handler.List(responseRecorder, req)
+		})
+
+		It("responds with the added routes only", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var routes []models.Route
+			json.Unmarshal(responseRecorder.Body.Bytes(), &routes)
+			Expect(routes).To(Equal([]models.Route{{Hostname: "preview.example.com", Port: 4000}}))
+		})
+	})
+
+	Describe("Create", func() {
+		create := func(request teapot.RouteCreateRequest) {
+			req = newTestRequest(request)
+			req.URL.RawQuery = ":name=w1"
+			handler.Create(responseRecorder, req)
+		}
+
+		Context("when a hostname is given", func() {
+			BeforeEach(func() {
+				create(teapot.RouteCreateRequest{Hostname: "Other.example.com", Port: 3000})
+			})
+
+			It("responds with 201 CREATED and the route", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+
+				var route models.Route
+				json.Unmarshal(responseRecorder.Body.Bytes(), &route)
+				Expect(route).To(Equal(models.Route{Hostname: "other.example.com", Port: 3000}))
+			})
+
+			It("adds the hostname to the port's route", func() {
+				Expect(updatedRoutes()).To(ContainElement(cfroutes.CFRoute{
+					Hostnames: []string{"tiego-w1.example.com", "other.example.com"},
+					Port:      3000,
+				}))
+			})
+		})
+
+		Context("when no hostname is given", func() {
+			BeforeEach(func() {
+				create(teapot.RouteCreateRequest{Port: 4000})
+			})
+
+			It("generates one", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+				Expect(updatedRoutes()).To(ContainElement(cfroutes.CFRoute{
+					Hostnames: []string{"preview.example.com", "4000-w1.example.com"},
+					Port:      4000,
+				}))
+			})
+		})
+
+		Context("when the port was not exposed", func() {
+			BeforeEach(func() {
+				create(teapot.RouteCreateRequest{Hostname: "app.example.com", Port: 5000})
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidRoute))
+			})
+
+			It("does not update the workstation", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the hostname is taken", func() {
+			BeforeEach(func() {
+				create(teapot.RouteCreateRequest{Hostname: "ssh-w1.example.com", Port: 4000})
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the hostname is outside the apps domain", func() {
+			BeforeEach(func() {
+				create(teapot.RouteCreateRequest{Hostname: "www.example.org", Port: 4000})
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when another workstation routes the hostname", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{
+					{
+						ProcessGuid: "w2",
+						Routes: cfroutes.CFRoutes{
+							{Hostnames: []string{"shared.example.com"}, Port: 4000},
+						}.RoutingInfo(),
+					},
+				}, nil)
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				create(teapot.RouteCreateRequest{Hostname: "shared.example.com", Port: 4000})
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+
+			It("refuses the other workstation's own hostnames", func() {
+				create(teapot.RouteCreateRequest{Hostname: "ssh-w2.example.com", Port: 4000})
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.RouteCreateRequest{Hostname: "other.example.com", Port: 3000})
+				req.URL.RawQuery = ":name=w1"
+				handler.Create(responseRecorder, WithUser(req, "bob"))
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		remove := func(hostname string) {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=w1&:hostname=" + hostname
+			handler.Delete(responseRecorder, req)
+		}
+
+		Context("when the route exists", func() {
+			BeforeEach(func() {
+				remove("preview.example.com")
+			})
+
+			It("responds with 204 NO CONTENT", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("removes the route from the workstation", func() {
+				Expect(updatedRoutes()).To(Equal(cfroutes.CFRoutes{
+					{Hostnames: []string{"tiego-w1.example.com"}, Port: 3000},
+					{Hostnames: []string{"ssh-w1.example.com"}, Port: 8080},
+				}))
+			})
+		})
+
+		Context("when the route is one of the workstation's own routes", func() {
+			BeforeEach(func() {
+				remove("ssh-w1.example.com")
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the route doesn't exist", func() {
+			BeforeEach(func() {
+				remove("nope.example.com")
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.RouteNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type ScheduleHandler struct {
+	manager managers.WorkstationManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+func NewScheduleHandler(manager managers.WorkstationManager, admins []string, logger lager.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("list-schedules", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	schedules, err := h.manager.ListSchedules(name)
+	if err != nil {
+		h.writeScheduleError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, schedules)
+}
+
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("create-schedule", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+	scheduleRequest := teapot.ScheduleCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&scheduleRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	schedule, err := h.manager.AddSchedule(name, models.NewSchedule(scheduleRequest))
+	if err != nil {
+		h.writeScheduleError(w, log, err)
+		return
+	}
+
+	log.Info("created", lager.Data{"workstation_name": name, "schedule_id": schedule.ID})
+
+	writeJSONResponse(w, http.StatusCreated, schedule)
+}
+
+// Update replaces the cron, action, command and location of a schedule,
+// keeping its id.
+func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	id := rata.Param(r, "id")
+	log := h.logger.Session("update-schedule", lager.Data{
+		"Name": name,
+		"ID":   id,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+	scheduleRequest := teapot.ScheduleCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&scheduleRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	schedule, err := h.manager.UpdateSchedule(name, id, models.NewSchedule(scheduleRequest))
+	if err != nil {
+		h.writeScheduleError(w, log, err)
+		return
+	}
+
+	log.Info("updated", lager.Data{"workstation_name": name, "schedule_id": id})
+
+	writeJSONResponse(w, http.StatusOK, schedule)
+}
+
+func (h *ScheduleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	id := rata.Param(r, "id")
+	log := h.logger.Session("delete-schedule", lager.Data{
+		"Name": name,
+		"ID":   id,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	err := h.manager.DeleteSchedule(name, id)
+	if err != nil {
+		h.writeScheduleError(w, log, err)
+		return
+	}
+
+	log.Info("deleted", lager.Data{"workstation_name": name, "schedule_id": id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScheduleHandler) writeScheduleError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Error("invalid-schedule", err)
+		writeBadRequestResponse(w, teapot.InvalidSchedule, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+		if t.Resource == "workstation" {
+			writeWorkstationNotFoundResponse(w, t.Name)
+		} else {
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.ScheduleNotFound,
+				Message: fmt.Sprintf("Schedule with id '%s' not found", t.Name),
+			})
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ScheduleHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *ScheduleHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		req                *http.Request
+	)
+
+	existingSchedules := `{"schedules":[{"id":"s1","cron":"0 8 * * 1-5","action":"start"}]}`
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		dataStore, _ := store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewScheduleHandler(manager, []string{"admin"}, logger)
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "workstation-name",
+			Annotation:  existingSchedules,
+		}, nil)
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name"
+		})
+
+		Context("when the workstation exists", func() {
+			BeforeEach(func() {
+				handler.List(responseRecorder, req)
+			})
+
+			It("responds with the stored schedules", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var schedules []models.Schedule
+				json.Unmarshal(responseRecorder.Body.Bytes(), &schedules)
+				Expect(schedules).To(Equal([]models.Schedule{
+					{ID: "s1", Cron: "0 8 * * 1-5", Action: models.ScheduleStartAction},
+				}))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, errors.New("not found"))
+				handler.List(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Create", func() {
+		Context("when the schedule is valid", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "0 19 * * 1-5", Action: "stop"})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Create(responseRecorder, req)
+			})
+
+			It("responds with 201 CREATED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("appends the schedule to the workstation annotation", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(1))
+				name, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(name).To(Equal("workstation-name"))
+
+				var annotation struct{ Schedules []models.Schedule }
+				Expect(json.Unmarshal([]byte(*update.Annotation), &annotation)).To(Succeed())
+				Expect(annotation.Schedules).To(HaveLen(2))
+				Expect(annotation.Schedules[1].Action).To(Equal(models.ScheduleStopAction))
+				Expect(annotation.Schedules[1].ID).NotTo(BeEmpty())
+			})
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "0 19 * * 1-5", Action: "stop"})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Create(responseRecorder, WithUser(req, "bob"))
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the schedule is invalid", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "whenever", Action: "stop"})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Create(responseRecorder, req)
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("does not update the workstation", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		Context("when the schedule exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "30 7 * * 1-5", Action: "start", Location: "Europe/London"})
+				req.URL.RawQuery = ":name=workstation-name&:id=s1"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("responds with 200 OK", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+
+			It("replaces the schedule, keeping its id", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(1))
+				_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+
+				var annotation struct{ Schedules []models.Schedule }
+				Expect(json.Unmarshal([]byte(*update.Annotation), &annotation)).To(Succeed())
+				Expect(annotation.Schedules).To(Equal([]models.Schedule{
+					{ID: "s1", Cron: "30 7 * * 1-5", Action: models.ScheduleStartAction, Location: "Europe/London"},
+				}))
+			})
+		})
+
+		Context("when the schedule is invalid", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "whenever", Action: "start"})
+				req.URL.RawQuery = ":name=workstation-name&:id=s1"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the schedule doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.ScheduleCreateRequest{Cron: "0 8 * * *", Action: "start"})
+				req.URL.RawQuery = ":name=workstation-name&:id=nope"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.ScheduleNotFound))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when the schedule exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=workstation-name&:id=s1"
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("responds with 204 NO CONTENT", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("removes the schedule from the workstation annotation", func() {
+				_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(*update.Annotation).To(Equal("{}"))
+			})
+		})
+
+		Context("when the schedule doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=workstation-name&:id=nope"
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.ScheduleNotFound))
+			})
+		})
+	})
+})
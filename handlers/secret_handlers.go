@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type SecretHandler struct {
+	manager managers.SecretManager
+	logger  lager.Logger
+}
+
+func NewSecretHandler(manager managers.SecretManager, logger lager.Logger) *SecretHandler {
+	return &SecretHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+func (h *SecretHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	log := h.logger.Session("list-secrets", lager.Data{
+		"User": user,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	secrets, err := h.manager.List(user)
+	if err != nil {
+		h.writeSecretError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, secrets)
+}
+
+// Save creates or replaces a secret of the user, the response describes the
+// secret without its value.
+func (h *SecretHandler) Save(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	name := rata.Param(r, "name")
+	log := h.logger.Session("save-secret", lager.Data{
+		"User": user,
+		"Name": name,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	secretRequest := teapot.SecretRequest{}
+	err := json.NewDecoder(r.Body).Decode(&secretRequest)
+	if err != nil {
+		log.Info("invalid-json")
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	secret, err := h.manager.Save(models.NewSecret(name, user, secretRequest))
+	if err != nil {
+		h.writeSecretError(w, log, err)
+		return
+	}
+
+	log.Info("saved", lager.Data{"secret_name": name})
+
+	writeJSONResponse(w, http.StatusOK, secret)
+}
+
+func (h *SecretHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	name := rata.Param(r, "name")
+	log := h.logger.Session("delete-secret", lager.Data{
+		"User": user,
+		"Name": name,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err := h.manager.Delete(user, name)
+	if err != nil {
+		h.writeSecretError(w, log, err)
+		return
+	}
+
+	log.Info("deleted", lager.Data{"secret_name": name})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SecretHandler) writeSecretError(w http.ResponseWriter, log lager.Logger, err error) {
+	if err == managers.ErrSecretsDisabled {
+		log.Info("secrets-disabled")
+		writeSecretsNotConfiguredResponse(w)
+		return
+	}
+
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Info("invalid-secret", lager.Data{"error": err.Error()})
+		writeBadRequestResponse(w, teapot.InvalidSecret, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"name": t.Name})
+		writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+			Type:    teapot.SecretNotFound,
+			Message: fmt.Sprintf("Secret with name '%s' not found", t.Name),
+		})
+	}
+}
+
+func writeSecretsNotConfiguredResponse(w http.ResponseWriter) {
+	writeJSONResponse(w, http.StatusNotImplemented, teapot.Error{
+		Type:    teapot.SecretsNotConfigured,
+		Message: "Secrets are not configured on this teapot",
+	})
+}
@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SecretHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *SecretHandler
+		secretManager    managers.SecretManager
+		dataStore        store.Store
+		req              *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		dataStore, _ = store.NewStore("")
+		secretManager, _ = managers.NewSecretManager([]byte("0123456789abcdef"), dataStore)
+		handler = NewSecretHandler(secretManager, logger)
+
+		secretManager.Save(models.Secret{Name: "GITHUB_TOKEN", Owner: "alice", Value: "t0ps3cret"})
+		secretManager.Save(models.Secret{Name: "NPM_TOKEN", Owner: "bob", Value: "n0tyours"})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req = WithUser(req, "alice")
+			handler.List(responseRecorder, req)
+		})
+
+		It("responds with the names of the user's secrets only", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var secrets []teapot.SecretResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &secrets)
+			Expect(secrets).To(HaveLen(1))
+			Expect(secrets[0].Name).To(Equal("GITHUB_TOKEN"))
+			Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("t0ps3cret"))
+		})
+	})
+
+	Describe("Save", func() {
+		Context("when the secret is valid", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.SecretRequest{Value: "n3w"})
+				req.URL.RawQuery = ":name=GITHUB_TOKEN"
+				req = WithUser(req, "alice")
+				handler.Save(responseRecorder, req)
+			})
+
+			It("responds with the secret without its value", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				Expect(responseRecorder.Body.String()).To(ContainSubstring(`"name":"GITHUB_TOKEN"`))
+				Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("n3w"))
+			})
+
+			It("stores the value encrypted", func() {
+				var stored map[string]interface{}
+				Expect(dataStore.Get("secrets", "alice/GITHUB_TOKEN", &stored)).To(Succeed())
+				Expect(stored).To(HaveKey("ciphertext"))
+				Expect(stored["ciphertext"]).NotTo(ContainSubstring("n3w"))
+
+				values, err := secretManager.Resolve("alice", []string{"GITHUB_TOKEN"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(values).To(Equal(map[string]string{"GITHUB_TOKEN": "n3w"}))
+			})
+		})
+
+		Context("when the name is not a valid environment variable", func() {
+			BeforeEach(func() {
+				req = WithUser(newTestRequest(teapot.SecretRequest{Value: "x"}), "alice")
+				req.URL.RawQuery = ":name=NOT-VALID"
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidSecret))
+			})
+		})
+
+		Context("when secrets are not configured", func() {
+			BeforeEach(func() {
+				secretManager, _ = managers.NewSecretManager(nil, dataStore)
+				handler = NewSecretHandler(secretManager, logger)
+
+				req = WithUser(newTestRequest(teapot.SecretRequest{Value: "x"}), "alice")
+				req.URL.RawQuery = ":name=GITHUB_TOKEN"
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails with a 501 NOT IMPLEMENTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotImplemented))
+			})
+		})
+
+		Context("when authentication is disabled", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.SecretRequest{Value: "x"})
+				req.URL.RawQuery = ":name=GITHUB_TOKEN"
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails with a 403 FORBIDDEN, nobody being identified", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when the secret exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=GITHUB_TOKEN"
+				req = WithUser(req, "alice")
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("responds with 204 NO CONTENT", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+				Expect(secretManager.List("alice")).To(BeEmpty())
+			})
+		})
+
+		Context("when the secret belongs to someone else", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=NPM_TOKEN"
+				req = WithUser(req, "alice")
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+				Expect(secretManager.List("bob")).To(HaveLen(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type SnapshotHandler struct {
+	manager            managers.BackupManager
+	workstationManager managers.WorkstationManager
+	admins             map[string]bool
+	logger             lager.Logger
+}
+
+// NewSnapshotHandler returns a SnapshotHandler that only lets the given
+// admins and the owners of workstations use their snapshots.
+func NewSnapshotHandler(manager managers.BackupManager, workstationManager managers.WorkstationManager, admins []string, logger lager.Logger) *SnapshotHandler {
+	return &SnapshotHandler{
+		manager:            manager,
+		workstationManager: workstationManager,
+		admins:             adminSet(admins),
+		logger:             logger,
+	}
+}
+
+func (h *SnapshotHandler) List(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("list-snapshots", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.workstationManager, h.admins, name) {
+		return
+	}
+
+	snapshots, err := h.manager.List(name)
+	if err != nil {
+		h.writeSnapshotError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, snapshots)
+}
+
+// Create starts a snapshot and responds right away, the snapshot stays
+// PENDING until the home directory is stored.
+func (h *SnapshotHandler) Create(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("create-snapshot", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.workstationManager, h.admins, name) {
+		return
+	}
+
+	snapshot, err := h.manager.Create(name)
+	if err != nil {
+		h.writeSnapshotError(w, log, err)
+		return
+	}
+
+	log.Info("started", lager.Data{"workstation_name": name, "snapshot_id": snapshot.ID})
+
+	writeJSONResponse(w, http.StatusAccepted, snapshot)
+}
+
+func (h *SnapshotHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("get-snapshot", lager.Data{
+		"ID": id,
+	})
+
+	snapshot, err := h.manager.Get(id)
+	if err != nil {
+		h.writeSnapshotError(w, log, err)
+		return
+	}
+
+	if !h.canAccess(r, log, snapshot) {
+		writeForbiddenResponse(w)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, snapshot)
+}
+
+func (h *SnapshotHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("delete-snapshot", lager.Data{
+		"ID": id,
+	})
+
+	snapshot, err := h.manager.Get(id)
+	if err != nil {
+		h.writeSnapshotError(w, log, err)
+		return
+	}
+
+	if !h.canAccess(r, log, snapshot) {
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err = h.manager.Delete(id)
+	if err != nil {
+		h.writeSnapshotError(w, log, err)
+		return
+	}
+
+	log.Info("deleted", lager.Data{"snapshot_id": id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canAccess tells whether the request's user may use the snapshot, which
+// belongs to the owner of the workstation it was taken from.
+func (h *SnapshotHandler) canAccess(r *http.Request, log lager.Logger, snapshot models.Snapshot) bool {
+	user := requestUser(r)
+	if !canAccess(user, h.admins, models.Workstation{Owner: snapshot.Owner}) {
+		log.Info("forbidden", lager.Data{"user": user, "snapshot_id": snapshot.ID})
+		return false
+	}
+	return true
+}
+
+func (h *SnapshotHandler) writeSnapshotError(w http.ResponseWriter, log lager.Logger, err error) {
+	if err == managers.ErrBackupsDisabled {
+		log.Info("backups-disabled")
+		writeBackupsNotConfiguredResponse(w)
+		return
+	}
+
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+		if t.Resource == "workstation" {
+			writeWorkstationNotFoundResponse(w, t.Name)
+		} else {
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.SnapshotNotFound,
+				Message: fmt.Sprintf("Snapshot with id '%s' not found", t.Name),
+			})
+		}
+	}
+}
+
+func writeBackupsNotConfiguredResponse(w http.ResponseWriter) {
+	writeJSONResponse(w, http.StatusNotImplemented, teapot.Error{
+		Type:    teapot.BackupsNotConfigured,
+		Message: "Backups are not configured on this teapot",
+	})
+}
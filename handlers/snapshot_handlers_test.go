@@ -0,0 +1,270 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/blobstore"
+	blob_fakes "github.com/luan/teapot/blobstore/fakes"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("SnapshotHandler", func() {
+	var (
+		logger             lager.Logger
+		responseRecorder   *httptest.ResponseRecorder
+		handler            *SnapshotHandler
+		fakeReceptorClient *fake_receptor.FakeClient
+		fakeBlobstore      *blob_fakes.FakeBlobstore
+		workstationManager managers.WorkstationManager
+		dataStore          store.Store
+		teaServer          *ghttp.Server
+		req                *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeBlobstore = new(blob_fakes.FakeBlobstore)
+		dataStore, _ = store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		workstationManager = managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		handler = NewSnapshotHandler(managers.NewBackupManager(workstationManager, fakeBlobstore, dataStore, logger), workstationManager, []string{"admin"}, logger)
+
+		teaServer = ghttp.NewServer()
+		teaURL, _ := url.Parse(teaServer.URL())
+		teaHostPort := strings.Split(teaURL.Host, ":")
+		teaPort, _ := strconv.Atoi(teaHostPort[1])
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			Annotation:  `{"owner":"alice"}`,
+		}, nil)
+		fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+			ProcessGuid: "w1",
+			Address:     teaHostPort[0],
+			Ports:       []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(teaPort)}},
+			State:       receptor.ActualLRPStateRunning,
+		}}, nil)
+
+		dataStore.Put("snapshots", "s1", models.Snapshot{ID: "s1", Workstation: "w1", State: models.SnapshotCompleteState})
+		dataStore.Put("snapshots", "s2", models.Snapshot{ID: "s2", Workstation: "w2", State: models.SnapshotCompleteState})
+		dataStore.Put("snapshots", "s3", models.Snapshot{ID: "s3", Workstation: "w3", Owner: "bob", State: models.SnapshotCompleteState})
+	})
+
+	AfterEach(func() {
+		teaServer.Close()
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=w1"
+			handler.List(responseRecorder, req)
+		})
+
+		It("responds with the snapshots of the workstation", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var snapshots []teapot.SnapshotResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &snapshots)
+			Expect(snapshots).To(HaveLen(1))
+			Expect(snapshots[0].ID).To(Equal("s1"))
+		})
+	})
+
+	Describe("Create", func() {
+		Context("when the home directory can be stored", func() {
+			BeforeEach(func() {
+				teaServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/files/something/home/vcap", "tar=true"),
+					ghttp.RespondWith(http.StatusOK, "tarball"),
+				))
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=w1"
+				handler.Create(responseRecorder, req)
+			})
+
+			It("responds with 202 ACCEPTED and the pending snapshot", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+
+				var snapshot teapot.SnapshotResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &snapshot)
+				Expect(snapshot.State).To(Equal(models.SnapshotPendingState))
+				Expect(snapshot.Owner).To(Equal("alice"))
+			})
+
+			It("uploads the home directory tarball", func() {
+				Eventually(fakeBlobstore.PutCallCount).Should(Equal(1))
+				key, content, size := fakeBlobstore.PutArgsForCall(0)
+
+				var snapshot teapot.SnapshotResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &snapshot)
+				Expect(key).To(Equal("snapshots/" + snapshot.ID + ".tar"))
+				Expect(size).To(Equal(int64(7)))
+				Expect(content).NotTo(BeNil())
+			})
+
+			It("completes the snapshot", func() {
+				var snapshot teapot.SnapshotResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &snapshot)
+
+				Eventually(func() string {
+					var stored models.Snapshot
+					dataStore.Get("snapshots", snapshot.ID, &stored)
+					return stored.State
+				}).Should(Equal(models.SnapshotCompleteState))
+			})
+		})
+
+		Context("when the upload fails", func() {
+			BeforeEach(func() {
+				teaServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, "tarball"))
+				fakeBlobstore.PutReturns(errors.New("bucket is gone"))
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=w1"
+				handler.Create(responseRecorder, req)
+			})
+
+			It("fails the snapshot with the reason", func() {
+				var snapshot teapot.SnapshotResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &snapshot)
+
+				var stored models.Snapshot
+				Eventually(func() string {
+					dataStore.Get("snapshots", snapshot.ID, &stored)
+					return stored.State
+				}).Should(Equal(models.SnapshotFailedState))
+				Expect(stored.FailureReason).To(Equal("bucket is gone"))
+			})
+		})
+
+		Context("when backups are not configured", func() {
+			BeforeEach(func() {
+				handler = NewSnapshotHandler(managers.NewBackupManager(workstationManager, nil, dataStore, logger), workstationManager, []string{"admin"}, logger)
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=w1"
+				handler.Create(responseRecorder, req)
+			})
+
+			It("fails with a 501 NOT IMPLEMENTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotImplemented))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.BackupsNotConfigured))
+			})
+		})
+	})
+
+	Describe("Get", func() {
+		Context("when the snapshot exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=s1"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("responds with the snapshot", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var snapshot teapot.SnapshotResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &snapshot)
+				Expect(snapshot.Workstation).To(Equal("w1"))
+			})
+		})
+
+		Context("when the snapshot doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=nope"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.SnapshotNotFound))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when the tarball is already gone", func() {
+			BeforeEach(func() {
+				fakeBlobstore.DeleteReturns(blobstore.ErrNotFound)
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=s1"
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("still forgets the snapshot", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+				Expect(fakeBlobstore.DeleteArgsForCall(0)).To(Equal("snapshots/s1.tar"))
+				Expect(dataStore.Keys("snapshots")).To(ConsistOf("s2", "s3"))
+			})
+		})
+	})
+
+	Context("when the user doesn't own the workstation", func() {
+		BeforeEach(func() {
+			req = WithUser(newTestRequest(""), "bob")
+		})
+
+		It("forbids listing its snapshots", func() {
+			req.URL.RawQuery = ":name=w1"
+			handler.List(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("forbids snapshotting it", func() {
+			req.URL.RawQuery = ":name=w1"
+			handler.Create(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			Expect(fakeBlobstore.PutCallCount()).To(Equal(0))
+		})
+
+		It("forbids getting the snapshots of someone else's workstation", func() {
+			req = WithUser(newTestRequest(""), "alice")
+			req.URL.RawQuery = ":id=s3"
+			handler.Get(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("forbids deleting the snapshots of someone else's workstation", func() {
+			req = WithUser(newTestRequest(""), "alice")
+			req.URL.RawQuery = ":id=s3"
+			handler.Delete(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			Expect(dataStore.Keys("snapshots")).To(ContainElement("s3"))
+		})
+	})
+})
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type TemplateHandler struct {
+	manager managers.TemplateManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+// NewTemplateHandler returns a TemplateHandler that only lets the given admins
+// change templates.
+func NewTemplateHandler(manager managers.TemplateManager, admins []string, logger lager.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("list-templates")
+
+	templates, err := h.manager.List()
+	if err != nil {
+		h.writeTemplateError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("get-template", lager.Data{
+		"Name": name,
+	})
+
+	template, err := h.manager.Get(name)
+	if err != nil {
+		h.writeTemplateError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, template)
+}
+
+func (h *TemplateHandler) Save(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("save-template", lager.Data{
+		"Name": name,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	templateRequest := teapot.TemplateRequest{}
+	err := json.NewDecoder(r.Body).Decode(&templateRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	template := models.NewTemplate(name, templateRequest)
+	err = h.manager.Save(template)
+	if err != nil {
+		h.writeTemplateError(w, log, err)
+		return
+	}
+
+	log.Info("saved", lager.Data{"template_name": name})
+
+	writeJSONResponse(w, http.StatusOK, template)
+}
+
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("delete-template", lager.Data{
+		"Name": name,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err := h.manager.Delete(name)
+	if err != nil {
+		h.writeTemplateError(w, log, err)
+		return
+	}
+
+	log.Info("deleted", lager.Data{"template_name": name})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TemplateHandler) writeTemplateError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Error("invalid-template", err)
+		writeBadRequestResponse(w, teapot.InvalidTemplate, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"name": t.Name})
+		writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+			Type:    teapot.TemplateNotFound,
+			Message: fmt.Sprintf("Template with name '%s' not found", t.Name),
+		})
+	}
+}
+
+func adminSet(admins []string) map[string]bool {
+	set := map[string]bool{}
+	for _, admin := range admins {
+		set[admin] = true
+	}
+	return set
+}
+
+func writeForbiddenResponse(w http.ResponseWriter) {
+	writeJSONResponse(w, http.StatusForbidden, teapot.Error{
+		Type:    teapot.Forbidden,
+		Message: http.StatusText(http.StatusForbidden),
+	})
+}
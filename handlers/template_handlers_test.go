@@ -0,0 +1,218 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TemplateHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *TemplateHandler
+		tmpDir           string
+		templatesPath    string
+		req              *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "teapot-templates")
+		Expect(err).NotTo(HaveOccurred())
+		templatesPath = filepath.Join(tmpDir, "templates.json")
+		err = ioutil.WriteFile(templatesPath, []byte(`[{"name":"small","memory_mb":256},{"name":"large","memory_mb":4096}]`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		manager, err := managers.NewTemplateManager(templatesPath)
+		Expect(err).NotTo(HaveOccurred())
+		handler = NewTemplateHandler(manager, []string{"admin"}, logger)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	storedTemplates := func() []models.Template {
+		var templates []models.Template
+		contents, _ := ioutil.ReadFile(templatesPath)
+		json.Unmarshal(contents, &templates)
+		return templates
+	}
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			handler.List(responseRecorder, newTestRequest(""))
+		})
+
+		It("responds with the templates from the file, by name", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var templates []teapot.TemplateResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &templates)
+			Expect(templates).To(Equal([]teapot.TemplateResponse{
+				{Name: "large", MemoryMB: 4096},
+				{Name: "small", MemoryMB: 256},
+			}))
+		})
+	})
+
+	Describe("Get", func() {
+		Context("when the template doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=nope"
+				handler.Get(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.TemplateNotFound))
+			})
+		})
+	})
+
+	Describe("Save", func() {
+		Context("when the user is an admin", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.TemplateRequest{DockerImage: "docker:///golang#1.3.3", MemoryMB: 1024})
+				req.URL.RawQuery = ":name=go"
+				req = WithUser(req, "admin")
+				handler.Save(responseRecorder, req)
+			})
+
+			It("responds with the saved template", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var template teapot.TemplateResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &template)
+				Expect(template.Name).To(Equal("go"))
+			})
+
+			It("writes the template back to the file", func() {
+				Expect(storedTemplates()).To(ContainElement(models.Template{
+					Name:        "go",
+					DockerImage: "docker:///golang#1.3.3",
+					MemoryMB:    1024,
+				}))
+			})
+		})
+
+		Context("when the template is invalid", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.TemplateRequest{DockerImage: "ubuntu"})
+				req.URL.RawQuery = ":name=bad"
+				req = WithUser(req, "admin")
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(storedTemplates()).To(HaveLen(2))
+			})
+		})
+
+		Context("when the file can't be written", func() {
+			BeforeEach(func() {
+				os.RemoveAll(tmpDir)
+
+				req = newTestRequest(teapot.TemplateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=go"
+				req = WithUser(req, "admin")
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails without keeping the template", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+
+				responseRecorder = httptest.NewRecorder()
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=go"
+				handler.Get(responseRecorder, req)
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the user is not an admin", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.TemplateRequest{MemoryMB: 1})
+				req.URL.RawQuery = ":name=small"
+				req = WithUser(req, "alice")
+				handler.Save(responseRecorder, req)
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(storedTemplates()).To(ContainElement(models.Template{Name: "small", MemoryMB: 256}))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when the user is an admin", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=small"
+				req = WithUser(req, "admin")
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("removes the template from the file", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+				Expect(storedTemplates()).To(Equal([]models.Template{{Name: "large", MemoryMB: 4096}}))
+			})
+		})
+
+		Context("when the file can't be written", func() {
+			BeforeEach(func() {
+				os.RemoveAll(tmpDir)
+
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=small"
+				req = WithUser(req, "admin")
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("fails without removing the template", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+
+				responseRecorder = httptest.NewRecorder()
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=small"
+				handler.Get(responseRecorder, req)
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the user is not an admin", func() {
+			BeforeEach(func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=small"
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+})
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+type UsageHandler struct {
+	manager managers.UsageManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+// NewUsageHandler returns a UsageHandler that only lets the given admins in,
+// the usage being everyone's.
+func NewUsageHandler(manager managers.UsageManager, admins []string, logger lager.Logger) *UsageHandler {
+	return &UsageHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+// Get reports the daily usage between the from and to days, both included,
+// grouped by user, team or image. It defaults to the current month by user.
+// The report is CSV when format=csv or the request accepts text/csv, JSON
+// otherwise.
+func (h *UsageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	log := h.logger.Session("get-usage", lager.Data{
+		"Query": query,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	groupBy, from, to, err := usageQuery(query.Get("group_by"), query.Get("from"), query.Get("to"), time.Now().UTC())
+	if err != nil {
+		log.Info("invalid-usage-query", lager.Data{"error": err.Error()})
+		writeBadRequestResponse(w, teapot.InvalidUsageQuery, err)
+		return
+	}
+
+	records, err := h.manager.Report(groupBy, from, to)
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	if query.Get("format") == "csv" || (query.Get("format") == "" && strings.Contains(r.Header.Get("Accept"), "text/csv")) {
+		writeUsageCSV(w, groupBy, records)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, records)
+}
+
+// usageQuery parses the report's parameters, to being returned as the
+// midnight ending that day.
+func usageQuery(groupBy, fromDay, toDay string, now time.Time) (string, time.Time, time.Time, error) {
+	var validationError models.ValidationError
+
+	if groupBy == "" {
+		groupBy = models.UsageByUser
+	}
+	if !models.ValidUsageGroupBy(groupBy) {
+		validationError = append(validationError, models.ErrInvalidField{"group_by"})
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if fromDay != "" {
+		var err error
+		from, err = time.Parse(models.UsageDayFormat, fromDay)
+		if err != nil {
+			validationError = append(validationError, models.ErrInvalidField{"from"})
+		}
+	}
+
+	to := today
+	if toDay != "" {
+		var err error
+		to, err = time.Parse(models.UsageDayFormat, toDay)
+		if err != nil || to.Before(from) {
+			validationError = append(validationError, models.ErrInvalidField{"to"})
+		}
+	}
+
+	if len(validationError) > 0 {
+		return "", time.Time{}, time.Time{}, validationError
+	}
+	return groupBy, from, to.Add(24 * time.Hour), nil
+}
+
+func writeUsageCSV(w http.ResponseWriter, groupBy string, records []models.UsageRecord) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+
+	formatHours := func(hours float64) string {
+		return strconv.FormatFloat(hours, 'f', -1, 64)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"day", groupBy, "workstations", "hours", "memory_mb_hours", "disk_mb_hours", "cpu_weight_hours"})
+	for _, record := range records {
+		writer.Write([]string{
+			record.Day,
+			record.Group,
+			strconv.Itoa(record.Workstations),
+			formatHours(record.Hours),
+			formatHours(record.MemoryMBHours),
+			formatHours(record.DiskMBHours),
+			formatHours(record.CPUWeightHours),
+		})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,143 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UsageHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *UsageHandler
+		quotasFile       *os.File
+		req              *http.Request
+	)
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2015, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+
+		var err error
+		quotasFile, err = ioutil.TempFile("", "teapot-quotas")
+		Expect(err).NotTo(HaveOccurred())
+		quotasFile.WriteString(`{"teams":[{"name":"core","members":["alice","bob"]}]}`)
+		quotasFile.Close()
+
+		dataStore, _ := store.NewStore("")
+		quotaManager, err := managers.NewQuotaManager(quotasFile.Name())
+		Expect(err).NotTo(HaveOccurred())
+		manager := managers.NewUsageManager(dataStore, quotaManager)
+		handler = NewUsageHandler(manager, []string{"admin"}, logger)
+
+		alice := models.Workstation{Name: "w1", Owner: "alice", DockerImage: "docker:///ubuntu", MemoryMB: 1024, DiskMB: 2048, CPUWeight: 2}
+		bob := models.Workstation{Name: "w2", Owner: "bob", DockerImage: "docker:///ubuntu", MemoryMB: 512, DiskMB: 1024, CPUWeight: 1}
+		Expect(manager.Start(alice, at(1, 20))).To(Succeed())
+		Expect(manager.Stop("w1", at(2, 2))).To(Succeed())
+		Expect(manager.Start(bob, at(2, 10))).To(Succeed())
+		Expect(manager.Stop("w2", at(2, 12))).To(Succeed())
+
+		req = newTestRequest("")
+		req = WithUser(req, "admin")
+	})
+
+	AfterEach(func() {
+		os.Remove(quotasFile.Name())
+	})
+
+	JustBeforeEach(func() {
+		handler.Get(responseRecorder, req)
+	})
+
+	Context("by user", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "group_by=user&from=2015-03-01&to=2015-03-02"
+		})
+
+		It("responds with the daily usage of each user", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var records []teapot.UsageRecordResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &records)
+			Expect(records).To(Equal([]teapot.UsageRecordResponse{
+				{Day: "2015-03-01", GroupBy: "user", Group: "alice", Workstations: 1, Hours: 4, MemoryMBHours: 4096, DiskMBHours: 8192, CPUWeightHours: 8},
+				{Day: "2015-03-02", GroupBy: "user", Group: "alice", Workstations: 1, Hours: 2, MemoryMBHours: 2048, DiskMBHours: 4096, CPUWeightHours: 4},
+				{Day: "2015-03-02", GroupBy: "user", Group: "bob", Workstations: 1, Hours: 2, MemoryMBHours: 1024, DiskMBHours: 2048, CPUWeightHours: 2},
+			}))
+		})
+	})
+
+	Context("by team, as CSV", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "group_by=team&from=2015-03-02&to=2015-03-02&format=csv"
+		})
+
+		It("responds with the daily usage of each team", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(responseRecorder.Header().Get("Content-Type")).To(Equal("text/csv"))
+			Expect(responseRecorder.Body.String()).To(Equal(
+				"day,team,workstations,hours,memory_mb_hours,disk_mb_hours,cpu_weight_hours\n" +
+					"2015-03-02,core,2,4,3072,6144,6\n",
+			))
+		})
+	})
+
+	Context("when CSV is accepted", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "group_by=image&from=2015-03-01&to=2015-03-01"
+			req.Header.Set("Accept", "text/csv")
+		})
+
+		It("responds with CSV", func() {
+			Expect(responseRecorder.Body.String()).To(Equal(
+				"day,image,workstations,hours,memory_mb_hours,disk_mb_hours,cpu_weight_hours\n" +
+					"2015-03-01,docker:///ubuntu,1,4,4096,8192,8\n",
+			))
+		})
+	})
+
+	Context("when the query is invalid", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "group_by=planet&from=2015-03-02&to=2015-03-01"
+		})
+
+		It("fails with a 400 BAD REQUEST", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+			var response teapot.Error
+			json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+			Expect(response.Type).To(Equal(teapot.InvalidUsageQuery))
+			Expect(response.Message).To(ContainSubstring("group_by"))
+			Expect(response.Message).To(ContainSubstring("to"))
+		})
+	})
+
+	Context("when the user is not an admin", func() {
+		BeforeEach(func() {
+			req = WithUser(req, "alice")
+		})
+
+		It("fails with a 403 FORBIDDEN", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type UserHandler struct {
+	manager managers.UserManager
+	logger  lager.Logger
+}
+
+func NewUserHandler(manager managers.UserManager, logger lager.Logger) *UserHandler {
+	return &UserHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+func (h *UserHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	log := h.logger.Session("list-user-keys", lager.Data{
+		"User": user,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	keys, err := h.manager.ListKeys(user)
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, keys)
+}
+
+func (h *UserHandler) AddKey(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	log := h.logger.Session("add-user-key", lager.Data{
+		"User": user,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+	keyRequest := teapot.UserKeyCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&keyRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	key, err := h.manager.AddKey(user, []byte(keyRequest.Key))
+	if err != nil {
+		switch err.(type) {
+		default:
+			log.Error("unknown-error", err)
+			writeUnknownErrorResponse(w, err)
+		case models.ValidationError:
+			log.Error("invalid-key", err)
+			writeBadRequestResponse(w, teapot.InvalidKey, err)
+		}
+		return
+	}
+
+	log.Info("added", lager.Data{"fingerprint": key.Fingerprint})
+
+	writeJSONResponse(w, http.StatusCreated, key)
+}
+
+func (h *UserHandler) RemoveKey(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	fingerprint := rata.Param(r, "fingerprint")
+	log := h.logger.Session("remove-user-key", lager.Data{
+		"User":        user,
+		"Fingerprint": fingerprint,
+	})
+
+	if user == "" {
+		log.Info("unauthenticated")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err := h.manager.RemoveKey(user, fingerprint)
+	if err != nil {
+		switch err.(type) {
+		default:
+			log.Error("unknown-error", err)
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found")
+			writeKeyNotFoundResponse(w, fingerprint)
+		}
+		return
+	}
+
+	log.Info("removed")
+
+	w.WriteHeader(http.StatusNoContent)
+}
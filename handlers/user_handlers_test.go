@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UserHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *UserHandler
+		userManager      managers.UserManager
+	)
+
+	newUserRequest := func(user string, body interface{}) *http.Request {
+		return WithUser(newTestRequest(body), user)
+	}
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		dataStore, _ := store.NewStore("")
+		userManager = managers.NewUserManager(dataStore)
+		handler = NewUserHandler(userManager, logger)
+	})
+
+	Describe("AddKey", func() {
+		Context("when the key is valid", func() {
+			BeforeEach(func() {
+				handler.AddKey(responseRecorder, newUserRequest("alice", teapot.UserKeyCreateRequest{Key: validKey}))
+			})
+
+			It("responds with 201 CREATED and the parsed key", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+
+				var key models.SSHKey
+				json.Unmarshal(responseRecorder.Body.Bytes(), &key)
+				Expect(key.Fingerprint).To(Equal(validKeyFingerprint))
+			})
+
+			It("stores the key in the requesting user's profile", func() {
+				Expect(userManager.ListKeys("alice")).To(HaveLen(1))
+				Expect(userManager.ListKeys("bob")).To(BeEmpty())
+			})
+		})
+
+		Context("when authentication is disabled", func() {
+			BeforeEach(func() {
+				handler.AddKey(responseRecorder, newTestRequest(teapot.UserKeyCreateRequest{Key: validKey}))
+			})
+
+			It("responds with 403 FORBIDDEN, nobody being identified", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(userManager.ListKeys("")).To(BeEmpty())
+			})
+		})
+
+		Context("when the key is invalid", func() {
+			BeforeEach(func() {
+				handler.AddKey(responseRecorder, newUserRequest("alice", teapot.UserKeyCreateRequest{Key: "nope"}))
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidKey))
+			})
+		})
+	})
+
+	Describe("ListKeys", func() {
+		BeforeEach(func() {
+			userManager.AddKey("alice", []byte(validKey))
+		})
+
+		It("responds with the requesting user's keys", func() {
+			handler.ListKeys(responseRecorder, newUserRequest("alice", ""))
+
+			var keys []models.SSHKey
+			json.Unmarshal(responseRecorder.Body.Bytes(), &keys)
+			Expect(keys).To(HaveLen(1))
+		})
+
+		It("responds with an empty list for users without keys", func() {
+			handler.ListKeys(responseRecorder, newUserRequest("bob", ""))
+
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(responseRecorder.Body.String()).To(Equal("[]"))
+		})
+	})
+
+	Describe("RemoveKey", func() {
+		BeforeEach(func() {
+			userManager.AddKey("alice", []byte(validKey))
+		})
+
+		It("removes the key from the profile", func() {
+			req := newUserRequest("alice", "")
+			req.URL.RawQuery = ":fingerprint=" + validKeyFingerprint
+			handler.RemoveKey(responseRecorder, req)
+
+			Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			Expect(userManager.ListKeys("alice")).To(BeEmpty())
+		})
+
+		It("fails with a 404 NOT FOUND for unknown keys", func() {
+			req := newUserRequest("bob", "")
+			req.URL.RawQuery = ":fingerprint=" + validKeyFingerprint
+			handler.RemoveKey(responseRecorder, req)
+
+			Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/rata"
+)
+
+type WebhookHandler struct {
+	manager managers.WebhookManager
+	admins  map[string]bool
+	logger  lager.Logger
+}
+
+// NewWebhookHandler returns a WebhookHandler that only lets the given admins
+// in, webhooks seeing every workstation's events.
+func NewWebhookHandler(manager managers.WebhookManager, admins []string, logger lager.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		manager: manager,
+		admins:  adminSet(admins),
+		logger:  logger,
+	}
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	log := h.logger.Session("create-webhook", lager.Data{
+		"User": user,
+	})
+
+	if !h.admins[user] {
+		log.Info("forbidden")
+		writeForbiddenResponse(w)
+		return
+	}
+
+	webhookRequest := teapot.WebhookRequest{}
+	err := json.NewDecoder(r.Body).Decode(&webhookRequest)
+	if err != nil {
+		log.Info("invalid-json")
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	webhook, err := h.manager.Create(models.NewWebhook(user, webhookRequest))
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	log.Info("created", lager.Data{"webhook_id": webhook.ID, "url": webhook.URL})
+
+	writeJSONResponse(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("list-webhooks")
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	webhooks, err := h.manager.List()
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, webhooks)
+}
+
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("get-webhook", lager.Data{
+		"ID": id,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	webhook, err := h.manager.Get(id)
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("delete-webhook", lager.Data{
+		"ID": id,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err := h.manager.Delete(id)
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	log.Info("deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("list-webhook-deliveries", lager.Data{
+		"ID": id,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	deliveries, err := h.manager.Deliveries(id)
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("list-dead-letters")
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	deliveries, err := h.manager.DeadLetters()
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, deliveries)
+}
+
+// Redeliver queues a dead-lettered delivery again, the response is the
+// delivery as queued.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id := rata.Param(r, "id")
+	log := h.logger.Session("redeliver", lager.Data{
+		"ID": id,
+	})
+
+	if !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r)})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	delivery, err := h.manager.Redeliver(id)
+	if err != nil {
+		h.writeWebhookError(w, log, err)
+		return
+	}
+
+	log.Info("queued", lager.Data{"webhook_id": delivery.WebhookID})
+
+	writeJSONResponse(w, http.StatusOK, delivery)
+}
+
+func (h *WebhookHandler) writeWebhookError(w http.ResponseWriter, log lager.Logger, err error) {
+	switch t := err.(type) {
+	default:
+		log.Error("unknown-error", err, lager.Data{"type": t})
+		writeUnknownErrorResponse(w, err)
+	case models.ValidationError:
+		log.Info("invalid-webhook", lager.Data{"error": err.Error()})
+		writeBadRequestResponse(w, teapot.InvalidWebhook, err)
+	case models.ErrNotFound:
+		log.Info("not-found", lager.Data{"name": t.Name})
+		if t.Resource == "dead letter" {
+			writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+				Type:    teapot.DeadLetterNotFound,
+				Message: fmt.Sprintf("Dead letter with id '%s' not found", t.Name),
+			})
+			return
+		}
+		writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+			Type:    teapot.WebhookNotFound,
+			Message: fmt.Sprintf("Webhook with id '%s' not found", t.Name),
+		})
+	}
+}
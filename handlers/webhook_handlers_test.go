@@ -0,0 +1,224 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/handlers"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookHandler", func() {
+	var (
+		logger           lager.Logger
+		responseRecorder *httptest.ResponseRecorder
+		handler          *WebhookHandler
+		manager          managers.WebhookManager
+		req              *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		responseRecorder = httptest.NewRecorder()
+		dataStore, _ := store.NewStore("")
+		manager = managers.NewWebhookManager(dataStore, time.Minute, 1)
+		handler = NewWebhookHandler(manager, []string{"admin"}, logger)
+	})
+
+	Describe("Create", func() {
+		var webhookRequest teapot.WebhookRequest
+
+		BeforeEach(func() {
+			webhookRequest = teapot.WebhookRequest{
+				URL:    "https://slack-bot.example.com/teapot",
+				Events: []string{"running", "crashed"},
+				Secret: "s3cret",
+			}
+		})
+
+		JustBeforeEach(func() {
+			req = newTestRequest(webhookRequest)
+			req = WithUser(req, "admin")
+			handler.Create(responseRecorder, req)
+		})
+
+		It("subscribes the webhook, without sending its secret back", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("s3cret"))
+
+			var response teapot.WebhookResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+			Expect(response.ID).NotTo(BeEmpty())
+			Expect(response.URL).To(Equal("https://slack-bot.example.com/teapot"))
+			Expect(response.Events).To(Equal([]string{"running", "crashed"}))
+			Expect(response.CreatedBy).To(Equal("admin"))
+
+			webhook, err := manager.Get(response.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.Secret).To(Equal("s3cret"))
+		})
+
+		Context("when the webhook is invalid", func() {
+			BeforeEach(func() {
+				webhookRequest.Events = []string{"exploded"}
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var response teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response.Type).To(Equal(teapot.InvalidWebhook))
+			})
+		})
+	})
+
+	Context("when the user is not an admin", func() {
+		BeforeEach(func() {
+			req = newTestRequest(teapot.WebhookRequest{URL: "http://example.com", Secret: "s3cret"})
+			req = WithUser(req, "alice")
+		})
+
+		It("can't create webhooks", func() {
+			handler.Create(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("can't list them", func() {
+			handler.List(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("can't list dead letters", func() {
+			handler.DeadLetters(responseRecorder, req)
+			Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("with a webhook", func() {
+		var webhook models.Webhook
+
+		BeforeEach(func() {
+			var err error
+			webhook, err = manager.Create(models.Webhook{URL: "http://billing.example.com", Secret: "s3cret"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(manager.Publish(models.WorkstationEvent{Type: models.WorkstationCreatedEvent, Workstation: "w1"})).To(Succeed())
+		})
+
+		Describe("List", func() {
+			It("lists the webhooks", func() {
+				req = newTestRequest("")
+				req = WithUser(req, "admin")
+				handler.List(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				var response []teapot.WebhookResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(HaveLen(1))
+				Expect(response[0].ID).To(Equal(webhook.ID))
+			})
+		})
+
+		Describe("Get", func() {
+			It("fails with a 404 NOT FOUND for unknown webhooks", func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=nope"
+				req = WithUser(req, "admin")
+				handler.Get(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+				var response teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(Equal(teapot.Error{
+					Type:    teapot.WebhookNotFound,
+					Message: "Webhook with id 'nope' not found",
+				}))
+			})
+		})
+
+		Describe("Deliveries", func() {
+			It("lists the deliveries of the webhook", func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=" + webhook.ID
+				req = WithUser(req, "admin")
+				handler.Deliveries(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				var response []teapot.DeliveryResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(HaveLen(1))
+				Expect(response[0].State).To(Equal(models.DeliveryPendingState))
+				Expect(response[0].Event.Type).To(Equal("created"))
+				Expect(response[0].Event.Workstation).To(Equal("w1"))
+			})
+		})
+
+		Describe("DeadLetters and Redeliver", func() {
+			var deadLetter models.Delivery
+
+			BeforeEach(func() {
+				due, _ := manager.Due(time.Now())
+				deadLetter, _ = manager.RecordAttempt(due[0].ID, 0, errors.New("connection refused"))
+				Expect(deadLetter.State).To(Equal(models.DeliveryFailedState))
+			})
+
+			It("lists the deliveries given up on", func() {
+				req = newTestRequest("")
+				req = WithUser(req, "admin")
+				handler.DeadLetters(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				var response []teapot.DeliveryResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(HaveLen(1))
+				Expect(response[0].ID).To(Equal(deadLetter.ID))
+				Expect(response[0].LastError).To(Equal("connection refused"))
+			})
+
+			It("queues a dead letter again", func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=" + deadLetter.ID
+				req = WithUser(req, "admin")
+				handler.Redeliver(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				due, _ := manager.Due(time.Now())
+				Expect(due).To(HaveLen(1))
+				Expect(due[0].Attempts).To(Equal(0))
+
+				responseRecorder = httptest.NewRecorder()
+				handler.Redeliver(responseRecorder, req)
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+				var response teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response.Type).To(Equal(teapot.DeadLetterNotFound))
+			})
+		})
+
+		Describe("Delete", func() {
+			It("deletes the webhook along with its deliveries", func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":id=" + webhook.ID
+				req = WithUser(req, "admin")
+				handler.Delete(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+				_, err := manager.Get(webhook.ID)
+				Expect(err).To(HaveOccurred())
+				Expect(manager.Due(time.Now())).To(BeEmpty())
+			})
+		})
+	})
+})
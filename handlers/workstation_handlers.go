@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/gorilla/websocket"
@@ -22,17 +23,37 @@ var upgrader = websocket.Upgrader{
 }
 
 type WorkstationHandler struct {
-	manager managers.WorkstationManager
-	logger  lager.Logger
+	manager          managers.WorkstationManager
+	backupManager    managers.BackupManager
+	templateManager  managers.TemplateManager
+	operationManager managers.OperationManager
+	secretManager    managers.SecretManager
+	crashManager     managers.CrashManager
+	privilegePolicy  string
+	admins           map[string]bool
+	logger           lager.Logger
 }
 
-func NewWorkstationHandler(manager managers.WorkstationManager, logger lager.Logger) *WorkstationHandler {
+// NewWorkstationHandler returns a WorkstationHandler that only lets the given
+// admins choose the egress policy of the workstations they create and approve
+// privileged ones. Workstations are privileged as privilegePolicy says, unless
+// their template has a policy of its own.
+func NewWorkstationHandler(manager managers.WorkstationManager, backupManager managers.BackupManager, templateManager managers.TemplateManager, operationManager managers.OperationManager, secretManager managers.SecretManager, crashManager managers.CrashManager, privilegePolicy string, admins []string, logger lager.Logger) *WorkstationHandler {
 	return &WorkstationHandler{
-		manager: manager,
-		logger:  logger,
+		manager:          manager,
+		backupManager:    backupManager,
+		templateManager:  templateManager,
+		operationManager: operationManager,
+		secretManager:    secretManager,
+		crashManager:     crashManager,
+		privilegePolicy:  privilegePolicy,
+		admins:           adminSet(admins),
+		logger:           logger,
 	}
 }
 
+// Create requests the workstation, it responds right away with the operation
+// tracking it until it's ready.
 func (h *WorkstationHandler) Create(w http.ResponseWriter, r *http.Request) {
 	log := h.logger.Session("create")
 	workstationRequest := teapot.WorkstationCreateRequest{}
@@ -47,11 +68,51 @@ func (h *WorkstationHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if workstationRequest.EgressPolicy != "" && !h.admins[requestUser(r)] {
+		log.Info("forbidden", lager.Data{"user": requestUser(r), "egress_policy": workstationRequest.EgressPolicy})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	if workstationRequest.Template != "" {
+		template, err := h.templateManager.Get(workstationRequest.Template)
+		if err != nil {
+			log.Info("template-not-found", lager.Data{"template_name": workstationRequest.Template})
+			writeBadRequestResponse(w, teapot.InvalidWorkstation, models.ValidationError{models.ErrInvalidField{"template"}})
+			return
+		}
+		workstationRequest = template.Apply(workstationRequest)
+	}
+
 	workstation := models.NewWorkstation(workstationRequest)
+	workstation.Owner = requestUser(r)
+	err = h.setPrivilege(&workstation, workstation.Owner, workstationRequest.Privileged)
 
-	err = h.manager.Create(workstation)
+	if err == nil && workstation.RestoreFrom != "" {
+		err = h.backupManager.Restorable(workstation.RestoreFrom, workstation.Owner)
+	}
+
+	if err == nil {
+		workstation.SecretEnv, err = h.secretManager.Resolve(workstation.Owner, workstation.Secrets)
+	}
+
+	operation := models.Operation{}
+	if err == nil {
+		operation, err = h.operationManager.Create(workstation)
+	}
 
 	if err != nil {
+		if err == managers.ErrBackupsDisabled {
+			log.Info("backups-disabled")
+			writeBackupsNotConfiguredResponse(w)
+			return
+		}
+		if err == managers.ErrSecretsDisabled {
+			log.Info("secrets-disabled")
+			writeSecretsNotConfiguredResponse(w)
+			return
+		}
+
 		switch t := err.(type) {
 		default:
 			log.Error("unknown-error", err, lager.Data{"type": t})
@@ -60,6 +121,12 @@ func (h *WorkstationHandler) Create(w http.ResponseWriter, r *http.Request) {
 				Type:    teapot.UnknownError,
 				Message: err.Error(),
 			})
+		case models.ErrInsufficientCapacity:
+			log.Info("insufficient-capacity", lager.Data{"error": err.Error()})
+			writeInsufficientCapacityResponse(w, t)
+		case models.ErrQuotaExceeded:
+			log.Info("quota-exceeded", lager.Data{"error": err.Error()})
+			writeQuotaExceededResponse(w, t)
 		case models.ValidationError:
 			log.Error("invalid-workstation", err)
 			writeJSONResponse(w, http.StatusBadRequest, teapot.Error{
@@ -70,11 +137,345 @@ func (h *WorkstationHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Info("created", lager.Data{"workstation_name": workstation.Name})
+	log.Info("creating", lager.Data{"workstation_name": workstation.Name, "operation_id": operation.ID})
+
+	writeJSONResponse(w, http.StatusAccepted, operation)
+}
+
+// Clone creates a workstation for the requesting user set up like an existing
+// one, optionally starting from its latest snapshot.
+func (h *WorkstationHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("clone", lager.Data{
+		"Name": name,
+	})
+	cloneRequest := teapot.WorkstationCloneRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&cloneRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	clone := models.Workstation{
+		Name:  cloneRequest.Name,
+		Owner: requestUser(r),
+	}
+
+	// The clone gets the secrets of the same names owned by whoever clones,
+	// and only admins keep the source's egress policy, others get the one of
+	// its template or the default one.
+	source, err := h.manager.Get(name)
+	if err == nil {
+		clone.Template = source.Template
+		clone.EgressPolicy = h.cloneEgressPolicy(source, clone.Owner)
+		err = h.setPrivilege(&clone, clone.Owner, source.Privilege != models.UnprivilegedMode)
+	}
+
+	if err == nil {
+		clone.Secrets = source.Secrets
+		clone.SecretEnv, err = h.secretManager.Resolve(clone.Owner, clone.Secrets)
+	}
+
+	if err == nil && cloneRequest.RestoreSnapshot {
+		var snapshot models.Snapshot
+		snapshot, err = h.backupManager.Latest(name)
+		if err == nil {
+			err = h.backupManager.Restorable(snapshot.ID, clone.Owner)
+		}
+		switch err.(type) {
+		case models.ErrNotFound, models.ValidationError:
+			err = models.ValidationError{models.ErrInvalidField{"restore_snapshot"}}
+		}
+		clone.RestoreFrom = snapshot.ID
+	}
+
+	if err == nil {
+		err = h.manager.Clone(name, clone)
+	}
+
+	if err != nil {
+		if err == managers.ErrBackupsDisabled {
+			log.Info("backups-disabled")
+			writeBackupsNotConfiguredResponse(w)
+			return
+		}
+		if err == managers.ErrSecretsDisabled {
+			log.Info("secrets-disabled")
+			writeSecretsNotConfiguredResponse(w)
+			return
+		}
+
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"workstation_name": name})
+			writeWorkstationNotFoundResponse(w, name)
+		case models.ErrInsufficientCapacity:
+			log.Info("insufficient-capacity", lager.Data{"error": err.Error()})
+			writeInsufficientCapacityResponse(w, t)
+		case models.ErrQuotaExceeded:
+			log.Info("quota-exceeded", lager.Data{"error": err.Error()})
+			writeQuotaExceededResponse(w, t)
+		case models.ValidationError:
+			log.Error("invalid-workstation", err)
+			writeBadRequestResponse(w, teapot.InvalidWorkstation, err)
+		}
+		return
+	}
+
+	log.Info("cloned", lager.Data{"workstation_name": name, "clone_name": clone.Name})
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (h *WorkstationHandler) cloneEgressPolicy(source models.Workstation, user string) string {
+	if h.admins[user] {
+		return source.EgressPolicy
+	}
+
+	if source.Template != "" {
+		if template, err := h.templateManager.Get(source.Template); err == nil {
+			return template.EgressPolicy
+		}
+	}
+
+	return ""
+}
+
+// Apply makes the workstations of a manifest, written in JSON or YAML, match
+// it, or only reports the changes it would make when dry_run is set. Running
+// workstations that need replacing keep their home directory, which needs
+// backups to be configured. Workstations that already exist are only changed
+// for admins and their owners.
+func (h *WorkstationHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.Session("apply")
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("failed-to-read-body", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	manifestRequest, err := teapot.ParseWorkstationManifest(body)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	manifest := models.NewManifest(manifestRequest)
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	// Manifests can't ask for privileged workstations, they get whatever the
+	// deployment's policy gives by default.
+	for i := range manifest.Workstations {
+		if err := h.setPrivilege(&manifest.Workstations[i].Workstation, requestUser(r), false); err != nil {
+			log.Error("invalid-manifest", err)
+			writeBadRequestResponse(w, teapot.InvalidManifest, err)
+			return
+		}
+	}
+
+	changes, err := h.manager.Plan(manifest)
+	if err == nil {
+		if !h.canChange(log, requestUser(r), changes) {
+			writeForbiddenResponse(w)
+			return
+		}
+		if !dryRun {
+			changes, err = h.operationManager.Apply(manifest)
+		}
+	}
+
+	if err != nil {
+		if err == managers.ErrBackupsDisabled {
+			log.Info("backups-disabled")
+			writeBackupsNotConfiguredResponse(w)
+			return
+		}
+
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrInsufficientCapacity:
+			log.Info("insufficient-capacity", lager.Data{"error": err.Error()})
+			writeInsufficientCapacityResponse(w, t)
+		case models.ErrQuotaExceeded:
+			log.Info("quota-exceeded", lager.Data{"error": err.Error()})
+			writeQuotaExceededResponse(w, t)
+		case models.ValidationError:
+			log.Error("invalid-manifest", err)
+			writeBadRequestResponse(w, teapot.InvalidManifest, err)
+		case models.ErrOperationInProgress:
+			log.Info("operation-in-progress", lager.Data{"workstation_name": t.Name})
+			writeOperationInProgressResponse(w, t.Name)
+		}
+		return
+	}
+
+	log.Info("applied", lager.Data{"manifest": manifest.Name, "changes": len(changes), "dry_run": dryRun})
+
+	writeJSONResponse(w, http.StatusOK, changes)
+}
+
+// canChange tells whether user may make the changes: anyone may create
+// workstations, but only admins and their owners may change existing ones.
+func (h *WorkstationHandler) canChange(log lager.Logger, user string, changes []models.Change) bool {
+	if h.admins[user] {
+		return true
+	}
+
+	for _, change := range changes {
+		if change.Action == models.ChangeCreate {
+			continue
+		}
+
+		workstation, err := h.manager.Get(change.Workstation)
+		if err != nil || !canAccess(user, h.admins, workstation) {
+			log.Info("forbidden", lager.Data{"user": user, "workstation": change.Workstation, "action": change.Action})
+			return false
+		}
+	}
+
+	return true
+}
+
+// Update resizes a workstation by replacing it, it responds right away with
+// the operation tracking the replacement.
+func (h *WorkstationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("update", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	updateRequest := teapot.WorkstationUpdateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&updateRequest)
+	if err != nil {
+		log.Error("invalid-json", err)
+		writeBadRequestResponse(w, teapot.InvalidJSON, err)
+		return
+	}
+
+	operation, err := h.operationManager.Resize(name, updateRequest)
+	if err != nil {
+		if err == managers.ErrBackupsDisabled {
+			log.Info("backups-disabled")
+			writeBackupsNotConfiguredResponse(w)
+			return
+		}
+
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"workstation_name": name})
+			writeWorkstationNotFoundResponse(w, name)
+		case models.ErrInsufficientCapacity:
+			log.Info("insufficient-capacity", lager.Data{"error": err.Error()})
+			writeInsufficientCapacityResponse(w, t)
+		case models.ErrQuotaExceeded:
+			log.Info("quota-exceeded", lager.Data{"error": err.Error()})
+			writeQuotaExceededResponse(w, t)
+		case models.ValidationError:
+			log.Error("invalid-workstation", err)
+			writeBadRequestResponse(w, teapot.InvalidWorkstation, err)
+		case models.ErrOperationInProgress:
+			log.Info("operation-in-progress", lager.Data{"workstation_name": t.Name})
+			writeOperationInProgressResponse(w, t.Name)
+		}
+		return
+	}
+
+	log.Info("resizing", lager.Data{"workstation_name": name, "operation_id": operation.ID})
+
+	writeJSONResponse(w, http.StatusAccepted, operation)
+}
+
+// ApprovePrivilege lets an admin approve a workstation pending approval, which
+// starts it privileged.
+func (h *WorkstationHandler) ApprovePrivilege(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("approve-privilege", lager.Data{
+		"Name": name,
+	})
+
+	approver := requestUser(r)
+	if !h.admins[approver] {
+		log.Info("forbidden", lager.Data{"user": approver})
+		writeForbiddenResponse(w)
+		return
+	}
+
+	err := h.operationManager.ApprovePrivilege(name, approver)
+	if err != nil {
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"workstation_name": name})
+			writeWorkstationNotFoundResponse(w, name)
+		case models.ErrInsufficientCapacity:
+			log.Info("insufficient-capacity", lager.Data{"error": err.Error()})
+			writeInsufficientCapacityResponse(w, t)
+		case models.ErrQuotaExceeded:
+			log.Info("quota-exceeded", lager.Data{"error": err.Error()})
+			writeQuotaExceededResponse(w, t)
+		case models.ValidationError:
+			log.Error("not-pending-approval", err)
+			writeBadRequestResponse(w, teapot.InvalidWorkstation, err)
+		}
+		return
+	}
+
+	log.Info("approved", lager.Data{"workstation_name": name, "approver": approver})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get responds with the workstation, including why Diego can't place it if
+// it's stuck UNCLAIMED and what's known of its crashes.
+func (h *WorkstationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("get", lager.Data{
+		"Name": name,
+	})
+
+	workstation, err := h.manager.Get(name)
+	if err != nil {
+		if _, ok := err.(models.ErrNotFound); ok {
+			log.Info("not-found")
+			writeWorkstationNotFoundResponse(w, name)
+			return
+		}
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+
+	crashes, err := h.crashManager.Get(name)
+	if err != nil {
+		log.Error("unknown-error", err)
+		writeUnknownErrorResponse(w, err)
+		return
+	}
+	workstation.Crashes = &crashes
+
+	writeJSONResponse(w, http.StatusOK, workstation)
+}
+
 func (h *WorkstationHandler) List(w http.ResponseWriter, r *http.Request) {
 	workstations, _ := h.manager.List()
 
@@ -90,6 +491,10 @@ func (h *WorkstationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		"Name": name,
 	})
 
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
 	err := h.manager.Delete(name)
 	if err != nil {
 		log.Info("delete-failed", lager.Data{"workstation_name": name})
@@ -97,17 +502,64 @@ func (h *WorkstationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.crashManager.Delete(name); err != nil {
+		log.Error("delete-crashes-failed", err)
+	}
+
 	log.Info("deleted", lager.Data{"workstation_name": name})
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Restart kills the workstation's instance so Diego starts a new one.
+func (h *WorkstationHandler) Restart(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("restart", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	err := h.manager.Restart(name)
+	if err != nil {
+		switch t := err.(type) {
+		default:
+			log.Error("unknown-error", err, lager.Data{"type": t})
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"workstation_name": name})
+			writeWorkstationNotFoundResponse(w, name)
+		case models.ErrNotRunning:
+			log.Info("not-running", lager.Data{"workstation_name": name})
+			writeNotRunningResponse(w, name)
+		case models.ErrOperationInProgress:
+			log.Info("operation-in-progress", lager.Data{"workstation_name": t.Name})
+			writeOperationInProgressResponse(w, t.Name)
+		}
+		return
+	}
+
+	if err := h.crashManager.RecordRestart(name, requestUser(r)); err != nil {
+		log.Error("record-restart-failed", err)
+	}
+
+	log.Info("restarted", lager.Data{"workstation_name": name})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *WorkstationHandler) Attach(w http.ResponseWriter, r *http.Request) {
 	name := rata.Param(r, "name")
 	log := h.logger.Session("attach", lager.Data{
 		"Name": name,
 	})
 
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
 	actualLRPs, err := h.manager.Fetch(name)
 	if err != nil || len(actualLRPs) == 0 {
 		log.Info("attach-failed", lager.Data{"workstation_name": name, "actual_lrps": actualLRPs, "error": err})
@@ -171,6 +623,10 @@ func (h *WorkstationHandler) AddKey(w http.ResponseWriter, r *http.Request) {
 		"Name": name,
 	})
 
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
 	actualLRPs, err := h.manager.Fetch(name)
 	if err != nil || len(actualLRPs) == 0 {
 		log.Info("not-found", lager.Data{"workstation_name": name, "actual_lrps": actualLRPs, "error": err})
@@ -195,13 +651,169 @@ func (h *WorkstationHandler) AddKey(w http.ResponseWriter, r *http.Request) {
 	err = h.manager.AddKey(name, key)
 	if err != nil {
 		log.Info("cannot-add", lager.Data{"workstation_name": name, "actual_lrps": actualLRPs, "error": err})
-		w.WriteHeader(http.StatusInternalServerError)
+		if _, ok := err.(models.ValidationError); ok {
+			writeBadRequestResponse(w, teapot.InvalidKey, err)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (h *WorkstationHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("list-keys", lager.Data{
+		"Name": name,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	keys, err := h.manager.ListKeys(name)
+	if err != nil {
+		log.Info("not-found", lager.Data{"workstation_name": name, "error": err})
+		writeWorkstationNotFoundResponse(w, name)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, keys)
+}
+
+func (h *WorkstationHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	fingerprint := rata.Param(r, "fingerprint")
+	log := h.logger.Session("get-key", lager.Data{
+		"Name":        name,
+		"Fingerprint": fingerprint,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	keys, err := h.manager.ListKeys(name)
+	if err != nil {
+		log.Info("not-found", lager.Data{"workstation_name": name, "error": err})
+		writeWorkstationNotFoundResponse(w, name)
+		return
+	}
+
+	for _, key := range keys {
+		if key.Fingerprint == fingerprint {
+			writeJSONResponse(w, http.StatusOK, key)
+			return
+		}
+	}
+
+	writeKeyNotFoundResponse(w, fingerprint)
+}
+
+func (h *WorkstationHandler) RemoveKey(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	fingerprint := rata.Param(r, "fingerprint")
+	log := h.logger.Session("remove-key", lager.Data{
+		"Name":        name,
+		"Fingerprint": fingerprint,
+	})
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	err := h.manager.RemoveKey(name, fingerprint)
+	if err != nil {
+		switch t := err.(type) {
+		default:
+			log.Error("cannot-remove", err)
+			writeUnknownErrorResponse(w, err)
+		case models.ErrNotFound:
+			log.Info("not-found", lager.Data{"resource": t.Resource, "name": t.Name})
+			if t.Resource == "workstation" {
+				writeWorkstationNotFoundResponse(w, name)
+			} else {
+				writeKeyNotFoundResponse(w, fingerprint)
+			}
+		}
+		return
+	}
+
+	log.Info("removed", lager.Data{"workstation_name": name, "fingerprint": fingerprint})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Forward tunnels the binary messages of a websocket to a port inside the
+// workstation.
+func (h *WorkstationHandler) Forward(w http.ResponseWriter, r *http.Request) {
+	name := rata.Param(r, "name")
+	log := h.logger.Session("forward", lager.Data{
+		"Name": name,
+		"Port": rata.Param(r, "port"),
+	})
+
+	port, err := strconv.ParseUint(rata.Param(r, "port"), 10, 16)
+	if err != nil || port == 0 {
+		log.Info("invalid-port")
+		writeBadRequestResponse(w, teapot.InvalidPort, models.ErrInvalidField{"port"})
+		return
+	}
+
+	if !authorizeWorkstation(w, r, log, h.manager, h.admins, name) {
+		return
+	}
+
+	tunnel, err := h.manager.Tunnel(name, uint16(port))
+	if err != nil {
+		log.Info("tunnel-failed", lager.Data{"error": err.Error()})
+		switch err.(type) {
+		case models.ErrNotFound:
+			writeWorkstationNotFoundResponse(w, name)
+		case models.ErrNotRunning:
+			writeNotRunningResponse(w, name)
+		default:
+			writeUnknownErrorResponse(w, err)
+		}
+		return
+	}
+	defer tunnel.Close()
+
+	wsClient, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Error("upgrade-failed", err)
+		return
+	}
+	defer wsClient.Close()
+
+	log.Info("forwarding")
+
+	go func() {
+		buffer := make([]byte, 32*1024)
+		for {
+			n, err := tunnel.Read(buffer)
+			if err != nil {
+				wsClient.Close()
+				return
+			}
+			wsClient.WriteMessage(websocket.BinaryMessage, buffer[:n])
+		}
+	}()
+
+	for {
+		_, message, err := wsClient.ReadMessage()
+		if err != nil {
+			break
+		}
+		if _, err := tunnel.Write(message); err != nil {
+			break
+		}
+	}
+
+	log.Info("closed")
+}
+
 func (h *WorkstationHandler) proxyWebsocket(s *websocket.Conn, d *websocket.Conn, done chan bool) {
 	log := h.logger.Session("proxy-websocket")
 
@@ -217,6 +829,30 @@ func (h *WorkstationHandler) proxyWebsocket(s *websocket.Conn, d *websocket.Conn
 	}
 }
 
+// setPrivilege sets the privilege mode of a workstation created by user, who
+// asks for a privileged one or not, under the policy of its template or else
+// the deployment's. Admins approve the privileged workstations they create.
+func (h *WorkstationHandler) setPrivilege(workstation *models.Workstation, user string, privileged bool) error {
+	policy := h.privilegePolicy
+	if workstation.Template != "" {
+		template, err := h.templateManager.Get(workstation.Template)
+		if err == nil && template.Privilege != "" {
+			policy = template.Privilege
+		}
+	}
+
+	mode, err := models.PrivilegeMode(policy, privileged, h.admins[user])
+	if err != nil {
+		return err
+	}
+
+	workstation.Privilege = mode
+	if policy == models.AdminApprovedPolicy && mode == models.PrivilegedMode {
+		workstation.PrivilegeApprovedBy = user
+	}
+	return nil
+}
+
 func writeWorkstationNotFoundResponse(w http.ResponseWriter, name string) {
 	writeJSONResponse(w, http.StatusNotFound, receptor.Error{
 		Type:    teapot.WorkstationNotFound,
@@ -224,9 +860,27 @@ func writeWorkstationNotFoundResponse(w http.ResponseWriter, name string) {
 	})
 }
 
+func writeKeyNotFoundResponse(w http.ResponseWriter, fingerprint string) {
+	writeJSONResponse(w, http.StatusNotFound, teapot.Error{
+		Type:    teapot.KeyNotFound,
+		Message: fmt.Sprintf("Key with fingerprint '%s' not found", fingerprint),
+	})
+}
+
 func writeInvalidWorkstationResponse(w http.ResponseWriter, workstation receptor.ActualLRPResponse) {
+	writeNotRunningResponse(w, workstation.ProcessGuid)
+}
+
+func writeOperationInProgressResponse(w http.ResponseWriter, name string) {
+	writeJSONResponse(w, http.StatusConflict, teapot.Error{
+		Type:    teapot.OperationInProgress,
+		Message: fmt.Sprintf("Workstation %s is being replaced.", name),
+	})
+}
+
+func writeNotRunningResponse(w http.ResponseWriter, name string) {
 	writeJSONResponse(w, http.StatusBadRequest, receptor.Error{
 		Type:    teapot.InvalidWorkstation,
-		Message: fmt.Sprintf("Workstation %s is not RUNNING.", workstation.ProcessGuid),
+		Message: fmt.Sprintf("Workstation %s is not RUNNING.", name),
 	})
 }
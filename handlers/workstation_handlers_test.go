@@ -4,44 +4,89 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
 	"github.com/luan/teapot"
+	blob_fakes "github.com/luan/teapot/blobstore/fakes"
 	. "github.com/luan/teapot/handlers"
 	"github.com/luan/teapot/managers"
 	"github.com/luan/teapot/models"
 	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
 	"github.com/pivotal-golang/lager"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/ghttp"
 )
 
+const (
+	validKey            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHWEf4+56yLRUuMjH57WdqKF6pgB0cB+oAyLag6FheTj user@example.com"
+	validKeyFingerprint = "21:07:6a:ee:20:2a:fa:2b:4e:87:ba:57:10:ed:35:c9"
+)
+
+var storedKeysAnnotation = `{"keys":[{"fingerprint":"` + validKeyFingerprint + `","type":"ssh-ed25519","key":"` + validKey + `"}]}`
+
 var _ = Describe("WorkstationHandler", func() {
 	var (
-		logger             lager.Logger
-		responseRecorder   *httptest.ResponseRecorder
-		handler            *WorkstationHandler
-		fakeReceptorClient *fake_receptor.FakeClient
-		manager            managers.WorkstationManager
-		fakeRouteProvider  *model_fakes.FakeRouteProvider
+		logger              lager.Logger
+		responseRecorder    *httptest.ResponseRecorder
+		handler             *WorkstationHandler
+		fakeReceptorClient  *fake_receptor.FakeClient
+		manager             managers.WorkstationManager
+		fakeRouteProvider   *model_fakes.FakeRouteProvider
+		dataStore           store.Store
+		templateManager     managers.TemplateManager
+		operationManager    managers.OperationManager
+		secretManager       managers.SecretManager
+		egressPolicyManager managers.EgressPolicyManager
+		quotaManager        managers.QuotaManager
+		capacityManager     managers.CapacityManager
+		crashManager        managers.CrashManager
+		logBuffer           *gbytes.Buffer
 	)
 
 	BeforeEach(func() {
 		logger = lager.NewLogger("test")
 		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		logBuffer = gbytes.NewBuffer()
+		logger.RegisterSink(lager.NewWriterSink(logBuffer, lager.DEBUG))
 		responseRecorder = httptest.NewRecorder()
 		fakeReceptorClient = new(fake_receptor.FakeClient)
 		teaSecret := "something"
 		fakeRouteProvider = &model_fakes.FakeRouteProvider{}
-		manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, teaSecret, logger)
-		handler = NewWorkstationHandler(manager, logger)
+		dataStore, _ = store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ = managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ = managers.NewQuotaManager("")
+		capacityManager = managers.NewCapacityManager(fakeReceptorClient, false)
+		manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, teaSecret, logger)
+		backupManager := managers.NewBackupManager(manager, new(blob_fakes.FakeBlobstore), dataStore, logger)
+		templateManager, _ = managers.NewTemplateManager("")
+		secretManager, _ = managers.NewSecretManager([]byte("0123456789abcdef0123456789abcdef"), dataStore)
+		operationManager = managers.NewOperationManager(manager, backupManager, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+		crashManager = managers.NewCrashManager(dataStore)
+		handler = NewWorkstationHandler(manager, backupManager, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
 	})
 
+	operationState := func(id string) func() string {
+		return func() string {
+			operation, _ := operationManager.Get(id)
+			return operation.State
+		}
+	}
+
 	Describe("Create", func() {
 		validCreateRequest := teapot.WorkstationCreateRequest{
 			Name:        "workstation-name-1",
@@ -57,12 +102,668 @@ var _ = Describe("WorkstationHandler", func() {
 				handler.Create(responseRecorder, newTestRequest(validCreateRequest))
 			})
 
-			It("responds with 201 CREATED", func() {
-				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			It("responds with 202 ACCEPTED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
 			})
 
-			It("responds with an empty body", func() {
-				Expect(responseRecorder.Body.String()).To(Equal(""))
+			It("responds with the operation tracking the creation", func() {
+				var operation teapot.OperationResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &operation)
+				Expect(operation.ID).NotTo(BeEmpty())
+				Expect(operation.Type).To(Equal(models.OperationCreate))
+				Expect(operation.Workstation).To(Equal("workstation-name-1"))
+			})
+		})
+
+		Context("when the workstation is being started", func() {
+			var accepted models.Operation
+
+			JustBeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(validCreateRequest))
+				json.Unmarshal(responseRecorder.Body.Bytes(), &accepted)
+			})
+
+			Context("when the TEA accepts connections", func() {
+				var tea net.Listener
+
+				BeforeEach(func() {
+					var err error
+					tea, err = net.Listen("tcp", "127.0.0.1:0")
+					Expect(err).NotTo(HaveOccurred())
+					teaAddress := tea.Addr().(*net.TCPAddr)
+
+					fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+						ProcessGuid: "workstation-name-1",
+						State:       receptor.ActualLRPStateRunning,
+						Address:     "127.0.0.1",
+						Ports:       []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(teaAddress.Port)}},
+					}}, nil)
+				})
+
+				AfterEach(func() {
+					tea.Close()
+				})
+
+				It("marks the workstation as ready", func() {
+					Expect(accepted.State).To(Equal(models.OperationInProgressState))
+					Expect(accepted.Step).To(Equal(models.OperationRequestingLRPStep))
+					Eventually(operationState(accepted.ID)).Should(Equal(models.OperationSucceededState))
+
+					ready, _ := operationManager.Get(accepted.ID)
+					Expect(ready.Step).To(Equal(models.OperationReadyStep))
+				})
+			})
+
+			Context("when the workstation can't be placed", func() {
+				BeforeEach(func() {
+					fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+						{ProcessGuid: "workstation-name-1", State: receptor.ActualLRPStateUnclaimed, PlacementError: "insufficient resources"},
+					}, nil)
+				})
+
+				It("fails the operation with the placement error", func() {
+					Eventually(operationState(accepted.ID)).Should(Equal(models.OperationFailedState))
+
+					failed, _ := operationManager.Get(accepted.ID)
+					Expect(failed.FailureReason).To(Equal("workstation could not be placed: insufficient resources"))
+				})
+			})
+
+			Context("when the setup takes too long", func() {
+				BeforeEach(func() {
+					fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+						{ProcessGuid: "workstation-name-1", State: receptor.ActualLRPStateClaimed},
+					}, nil)
+				})
+
+				It("fails the operation once it times out", func() {
+					Eventually(operationState(accepted.ID)).Should(Equal(models.OperationFailedState))
+
+					failed, _ := operationManager.Get(accepted.ID)
+					Expect(failed.Step).To(Equal(models.OperationDownloadingSetupStep))
+					Expect(failed.FailureReason).To(ContainSubstring("was not ready after"))
+				})
+			})
+		})
+
+		Context("when the request is authenticated", func() {
+			JustBeforeEach(func() {
+				req := newTestRequest(validCreateRequest)
+				req = WithUser(req, "alice")
+				handler.Create(responseRecorder, req)
+			})
+
+			It("records the user as the owner of the workstation", func() {
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","egress_policy":"open","privilege":"privileged"}`))
+			})
+		})
+
+		Context("when extra ports are requested", func() {
+			JustBeforeEach(func() {
+				request := validCreateRequest
+				request.Ports = []uint16{4000, 8080}
+				handler.Create(responseRecorder, newTestRequest(request))
+			})
+
+			It("exposes them along with the TEA and tiego ports", func() {
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Ports).To(Equal([]uint16{8080, 3000, 4000}))
+			})
+		})
+
+		Context("when a template is requested", func() {
+			BeforeEach(func() {
+				templateManager.Save(models.Template{
+					Name:        "go-large",
+					DockerImage: "docker:///golang#1.3.3",
+					MemoryMB:    2048,
+					Env:         map[string]string{"GOPATH": "/home/vcap/go"},
+					Setup:       []string{"go get github.com/tools/godep"},
+				})
+			})
+
+			Context("when the template exists", func() {
+				JustBeforeEach(func() {
+					handler.Create(responseRecorder, newTestRequest(teapot.WorkstationCreateRequest{
+						Name:     "w1",
+						Template: "go-large",
+						DiskMB:   512,
+					}))
+				})
+
+				It("creates the workstation from the template", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.RootFSPath).To(Equal("docker:///golang#1.3.3"))
+					Expect(lrpRequest.MemoryMB).To(Equal(2048))
+					Expect(lrpRequest.DiskMB).To(Equal(512))
+					Expect(lrpRequest.EnvironmentVariables).To(Equal([]receptor.EnvironmentVariable{{Name: "GOPATH", Value: "/home/vcap/go"}}))
+					Expect(lrpRequest.Annotation).To(Equal(`{"setup":["go get github.com/tools/godep"],"template":"go-large","egress_policy":"open","privilege":"privileged"}`))
+				})
+
+				It("runs the setup scripts after the standard setup", func() {
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					setup, ok := lrpRequest.Setup.(*diego_models.SerialAction)
+					Expect(ok).To(BeTrue())
+					Expect(setup.Actions[len(setup.Actions)-1]).To(Equal(&diego_models.RunAction{
+						Path:      "/bin/bash",
+						Args:      []string{"-c", "go get github.com/tools/godep"},
+						LogSource: "SETUP",
+					}))
+				})
+			})
+
+			Context("when the template doesn't exist", func() {
+				JustBeforeEach(func() {
+					handler.Create(responseRecorder, newTestRequest(teapot.WorkstationCreateRequest{Name: "w1", Template: "nope"}))
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.InvalidWorkstation,
+						Message: "Invalid field: template",
+					})
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+				})
+			})
+		})
+
+		Context("when an egress policy is chosen", func() {
+			var (
+				policiesFile string
+				request      teapot.WorkstationCreateRequest
+				user         string
+			)
+
+			internetOnly := []diego_models.SecurityGroupRule{
+				{Protocol: diego_models.TCPProtocol, Destinations: []string{"0.0.0.0-9.255.255.255", "11.0.0.0-255.255.255.255"}, Ports: []uint16{80, 443}},
+			}
+
+			BeforeEach(func() {
+				file, err := ioutil.TempFile("", "egress-policies")
+				Expect(err).NotTo(HaveOccurred())
+				file.WriteString(egressPoliciesJSON)
+				file.Close()
+				policiesFile = file.Name()
+
+				egressPolicyManager, err = managers.NewEgressPolicyManager(policiesFile, models.OpenEgressPolicy)
+				Expect(err).NotTo(HaveOccurred())
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+				request = validCreateRequest
+				request.EgressPolicy = "internet-only"
+				user = "admin"
+			})
+
+			AfterEach(func() {
+				os.Remove(policiesFile)
+			})
+
+			JustBeforeEach(func() {
+				req := newTestRequest(request)
+				req = WithUser(req, user)
+				handler.Create(responseRecorder, req)
+			})
+
+			It("desires the workstation with the policy's rules", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.EgressRules).To(Equal(internetOnly))
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"admin","egress_policy":"internet-only","privilege":"privileged"}`))
+			})
+
+			Context("when the user isn't an admin", func() {
+				BeforeEach(func() {
+					user = "alice"
+				})
+
+				It("fails with a 403 FORBIDDEN", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the policy comes from the template", func() {
+				BeforeEach(func() {
+					templateManager.Save(models.Template{Name: "locked-down", EgressPolicy: "internet-only"})
+					request = validCreateRequest
+					request.Template = "locked-down"
+					user = "alice"
+				})
+
+				It("uses it even though the user isn't an admin", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					Expect(fakeReceptorClient.CreateDesiredLRPArgsForCall(0).EgressRules).To(Equal(internetOnly))
+				})
+			})
+
+			Context("when the policy doesn't exist", func() {
+				BeforeEach(func() {
+					request.EgressPolicy = "nope"
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.InvalidWorkstation,
+						Message: "Invalid field: egress_policy",
+					})
+					Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+				})
+			})
+
+			Context("when no policy is chosen", func() {
+				BeforeEach(func() {
+					request.EgressPolicy = ""
+				})
+
+				It("uses the default one", func() {
+					Expect(fakeReceptorClient.CreateDesiredLRPArgsForCall(0).EgressRules).To(Equal(models.NewOpenEgressPolicy().SecurityGroupRules()))
+				})
+			})
+		})
+
+		Context("when privilege is restricted", func() {
+			var (
+				policy  string
+				request teapot.WorkstationCreateRequest
+				user    string
+			)
+
+			BeforeEach(func() {
+				policy = models.AdminApprovedPolicy
+				request = validCreateRequest
+				request.Privileged = true
+				user = "alice"
+			})
+
+			JustBeforeEach(func() {
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, policy, []string{"admin"}, logger)
+				req := newTestRequest(request)
+				req = WithUser(req, user)
+				handler.Create(responseRecorder, req)
+			})
+
+			It("desires privileged workstations stopped and unprivileged until they're approved", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Privileged).To(BeFalse())
+				Expect(lrpRequest.Instances).To(Equal(0))
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","egress_policy":"open","privilege":"pending-approval"}`))
+			})
+
+			It("holds the operation until an admin approves the workstation", func() {
+				var operation models.Operation
+				json.Unmarshal(responseRecorder.Body.Bytes(), &operation)
+				Expect(operation.State).To(Equal(models.OperationPendingApprovalState))
+				Expect(operation.Step).To(Equal(models.OperationPendingApprovalStep))
+			})
+
+			Context("when an admin creates it", func() {
+				BeforeEach(func() {
+					user = "admin"
+				})
+
+				It("records the admin's approval", func() {
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.Privileged).To(BeTrue())
+					Expect(lrpRequest.Instances).To(Equal(1))
+					Expect(lrpRequest.Annotation).To(Equal(`{"owner":"admin","egress_policy":"open","privilege":"privileged","privilege_approved_by":"admin"}`))
+				})
+			})
+
+			Context("when privilege isn't asked for", func() {
+				BeforeEach(func() {
+					request.Privileged = false
+				})
+
+				It("desires an unprivileged workstation", func() {
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.Privileged).To(BeFalse())
+					Expect(lrpRequest.Instances).To(Equal(1))
+					Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","egress_policy":"open","privilege":"unprivileged"}`))
+				})
+			})
+
+			Context("when workstations can't be privileged", func() {
+				BeforeEach(func() {
+					policy = models.UnprivilegedPolicy
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.InvalidWorkstation,
+						Message: "Invalid field: privileged",
+					})
+					Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the template has a policy of its own", func() {
+				BeforeEach(func() {
+					templateManager.Save(models.Template{Name: "docker-in-docker", Privilege: models.PrivilegedPolicy})
+					policy = models.UnprivilegedPolicy
+					request.Template = "docker-in-docker"
+				})
+
+				It("follows the template's policy", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					Expect(fakeReceptorClient.CreateDesiredLRPArgsForCall(0).Privileged).To(BeTrue())
+				})
+			})
+		})
+
+		Context("when the owner has a quota", func() {
+			var quotasFile string
+
+			BeforeEach(func() {
+				file, err := ioutil.TempFile("", "quotas")
+				Expect(err).NotTo(HaveOccurred())
+				file.WriteString(`{"default": {"max_workstations": 1, "memory_mb": 2048}}`)
+				file.Close()
+				quotasFile = file.Name()
+
+				quotaManager, err = managers.NewQuotaManager(quotasFile)
+				Expect(err).NotTo(HaveOccurred())
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+			})
+
+			AfterEach(func() {
+				os.Remove(quotasFile)
+			})
+
+			JustBeforeEach(func() {
+				request := validCreateRequest
+				request.MemoryMB = 1024
+				req := newTestRequest(request)
+				req = WithUser(req, "alice")
+				handler.Create(responseRecorder, req)
+			})
+
+			It("creates workstations within it", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+			})
+
+			Context("when the workstation would exceed it", func() {
+				BeforeEach(func() {
+					fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{
+						{ProcessGuid: "other", Instances: 1, MemoryMB: 1536, Annotation: `{"owner":"alice"}`},
+						{ProcessGuid: "bobs", Instances: 1, MemoryMB: 4096, Annotation: `{"owner":"bob"}`},
+					}, nil)
+				})
+
+				It("fails with a 403 FORBIDDEN and the usage", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.QuotaExceeded,
+						Message: "quota of user alice exceeded for max_workstations, memory_mb: 2 workstations, 2560 MB of memory, 0 MB of disk and 1 privileged workstations would be in use",
+					})
+					Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when another workstation is created meanwhile", func() {
+				var rivalErr chan error
+
+				BeforeEach(func() {
+					rivalErr = make(chan error, 1)
+
+					var lock sync.Mutex
+					desired := []receptor.DesiredLRPResponse{}
+					fakeReceptorClient.DesiredLRPsByDomainStub = func(string) ([]receptor.DesiredLRPResponse, error) {
+						lock.Lock()
+						defer lock.Unlock()
+						return desired, nil
+					}
+					fakeReceptorClient.CreateDesiredLRPStub = func(request receptor.DesiredLRPCreateRequest) error {
+						if request.ProcessGuid == validCreateRequest.Name {
+							rival := models.NewWorkstation(teapot.WorkstationCreateRequest{Name: "rival", MemoryMB: 1024})
+							rival.Owner = "alice"
+							go func() {
+								rivalErr <- manager.Create(rival)
+							}()
+							time.Sleep(50 * time.Millisecond)
+						}
+
+						lock.Lock()
+						defer lock.Unlock()
+						desired = append(desired, receptor.DesiredLRPResponse{
+							ProcessGuid: request.ProcessGuid,
+							Instances:   request.Instances,
+							MemoryMB:    request.MemoryMB,
+							Annotation:  request.Annotation,
+						})
+						return nil
+					}
+				})
+
+				It("only lets one of them take the room left", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+
+					var err error
+					Eventually(rivalErr).Should(Receive(&err))
+					Expect(err).To(BeAssignableToTypeOf(models.ErrQuotaExceeded{}))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when capacity admission is on", func() {
+			BeforeEach(func() {
+				capacityManager = managers.NewCapacityManager(fakeReceptorClient, true)
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+				fakeReceptorClient.CellsReturns([]receptor.CellResponse{
+					{CellID: "cell-1", Stack: "lucid64", Capacity: receptor.CellCapacity{MemoryMB: 2048, DiskMB: 4096, Containers: 10}},
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				request := validCreateRequest
+				request.MemoryMB = 1024
+				handler.Create(responseRecorder, newTestRequest(request))
+			})
+
+			It("creates workstations a cell has room for", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+			})
+
+			Context("when no cell has room for the workstation", func() {
+				BeforeEach(func() {
+					fakeReceptorClient.DesiredLRPsReturns([]receptor.DesiredLRPResponse{{ProcessGuid: "big", MemoryMB: 1536}}, nil)
+					fakeReceptorClient.ActualLRPsReturns([]receptor.ActualLRPResponse{
+						{ProcessGuid: "big", CellID: "cell-1", State: receptor.ActualLRPStateRunning},
+					}, nil)
+				})
+
+				It("fails with a 503 SERVICE UNAVAILABLE instead of leaving it UNCLAIMED", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.InsufficientCapacity,
+						Message: "no cell of stack lucid64 can fit a workstation with 1024 MB of memory and 0 MB of disk",
+					})
+					Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the bootstrap is configured", func() {
+			const checksum = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+			BeforeEach(func() {
+				bootstrap := models.DefaultBootstrap()
+				bootstrap.Stack = "trusty64"
+				bootstrap.Domain = "workstations"
+				bootstrap.Steps = []models.BootstrapStep{{Run: "apt-get install -y git"}}
+				bootstrap.Templates = map[string]models.BootstrapOverride{
+					"go-large": {
+						Tea:      &models.Artifact{URL: "http://mirror.internal/tea.tgz", CacheKey: "tea", Checksum: checksum},
+						Dotfiles: "https://git.internal/dotfiles.git",
+					},
+				}
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), bootstrap, egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, managers.NewBackupManager(manager, new(blob_fakes.FakeBlobstore), dataStore, logger), templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+				templateManager.Save(models.Template{Name: "go-large", DockerImage: "docker:///golang#1.3.3"})
+			})
+
+			JustBeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(teapot.WorkstationCreateRequest{Name: "w1", Template: "go-large"}))
+			})
+
+			It("places the workstation on the configured stack and domain", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Stack).To(Equal("trusty64"))
+				Expect(lrpRequest.Domain).To(Equal("workstations"))
+			})
+
+			It("downloads the template's artifacts, cached under their checksum", func() {
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				setup := lrpRequest.Setup.(*diego_models.SerialAction)
+				Expect(setup.Actions[1]).To(Equal(&diego_models.DownloadAction{
+					From:     "http://mirror.internal/tea.tgz",
+					To:       "/tmp",
+					CacheKey: "tea-" + checksum,
+				}))
+			})
+
+			It("runs the steps and installs the dotfiles before the setup scripts", func() {
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				setup := lrpRequest.Setup.(*diego_models.SerialAction)
+				Expect(setup.Actions).To(HaveLen(7))
+				Expect(setup.Actions[5]).To(Equal(&diego_models.RunAction{
+					Path:      "/bin/bash",
+					Args:      []string{"-c", "apt-get install -y git"},
+					LogSource: "BOOTSTRAP",
+				}))
+				dotfiles := setup.Actions[6].(*diego_models.RunAction)
+				Expect(dotfiles.LogSource).To(Equal("DOTFILES"))
+				Expect(dotfiles.Args[1]).To(ContainSubstring("git clone --depth 1 'https://git.internal/dotfiles.git' /home/vcap/.dotfiles"))
+			})
+		})
+
+		Context("when secrets are requested", func() {
+			var request teapot.WorkstationCreateRequest
+
+			BeforeEach(func() {
+				secretManager.Save(models.Secret{Name: "GITHUB_TOKEN", Owner: "alice", Value: "t0ps3cret"})
+				request = validCreateRequest
+				request.Env = map[string]string{"EDITOR": "vim"}
+				request.Secrets = []string{"GITHUB_TOKEN"}
+			})
+
+			Context("when the user has the secrets", func() {
+				JustBeforeEach(func() {
+					req := newTestRequest(request)
+					req = WithUser(req, "alice")
+					handler.Create(responseRecorder, req)
+				})
+
+				It("sets them in the environment of sshd and the TEA", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					action, ok := lrpRequest.Action.(*diego_models.ParallelAction)
+					Expect(ok).To(BeTrue())
+					for _, runAction := range action.Actions {
+						Expect(runAction.(*diego_models.RunAction).Env).To(Equal([]diego_models.EnvironmentVariable{
+							{Name: "GITHUB_TOKEN", Value: "t0ps3cret"},
+						}))
+					}
+				})
+
+				It("only keeps their names in the workstation's description", func() {
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.EnvironmentVariables).To(Equal([]receptor.EnvironmentVariable{{Name: "EDITOR", Value: "vim"}}))
+					Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","secrets":["GITHUB_TOKEN"],"egress_policy":"open","privilege":"privileged"}`))
+				})
+
+				It("doesn't log their values", func() {
+					Expect(logBuffer.Contents()).NotTo(BeEmpty())
+					Expect(string(logBuffer.Contents())).NotTo(ContainSubstring("t0ps3cret"))
+				})
+			})
+
+			Context("when the user doesn't have the secrets", func() {
+				JustBeforeEach(func() {
+					req := newTestRequest(request)
+					req = WithUser(req, "bob")
+					handler.Create(responseRecorder, req)
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+					Expect(responseRecorder.Body.String()).To(ContainSubstring("Invalid field: secrets"))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when restoring from a snapshot", func() {
+			var request teapot.WorkstationCreateRequest
+
+			BeforeEach(func() {
+				dataStore.Put("snapshots", "snap", models.Snapshot{ID: "snap", Owner: "alice", State: models.SnapshotCompleteState})
+				request = validCreateRequest
+				request.RestoreFrom = "snap"
+			})
+
+			Context("when the snapshot belongs to the user", func() {
+				JustBeforeEach(func() {
+					req := newTestRequest(request)
+					req = WithUser(req, "alice")
+					handler.Create(responseRecorder, req)
+				})
+
+				It("records the snapshot to restore", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","restore_from":"snap","egress_policy":"open","privilege":"privileged"}`))
+				})
+			})
+
+			Context("when the snapshot belongs to someone else", func() {
+				JustBeforeEach(func() {
+					req := newTestRequest(request)
+					req = WithUser(req, "bob")
+					handler.Create(responseRecorder, req)
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+					Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the snapshot doesn't exist", func() {
+				JustBeforeEach(func() {
+					request.RestoreFrom = "nope"
+					handler.Create(responseRecorder, newTestRequest(request))
+				})
+
+				It("fails with a 400 BAD REQUEST", func() {
+					expectedBody, _ := json.Marshal(teapot.Error{
+						Type:    teapot.InvalidWorkstation,
+						Message: "Invalid field: restore_from",
+					})
+					Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+				})
 			})
 		})
 
@@ -85,42 +786,891 @@ var _ = Describe("WorkstationHandler", func() {
 			})
 		})
 
-		Context("when the requested workstation is invalid", func() {
+		Context("when the requested workstation is invalid", func() {
+			BeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(invalidCreateRequest))
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("responds with a relevant error message", func() {
+				expectedBody, _ := json.Marshal(teapot.Error{
+					Type:    teapot.InvalidWorkstation,
+					Message: "Invalid field: name",
+				})
+				Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+			})
+
+			It("records no operation", func() {
+				Expect(dataStore.Keys("operations")).To(BeEmpty())
+			})
+		})
+
+		Context("when the request does not contain a WorkstationCreateRequest", func() {
+			var garbageRequest = []byte(`hello`)
+
+			BeforeEach(func() {
+				handler.Create(responseRecorder, newTestRequest(garbageRequest))
+			})
+
+			It("responds with 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("responds with a relevant error message", func() {
+				err := json.Unmarshal(garbageRequest, &teapot.WorkstationCreateRequest{})
+				expectedBody, _ := json.Marshal(teapot.Error{
+					Type:    teapot.InvalidJSON,
+					Message: err.Error(),
+				})
+				Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+			})
+		})
+	})
+
+	Describe("Clone", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+				if name != "original" {
+					return receptor.DesiredLRPResponse{}, errors.New("not found")
+				}
+				return receptor.DesiredLRPResponse{
+					ProcessGuid: "original",
+					RootFSPath:  "docker:///golang#1.3.3",
+					CPUWeight:   3,
+					DiskMB:      1024,
+					MemoryMB:    128,
+					Ports:       []uint16{8080, 3000, 4000},
+					Annotation:  `{"owner":"alice",` + storedKeysAnnotation[1:],
+				}, nil
+			}
+
+			dataStore.Put("snapshots", "old", models.Snapshot{ID: "old", Workstation: "original", Owner: "alice", State: models.SnapshotCompleteState, CreatedAt: time.Unix(100, 0)})
+			dataStore.Put("snapshots", "new", models.Snapshot{ID: "new", Workstation: "original", Owner: "alice", State: models.SnapshotCompleteState, CreatedAt: time.Unix(200, 0)})
+			dataStore.Put("snapshots", "failed", models.Snapshot{ID: "failed", Workstation: "original", Owner: "alice", State: models.SnapshotFailedState, CreatedAt: time.Unix(300, 0)})
+		})
+
+		Context("when the workstation exists", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy"})
+				req.URL.RawQuery = ":name=original"
+				req = WithUser(req, "bob")
+				handler.Clone(responseRecorder, req)
+			})
+
+			It("responds with 201 CREATED", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("creates a workstation with the same image, resources and ports", func() {
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.ProcessGuid).To(Equal("copy"))
+				Expect(lrpRequest.RootFSPath).To(Equal("docker:///golang#1.3.3"))
+				Expect(lrpRequest.CPUWeight).To(Equal(uint(3)))
+				Expect(lrpRequest.DiskMB).To(Equal(1024))
+				Expect(lrpRequest.MemoryMB).To(Equal(128))
+				Expect(lrpRequest.Ports).To(Equal([]uint16{8080, 3000, 4000}))
+			})
+
+			It("copies the keys and makes the user the owner", func() {
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"bob",` + storedKeysAnnotation[1:len(storedKeysAnnotation)-1] + `,"egress_policy":"open","privilege":"privileged"}`))
+			})
+		})
+
+		Context("when the workstation has an egress policy", func() {
+			var (
+				policiesFile string
+				user         string
+			)
+
+			BeforeEach(func() {
+				file, err := ioutil.TempFile("", "egress-policies")
+				Expect(err).NotTo(HaveOccurred())
+				file.WriteString(egressPoliciesJSON)
+				file.Close()
+				policiesFile = file.Name()
+
+				egressPolicyManager, err = managers.NewEgressPolicyManager(policiesFile, models.OpenEgressPolicy)
+				Expect(err).NotTo(HaveOccurred())
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+				fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+					if name != "original" {
+						return receptor.DesiredLRPResponse{}, errors.New("not found")
+					}
+					return receptor.DesiredLRPResponse{
+						ProcessGuid: "original",
+						RootFSPath:  "docker:///golang#1.3.3",
+						Annotation:  `{"owner":"alice","egress_policy":"internet-only"}`,
+					}, nil
+				}
+			})
+
+			JustBeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy"})
+				req.URL.RawQuery = ":name=original"
+				handler.Clone(responseRecorder, WithUser(req, user))
+			})
+
+			AfterEach(func() {
+				os.Remove(policiesFile)
+			})
+
+			Context("when an admin clones it", func() {
+				BeforeEach(func() {
+					user = "admin"
+				})
+
+				It("keeps the egress policy", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.Annotation).To(ContainSubstring(`"egress_policy":"internet-only"`))
+				})
+			})
+
+			Context("when someone else clones it", func() {
+				BeforeEach(func() {
+					user = "bob"
+				})
+
+				It("gets the default egress policy", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+					lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+					Expect(lrpRequest.Annotation).To(ContainSubstring(`"egress_policy":"open"`))
+				})
+			})
+		})
+
+		Context("when the workstation has secrets", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+					if name != "original" {
+						return receptor.DesiredLRPResponse{}, errors.New("not found")
+					}
+					return receptor.DesiredLRPResponse{
+						ProcessGuid: "original",
+						RootFSPath:  "docker:///golang#1.3.3",
+						Annotation:  `{"owner":"alice","secrets":["GITHUB_TOKEN"]}`,
+					}, nil
+				}
+				secretManager.Save(models.Secret{Name: "GITHUB_TOKEN", Owner: "bob", Value: "b0bs"})
+			})
+
+			It("gives the clone the secrets of the same names owned by the user", func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy"})
+				req.URL.RawQuery = ":name=original"
+				req = WithUser(req, "bob")
+				handler.Clone(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"bob","secrets":["GITHUB_TOKEN"],"egress_policy":"open","privilege":"privileged"}`))
+				action := lrpRequest.Action.(*diego_models.ParallelAction)
+				Expect(action.Actions[0].(*diego_models.RunAction).Env).To(Equal([]diego_models.EnvironmentVariable{
+					{Name: "GITHUB_TOKEN", Value: "b0bs"},
+				}))
+			})
+
+			It("fails with a 400 BAD REQUEST when the user doesn't have them", func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy"})
+				req.URL.RawQuery = ":name=original"
+				req = WithUser(req, "carol")
+				handler.Clone(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the latest snapshot is requested", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy", RestoreSnapshot: true})
+				req.URL.RawQuery = ":name=original"
+			})
+
+			It("restores the latest complete snapshot into the clone", func() {
+				handler.Clone(responseRecorder, WithUser(req, "alice"))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.Annotation).To(ContainSubstring(`"restore_from":"new"`))
+			})
+
+			It("fails with a 400 BAD REQUEST when the user doesn't own the snapshot", func() {
+				handler.Clone(responseRecorder, WithUser(req, "bob"))
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "copy"})
+				req.URL.RawQuery = ":name=nope"
+				handler.Clone(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the clone name is taken", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationCloneRequest{Name: "original"})
+				req.URL.RawQuery = ":name=original"
+				handler.Clone(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Get", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+				ProcessGuid: "ws",
+				RootFSPath:  "docker:///ubuntu#trusty",
+				MemoryMB:    1024,
+				Annotation:  `{"owner":"alice"}`,
+			}, nil)
+			fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+				{ProcessGuid: "ws", State: receptor.ActualLRPStateUnclaimed, PlacementError: "insufficient resources"},
+			}, nil)
+
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=ws"
+		})
+
+		JustBeforeEach(func() {
+			handler.Get(responseRecorder, req)
+		})
+
+		It("responds with the workstation and why it can't be placed", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+			var workstation teapot.WorkstationResponse
+			json.Unmarshal(responseRecorder.Body.Bytes(), &workstation)
+			Expect(workstation.Name).To(Equal("ws"))
+			Expect(workstation.Owner).To(Equal("alice"))
+			Expect(workstation.State).To(Equal("UNCLAIMED"))
+			Expect(workstation.PlacementError).To(Equal("insufficient resources"))
+		})
+
+		Context("when the workstation has crashed", func() {
+			BeforeEach(func() {
+				crashManager.RecordCrash("ws", models.CrashedWhileRunningReason, 2)
+				crashManager.RecordRestart("ws", "")
+			})
+
+			It("responds with its crashes and restarts", func() {
+				var workstation teapot.WorkstationResponse
+				json.Unmarshal(responseRecorder.Body.Bytes(), &workstation)
+				Expect(workstation.Crashes.CrashCount).To(Equal(2))
+				Expect(workstation.Crashes.LastCrashReason).To(Equal(models.CrashedWhileRunningReason))
+				Expect(workstation.Crashes.LastCrashedAt).NotTo(BeNil())
+				Expect(workstation.Crashes.Restarts).To(HaveLen(1))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				req.URL.RawQuery = ":name=nope"
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("ApprovePrivilege", func() {
+		var (
+			req        *http.Request
+			desiredLRP receptor.DesiredLRPResponse
+		)
+
+		BeforeEach(func() {
+			desiredLRP = receptor.DesiredLRPResponse{
+				ProcessGuid: "ws",
+				Annotation:  `{"owner":"alice","privilege":"pending-approval"}`,
+			}
+			fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+				return desiredLRP, nil
+			}
+
+			req = newTestRequest("")
+			req = WithUser(req, "admin")
+			req.URL.RawQuery = ":name=ws"
+		})
+
+		JustBeforeEach(func() {
+			handler.ApprovePrivilege(responseRecorder, req)
+		})
+
+		It("replaces the workstation with a privileged one, recording who approved it", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(1))
+			lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+			Expect(lrpRequest.Privileged).To(BeTrue())
+			Expect(lrpRequest.Instances).To(Equal(1))
+			Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","privilege":"privileged","privilege_approved_by":"admin"}`))
+		})
+
+		Context("when the workstation's creation is pending approval", func() {
+			var pending models.Operation
+
+			BeforeEach(func() {
+				pending = models.Operation{
+					ID:          "pending",
+					Type:        models.OperationCreate,
+					Workstation: "ws",
+					State:       models.OperationPendingApprovalState,
+					Step:        models.OperationPendingApprovalStep,
+				}
+				dataStore.Put("operations", pending.ID, pending)
+			})
+
+			It("follows the creation on", func() {
+				Eventually(operationState(pending.ID)).Should(Equal(models.OperationFailedState))
+
+				operation, _ := operationManager.Get(pending.ID)
+				Expect(operation.FailureReason).To(ContainSubstring("not ready"))
+			})
+		})
+
+		Context("when the user isn't an admin", func() {
+			BeforeEach(func() {
+				req = WithUser(req, "alice")
+			})
+
+			It("fails with a 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation isn't pending approval", func() {
+			BeforeEach(func() {
+				desiredLRP.Annotation = `{"owner":"alice","privilege":"unprivileged"}`
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				desiredLRP = receptor.DesiredLRPResponse{}
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		var (
+			req        *http.Request
+			desiredLRP receptor.DesiredLRPResponse
+		)
+
+		BeforeEach(func() {
+			desiredLRP = receptor.DesiredLRPResponse{
+				ProcessGuid: "workstation-name",
+				Domain:      "tiego",
+				Instances:   1,
+				RootFSPath:  "docker:///golang#1.3.3",
+				CPUWeight:   3,
+				DiskMB:      1024,
+				MemoryMB:    256,
+				Ports:       []uint16{8080, 3000, 4000},
+				Routes: cfroutes.CFRoutes{
+					{Hostnames: []string{"extra.example.com"}, Port: 4000},
+				}.RoutingInfo(),
+				Annotation: storedKeysAnnotation,
+			}
+			fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+				if name != desiredLRP.ProcessGuid {
+					return receptor.DesiredLRPResponse{}, errors.New("not found")
+				}
+				return desiredLRP, nil
+			}
+		})
+
+		Context("when the user doesn't own the workstation", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, WithUser(req, "alice"))
+			})
+
+			It("responds with 403 FORBIDDEN", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+			})
+
+			It("doesn't resize the workstation", func() {
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation is STOPPED", func() {
+			var accepted models.Operation
+
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, req)
+				json.Unmarshal(responseRecorder.Body.Bytes(), &accepted)
+			})
+
+			It("responds with 202 ACCEPTED and the operation", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+				Expect(accepted.ID).NotTo(BeEmpty())
+				Expect(accepted.Type).To(Equal(models.OperationResize))
+				Expect(accepted.Workstation).To(Equal("workstation-name"))
+				Expect(accepted.State).To(Equal(models.OperationInProgressState))
+			})
+
+			It("replaces the workstation with a resized one", func() {
+				Eventually(operationState(accepted.ID)).Should(Equal(models.OperationSucceededState))
+
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(1))
+				Expect(fakeReceptorClient.DeleteDesiredLRPArgsForCall(0)).To(Equal("workstation-name"))
+
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.MemoryMB).To(Equal(1024))
+				Expect(lrpRequest.DiskMB).To(Equal(1024))
+				Expect(lrpRequest.RootFSPath).To(Equal("docker:///golang#1.3.3"))
+				Expect(lrpRequest.Instances).To(Equal(1))
+				Expect(lrpRequest.Ports).To(Equal(desiredLRP.Ports))
+				Expect(lrpRequest.Routes).To(Equal(desiredLRP.Routes))
+				Expect(lrpRequest.Annotation).To(Equal(storedKeysAnnotation))
+			})
+		})
+
+		Context("when the workstation is already being replaced", func() {
+			var deleting chan struct{}
+
+			BeforeEach(func() {
+				blocked := make(chan struct{})
+				fakeReceptorClient.DeleteDesiredLRPStub = func(string) error {
+					<-blocked
+					return nil
+				}
+				deleting = blocked
+
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(httptest.NewRecorder(), req)
+			})
+
+			AfterEach(func() {
+				close(deleting)
+			})
+
+			It("fails another resize with a 409 CONFLICT", func() {
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 512})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.OperationInProgress))
+			})
+
+			It("fails restarts with a 409 CONFLICT", func() {
+				req = newTestRequest("")
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Restart(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+			})
+
+			It("keeps the workstation from being started or stopped", func() {
+				Expect(manager.Start("workstation-name")).To(Equal(models.ErrOperationInProgress{"workstation-name"}))
+				Expect(manager.Stop("workstation-name")).To(Equal(models.ErrOperationInProgress{"workstation-name"}))
+			})
+
+			It("releases the workstation once it is replaced", func() {
+				deleting <- struct{}{}
+				Eventually(func() error {
+					return manager.Stop("workstation-name")
+				}).Should(Succeed())
+			})
+		})
+
+		Context("when the resize would exceed the owner's quota", func() {
+			var quotasFile string
+
+			BeforeEach(func() {
+				file, err := ioutil.TempFile("", "quotas")
+				Expect(err).NotTo(HaveOccurred())
+				file.WriteString(`{"default": {"memory_mb": 512}}`)
+				file.Close()
+				quotasFile = file.Name()
+
+				quotaManager, err = managers.NewQuotaManager(quotasFile)
+				Expect(err).NotTo(HaveOccurred())
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+				fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{desiredLRP}, nil)
+
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, req)
+			})
+
+			AfterEach(func() {
+				os.Remove(quotasFile)
+			})
+
+			It("fails with a 403 FORBIDDEN without replacing the workstation", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(responseRecorder.Body.String()).To(ContainSubstring(teapot.QuotaExceeded))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when no cell has room for the resized workstation", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+					{ProcessGuid: "workstation-name", State: receptor.ActualLRPStateClaimed},
+				}, nil)
+				capacityManager = managers.NewCapacityManager(fakeReceptorClient, true)
+				manager = managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+				operationManager = managers.NewOperationManager(manager, nil, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, nil, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+				fakeReceptorClient.CellsReturns([]receptor.CellResponse{
+					{CellID: "cell-1", Stack: "lucid64", Capacity: receptor.CellCapacity{MemoryMB: 2048, DiskMB: 4096, Containers: 10}},
+				}, nil)
+
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 4096})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("fails with a 503 SERVICE UNAVAILABLE without replacing the workstation", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(responseRecorder.Body.String()).To(ContainSubstring(teapot.InsufficientCapacity))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation is RUNNING", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+					{ProcessGuid: "workstation-name", State: receptor.ActualLRPStateRunning},
+				}, nil)
+			})
+
+			Context("when backups are not configured", func() {
+				BeforeEach(func() {
+					backupManager := managers.NewBackupManager(manager, nil, dataStore, logger)
+					operationManager = managers.NewOperationManager(manager, backupManager, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+					handler = NewWorkstationHandler(manager, backupManager, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+					req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+					req.URL.RawQuery = ":name=workstation-name"
+					handler.Update(responseRecorder, req)
+				})
+
+				It("refuses to replace it with a 501 NOT IMPLEMENTED", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusNotImplemented))
+					Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the home directory cannot be backed up", func() {
+				var accepted models.Operation
+
+				BeforeEach(func() {
+					backupManager := managers.NewBackupManager(manager, new(blob_fakes.FakeBlobstore), dataStore, logger)
+					operationManager = managers.NewOperationManager(manager, backupManager, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+					handler = NewWorkstationHandler(manager, backupManager, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+
+					req = newTestRequest(teapot.WorkstationUpdateRequest{CPUWeight: 5})
+					req.URL.RawQuery = ":name=workstation-name"
+					handler.Update(responseRecorder, req)
+					json.Unmarshal(responseRecorder.Body.Bytes(), &accepted)
+				})
+
+				It("fails the operation without touching the workstation", func() {
+					Expect(responseRecorder.Code).To(Equal(http.StatusAccepted))
+					Eventually(operationState(accepted.ID)).Should(Equal(models.OperationFailedState))
+
+					failed, _ := operationManager.Get(accepted.ID)
+					Expect(failed.Step).To(Equal(models.OperationBackingUpStep))
+					Expect(failed.FailureReason).To(ContainSubstring("backup failed"))
+					Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when nothing would change", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationUpdateRequest{})
+				req.URL.RawQuery = ":name=workstation-name"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				req = newTestRequest(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+				req.URL.RawQuery = ":name=nope"
+				handler.Update(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Apply", func() {
+		var (
+			req      *http.Request
+			manifest teapot.WorkstationManifest
+			deleted  map[string]bool
+		)
+
+		BeforeEach(func() {
+			existing := []receptor.DesiredLRPResponse{
+				{
+					ProcessGuid: "lab-02",
+					RootFSPath:  models.DefaultDockerImage,
+					Annotation:  `{"owner":"alice","schedules":[{"id":"s1","cron":"0 8 * * 1-5","action":"start"}],"manifest":"lab"}`,
+				},
+				{ProcessGuid: "lab-03", RootFSPath: models.DefaultDockerImage, MemoryMB: 256, Annotation: `{"manifest":"lab"}`},
+				{ProcessGuid: "lab-04", RootFSPath: models.DefaultDockerImage, Annotation: `{"manifest":"lab"}`},
+				{ProcessGuid: "other", RootFSPath: models.DefaultDockerImage},
+			}
+			fakeReceptorClient.DesiredLRPsByDomainReturns(existing, nil)
+
+			deleted = map[string]bool{}
+			fakeReceptorClient.DeleteDesiredLRPStub = func(name string) error {
+				deleted[name] = true
+				return nil
+			}
+			fakeReceptorClient.GetDesiredLRPStub = func(name string) (receptor.DesiredLRPResponse, error) {
+				for _, desiredLRP := range existing {
+					if desiredLRP.ProcessGuid == name && !deleted[name] {
+						return desiredLRP, nil
+					}
+				}
+				return receptor.DesiredLRPResponse{}, errors.New("not found")
+			}
+			fakeReceptorClient.CreateDesiredLRPStub = func(request receptor.DesiredLRPCreateRequest) error {
+				delete(deleted, request.ProcessGuid)
+				return nil
+			}
+
+			manifest = teapot.WorkstationManifest{
+				Name: "lab",
+				Workstations: []teapot.WorkstationSpec{
+					{Name: "lab-01", Owner: "alice", Keys: []string{validKey}},
+					{Name: "lab-02", Owner: "bob", Schedules: []teapot.ScheduleCreateRequest{{Cron: "0 8 * * 1-5", Action: "start"}}},
+					{Name: "lab-03", MemoryMB: 512},
+				},
+			}
+		})
+
+		Context("when it is a dry run", func() {
+			BeforeEach(func() {
+				req = WithUser(newTestRequest(manifest), "admin")
+				req.URL.RawQuery = "dry_run=true"
+				handler.Apply(responseRecorder, req)
+			})
+
+			It("responds with the changes to make", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var changes []teapot.WorkstationChange
+				json.Unmarshal(responseRecorder.Body.Bytes(), &changes)
+				Expect(changes).To(Equal([]teapot.WorkstationChange{
+					{Workstation: "lab-01", Action: "create"},
+					{Workstation: "lab-02", Action: "update", Fields: []teapot.FieldChange{{Field: "owner", From: "alice", To: "bob"}}},
+					{Workstation: "lab-03", Action: "replace", Fields: []teapot.FieldChange{{Field: "memory_mb", From: "256", To: "512"}}},
+					{Workstation: "lab-04", Action: "delete"},
+				}))
+			})
+
+			It("changes nothing", func() {
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the manifest is written in YAML", func() {
+			BeforeEach(func() {
+				req = WithUser(newTestRequest(`
+name: lab
+workstations:
+- name: lab-01
+  owner: alice
+- name: lab-02
+  owner: bob
+  schedules:
+  - cron: "0 8 * * 1-5"
+    action: start
+- name: lab-03
+  memory_mb: 512
+`), "admin")
+				req.URL.RawQuery = "dry_run=true"
+				handler.Apply(responseRecorder, req)
+			})
+
+			It("responds with the changes to make", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var changes []teapot.WorkstationChange
+				json.Unmarshal(responseRecorder.Body.Bytes(), &changes)
+				Expect(changes).To(Equal([]teapot.WorkstationChange{
+					{Workstation: "lab-01", Action: "create"},
+					{Workstation: "lab-02", Action: "update", Fields: []teapot.FieldChange{{Field: "owner", From: "alice", To: "bob"}}},
+					{Workstation: "lab-03", Action: "replace", Fields: []teapot.FieldChange{{Field: "memory_mb", From: "256", To: "512"}}},
+					{Workstation: "lab-04", Action: "delete"},
+				}))
+			})
+		})
+
+		Context("when the user doesn't own every workstation the manifest changes", func() {
+			BeforeEach(func() {
+				handler.Apply(responseRecorder, WithUser(newTestRequest(manifest), "alice"))
+			})
+
+			It("fails with a 403 FORBIDDEN without changing anything", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the user owns every workstation the manifest changes", func() {
+			BeforeEach(func() {
+				existing, _ := fakeReceptorClient.DesiredLRPsByDomain("tiego")
+				fakeReceptorClient.DesiredLRPsByDomainReturns(existing[:1], nil)
+				manifest.Workstations = manifest.Workstations[:2]
+				handler.Apply(responseRecorder, WithUser(newTestRequest(manifest), "alice"))
+			})
+
+			It("applies it", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(1))
+				name, _ := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(name).To(Equal("lab-02"))
+			})
+		})
+
+		Context("when the manifest is applied", func() {
+			var changes []teapot.WorkstationChange
+
+			BeforeEach(func() {
+				handler.Apply(responseRecorder, WithUser(newTestRequest(manifest), "admin"))
+				json.Unmarshal(responseRecorder.Body.Bytes(), &changes)
+			})
+
+			It("creates the new workstations, labelled with the manifest", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(0)
+				Expect(lrpRequest.ProcessGuid).To(Equal("lab-01"))
+				Expect(lrpRequest.Annotation).To(Equal(`{"owner":"alice","keys":[{"fingerprint":"` + validKeyFingerprint + `","type":"ssh-ed25519","comment":"user@example.com","key":"` + validKey + `"}],"manifest":"lab","egress_policy":"open","privilege":"privileged"}`))
+			})
+
+			It("updates workstations in place, keeping unchanged schedules", func() {
+				name, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(name).To(Equal("lab-02"))
+				Expect(*update.Annotation).To(Equal(`{"owner":"bob","schedules":[{"id":"s1","cron":"0 8 * * 1-5","action":"start"}],"manifest":"lab"}`))
+			})
+
+			It("replaces workstations that can't be updated in place through an operation", func() {
+				Expect(changes).To(HaveLen(4))
+				Expect(changes[2].Workstation).To(Equal("lab-03"))
+				Expect(changes[2].OperationID).NotTo(BeEmpty())
+				Eventually(operationState(changes[2].OperationID)).Should(Equal(models.OperationSucceededState))
+
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(2))
+				lrpRequest := fakeReceptorClient.CreateDesiredLRPArgsForCall(1)
+				Expect(lrpRequest.ProcessGuid).To(Equal("lab-03"))
+				Expect(lrpRequest.MemoryMB).To(Equal(512))
+			})
+
+			It("deletes the workstations left out of the manifest", func() {
+				Expect(changes[2].OperationID).NotTo(BeEmpty())
+				Eventually(operationState(changes[2].OperationID)).Should(Equal(models.OperationSucceededState))
+
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(2))
+				Expect([]string{
+					fakeReceptorClient.DeleteDesiredLRPArgsForCall(0),
+					fakeReceptorClient.DeleteDesiredLRPArgsForCall(1),
+				}).To(ConsistOf("lab-03", "lab-04"))
+			})
+		})
+
+		Context("when a running workstation would be replaced without backups", func() {
 			BeforeEach(func() {
-				handler.Create(responseRecorder, newTestRequest(invalidCreateRequest))
-			})
-
-			It("responds with 400 BAD REQUEST", func() {
-				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				fakeReceptorClient.ActualLRPsByProcessGuidStub = func(name string) ([]receptor.ActualLRPResponse, error) {
+					if name == "lab-03" {
+						return []receptor.ActualLRPResponse{{ProcessGuid: name, State: receptor.ActualLRPStateRunning}}, nil
+					}
+					return nil, nil
+				}
+				backupManager := managers.NewBackupManager(manager, nil, dataStore, logger)
+				operationManager = managers.NewOperationManager(manager, backupManager, dataStore, 10*time.Millisecond, 10*time.Millisecond, logger)
+				handler = NewWorkstationHandler(manager, backupManager, templateManager, operationManager, secretManager, crashManager, models.PrivilegedPolicy, []string{"admin"}, logger)
+				manifest.Workstations = manifest.Workstations[2:]
+				handler.Apply(responseRecorder, WithUser(newTestRequest(manifest), "admin"))
 			})
 
-			It("responds with a relevant error message", func() {
-				expectedBody, _ := json.Marshal(teapot.Error{
-					Type:    teapot.InvalidWorkstation,
-					Message: "Invalid field: name",
-				})
-				Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+			It("refuses to replace it, losing its home directory", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotImplemented))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
 			})
 		})
 
-		Context("when the request does not contain a WorkstationCreateRequest", func() {
-			var garbageRequest = []byte(`hello`)
-
+		Context("when the manifest declares a workstation it doesn't manage", func() {
 			BeforeEach(func() {
-				handler.Create(responseRecorder, newTestRequest(garbageRequest))
+				manifest.Workstations = append(manifest.Workstations, teapot.WorkstationSpec{Name: "other"})
+				handler.Apply(responseRecorder, newTestRequest(manifest))
 			})
 
-			It("responds with 400 BAD REQUEST", func() {
+			It("fails with a 400 BAD REQUEST without changing anything", func() {
 				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
-			})
 
-			It("responds with a relevant error message", func() {
-				err := json.Unmarshal(garbageRequest, &teapot.WorkstationCreateRequest{})
-				expectedBody, _ := json.Marshal(teapot.Error{
-					Type:    teapot.InvalidJSON,
-					Message: err.Error(),
-				})
-				Expect(responseRecorder.Body.String()).To(Equal(string(expectedBody)))
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidManifest))
+				Expect(fakeReceptorClient.CreateDesiredLRPCallCount()).To(Equal(0))
 			})
 		})
 	})
@@ -156,12 +1706,32 @@ var _ = Describe("WorkstationHandler", func() {
 				Expect(response[1].Name).To(Equal(secondDesiredLRP.ProcessGuid))
 			})
 		})
+
+		Context("when a workstation has secrets", func() {
+			BeforeEach(func() {
+				firstDesiredLRP.Annotation = `{"secrets":["GITHUB_TOKEN"]}`
+				firstDesiredLRP.Action = &diego_models.RunAction{
+					Path: "/tmp/tea",
+					Env:  []diego_models.EnvironmentVariable{{Name: "GITHUB_TOKEN", Value: "t0ps3cret"}},
+				}
+				fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{firstDesiredLRP}, nil)
+				handler.List(responseRecorder, req)
+			})
+
+			It("lists their names but not their values", func() {
+				var response []models.Workstation
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response[0].Secrets).To(Equal([]string{"GITHUB_TOKEN"}))
+				Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("t0ps3cret"))
+			})
+		})
 	})
 
 	Describe("Delete", func() {
 		var req *http.Request
 
 		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
 			req = newTestRequest("")
 			req.URL.RawQuery = ":name=workstation-name"
 		})
@@ -180,8 +1750,21 @@ var _ = Describe("WorkstationHandler", func() {
 			})
 		})
 
+		Context("when the workstation has crashed before", func() {
+			BeforeEach(func() {
+				crashManager.RecordCrash("workstation-name", models.CrashedWhileStartingReason, 1)
+				handler.Delete(responseRecorder, req)
+			})
+
+			It("forgets its crashes", func() {
+				history, _ := crashManager.Get("workstation-name")
+				Expect(history).To(Equal(models.CrashHistory{}))
+			})
+		})
+
 		Context("when the workstation doesn't exists", func() {
 			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{}, errors.New("receptor error"))
 				fakeReceptorClient.DeleteDesiredLRPReturns(errors.New("receptor error"))
 				handler.Delete(responseRecorder, req)
 			})
@@ -203,6 +1786,59 @@ var _ = Describe("WorkstationHandler", func() {
 		})
 	})
 
+	Describe("Restart", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "ws", Annotation: `{"owner":"alice"}`}, nil)
+			fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{
+				{ProcessGuid: "ws", Index: 0, State: receptor.ActualLRPStateCrashed},
+			}, nil)
+
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=ws"
+			req = WithUser(req, "alice")
+		})
+
+		JustBeforeEach(func() {
+			handler.Restart(responseRecorder, req)
+		})
+
+		It("kills the instance and records who restarted it", func() {
+			Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			Expect(fakeReceptorClient.KillActualLRPByProcessGuidAndIndexCallCount()).To(Equal(1))
+			processGuid, index := fakeReceptorClient.KillActualLRPByProcessGuidAndIndexArgsForCall(0)
+			Expect(processGuid).To(Equal("ws"))
+			Expect(index).To(Equal(0))
+
+			history, _ := crashManager.Get("ws")
+			Expect(history.Restarts).To(HaveLen(1))
+			Expect(history.Restarts[0].RequestedBy).To(Equal("alice"))
+		})
+
+		Context("when the workstation is STOPPED", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns(nil, nil)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(fakeReceptorClient.KillActualLRPByProcessGuidAndIndexCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns(nil, nil)
+				req.URL.RawQuery = ":name=nope"
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
 	Describe("Attach", func() {
 		var req *http.Request
 
@@ -239,6 +1875,7 @@ var _ = Describe("WorkstationHandler", func() {
 					State:       receptor.ActualLRPStateClaimed,
 				}
 				response := []receptor.ActualLRPResponse{actualLRPResponse}
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
 				fakeReceptorClient.ActualLRPsByProcessGuidReturns(response, nil)
 				handler.Attach(responseRecorder, req)
 			})
@@ -271,11 +1908,67 @@ var _ = Describe("WorkstationHandler", func() {
 		})
 	})
 
+	Describe("Forward", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name&:port=4000"
+		})
+
+		Context("when the port is invalid", func() {
+			BeforeEach(func() {
+				req.URL.RawQuery = ":name=workstation-name&:port=http"
+				handler.Forward(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidPort))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				handler.Forward(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the workstation is not RUNNING", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "workstation-name",
+					State:       receptor.ActualLRPStateClaimed,
+				}}, nil)
+				handler.Forward(responseRecorder, req)
+			})
+
+			It("fails with a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+
+				var responseError receptor.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError).To(Equal(receptor.Error{
+					Type:    teapot.InvalidWorkstation,
+					Message: "Workstation workstation-name is not RUNNING.",
+				}))
+			})
+		})
+	})
+
 	Describe("AddKey", func() {
 		var req *http.Request
 
 		BeforeEach(func() {
-			req = newTestRequest("a-key")
+			req = newTestRequest(validKey)
 			req.URL.RawQuery = ":name=workstation-name"
 		})
 
@@ -290,11 +1983,12 @@ var _ = Describe("WorkstationHandler", func() {
 						func(w http.ResponseWriter, r *http.Request) {
 							body, err := ioutil.ReadAll(r.Body)
 							Expect(err).NotTo(HaveOccurred())
-							Expect(string(body)).To(Equal("a-key"))
+							Expect(string(body)).To(Equal(validKey))
 						},
 					),
 				)
 				fakeRouteProvider.SSHRouteReturns(server.URL())
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
 				actualLRPResponse := receptor.ActualLRPResponse{
 					ProcessGuid: "my-workstation",
 					State:       receptor.ActualLRPStateRunning,
@@ -311,6 +2005,97 @@ var _ = Describe("WorkstationHandler", func() {
 			It("sends a add-key request to the TEA", func() {
 				Expect(server.ReceivedRequests()).To(HaveLen(1))
 			})
+
+			It("stores the key with the workstation", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(1))
+				_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(*update.Annotation).To(ContainSubstring(validKeyFingerprint))
+			})
+		})
+
+		Context("when the workstation has no room left to store the key", func() {
+			var server *ghttp.Server
+
+			BeforeEach(func() {
+				server = ghttp.NewServer()
+				server.AppendHandlers(
+					ghttp.VerifyRequest("POST", "/add-key/something"),
+					ghttp.VerifyRequest("POST", "/remove-key/something"),
+				)
+				fakeRouteProvider.SSHRouteReturns(server.URL())
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+					ProcessGuid: "workstation-name",
+					Annotation:  `{"setup":["` + strings.Repeat("x", 10*1024) + `"]}`,
+				}, nil)
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "workstation-name",
+					State:       receptor.ActualLRPStateRunning,
+				}}, nil)
+				handler.AddKey(responseRecorder, req)
+			})
+
+			It("returns a 400 BAD REQUEST", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("doesn't store the key", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+
+			It("takes the key back out of the TEA", func() {
+				Expect(server.ReceivedRequests()).To(HaveLen(2))
+			})
+		})
+
+		Context("when the TEA rejects the key", func() {
+			var server *ghttp.Server
+
+			BeforeEach(func() {
+				server = ghttp.NewServer()
+				server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, ""))
+				fakeRouteProvider.SSHRouteReturns(server.URL())
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "workstation-name",
+					State:       receptor.ActualLRPStateRunning,
+				}}, nil)
+				handler.AddKey(responseRecorder, req)
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("fails with a 500 INTERNAL SERVER ERROR", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("does not store the key", func() {
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the key is not a valid authorized_keys line", func() {
+			BeforeEach(func() {
+				req = newTestRequest("a-key")
+				req.URL.RawQuery = ":name=workstation-name"
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "workstation-name",
+					State:       receptor.ActualLRPStateRunning,
+				}}, nil)
+				handler.AddKey(responseRecorder, req)
+			})
+
+			It("fails with a 400 Bad Request", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("returns an InvalidKey error", func() {
+				var responseError teapot.Error
+				json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+				Expect(responseError.Type).To(Equal(teapot.InvalidKey))
+			})
 		})
 
 		Context("when the workstation doesn't exists", func() {
@@ -341,6 +2126,7 @@ var _ = Describe("WorkstationHandler", func() {
 					State:       receptor.ActualLRPStateClaimed,
 				}
 				response := []receptor.ActualLRPResponse{actualLRPResponse}
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "workstation-name"}, nil)
 				fakeReceptorClient.ActualLRPsByProcessGuidReturns(response, nil)
 				handler.AddKey(responseRecorder, req)
 			})
@@ -361,4 +2147,196 @@ var _ = Describe("WorkstationHandler", func() {
 			})
 		})
 	})
+	Describe("ListKeys", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name"
+		})
+
+		Context("when the workstation has keys", func() {
+			BeforeEach(func() {
+				fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+					ProcessGuid: "workstation-name",
+					Annotation:  storedKeysAnnotation,
+				}, nil)
+				handler.ListKeys(responseRecorder, req)
+			})
+
+			It("responds with the stored keys", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+
+				var keys []models.SSHKey
+				json.Unmarshal(responseRecorder.Body.Bytes(), &keys)
+				Expect(keys).To(HaveLen(1))
+				Expect(keys[0].Fingerprint).To(Equal(validKeyFingerprint))
+			})
+		})
+
+		Context("when the workstation doesn't exist", func() {
+			BeforeEach(func() {
+				handler.ListKeys(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("GetKey", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+				ProcessGuid: "workstation-name",
+				Annotation:  storedKeysAnnotation,
+			}, nil)
+		})
+
+		It("responds with the key matching the fingerprint", func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name&:fingerprint=" + validKeyFingerprint
+			handler.GetKey(responseRecorder, req)
+
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			var key models.SSHKey
+			json.Unmarshal(responseRecorder.Body.Bytes(), &key)
+			Expect(key.Key).To(Equal(validKey))
+		})
+
+		It("fails with a 404 NOT FOUND for unknown fingerprints", func() {
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name&:fingerprint=00:11"
+			handler.GetKey(responseRecorder, req)
+
+			Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			var responseError teapot.Error
+			json.Unmarshal(responseRecorder.Body.Bytes(), &responseError)
+			Expect(responseError.Type).To(Equal(teapot.KeyNotFound))
+		})
+	})
+
+	Describe("RemoveKey", func() {
+		var (
+			req    *http.Request
+			server *ghttp.Server
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			fakeRouteProvider.SSHRouteReturns(server.URL())
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+				ProcessGuid: "workstation-name",
+				Annotation:  storedKeysAnnotation,
+			}, nil)
+			req = newTestRequest("")
+			req.URL.RawQuery = ":name=workstation-name&:fingerprint=" + validKeyFingerprint
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when the workstation is RUNNING", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/remove-key/something"),
+					ghttp.RespondWith(http.StatusOK, ""),
+				))
+				fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+					ProcessGuid: "workstation-name",
+					State:       receptor.ActualLRPStateRunning,
+				}}, nil)
+				handler.RemoveKey(responseRecorder, req)
+			})
+
+			It("responds with 204 NO CONTENT", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("removes the key from the running TEA", func() {
+				Expect(server.ReceivedRequests()).To(HaveLen(1))
+			})
+
+			It("removes the key from the workstation", func() {
+				_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+				Expect(*update.Annotation).To(Equal("{}"))
+			})
+		})
+
+		Context("when the workstation is not RUNNING", func() {
+			BeforeEach(func() {
+				handler.RemoveKey(responseRecorder, req)
+			})
+
+			It("only removes the stored key", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the key doesn't exist", func() {
+			BeforeEach(func() {
+				req.URL.RawQuery = ":name=workstation-name&:fingerprint=00:11"
+				handler.RemoveKey(responseRecorder, req)
+			})
+
+			It("fails with a 404 NOT FOUND", func() {
+				Expect(responseRecorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("when the user doesn't own the workstation", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+				ProcessGuid: "workstation-name",
+				Annotation:  `{"owner":"bob"}`,
+			}, nil)
+			fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+				ProcessGuid: "workstation-name",
+				State:       receptor.ActualLRPStateRunning,
+			}}, nil)
+
+			req = newTestRequest(validKey)
+			req.URL.RawQuery = ":name=workstation-name&:port=22000&:fingerprint=" + validKeyFingerprint
+			req = WithUser(req, "alice")
+		})
+
+		forbids := func(action func(http.ResponseWriter, *http.Request)) func() {
+			return func() {
+				action(responseRecorder, req)
+
+				Expect(responseRecorder.Code).To(Equal(http.StatusForbidden))
+				Expect(fakeReceptorClient.DeleteDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.UpdateDesiredLRPCallCount()).To(Equal(0))
+				Expect(fakeReceptorClient.KillActualLRPByProcessGuidAndIndexCallCount()).To(Equal(0))
+			}
+		}
+
+		It("forbids deleting it", forbids(func(w http.ResponseWriter, r *http.Request) { handler.Delete(w, r) }))
+		It("forbids restarting it", forbids(func(w http.ResponseWriter, r *http.Request) { handler.Restart(w, r) }))
+		It("forbids attaching to it", forbids(func(w http.ResponseWriter, r *http.Request) { handler.Attach(w, r) }))
+		It("forbids forwarding ports into it", forbids(func(w http.ResponseWriter, r *http.Request) { handler.Forward(w, r) }))
+		It("forbids adding keys to it", forbids(func(w http.ResponseWriter, r *http.Request) { handler.AddKey(w, r) }))
+		It("forbids listing its keys", forbids(func(w http.ResponseWriter, r *http.Request) { handler.ListKeys(w, r) }))
+		It("forbids getting its keys", forbids(func(w http.ResponseWriter, r *http.Request) { handler.GetKey(w, r) }))
+		It("forbids removing its keys", forbids(func(w http.ResponseWriter, r *http.Request) { handler.RemoveKey(w, r) }))
+
+		Context("when the user is an admin", func() {
+			BeforeEach(func() {
+				req = WithUser(req, "admin")
+			})
+
+			It("lets them delete it", func() {
+				handler.Delete(responseRecorder, req)
+				Expect(responseRecorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+	})
 })
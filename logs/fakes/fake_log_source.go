@@ -0,0 +1,111 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/luan/teapot/logs"
+)
+
+// FakeLogSource is an in-memory LogSource. Lines logged with Log are kept per
+// LogGuid and sent to whoever follows it.
+type FakeLogSource struct {
+	// StreamError, when set, is returned by Stream.
+	StreamError error
+
+	mutex     sync.Mutex
+	lines     map[string][]logs.Line
+	followers map[string][]chan logs.Line
+}
+
+func (f *FakeLogSource) Log(logGuid string, line logs.Line) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.lines == nil {
+		f.lines = map[string][]logs.Line{}
+	}
+	f.lines[logGuid] = append(f.lines[logGuid], line)
+
+	for _, follower := range f.followers[logGuid] {
+		select {
+		case follower <- line:
+		default:
+		}
+	}
+}
+
+// Followers tells how many streams are following logGuid.
+func (f *FakeLogSource) Followers(logGuid string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.followers[logGuid])
+}
+
+func (f *FakeLogSource) Stream(logGuid string, follow bool, stop <-chan struct{}) (<-chan logs.Line, error) {
+	if f.StreamError != nil {
+		return nil, f.StreamError
+	}
+
+	f.mutex.Lock()
+	recent := append([]logs.Line{}, f.lines[logGuid]...)
+	var follower chan logs.Line
+	if follow {
+		follower = make(chan logs.Line, 1024)
+		if f.followers == nil {
+			f.followers = map[string][]chan logs.Line{}
+		}
+		f.followers[logGuid] = append(f.followers[logGuid], follower)
+	}
+	f.mutex.Unlock()
+
+	lines := make(chan logs.Line)
+	go func() {
+		defer close(lines)
+		defer f.unfollow(logGuid, follower)
+
+		for _, line := range recent {
+			select {
+			case lines <- line:
+			case <-stop:
+				return
+			}
+		}
+
+		if follower == nil {
+			return
+		}
+
+		for {
+			select {
+			case line := <-follower:
+				select {
+				case lines <- line:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return lines, nil
+}
+
+func (f *FakeLogSource) unfollow(logGuid string, follower chan logs.Line) {
+	if follower == nil {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	followers := f.followers[logGuid]
+	for i, existing := range followers {
+		if existing == follower {
+			f.followers[logGuid] = append(followers[:i], followers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package logs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"code.google.com/p/gogoprotobuf/proto"
+	"github.com/cloudfoundry/dropsonde/events"
+	"github.com/gorilla/websocket"
+)
+
+// Line is a line logged by one of a workstation's processes, Source being the
+// LogSource of the action that ran it, such as SSHD, TEA or SETUP.
+type Line struct {
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Instance  string    `json:"instance,omitempty"`
+	Stream    string    `json:"stream"`
+	Message   string    `json:"message"`
+}
+
+// LogSource reads the logs Diego forwards for a LogGuid.
+type LogSource interface {
+	// Stream sends the lines still buffered for logGuid, then the ones
+	// logged from then on if follow is set, until stop is closed. The channel
+	// is closed once there's nothing more to send.
+	Stream(logGuid string, follow bool, stop <-chan struct{}) (<-chan Line, error)
+}
+
+// TokenSource returns the Authorization header to send to the Doppler, read
+// again every time it is dialed. An empty token sends no header.
+type TokenSource func() (string, error)
+
+// StaticToken always returns token. UAA tokens expire, so a static one only
+// works until then.
+func StaticToken(token string) TokenSource {
+	return func() (string, error) {
+		return token, nil
+	}
+}
+
+// TokenFile returns the contents of the file at path, which something else
+// keeps refreshed.
+func TokenFile(path string) TokenSource {
+	return func() (string, error) {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(contents)), nil
+	}
+}
+
+type dopplerLogSource struct {
+	address   *url.URL
+	authToken TokenSource
+	dialer    *websocket.Dialer
+	timeout   time.Duration
+}
+
+// NewDoppler returns a LogSource reading from the websocket endpoints of the
+// Doppler at address, such as wss://doppler.example.com:443. The token
+// authToken returns is sent as is in the Authorization header. Handshakes and
+// reading the recent lines give up after timeout.
+func NewDoppler(address string, authToken TokenSource, skipSSLValidation bool, timeout time.Duration) (LogSource, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		return nil, fmt.Errorf("invalid doppler address: %s", address)
+	}
+
+	return &dopplerLogSource{
+		address:   u,
+		authToken: authToken,
+		dialer: &websocket.Dialer{
+			TLSClientConfig:  &tls.Config{InsecureSkipVerify: skipSSLValidation},
+			HandshakeTimeout: timeout,
+		},
+		timeout: timeout,
+	}, nil
+}
+
+func (s *dopplerLogSource) Stream(logGuid string, follow bool, stop <-chan struct{}) (<-chan Line, error) {
+	recent, err := s.recent(logGuid, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-stop:
+		lines := make(chan Line)
+		close(lines)
+		return lines, nil
+	default:
+	}
+
+	var conn *websocket.Conn
+	if follow {
+		conn, err = s.dial(logGuid, "stream")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lines := make(chan Line)
+	go func() {
+		defer close(lines)
+
+		for _, line := range recent {
+			select {
+			case lines <- line:
+			case <-stop:
+				if conn != nil {
+					conn.Close()
+				}
+				return
+			}
+		}
+
+		if conn != nil {
+			s.follow(conn, lines, stop)
+		}
+	}()
+
+	return lines, nil
+}
+
+// recent reads the lines Doppler still has for logGuid, which it sends out of
+// order. It keeps the lines read so far if Doppler doesn't close the
+// connection within the timeout or stop is closed.
+func (s *dopplerLogSource) recent(logGuid string, stop <-chan struct{}) ([]Line, error) {
+	conn, err := s.dial(logGuid, "recentlogs")
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	if err := conn.SetReadDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, err
+	}
+
+	lines := []Line{}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if line, ok := parseEnvelope(data); ok {
+			lines = append(lines, line)
+		}
+	}
+
+	sort.Sort(byTimestamp(lines))
+	return lines, nil
+}
+
+func (s *dopplerLogSource) follow(conn *websocket.Conn, lines chan<- Line, stop <-chan struct{}) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		line, ok := parseEnvelope(data)
+		if !ok {
+			continue
+		}
+
+		select {
+		case lines <- line:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *dopplerLogSource) dial(logGuid, endpoint string) (*websocket.Conn, error) {
+	u := *s.address
+	u.Path = fmt.Sprintf("/apps/%s/%s", url.QueryEscape(logGuid), endpoint)
+
+	token, err := s.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", token)
+	}
+
+	conn, _, err := s.dialer.Dial(u.String(), header)
+	return conn, err
+}
+
+func parseEnvelope(data []byte) (Line, bool) {
+	envelope := &events.Envelope{}
+	if err := proto.Unmarshal(data, envelope); err != nil {
+		return Line{}, false
+	}
+
+	message := envelope.GetLogMessage()
+	if envelope.GetEventType() != events.Envelope_LogMessage || message == nil {
+		return Line{}, false
+	}
+
+	stream := "stdout"
+	if message.GetMessageType() == events.LogMessage_ERR {
+		stream = "stderr"
+	}
+
+	return Line{
+		Timestamp: time.Unix(0, message.GetTimestamp()).UTC(),
+		Source:    message.GetSourceType(),
+		Instance:  message.GetSourceInstance(),
+		Stream:    stream,
+		Message:   string(message.GetMessage()),
+	}, true
+}
+
+type byTimestamp []Line
+
+func (lines byTimestamp) Len() int           { return len(lines) }
+func (lines byTimestamp) Less(i, j int) bool { return lines[i].Timestamp.Before(lines[j].Timestamp) }
+func (lines byTimestamp) Swap(i, j int)      { lines[i], lines[j] = lines[j], lines[i] }
@@ -0,0 +1,167 @@
+package logs_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"code.google.com/p/gogoprotobuf/proto"
+	"github.com/cloudfoundry/dropsonde/events"
+	"github.com/gorilla/websocket"
+	. "github.com/luan/teapot/logs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func envelope(timestamp int64, source, message string, messageType events.LogMessage_MessageType) []byte {
+	data, err := proto.Marshal(&events.Envelope{
+		Origin:    proto.String("test"),
+		EventType: events.Envelope_LogMessage.Enum(),
+		LogMessage: &events.LogMessage{
+			Message:        []byte(message),
+			MessageType:    messageType.Enum(),
+			Timestamp:      proto.Int64(timestamp),
+			AppId:          proto.String("ws"),
+			SourceType:     proto.String(source),
+			SourceInstance: proto.String("0"),
+		},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return data
+}
+
+var _ = Describe("Doppler", func() {
+	var (
+		server        *httptest.Server
+		source        LogSource
+		authorization chan string
+		streamed      chan []byte
+		hanging       chan struct{}
+		stop          chan struct{}
+	)
+
+	BeforeEach(func() {
+		authorization = make(chan string, 2)
+		streamed = make(chan []byte)
+		hanging = make(chan struct{})
+		stop = make(chan struct{})
+		hang := hanging
+
+		upgrader := websocket.Upgrader{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization <- r.Header.Get("Authorization")
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			switch r.URL.Path {
+			case "/apps/ws/recentlogs":
+				conn.WriteMessage(websocket.BinaryMessage, envelope(2000, "TEA", "second", events.LogMessage_ERR))
+				conn.WriteMessage(websocket.BinaryMessage, []byte("garbage"))
+				conn.WriteMessage(websocket.BinaryMessage, envelope(1000, "SSHD", "first", events.LogMessage_OUT))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			case "/apps/hanging/recentlogs":
+				conn.WriteMessage(websocket.BinaryMessage, envelope(1000, "SSHD", "first", events.LogMessage_OUT))
+				<-hang
+			case "/apps/ws/stream":
+				for data := range streamed {
+					if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+						return
+					}
+				}
+			}
+		}))
+
+		var err error
+		source, err = NewDoppler("ws"+strings.TrimPrefix(server.URL, "http"), StaticToken("bearer token"), false, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		close(stop)
+		close(streamed)
+		close(hanging)
+		server.Close()
+	})
+
+	It("refuses addresses that aren't websockets", func() {
+		_, err := NewDoppler("http://doppler.example.com", StaticToken(""), false, time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("sends the recent lines in order, then closes", func() {
+		lines, err := source.Stream("ws", false, stop)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(<-lines).To(Equal(Line{Timestamp: time.Unix(0, 1000).UTC(), Source: "SSHD", Instance: "0", Stream: "stdout", Message: "first"}))
+		Expect(<-lines).To(Equal(Line{Timestamp: time.Unix(0, 2000).UTC(), Source: "TEA", Instance: "0", Stream: "stderr", Message: "second"}))
+		Eventually(lines).Should(BeClosed())
+
+		Expect(<-authorization).To(Equal("bearer token"))
+	})
+
+	It("keeps sending the streamed lines when following", func() {
+		lines, err := source.Stream("ws", true, stop)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect((<-lines).Message).To(Equal("first"))
+		Expect((<-lines).Message).To(Equal("second"))
+
+		streamed <- envelope(3000, "TEA", "third", events.LogMessage_OUT)
+		Expect((<-lines).Message).To(Equal("third"))
+	})
+
+	It("keeps the recent lines read when the Doppler doesn't close the connection in time", func() {
+		lines, err := source.Stream("hanging", false, stop)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect((<-lines).Message).To(Equal("first"))
+		Eventually(lines).Should(BeClosed())
+	})
+
+	It("stops reading the recent lines when stopped", func() {
+		slow, err := NewDoppler("ws"+strings.TrimPrefix(server.URL, "http"), StaticToken(""), false, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		stopping := make(chan struct{})
+		streams := make(chan (<-chan Line))
+		go func() {
+			defer GinkgoRecover()
+			lines, err := slow.Stream("hanging", true, stopping)
+			Expect(err).NotTo(HaveOccurred())
+			streams <- lines
+		}()
+
+		Consistently(streams).ShouldNot(Receive())
+		close(stopping)
+
+		var lines <-chan Line
+		Eventually(streams).Should(Receive(&lines))
+		Eventually(lines).Should(BeClosed())
+	})
+
+	It("reads the token from the file again on every connection", func() {
+		file, err := ioutil.TempFile("", "doppler-token")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		refreshed, err := NewDoppler("ws"+strings.TrimPrefix(server.URL, "http"), TokenFile(file.Name()), false, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(file.Name(), []byte("bearer first\n"), 0600)).To(Succeed())
+		_, err = refreshed.Stream("ws", false, stop)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authorization).To(Equal("bearer first"))
+
+		Expect(ioutil.WriteFile(file.Name(), []byte("bearer second\n"), 0600)).To(Succeed())
+		_, err = refreshed.Stream("ws", false, stop)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-authorization).To(Equal("bearer second"))
+	})
+})
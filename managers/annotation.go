@@ -0,0 +1,93 @@
+package managers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/models"
+)
+
+// maximumAnnotationLength is the longest annotation Diego accepts on a
+// DesiredLRP.
+const maximumAnnotationLength = 10 * 1024
+
+// workstationAnnotation is the teapot-specific state of a workstation. It is
+// stored as JSON in the DesiredLRP annotation so it lives and dies with the
+// workstation itself.
+type workstationAnnotation struct {
+	Owner     string            `json:"owner,omitempty"`
+	Schedules []models.Schedule `json:"schedules,omitempty"`
+	Keys      []models.SSHKey   `json:"keys,omitempty"`
+
+	Secrets     []string `json:"secrets,omitempty"`
+	Setup       []string `json:"setup,omitempty"`
+	Template    string   `json:"template,omitempty"`
+	Manifest    string   `json:"manifest,omitempty"`
+	RestoreFrom string   `json:"restore_from,omitempty"`
+
+	EgressPolicy string `json:"egress_policy,omitempty"`
+
+	Privilege           string `json:"privilege,omitempty"`
+	PrivilegeApprovedBy string `json:"privilege_approved_by,omitempty"`
+}
+
+func parseAnnotation(desiredLRP receptor.DesiredLRPResponse) workstationAnnotation {
+	annotation := workstationAnnotation{}
+	if desiredLRP.Annotation != "" {
+		json.Unmarshal([]byte(desiredLRP.Annotation), &annotation)
+	}
+	return annotation
+}
+
+// encodeAnnotation serializes the annotation of the named workstation,
+// failing validation when it no longer fits in a DesiredLRP.
+func encodeAnnotation(name string, annotation workstationAnnotation) (string, error) {
+	annotationJSON, err := json.Marshal(annotation)
+	if err != nil {
+		return "", err
+	}
+
+	if len(annotationJSON) > maximumAnnotationLength {
+		return "", models.ValidationError{models.ErrAnnotationTooLarge{name}}
+	}
+	return string(annotationJSON), nil
+}
+
+func (m *workstationManager) fetchDesiredLRP(name string) (receptor.DesiredLRPResponse, error) {
+	desiredLRP, err := m.receptorClient.GetDesiredLRP(name)
+	if err != nil || desiredLRP.ProcessGuid != name {
+		return desiredLRP, models.ErrNotFound{"workstation", name}
+	}
+	return desiredLRP, nil
+}
+
+func (m *workstationManager) fetchAnnotation(name string) (workstationAnnotation, error) {
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return workstationAnnotation{}, err
+	}
+	return parseAnnotation(desiredLRP), nil
+}
+
+func (m *workstationManager) updateAnnotation(name string, update func(*workstationAnnotation) error) error {
+	m.updateMutex.Lock()
+	defer m.updateMutex.Unlock()
+
+	annotation, err := m.fetchAnnotation(name)
+	if err != nil {
+		return err
+	}
+
+	if err := update(&annotation); err != nil {
+		return err
+	}
+
+	annotationString, err := encodeAnnotation(name, annotation)
+	if err != nil {
+		return err
+	}
+
+	return m.receptorClient.UpdateDesiredLRP(name, receptor.DesiredLRPUpdateRequest{
+		Annotation: &annotationString,
+	})
+}
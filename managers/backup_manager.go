@@ -0,0 +1,285 @@
+package managers
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/luan/teapot/blobstore"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/nu7hatch/gouuid"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	snapshotsCollection = "snapshots"
+	homeDirectory       = "/home/vcap"
+)
+
+var ErrBackupsDisabled = errors.New("backups are not configured")
+
+type BackupManager interface {
+	Enabled() bool
+	Create(name string) (models.Snapshot, error)
+	Backup(name string) (models.Snapshot, error)
+	List(name string) ([]models.Snapshot, error)
+	Get(id string) (models.Snapshot, error)
+	Latest(name string) (models.Snapshot, error)
+	Delete(id string) error
+	Restorable(id, user string) error
+	Restore(name, id string) error
+}
+
+type backupManager struct {
+	workstationManager WorkstationManager
+	blobstore          blobstore.Blobstore
+	store              store.Store
+	logger             lager.Logger
+}
+
+// NewBackupManager returns a BackupManager that keeps snapshots in blobstore.
+// Every operation fails with ErrBackupsDisabled when blobstore is nil.
+func NewBackupManager(workstationManager WorkstationManager, blobstore blobstore.Blobstore, store store.Store, logger lager.Logger) BackupManager {
+	return &backupManager{
+		workstationManager: workstationManager,
+		blobstore:          blobstore,
+		store:              store,
+		logger:             logger.Session("backup-manager"),
+	}
+}
+
+func (m *backupManager) Enabled() bool {
+	return m.blobstore != nil
+}
+
+// Create starts a snapshot of the workstation's home directory in the
+// background and returns it while still PENDING.
+func (m *backupManager) Create(name string) (models.Snapshot, error) {
+	snapshot, err := m.newSnapshot(name)
+	if err != nil {
+		return snapshot, err
+	}
+
+	go m.run(snapshot)
+
+	return snapshot, nil
+}
+
+// Backup snapshots the workstation's home directory and only returns once the
+// snapshot is stored.
+func (m *backupManager) Backup(name string) (models.Snapshot, error) {
+	snapshot, err := m.newSnapshot(name)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot = m.run(snapshot)
+	if snapshot.State == models.SnapshotFailedState {
+		return snapshot, errors.New(snapshot.FailureReason)
+	}
+
+	return snapshot, nil
+}
+
+func (m *backupManager) List(name string) ([]models.Snapshot, error) {
+	if m.blobstore == nil {
+		return nil, ErrBackupsDisabled
+	}
+
+	ids, err := m.store.Keys(snapshotsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []models.Snapshot{}
+	for _, id := range ids {
+		snapshot, err := m.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.Workstation == name {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (m *backupManager) Get(id string) (models.Snapshot, error) {
+	if m.blobstore == nil {
+		return models.Snapshot{}, ErrBackupsDisabled
+	}
+
+	snapshot := models.Snapshot{}
+	err := m.store.Get(snapshotsCollection, id, &snapshot)
+	if err == store.ErrNotFound {
+		return snapshot, models.ErrNotFound{"snapshot", id}
+	}
+
+	return snapshot, err
+}
+
+func (m *backupManager) Delete(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+
+	err := m.blobstore.Delete(snapshotKey(id))
+	if err != nil && err != blobstore.ErrNotFound {
+		return err
+	}
+
+	return m.store.Delete(snapshotsCollection, id)
+}
+
+// Latest returns the most recent COMPLETE snapshot of the workstation.
+func (m *backupManager) Latest(name string) (models.Snapshot, error) {
+	snapshots, err := m.List(name)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	var latest *models.Snapshot
+	for i, snapshot := range snapshots {
+		if snapshot.State != models.SnapshotCompleteState {
+			continue
+		}
+		if latest == nil || snapshot.CreatedAt.After(latest.CreatedAt) {
+			latest = &snapshots[i]
+		}
+	}
+
+	if latest == nil {
+		return models.Snapshot{}, models.ErrNotFound{"snapshot", name}
+	}
+
+	return *latest, nil
+}
+
+// Restorable checks that a workstation created by user can be restored from
+// the snapshot.
+func (m *backupManager) Restorable(id, user string) error {
+	snapshot, err := m.Get(id)
+	if _, ok := err.(models.ErrNotFound); ok {
+		return models.ValidationError{models.ErrInvalidField{"restore_from"}}
+	}
+	if err != nil {
+		return err
+	}
+
+	if snapshot.State != models.SnapshotCompleteState || (snapshot.Owner != "" && snapshot.Owner != user) {
+		return models.ValidationError{models.ErrInvalidField{"restore_from"}}
+	}
+
+	return nil
+}
+
+// Restore extracts the snapshot into the home directory of the running
+// workstation.
+func (m *backupManager) Restore(name, id string) error {
+	log := m.logger.Session("restore", lager.Data{"workstation_name": name, "snapshot_id": id})
+
+	snapshot, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	content, err := m.blobstore.Get(snapshotKey(id))
+	if err != nil {
+		log.Error("download-failed", err)
+		return err
+	}
+	defer content.Close()
+
+	err = m.workstationManager.Upload(name, homeDirectory, true, content, snapshot.Size)
+	if err != nil {
+		log.Error("upload-failed", err)
+		return err
+	}
+
+	log.Info("restored")
+	return nil
+}
+
+func (m *backupManager) newSnapshot(name string) (models.Snapshot, error) {
+	if m.blobstore == nil {
+		return models.Snapshot{}, ErrBackupsDisabled
+	}
+
+	workstation, err := m.workstationManager.Get(name)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	snapshot := models.Snapshot{
+		ID:          guid.String(),
+		Workstation: name,
+		Owner:       workstation.Owner,
+		State:       models.SnapshotPendingState,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	return snapshot, m.store.Put(snapshotsCollection, snapshot.ID, snapshot)
+}
+
+func (m *backupManager) run(snapshot models.Snapshot) models.Snapshot {
+	log := m.logger.Session("backup", lager.Data{"workstation_name": snapshot.Workstation, "snapshot_id": snapshot.ID})
+
+	size, err := m.upload(snapshot)
+	if err != nil {
+		log.Error("failed", err)
+		snapshot.State = models.SnapshotFailedState
+		snapshot.FailureReason = err.Error()
+	} else {
+		log.Info("stored", lager.Data{"size": size})
+		snapshot.State = models.SnapshotCompleteState
+		snapshot.Size = size
+	}
+
+	err = m.store.Put(snapshotsCollection, snapshot.ID, snapshot)
+	if err != nil {
+		log.Error("record-failed", err)
+	}
+
+	return snapshot
+}
+
+// upload spools the home directory tarball to disk first, since the object
+// store needs to know its size up front.
+func (m *backupManager) upload(snapshot models.Snapshot) (int64, error) {
+	content, err := m.workstationManager.Download(snapshot.Workstation, homeDirectory, true)
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
+
+	spool, err := ioutil.TempFile("", "teapot-snapshot")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, content)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := spool.Seek(0, 0); err != nil {
+		return 0, err
+	}
+
+	return size, m.blobstore.Put(snapshotKey(snapshot.ID), spool, size)
+}
+
+func snapshotKey(id string) string {
+	return "snapshots/" + id + ".tar"
+}
@@ -0,0 +1,152 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot/models"
+)
+
+// LoadBootstrap reads the bootstrap spec from the JSON file at path. Fields
+// the file leaves out keep their defaults, and an empty path gives the
+// default bootstrap.
+func LoadBootstrap(path string) (models.Bootstrap, error) {
+	bootstrap := models.DefaultBootstrap()
+	if path == "" {
+		return bootstrap, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return bootstrap, err
+	}
+
+	if err := json.Unmarshal(contents, &bootstrap); err != nil {
+		return bootstrap, err
+	}
+
+	return bootstrap, bootstrap.Validate()
+}
+
+// VerifyArtifacts downloads every artifact of the bootstrap that has a
+// checksum and checks it. Diego doesn't check what it downloads, so this is
+// how a mismatched mirror is caught before any workstation uses it.
+func VerifyArtifacts(bootstrap models.Bootstrap, httpClient *http.Client) error {
+	for _, artifact := range bootstrap.Artifacts() {
+		if artifact.Checksum == "" {
+			continue
+		}
+
+		checksum, err := downloadChecksum(httpClient, artifact.URL)
+		if err != nil {
+			return fmt.Errorf("downloading %s failed: %s", artifact.URL, err)
+		}
+
+		if checksum != artifact.Checksum {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", artifact.URL, artifact.Checksum, checksum)
+		}
+	}
+
+	return nil
+}
+
+func downloadChecksum(httpClient *http.Client, url string) (string, error) {
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, response.Body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// setupAction downloads dropbear and the TEA, generates sshd's host keys, then
+// runs the bootstrap's extra steps, installs the dotfiles and finally runs the
+// workstation's own setup scripts.
+func setupAction(bootstrap models.Bootstrap, scripts []string) diego_models.Action {
+	actions := []diego_models.Action{
+		downloadAction(bootstrap.Dropbear, "/tmp"),
+		downloadAction(bootstrap.Tea, "/tmp"),
+	}
+
+	for _, keyType := range []string{"rsa", "dss", "ecdsa"} {
+		actions = append(actions, &diego_models.RunAction{
+			Path:      "/tmp/dropbearkey",
+			LogSource: "KEYGEN",
+			Args:      []string{"-t", keyType, "-f", "/tmp/dropbear_" + keyType + "_host_key"},
+		})
+	}
+
+	for _, step := range bootstrap.Steps {
+		if step.Download != nil {
+			actions = append(actions, downloadAction(*step.Download, step.To))
+		} else {
+			actions = append(actions, &diego_models.RunAction{
+				Path:      "/bin/bash",
+				Args:      []string{"-c", step.Run},
+				LogSource: "BOOTSTRAP",
+			})
+		}
+	}
+
+	if bootstrap.Dotfiles != "" {
+		actions = append(actions, &diego_models.RunAction{
+			Path:      "/bin/bash",
+			Args:      []string{"-c", dotfilesScript(bootstrap.Dotfiles)},
+			LogSource: "DOTFILES",
+		})
+	}
+
+	for _, script := range scripts {
+		actions = append(actions, &diego_models.RunAction{
+			Path:      "/bin/bash",
+			Args:      []string{"-c", script},
+			LogSource: "SETUP",
+		})
+	}
+
+	return &diego_models.SerialAction{Actions: actions}
+}
+
+// downloadAction caches checksummed artifacts under their checksum, so a new
+// version of the artifact is never served from a stale cache.
+func downloadAction(artifact models.Artifact, to string) diego_models.Action {
+	cacheKey := artifact.CacheKey
+	if cacheKey != "" && artifact.Checksum != "" {
+		cacheKey += "-" + artifact.Checksum
+	}
+
+	return &diego_models.DownloadAction{
+		From:     artifact.URL,
+		To:       to,
+		CacheKey: cacheKey,
+	}
+}
+
+func dotfilesScript(repository string) string {
+	dotfiles := homeDirectory + "/.dotfiles"
+	return fmt.Sprintf(
+		`set -e && if [ ! -d %[1]s ]; then git clone --depth 1 %[2]s %[1]s; fi && if [ -x %[1]s/install.sh ]; then cd %[1]s && ./install.sh; fi`,
+		dotfiles, shellQuote(repository),
+	)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
@@ -0,0 +1,148 @@
+package managers
+
+import (
+	"sort"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/models"
+)
+
+type CapacityManager interface {
+	Cells() ([]models.Cell, error)
+	Stacks() ([]models.StackCapacity, error)
+	Place(stack string, memoryMB, diskMB int) error
+}
+
+type capacityManager struct {
+	receptorClient receptor.Client
+	admission      bool
+}
+
+// NewCapacityManager returns a CapacityManager working out what the Diego cells
+// have left from the LRPs and tasks placed on them. Unless admission is set,
+// Place lets everything through.
+func NewCapacityManager(receptorClient receptor.Client, admission bool) CapacityManager {
+	return &capacityManager{
+		receptorClient: receptorClient,
+		admission:      admission,
+	}
+}
+
+func (m *capacityManager) Cells() ([]models.Cell, error) {
+	cellResponses, err := m.receptorClient.Cells()
+	if err != nil {
+		return nil, err
+	}
+
+	desiredLRPs, err := m.receptorClient.DesiredLRPs()
+	if err != nil {
+		return nil, err
+	}
+
+	actualLRPs, err := m.receptorClient.ActualLRPs()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := m.receptorClient.Tasks()
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := map[string]string{}
+	free := map[string]*models.Capacity{}
+	for _, cell := range cellResponses {
+		stacks[cell.CellID] = cell.Stack
+		free[cell.CellID] = &models.Capacity{
+			MemoryMB:   cell.Capacity.MemoryMB,
+			DiskMB:     cell.Capacity.DiskMB,
+			Containers: cell.Capacity.Containers,
+		}
+	}
+
+	use := func(cellID string, memoryMB, diskMB int) {
+		if capacity, ok := free[cellID]; ok {
+			capacity.MemoryMB -= memoryMB
+			capacity.DiskMB -= diskMB
+			capacity.Containers--
+		}
+	}
+
+	desired := map[string]receptor.DesiredLRPResponse{}
+	for _, desiredLRP := range desiredLRPs {
+		desired[desiredLRP.ProcessGuid] = desiredLRP
+	}
+
+	for _, actualLRP := range actualLRPs {
+		if actualLRP.State == receptor.ActualLRPStateClaimed || actualLRP.State == receptor.ActualLRPStateRunning {
+			desiredLRP := desired[actualLRP.ProcessGuid]
+			use(actualLRP.CellID, desiredLRP.MemoryMB, desiredLRP.DiskMB)
+		}
+	}
+
+	for _, task := range tasks {
+		if task.State == receptor.TaskStateRunning {
+			use(task.CellID, task.MemoryMB, task.DiskMB)
+		}
+	}
+
+	ids := make([]string, 0, len(free))
+	for id := range free {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	cells := []models.Cell{}
+	for _, id := range ids {
+		cells = append(cells, models.Cell{ID: id, Stack: stacks[id], Free: *free[id]})
+	}
+	return cells, nil
+}
+
+func (m *capacityManager) Stacks() ([]models.StackCapacity, error) {
+	cells, err := m.Cells()
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := []models.StackCapacity{}
+	index := map[string]int{}
+	for _, cell := range cells {
+		i, ok := index[cell.Stack]
+		if !ok {
+			i = len(stacks)
+			index[cell.Stack] = i
+			stacks = append(stacks, models.StackCapacity{Stack: cell.Stack})
+		}
+
+		stack := &stacks[i]
+		stack.Cells++
+		stack.Free = stack.Free.Add(cell.Free)
+		if stack.Cells == 1 || cell.Free.MemoryMB > stack.LargestFree.MemoryMB {
+			stack.LargestFree = cell.Free
+		}
+	}
+
+	return stacks, nil
+}
+
+// Place fails with ErrInsufficientCapacity when no cell of stack can fit a
+// container with memoryMB and diskMB, rather than leaving it UNCLAIMED.
+func (m *capacityManager) Place(stack string, memoryMB, diskMB int) error {
+	if !m.admission {
+		return nil
+	}
+
+	cells, err := m.Cells()
+	if err != nil {
+		return err
+	}
+
+	for _, cell := range cells {
+		if cell.Stack == stack && cell.Free.Fits(memoryMB, diskMB) {
+			return nil
+		}
+	}
+
+	return models.ErrInsufficientCapacity{Stack: stack, MemoryMB: memoryMB, DiskMB: diskMB}
+}
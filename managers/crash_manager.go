@@ -0,0 +1,87 @@
+package managers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+)
+
+const (
+	crashesCollection = "crashes"
+	maxRestarts       = 10
+)
+
+type CrashManager interface {
+	RecordCrash(name, reason string, crashCount int) error
+	RecordRestart(name, requestedBy string) error
+	Get(name string) (models.CrashHistory, error)
+	Delete(name string) error
+}
+
+type crashManager struct {
+	store store.Store
+	mutex sync.Mutex
+}
+
+// NewCrashManager returns a CrashManager keeping the crash history of each
+// workstation, along with its last few restarts.
+func NewCrashManager(store store.Store) CrashManager {
+	return &crashManager{
+		store: store,
+	}
+}
+
+func (m *crashManager) RecordCrash(name, reason string, crashCount int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	history.CrashCount = crashCount
+	history.LastCrashReason = reason
+	history.LastCrashedAt = &now
+
+	return m.store.Put(crashesCollection, name, history)
+}
+
+func (m *crashManager) RecordRestart(name, requestedBy string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+
+	history.Restarts = append(history.Restarts, models.Restart{At: time.Now().UTC(), RequestedBy: requestedBy})
+	if len(history.Restarts) > maxRestarts {
+		history.Restarts = history.Restarts[len(history.Restarts)-maxRestarts:]
+	}
+
+	return m.store.Put(crashesCollection, name, history)
+}
+
+func (m *crashManager) Get(name string) (models.CrashHistory, error) {
+	history := models.CrashHistory{}
+
+	err := m.store.Get(crashesCollection, name, &history)
+	if err != nil && err != store.ErrNotFound {
+		return history, err
+	}
+
+	return history, nil
+}
+
+func (m *crashManager) Delete(name string) error {
+	err := m.store.Delete(crashesCollection, name)
+	if err == store.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/luan/teapot/models"
+)
+
+type EgressPolicyManager interface {
+	List() ([]models.EgressPolicy, error)
+	Get(name string) (models.EgressPolicy, error)
+}
+
+type egressPolicyManager struct {
+	policies      map[string]models.EgressPolicy
+	defaultPolicy string
+}
+
+// NewEgressPolicyManager loads the egress policies from the JSON list at path,
+// along with the built-in open policy unless the list redefines it. Getting
+// the empty name gives the policy named defaultPolicy, which must exist.
+func NewEgressPolicyManager(path, defaultPolicy string) (EgressPolicyManager, error) {
+	open := models.NewOpenEgressPolicy()
+	m := &egressPolicyManager{
+		policies:      map[string]models.EgressPolicy{open.Name: open},
+		defaultPolicy: defaultPolicy,
+	}
+
+	if path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var policies []models.EgressPolicy
+		if err := json.Unmarshal(contents, &policies); err != nil {
+			return nil, err
+		}
+
+		for _, policy := range policies {
+			if err := policy.Validate(); err != nil {
+				return nil, fmt.Errorf("egress policy %s: %s", policy.Name, err)
+			}
+			m.policies[policy.Name] = policy
+		}
+	}
+
+	if _, ok := m.policies[defaultPolicy]; !ok {
+		return nil, fmt.Errorf("default egress policy %s is not defined", defaultPolicy)
+	}
+
+	return m, nil
+}
+
+func (m *egressPolicyManager) List() ([]models.EgressPolicy, error) {
+	names := make([]string, 0, len(m.policies))
+	for name := range m.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policies := []models.EgressPolicy{}
+	for _, name := range names {
+		policies = append(policies, m.policies[name])
+	}
+	return policies, nil
+}
+
+func (m *egressPolicyManager) Get(name string) (models.EgressPolicy, error) {
+	if name == "" {
+		name = m.defaultPolicy
+	}
+
+	policy, ok := m.policies[name]
+	if !ok {
+		return policy, models.ErrNotFound{"egress policy", name}
+	}
+	return policy, nil
+}
@@ -0,0 +1,197 @@
+package managers
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot/models"
+	"github.com/nu7hatch/gouuid"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	jobOutputFile = "/tmp/teapot-job-output"
+	jobResultFile = "/tmp/teapot-job-result"
+
+	// Diego only reads the result file of successful tasks, so the script always
+	// exits cleanly and records the command's exit status on the first line.
+	jobScript = `/bin/bash -c "$1" > ` + jobOutputFile + ` 2>&1; echo $? > ` + jobResultFile + `; tail -c 8192 ` + jobOutputFile + ` >> ` + jobResultFile
+)
+
+type JobManager interface {
+	Create(job models.Job) (models.Job, error)
+	Exec(name, command string) (models.Job, error)
+	Get(id string) (models.Job, error)
+	Cancel(id string) error
+}
+
+type jobManager struct {
+	receptorClient receptor.Client
+	bootstrap      models.Bootstrap
+	egressPolicies EgressPolicyManager
+	logger         lager.Logger
+}
+
+type jobAnnotation struct {
+	Workstation string `json:"workstation,omitempty"`
+	Command     string `json:"command"`
+}
+
+// NewJobManager returns a JobManager that runs jobs on the stack and in the
+// domain of bootstrap. Jobs run in a workstation get its egress rules, the
+// others those of the default egress policy.
+func NewJobManager(receptorClient receptor.Client, bootstrap models.Bootstrap, egressPolicies EgressPolicyManager, logger lager.Logger) JobManager {
+	return &jobManager{
+		receptorClient: receptorClient,
+		bootstrap:      bootstrap,
+		egressPolicies: egressPolicies,
+		logger:         logger,
+	}
+}
+
+func (m *jobManager) Create(job models.Job) (models.Job, error) {
+	if err := job.Validate(); err != nil {
+		return job, err
+	}
+
+	policy, err := m.egressPolicies.Get("")
+	if err != nil {
+		return job, err
+	}
+
+	return m.createTask(job, policy.SecurityGroupRules())
+}
+
+func (m *jobManager) Exec(name, command string) (models.Job, error) {
+	desiredLRP, err := m.receptorClient.GetDesiredLRP(name)
+	if err != nil || desiredLRP.ProcessGuid != name {
+		return models.Job{}, models.ErrNotFound{"workstation", name}
+	}
+
+	job := models.Job{
+		Workstation: name,
+		DockerImage: desiredLRP.RootFSPath,
+		Command:     command,
+		CPUWeight:   desiredLRP.CPUWeight,
+		DiskMB:      desiredLRP.DiskMB,
+		MemoryMB:    desiredLRP.MemoryMB,
+		Privileged:  desiredLRP.Privileged,
+	}
+	if err := job.Validate(); err != nil {
+		return job, err
+	}
+
+	return m.createTask(job, desiredLRP.EgressRules)
+}
+
+func (m *jobManager) Get(id string) (models.Job, error) {
+	task, err := m.receptorClient.GetTask(id)
+	if err != nil {
+		if rErr, ok := err.(receptor.Error); ok && rErr.Type == receptor.TaskNotFound {
+			return models.Job{}, models.ErrNotFound{"job", id}
+		}
+		return models.Job{}, err
+	}
+
+	if task.Domain != m.bootstrap.Domain || task.TaskGuid != id {
+		return models.Job{}, models.ErrNotFound{"job", id}
+	}
+
+	return jobFromTask(task), nil
+}
+
+func (m *jobManager) Cancel(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+
+	return m.receptorClient.CancelTask(id)
+}
+
+func (m *jobManager) createTask(job models.Job, egressRules []diego_models.SecurityGroupRule) (models.Job, error) {
+	log := m.logger.Session("job-manager-create", lager.Data{"job": job})
+
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return job, err
+	}
+	job.ID = guid.String()
+	job.State = receptor.TaskStatePending
+
+	annotation, err := json.Marshal(jobAnnotation{
+		Workstation: job.Workstation,
+		Command:     job.Command,
+	})
+	if err != nil {
+		return job, err
+	}
+
+	taskRequest := receptor.TaskCreateRequest{
+		TaskGuid:   job.ID,
+		Domain:     m.bootstrap.Domain,
+		Stack:      m.bootstrap.Stack,
+		RootFSPath: job.DockerImage,
+		CPUWeight:  job.CPUWeight,
+		DiskMB:     job.DiskMB,
+		MemoryMB:   job.MemoryMB,
+		LogGuid:    job.ID,
+		LogSource:  "TEAPOT-JOB",
+		ResultFile: jobResultFile,
+		Privileged: job.Privileged,
+		Annotation: string(annotation),
+		Action: &diego_models.RunAction{
+			Path:      "/bin/bash",
+			Args:      []string{"-c", jobScript, "teapot-job", job.Command},
+			LogSource: "JOB",
+		},
+		EgressRules: egressRules,
+	}
+
+	log.Debug("requesting-task", lager.Data{"task_request": taskRequest})
+	err = m.receptorClient.CreateTask(taskRequest)
+	if err != nil {
+		log.Debug("request-failed", lager.Data{"error": err})
+	} else {
+		log.Debug("request-suceeded")
+	}
+
+	return job, err
+}
+
+func jobFromTask(task receptor.TaskResponse) models.Job {
+	annotation := jobAnnotation{}
+	json.Unmarshal([]byte(task.Annotation), &annotation)
+
+	job := models.Job{
+		ID:            task.TaskGuid,
+		Workstation:   annotation.Workstation,
+		DockerImage:   task.RootFSPath,
+		Command:       annotation.Command,
+		CPUWeight:     task.CPUWeight,
+		DiskMB:        task.DiskMB,
+		MemoryMB:      task.MemoryMB,
+		Privileged:    task.Privileged,
+		State:         task.State,
+		Failed:        task.Failed,
+		FailureReason: task.FailureReason,
+	}
+
+	if task.Failed || task.Result == "" {
+		return job
+	}
+
+	parts := strings.SplitN(task.Result, "\n", 2)
+	exitCode, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return job
+	}
+	job.ExitCode = &exitCode
+	if len(parts) > 1 {
+		job.Output = parts[1]
+	}
+
+	return job
+}
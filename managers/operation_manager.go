@@ -0,0 +1,498 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/nu7hatch/gouuid"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	operationsCollection  = "operations"
+	operationPollInterval = time.Second
+	teaDialTimeout        = 5 * time.Second
+
+	// Finished operations are kept this long after their last update.
+	operationRetention = 24 * time.Hour
+)
+
+type OperationManager interface {
+	Create(workstation models.Workstation) (models.Operation, error)
+	ApprovePrivilege(name, approver string) error
+	Resize(name string, request teapot.WorkstationUpdateRequest) (models.Operation, error)
+	Apply(manifest models.Manifest) ([]models.Change, error)
+	Get(id string) (models.Operation, error)
+	FailInterrupted() error
+}
+
+type operationManager struct {
+	workstationManager WorkstationManager
+	backupManager      BackupManager
+	store              store.Store
+	startTimeout       time.Duration
+	createTimeout      time.Duration
+	logger             lager.Logger
+}
+
+// NewOperationManager returns an OperationManager that gives replaced
+// workstations startTimeout to be RUNNING again, and created ones
+// createTimeout to be ready.
+func NewOperationManager(workstationManager WorkstationManager, backupManager BackupManager, store store.Store, startTimeout, createTimeout time.Duration, logger lager.Logger) OperationManager {
+	return &operationManager{
+		workstationManager: workstationManager,
+		backupManager:      backupManager,
+		store:              store,
+		startTimeout:       startTimeout,
+		createTimeout:      createTimeout,
+		logger:             logger.Session("operation-manager"),
+	}
+}
+
+// Create validates the workstation and requests its LRP, then follows it in
+// the background until sshd and the TEA are up. Errors validating or
+// requesting it are returned without recording an operation.
+func (m *operationManager) Create(workstation models.Workstation) (models.Operation, error) {
+	log := m.logger.Session("create", lager.Data{"workstation_name": workstation.Name})
+
+	if err := workstation.Validate(); err != nil {
+		return models.Operation{}, err
+	}
+
+	if err := m.workstationManager.Create(workstation); err != nil {
+		return models.Operation{}, err
+	}
+
+	operation, err := m.newOperation(models.OperationCreate, workstation.Name)
+	if err != nil {
+		return operation, err
+	}
+
+	// Workstations pending approval only start once an admin approves them.
+	if workstation.Privilege == models.PendingApprovalMode {
+		operation.State = models.OperationPendingApprovalState
+		m.step(log, &operation, models.OperationPendingApprovalStep)
+		return operation, nil
+	}
+
+	m.step(log, &operation, models.OperationRequestingLRPStep)
+	go m.create(log, operation)
+
+	return operation, nil
+}
+
+// ApprovePrivilege approves the workstation and follows the operation that
+// created it on until it is ready.
+func (m *operationManager) ApprovePrivilege(name, approver string) error {
+	log := m.logger.Session("approve-privilege", lager.Data{"workstation_name": name})
+
+	if err := m.workstationManager.ApprovePrivilege(name, approver); err != nil {
+		return err
+	}
+
+	operations, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	for _, operation := range operations {
+		if operation.Workstation == name && operation.State == models.OperationPendingApprovalState {
+			operation.State = models.OperationInProgressState
+			m.step(log, &operation, models.OperationRequestingLRPStep)
+			go m.create(log, operation)
+		}
+	}
+
+	return nil
+}
+
+// Resize checks the request, the owner's quotas and the capacity left, and
+// starts replacing the workstation with a resized one in the background. A
+// RUNNING workstation has its home directory backed up first and restored into
+// the replacement, so resizing it needs backups to be configured. The workstation stays reserved
+// until it is replaced, so it can't be resized, started or stopped meanwhile.
+func (m *operationManager) Resize(name string, request teapot.WorkstationUpdateRequest) (models.Operation, error) {
+	if err := m.workstationManager.Reserve(name); err != nil {
+		return models.Operation{}, err
+	}
+
+	operation, err := m.resize(name, request)
+	if err != nil {
+		m.workstationManager.Release(name)
+	}
+
+	return operation, err
+}
+
+func (m *operationManager) resize(name string, request teapot.WorkstationUpdateRequest) (models.Operation, error) {
+	workstation, err := m.workstationManager.Get(name)
+	if err != nil {
+		return models.Operation{}, err
+	}
+
+	resized, err := workstation.Resize(request)
+	if err != nil {
+		return models.Operation{}, err
+	}
+
+	if err := m.workstationManager.CheckQuota(resized); err != nil {
+		return models.Operation{}, err
+	}
+
+	if err := m.workstationManager.CheckCapacity(resized); err != nil {
+		return models.Operation{}, err
+	}
+
+	running := workstation.State == models.RunningState
+	if running && !m.backupManager.Enabled() {
+		return models.Operation{}, ErrBackupsDisabled
+	}
+
+	operation, err := m.newOperation(models.OperationResize, name)
+	if err != nil {
+		return operation, err
+	}
+
+	go m.replace(operation, resized, running, workstation.State != models.StoppedState, nil)
+
+	return operation, nil
+}
+
+// Apply makes the changes of the manifest's plan, stopping at the first one
+// that fails. It returns the changes that were planned either way.
+// Workstations whose image, resources, ports or env change are replaced in
+// the background like resized ones, keeping their home directory, and their
+// change carries the operation tracking it.
+func (m *operationManager) Apply(manifest models.Manifest) ([]models.Change, error) {
+	log := m.logger.Session("apply", lager.Data{"manifest": manifest.Name})
+
+	changes, err := m.workstationManager.Plan(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]models.ManifestWorkstation{}
+	for _, entry := range manifest.Workstations {
+		entries[entry.Name] = entry
+	}
+
+	for i, change := range changes {
+		entry := entries[change.Workstation]
+
+		if change.Action == models.ChangeReplace {
+			err = m.workstationManager.Reserve(change.Workstation)
+			if err == nil {
+				var operation models.Operation
+				operation, err = m.replaceFromManifest(change, entry)
+				if err != nil {
+					m.workstationManager.Release(change.Workstation)
+				}
+				changes[i].OperationID = operation.ID
+			}
+		} else {
+			err = m.workstationManager.ApplyChange(change, entry)
+		}
+
+		if err != nil {
+			log.Error("change-failed", err, lager.Data{"change": change})
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+// FailInterrupted marks the operations left IN_PROGRESS by a previous run as
+// FAILED, since nothing follows them anymore.
+func (m *operationManager) FailInterrupted() error {
+	log := m.logger.Session("fail-interrupted")
+
+	operations, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	for _, operation := range operations {
+		if operation.State == models.OperationInProgressState {
+			m.fail(log, &operation, errors.New("interrupted by a teapot restart"))
+		}
+	}
+
+	return nil
+}
+
+func (m *operationManager) list() ([]models.Operation, error) {
+	ids, err := m.store.Keys(operationsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := []models.Operation{}
+	for _, id := range ids {
+		operation, err := m.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// expire deletes the operations that finished more than operationRetention
+// ago.
+func (m *operationManager) expire() error {
+	operations, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	expired := time.Now().Add(-operationRetention)
+	for _, operation := range operations {
+		finished := operation.State == models.OperationSucceededState || operation.State == models.OperationFailedState
+		if finished && operation.UpdatedAt.Before(expired) {
+			if err := m.store.Delete(operationsCollection, operation.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *operationManager) Get(id string) (models.Operation, error) {
+	operation := models.Operation{}
+	err := m.store.Get(operationsCollection, id, &operation)
+	if err == store.ErrNotFound {
+		return operation, models.ErrNotFound{"operation", id}
+	}
+
+	return operation, err
+}
+
+// replaceFromManifest checks the replacement a manifest asks for the way
+// Resize checks a resize and starts it in the background, updating what can
+// be updated in place once the workstation is replaced.
+func (m *operationManager) replaceFromManifest(change models.Change, entry models.ManifestWorkstation) (models.Operation, error) {
+	workstation, err := m.workstationManager.Get(entry.Name)
+	if err != nil {
+		return models.Operation{}, err
+	}
+
+	replacement := workstation
+	replacement.DockerImage = entry.DockerImage
+	replacement.CPUWeight = entry.CPUWeight
+	replacement.DiskMB = entry.DiskMB
+	replacement.MemoryMB = entry.MemoryMB
+	replacement.Ports = entry.Ports
+	replacement.Env = entry.Env
+	replacement.Owner = entry.Owner
+	if err := replacement.Validate(); err != nil {
+		return models.Operation{}, err
+	}
+
+	if err := m.workstationManager.CheckQuota(replacement); err != nil {
+		return models.Operation{}, err
+	}
+
+	if err := m.workstationManager.CheckCapacity(replacement); err != nil {
+		return models.Operation{}, err
+	}
+
+	running := workstation.State == models.RunningState
+	if running && !m.backupManager.Enabled() {
+		return models.Operation{}, ErrBackupsDisabled
+	}
+
+	operation, err := m.newOperation(models.OperationReplace, entry.Name)
+	if err != nil {
+		return operation, err
+	}
+
+	go m.replace(operation, replacement, running, workstation.State != models.StoppedState, func() error {
+		return m.workstationManager.ApplyChange(change, entry)
+	})
+
+	return operation, nil
+}
+
+// replace backs up the workstation's home directory when backup is set and
+// replaces it with replacement, restoring the backup into it. The replacement
+// is then passed to update, when given, and waited for when wait is set.
+// The workstation is released once it's done.
+func (m *operationManager) replace(operation models.Operation, replacement models.Workstation, backup, wait bool, update func() error) {
+	log := m.logger.Session(operation.Type, lager.Data{"workstation_name": replacement.Name, "operation_id": operation.ID})
+	defer m.workstationManager.Release(replacement.Name)
+
+	if backup {
+		m.step(log, &operation, models.OperationBackingUpStep)
+		snapshot, err := m.backupManager.Backup(replacement.Name)
+		if err != nil {
+			m.fail(log, &operation, fmt.Errorf("backup failed: %s", err))
+			return
+		}
+		replacement.RestoreFrom = snapshot.ID
+	}
+
+	m.step(log, &operation, models.OperationReplacingStep)
+	replacedAt := time.Now()
+	if err := m.workstationManager.Replace(replacement); err != nil {
+		m.fail(log, &operation, fmt.Errorf("replace failed: %s", err))
+		return
+	}
+
+	if update != nil {
+		if err := update(); err != nil {
+			m.fail(log, &operation, fmt.Errorf("update failed: %s", err))
+			return
+		}
+	}
+
+	if wait {
+		m.step(log, &operation, models.OperationStartingStep)
+		if err := m.waitUntilRunning(replacement.Name, replacedAt); err != nil {
+			m.fail(log, &operation, err)
+			return
+		}
+	}
+
+	operation.State = models.OperationSucceededState
+	m.step(log, &operation, models.OperationDoneStep)
+}
+
+func (m *operationManager) create(log lager.Logger, operation models.Operation) {
+	if err := m.waitUntilReady(log, &operation); err != nil {
+		m.fail(log, &operation, err)
+		return
+	}
+
+	operation.State = models.OperationSucceededState
+	m.step(log, &operation, models.OperationReadyStep)
+}
+
+// waitUntilReady polls the created workstation, moving the operation along as
+// its LRP is placed, downloads the setup and runs, until the TEA accepts
+// connections. Workstations that can't be placed or crash fail right away.
+func (m *operationManager) waitUntilReady(log lager.Logger, operation *models.Operation) error {
+	deadline := time.Now().Add(m.createTimeout)
+
+	for {
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return fmt.Errorf("workstation was not ready after %s", m.createTimeout)
+		}
+		if remaining > operationPollInterval {
+			remaining = operationPollInterval
+		}
+		time.Sleep(remaining)
+
+		actualLRPs, err := m.workstationManager.Fetch(operation.Workstation)
+		if err != nil || len(actualLRPs) == 0 {
+			continue
+		}
+
+		actualLRP := actualLRPs[0]
+		switch actualLRP.State {
+		case receptor.ActualLRPStateUnclaimed:
+			if actualLRP.PlacementError != "" {
+				return fmt.Errorf("workstation could not be placed: %s", actualLRP.PlacementError)
+			}
+		case receptor.ActualLRPStateClaimed:
+			if operation.Step != models.OperationDownloadingSetupStep {
+				m.step(log, operation, models.OperationDownloadingSetupStep)
+			}
+		case receptor.ActualLRPStateCrashed:
+			return fmt.Errorf("workstation crashed %d times while starting", actualLRP.CrashCount)
+		case receptor.ActualLRPStateRunning:
+			if operation.Step != models.OperationStartingStep {
+				m.step(log, operation, models.OperationStartingStep)
+			}
+
+			hostPort := teaHostPort(actualLRP)
+			if hostPort == 0 {
+				continue
+			}
+
+			conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", actualLRP.Address, hostPort), teaDialTimeout)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+		}
+	}
+}
+
+// waitUntilRunning polls the workstation until an instance started after
+// since is RUNNING, so the instance being torn down doesn't count.
+func (m *operationManager) waitUntilRunning(name string, since time.Time) error {
+	deadline := time.Now().Add(m.startTimeout)
+
+	for {
+		actualLRPs, err := m.workstationManager.Fetch(name)
+		if err == nil {
+			for _, actualLRP := range actualLRPs {
+				if actualLRP.State == receptor.ActualLRPStateRunning && actualLRP.Since >= since.UnixNano() {
+					return nil
+				}
+			}
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return fmt.Errorf("workstation was not RUNNING after %s", m.startTimeout)
+		}
+		if remaining > operationPollInterval {
+			remaining = operationPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+func (m *operationManager) newOperation(operationType, name string) (models.Operation, error) {
+	if err := m.expire(); err != nil {
+		m.logger.Error("expire-failed", err)
+	}
+
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return models.Operation{}, err
+	}
+
+	now := time.Now().UTC()
+	operation := models.Operation{
+		ID:          guid.String(),
+		Type:        operationType,
+		Workstation: name,
+		State:       models.OperationInProgressState,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	return operation, m.store.Put(operationsCollection, operation.ID, operation)
+}
+
+func (m *operationManager) step(log lager.Logger, operation *models.Operation, step string) {
+	log.Info(step)
+	operation.Step = step
+	m.record(log, operation)
+}
+
+func (m *operationManager) fail(log lager.Logger, operation *models.Operation, err error) {
+	log.Error("failed", err, lager.Data{"step": operation.Step})
+	operation.State = models.OperationFailedState
+	operation.FailureReason = err.Error()
+	m.record(log, operation)
+}
+
+func (m *operationManager) record(log lager.Logger, operation *models.Operation) {
+	operation.UpdatedAt = time.Now().UTC()
+	if err := m.store.Put(operationsCollection, operation.ID, *operation); err != nil {
+		log.Error("record-failed", err)
+	}
+}
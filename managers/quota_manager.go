@@ -0,0 +1,63 @@
+package managers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/luan/teapot/models"
+)
+
+type QuotaManager interface {
+	UserQuota(user string) models.Quota
+	Teams(user string) []models.Team
+}
+
+type quotaManager struct {
+	config models.QuotaConfig
+}
+
+// NewQuotaManager loads the quotas from the JSON file at path. Without a file
+// nobody has any limits.
+func NewQuotaManager(path string) (QuotaManager, error) {
+	m := &quotaManager{}
+
+	if path == "" {
+		return m, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(contents, &m.config); err != nil {
+		return nil, err
+	}
+
+	if err := m.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *quotaManager) UserQuota(user string) models.Quota {
+	if quota, ok := m.config.Users[user]; ok {
+		return quota
+	}
+	return m.config.Default
+}
+
+// Teams lists the teams user is a member of.
+func (m *quotaManager) Teams(user string) []models.Team {
+	teams := []models.Team{}
+	for _, team := range m.config.Teams {
+		for _, member := range team.Members {
+			if member == user {
+				teams = append(teams, team)
+				break
+			}
+		}
+	}
+	return teams
+}
@@ -0,0 +1,193 @@
+package managers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+)
+
+const secretsCollection = "secrets"
+
+var ErrSecretsDisabled = errors.New("secrets are not configured")
+
+type SecretManager interface {
+	List(owner string) ([]models.Secret, error)
+	Save(secret models.Secret) (models.Secret, error)
+	Delete(owner, name string) error
+	Resolve(owner string, names []string) (map[string]string, error)
+}
+
+// storedSecret is a secret as kept in the store, its value sealed with
+// AES-GCM and the nonce prepended.
+type storedSecret struct {
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner,omitempty"`
+	Ciphertext []byte    `json:"ciphertext"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type secretManager struct {
+	aead  cipher.AEAD
+	store store.Store
+	mutex sync.Mutex
+}
+
+// NewSecretManager returns a SecretManager that encrypts secrets with key,
+// which must be 16, 24 or 32 bytes long. When key is empty, secrets are
+// disabled and using any fails with ErrSecretsDisabled.
+func NewSecretManager(key []byte, store store.Store) (SecretManager, error) {
+	manager := &secretManager{store: store}
+	if len(key) == 0 {
+		return manager, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func (m *secretManager) List(owner string) ([]models.Secret, error) {
+	if m.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	keys, err := m.store.Keys(secretsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []models.Secret{}
+	for _, key := range keys {
+		prefix := owner + "/"
+		if !strings.HasPrefix(key, prefix) || strings.Contains(key[len(prefix):], "/") {
+			continue
+		}
+
+		stored := storedSecret{}
+		if err := m.store.Get(secretsCollection, key, &stored); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, models.Secret{
+			Name:      stored.Name,
+			Owner:     stored.Owner,
+			UpdatedAt: stored.UpdatedAt,
+		})
+	}
+
+	return secrets, nil
+}
+
+// Save encrypts the secret and stores it, replacing the owner's secret of the
+// same name. The returned secret has no value.
+func (m *secretManager) Save(secret models.Secret) (models.Secret, error) {
+	if m.aead == nil {
+		return models.Secret{}, ErrSecretsDisabled
+	}
+
+	if err := secret.Validate(); err != nil {
+		return models.Secret{}, err
+	}
+
+	key := secretKey(secret.Owner, secret.Name)
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return models.Secret{}, err
+	}
+
+	stored := storedSecret{
+		Name:       secret.Name,
+		Owner:      secret.Owner,
+		Ciphertext: m.aead.Seal(nonce, nonce, []byte(secret.Value), []byte(key)),
+		UpdatedAt:  time.Now().UTC(),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := m.store.Put(secretsCollection, key, stored)
+	return models.Secret{Name: stored.Name, Owner: stored.Owner, UpdatedAt: stored.UpdatedAt}, err
+}
+
+func (m *secretManager) Delete(owner, name string) error {
+	if m.aead == nil {
+		return ErrSecretsDisabled
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := m.store.Delete(secretsCollection, secretKey(owner, name))
+	if err == store.ErrNotFound {
+		return models.ErrNotFound{"secret", name}
+	}
+	return err
+}
+
+// Resolve decrypts the owner's secrets with the given names, keyed by name.
+func (m *secretManager) Resolve(owner string, names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	if m.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	values := map[string]string{}
+	for _, name := range names {
+		key := secretKey(owner, name)
+
+		stored := storedSecret{}
+		err := m.store.Get(secretsCollection, key, &stored)
+		if err == store.ErrNotFound {
+			return nil, models.ValidationError{models.ErrInvalidField{"secrets"}}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := m.open(key, stored.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+func (m *secretManager) open(key string, ciphertext []byte) (string, error) {
+	nonceSize := m.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return "", errors.New("secret is corrupt: " + key)
+	}
+
+	value, err := m.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], []byte(key))
+	if err != nil {
+		return "", errors.New("secret cannot be decrypted: " + key)
+	}
+
+	return string(value), nil
+}
+
+// secretKey namespaces secrets by owner, the key is also authenticated along
+// with the value so a ciphertext can't be moved to another secret.
+func secretKey(owner, name string) string {
+	return owner + "/" + name
+}
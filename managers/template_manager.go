@@ -0,0 +1,165 @@
+package managers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/luan/teapot/models"
+)
+
+type TemplateManager interface {
+	List() ([]models.Template, error)
+	Get(name string) (models.Template, error)
+	Save(template models.Template) error
+	Delete(name string) error
+}
+
+type templateManager struct {
+	path      string
+	templates map[string]models.Template
+	mutex     sync.RWMutex
+}
+
+// NewTemplateManager loads the templates from the JSON list at path and
+// writes edits back to it. When path is empty templates only live in memory.
+func NewTemplateManager(path string) (TemplateManager, error) {
+	m := &templateManager{
+		path:      path,
+		templates: map[string]models.Template{},
+	}
+
+	if path == "" {
+		return m, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []models.Template
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, &templates); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, template := range templates {
+		if err := template.Validate(); err != nil {
+			return nil, err
+		}
+		m.templates[template.Name] = template
+	}
+
+	return m, nil
+}
+
+func (m *templateManager) List() ([]models.Template, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return sorted(m.templates), nil
+}
+
+func (m *templateManager) Get(name string) (models.Template, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	template, ok := m.templates[name]
+	if !ok {
+		return template, models.ErrNotFound{"template", name}
+	}
+	return template, nil
+}
+
+// Save creates the template or replaces the one with the same name.
+func (m *templateManager) Save(template models.Template) error {
+	if err := template.Validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	templates := m.copy()
+	templates[template.Name] = template
+	return m.save(templates)
+}
+
+func (m *templateManager) Delete(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.templates[name]; !ok {
+		return models.ErrNotFound{"template", name}
+	}
+	templates := m.copy()
+	delete(templates, name)
+
+	return m.save(templates)
+}
+
+func (m *templateManager) copy() map[string]models.Template {
+	templates := make(map[string]models.Template, len(m.templates))
+	for name, template := range m.templates {
+		templates[name] = template
+	}
+	return templates
+}
+
+func sorted(templates map[string]models.Template) []models.Template {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []models.Template{}
+	for _, name := range names {
+		list = append(list, templates[name])
+	}
+	return list
+}
+
+// save writes templates to the file and only then makes them the current
+// ones, so a failed write leaves both as they were.
+func (m *templateManager) save(templates map[string]models.Template) error {
+	if m.path == "" {
+		m.templates = templates
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(sorted(templates), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(m.path), filepath.Base(m.path))
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	m.templates = templates
+	return nil
+}
@@ -0,0 +1,258 @@
+package managers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+)
+
+const (
+	runningIntervalsCollection = "usage-running"
+	usageIntervalsCollection   = "usage-intervals"
+	dailyUsageCollection       = "usage-daily"
+)
+
+var usageGroupBys = []string{models.UsageByUser, models.UsageByTeam, models.UsageByImage}
+
+type UsageManager interface {
+	Start(workstation models.Workstation, at time.Time) error
+	Stop(name string, at time.Time) error
+	Running() ([]string, error)
+	Report(groupBy string, from, to time.Time) ([]models.UsageRecord, error)
+}
+
+type usageManager struct {
+	store        store.Store
+	quotaManager QuotaManager
+	mutex        sync.Mutex
+	rolledUpTo   time.Time
+}
+
+// NewUsageManager returns a UsageManager metering the intervals workstations
+// run for, charged to the team their owner is in according to quotaManager.
+// The intervals run before the current UTC day are rolled up into daily usage
+// records, kept in place of them.
+func NewUsageManager(store store.Store, quotaManager QuotaManager) UsageManager {
+	return &usageManager{
+		store:        store,
+		quotaManager: quotaManager,
+	}
+}
+
+// Start opens a running interval for the workstation, closing the one it may
+// still have open at the same time.
+func (m *usageManager) Start(workstation models.Workstation, at time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.stop(workstation.Name, at); err != nil {
+		return err
+	}
+
+	team := ""
+	if teams := m.quotaManager.Teams(workstation.Owner); len(teams) > 0 {
+		team = teams[0].Name
+	}
+
+	interval := models.NewRunningInterval(workstation, team, at.UTC())
+	return m.store.Put(runningIntervalsCollection, workstation.Name, interval)
+}
+
+// Stop closes the workstation's running interval, if it has one open.
+func (m *usageManager) Stop(name string, at time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.stop(name, at)
+}
+
+// Running returns the names of the workstations with a running interval
+// open.
+func (m *usageManager) Running() ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.store.Keys(runningIntervalsCollection)
+}
+
+// Report sums the daily usage records rolled up between from and to with the
+// intervals run since, grouped by user, team or image.
+func (m *usageManager) Report(groupBy string, from, to time.Time) ([]models.UsageRecord, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now().UTC()
+	if err := m.rollUp(now); err != nil {
+		return nil, err
+	}
+
+	usages, err := m.dailyUsages(groupBy, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := []models.RunningInterval{}
+	for _, collection := range []string{usageIntervalsCollection, runningIntervalsCollection} {
+		collected, err := m.intervals(collection)
+		if err != nil {
+			return nil, err
+		}
+		for _, interval := range collected {
+			intervals = append(intervals, interval)
+		}
+	}
+
+	usages = append(usages, models.SplitUsage(intervals, groupBy, from, to, now)...)
+	return models.UsageRecords(usages), nil
+}
+
+func (m *usageManager) dailyUsages(groupBy string, from, to time.Time) ([]models.DailyUsage, error) {
+	keys, err := m.store.Keys(dailyUsageCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := []models.DailyUsage{}
+	for _, key := range keys {
+		usage := models.DailyUsage{}
+		err := m.store.Get(dailyUsageCollection, key, &usage)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		day, err := time.Parse(models.UsageDayFormat, usage.Day)
+		if err != nil || usage.GroupBy != groupBy || day.Before(from.UTC().Truncate(24*time.Hour)) || !day.Before(to) {
+			continue
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+func (m *usageManager) intervals(collection string) (map[string]models.RunningInterval, error) {
+	keys, err := m.store.Keys(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := map[string]models.RunningInterval{}
+	for _, key := range keys {
+		interval := models.RunningInterval{}
+		err := m.store.Get(collection, key, &interval)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		intervals[key] = interval
+	}
+	return intervals, nil
+}
+
+// rollUp adds the time intervals ran for before the start of now's UTC day to
+// the daily usage records, once a day. Intervals that ended by then are
+// removed, and the others kept from then on only.
+func (m *usageManager) rollUp(now time.Time) error {
+	cutoff := now.UTC().Truncate(24 * time.Hour)
+	if !cutoff.After(m.rolledUpTo) {
+		return nil
+	}
+
+	usages := []models.DailyUsage{}
+	rolledUp := map[string]map[string]models.RunningInterval{}
+	for _, collection := range []string{usageIntervalsCollection, runningIntervalsCollection} {
+		intervals, err := m.intervals(collection)
+		if err != nil {
+			return err
+		}
+
+		rolledUp[collection] = map[string]models.RunningInterval{}
+		for key, interval := range intervals {
+			if !interval.StartedAt.Before(cutoff) {
+				continue
+			}
+
+			for _, groupBy := range usageGroupBys {
+				usages = append(usages, models.SplitUsage([]models.RunningInterval{interval}, groupBy, interval.StartedAt, cutoff, now)...)
+			}
+			rolledUp[collection][key] = interval
+		}
+	}
+
+	for _, usage := range usages {
+		key := usage.GroupBy + "/" + usage.Day + "/" + usage.Group
+
+		total := models.DailyUsage{}
+		err := m.store.Get(dailyUsageCollection, key, &total)
+		if err == store.ErrNotFound {
+			total = models.DailyUsage{Day: usage.Day, GroupBy: usage.GroupBy, Group: usage.Group}
+		} else if err != nil {
+			return err
+		}
+
+		total.Add(usage)
+		if err := m.store.Put(dailyUsageCollection, key, total); err != nil {
+			return err
+		}
+	}
+
+	for key, interval := range rolledUp[usageIntervalsCollection] {
+		if err := m.store.Delete(usageIntervalsCollection, key); err != nil {
+			return err
+		}
+
+		if interval.StoppedAt.After(cutoff) {
+			interval.StartedAt = cutoff
+			if err := m.store.Put(usageIntervalsCollection, intervalKey(interval), interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, interval := range rolledUp[runningIntervalsCollection] {
+		interval.StartedAt = cutoff
+		if err := m.store.Put(runningIntervalsCollection, key, interval); err != nil {
+			return err
+		}
+	}
+
+	m.rolledUpTo = cutoff
+	return nil
+}
+
+func (m *usageManager) stop(name string, at time.Time) error {
+	interval := models.RunningInterval{}
+	err := m.store.Get(runningIntervalsCollection, name, &interval)
+	if err == store.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stoppedAt := at.UTC()
+	if stoppedAt.Before(interval.StartedAt) {
+		stoppedAt = interval.StartedAt
+	}
+	interval.StoppedAt = &stoppedAt
+
+	if err := m.store.Put(usageIntervalsCollection, intervalKey(interval), interval); err != nil {
+		return err
+	}
+
+	if err := m.store.Delete(runningIntervalsCollection, name); err != nil {
+		return err
+	}
+
+	return m.rollUp(at)
+}
+
+func intervalKey(interval models.RunningInterval) string {
+	return interval.Workstation + "/" + interval.StartedAt.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,79 @@
+package managers
+
+import (
+	"sync"
+
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+)
+
+const userKeysCollection = "user-keys"
+
+type UserManager interface {
+	ListKeys(user string) ([]models.SSHKey, error)
+	AddKey(user string, key []byte) (models.SSHKey, error)
+	RemoveKey(user, fingerprint string) error
+}
+
+type userManager struct {
+	store store.Store
+	mutex sync.Mutex
+}
+
+func NewUserManager(store store.Store) UserManager {
+	return &userManager{
+		store: store,
+	}
+}
+
+func (m *userManager) ListKeys(user string) ([]models.SSHKey, error) {
+	keys := []models.SSHKey{}
+
+	err := m.store.Get(userKeysCollection, user, &keys)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (m *userManager) AddKey(user string, key []byte) (models.SSHKey, error) {
+	sshKey, err := models.ParseSSHKey(string(key))
+	if err != nil {
+		return sshKey, models.ValidationError{err}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys, err := m.ListKeys(user)
+	if err != nil {
+		return sshKey, err
+	}
+
+	for _, existing := range keys {
+		if existing.Fingerprint == sshKey.Fingerprint {
+			return existing, nil
+		}
+	}
+
+	return sshKey, m.store.Put(userKeysCollection, user, append(keys, sshKey))
+}
+
+func (m *userManager) RemoveKey(user, fingerprint string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys, err := m.ListKeys(user)
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		if key.Fingerprint == fingerprint {
+			return m.store.Put(userKeysCollection, user, append(keys[:i], keys[i+1:]...))
+		}
+	}
+
+	return models.ErrNotFound{"key", fingerprint}
+}
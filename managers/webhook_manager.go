@@ -0,0 +1,368 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	"github.com/nu7hatch/gouuid"
+)
+
+const (
+	webhooksCollection   = "webhooks"
+	deliveriesCollection = "webhook-deliveries"
+	maxDeliveryHistory   = 50
+)
+
+type WebhookManager interface {
+	Create(webhook models.Webhook) (models.Webhook, error)
+	List() ([]models.Webhook, error)
+	Get(id string) (models.Webhook, error)
+	Delete(id string) error
+
+	Publish(event models.WorkstationEvent) error
+	Due(now time.Time) ([]models.Delivery, error)
+	RecordAttempt(id string, responseStatus int, attemptErr error) (models.Delivery, error)
+	Deliveries(webhookID string) ([]models.Delivery, error)
+	DeadLetters() ([]models.Delivery, error)
+	Redeliver(id string) (models.Delivery, error)
+}
+
+// storedWebhook is a webhook as kept in the store, secret included.
+type storedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+type webhookManager struct {
+	store         store.Store
+	retryInterval time.Duration
+	maxAttempts   int
+	mutex         sync.Mutex
+}
+
+// NewWebhookManager returns a WebhookManager queueing a delivery for every
+// event a webhook subscribes to. A delivery that fails is retried after
+// retryInterval, twice as long after every further failure, and dead-lettered
+// once it failed maxAttempts times.
+func NewWebhookManager(store store.Store, retryInterval time.Duration, maxAttempts int) WebhookManager {
+	return &webhookManager{
+		store:         store,
+		retryInterval: retryInterval,
+		maxAttempts:   maxAttempts,
+	}
+}
+
+func (m *webhookManager) Create(webhook models.Webhook) (models.Webhook, error) {
+	if err := webhook.Validate(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	webhook.ID = guid.String()
+	webhook.CreatedAt = time.Now().UTC()
+
+	err = m.store.Put(webhooksCollection, webhook.ID, storedWebhook{Webhook: webhook, Secret: webhook.Secret})
+	return webhook, err
+}
+
+func (m *webhookManager) List() ([]models.Webhook, error) {
+	keys, err := m.store.Keys(webhooksCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []models.Webhook{}
+	for _, key := range keys {
+		webhook, err := m.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	sort.Sort(webhooksByCreatedAt(webhooks))
+	return webhooks, nil
+}
+
+func (m *webhookManager) Get(id string) (models.Webhook, error) {
+	stored := storedWebhook{}
+	err := m.store.Get(webhooksCollection, id, &stored)
+	if err == store.ErrNotFound {
+		return models.Webhook{}, models.ErrNotFound{"webhook", id}
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook := stored.Webhook
+	webhook.Secret = stored.Secret
+	return webhook, nil
+}
+
+// Delete removes the webhook along with its deliveries, pending or not.
+func (m *webhookManager) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := m.store.Delete(webhooksCollection, id)
+	if err == store.ErrNotFound {
+		return models.ErrNotFound{"webhook", id}
+	}
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := m.deliveries(func(delivery models.Delivery) bool {
+		return delivery.WebhookID == id
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := m.store.Delete(deliveriesCollection, delivery.ID); err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish queues a delivery of the event to every webhook subscribed to it,
+// due right away.
+func (m *webhookManager) Publish(event models.WorkstationEvent) error {
+	webhooks, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	if event.ID == "" {
+		guid, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		event.ID = guid.String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+
+		guid, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		delivery := models.Delivery{
+			ID:            guid.String(),
+			WebhookID:     webhook.ID,
+			Event:         event,
+			State:         models.DeliveryPendingState,
+			CreatedAt:     now,
+			NextAttemptAt: &now,
+		}
+		if err := m.store.Put(deliveriesCollection, delivery.ID, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Due returns the pending deliveries to attempt at now, oldest first.
+func (m *webhookManager) Due(now time.Time) ([]models.Delivery, error) {
+	deliveries, err := m.deliveries(func(delivery models.Delivery) bool {
+		return delivery.State == models.DeliveryPendingState &&
+			delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(deliveriesByCreatedAt(deliveries)))
+	return deliveries, nil
+}
+
+// RecordAttempt records the outcome of sending a delivery, which succeeded if
+// the webhook answered with a 2xx status. Otherwise it's scheduled again or,
+// after too many attempts, dead-lettered.
+func (m *webhookManager) RecordAttempt(id string, responseStatus int, attemptErr error) (models.Delivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delivery := models.Delivery{}
+	err := m.store.Get(deliveriesCollection, id, &delivery)
+	if err == store.ErrNotFound {
+		return delivery, models.ErrNotFound{"delivery", id}
+	}
+	if err != nil {
+		return delivery, err
+	}
+
+	if attemptErr == nil && (responseStatus < 200 || responseStatus > 299) {
+		attemptErr = fmt.Errorf("webhook responded with status %d", responseStatus)
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = responseStatus
+	delivery.LastAttemptAt = &now
+	delivery.NextAttemptAt = nil
+	delivery.LastError = ""
+
+	switch {
+	case attemptErr == nil:
+		delivery.State = models.DeliveryDeliveredState
+	case delivery.Attempts >= m.maxAttempts:
+		delivery.State = models.DeliveryFailedState
+		delivery.LastError = attemptErr.Error()
+	default:
+		next := now.Add(m.retryInterval << uint(delivery.Attempts-1))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = attemptErr.Error()
+	}
+
+	if err := m.store.Put(deliveriesCollection, delivery.ID, delivery); err != nil {
+		return delivery, err
+	}
+
+	if delivery.State == models.DeliveryDeliveredState {
+		return delivery, m.pruneDelivered(delivery.WebhookID)
+	}
+	return delivery, nil
+}
+
+// Deliveries returns the webhook's delivery history, newest first.
+func (m *webhookManager) Deliveries(webhookID string) ([]models.Delivery, error) {
+	if _, err := m.Get(webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := m.deliveries(func(delivery models.Delivery) bool {
+		return delivery.WebhookID == webhookID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(deliveriesByCreatedAt(deliveries))
+	return deliveries, nil
+}
+
+// DeadLetters returns the deliveries given up on, newest first. They are kept
+// until redelivered or their webhook is deleted.
+func (m *webhookManager) DeadLetters() ([]models.Delivery, error) {
+	deliveries, err := m.deliveries(func(delivery models.Delivery) bool {
+		return delivery.State == models.DeliveryFailedState
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(deliveriesByCreatedAt(deliveries))
+	return deliveries, nil
+}
+
+// Redeliver queues a dead-lettered delivery again, with a fresh set of
+// attempts.
+func (m *webhookManager) Redeliver(id string) (models.Delivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delivery := models.Delivery{}
+	err := m.store.Get(deliveriesCollection, id, &delivery)
+	if err == store.ErrNotFound || (err == nil && delivery.State != models.DeliveryFailedState) {
+		return models.Delivery{}, models.ErrNotFound{"dead letter", id}
+	}
+	if err != nil {
+		return models.Delivery{}, err
+	}
+
+	now := time.Now().UTC()
+	delivery.State = models.DeliveryPendingState
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+
+	return delivery, m.store.Put(deliveriesCollection, delivery.ID, delivery)
+}
+
+// pruneDelivered forgets the webhook's oldest successful deliveries beyond
+// maxDeliveryHistory. Pending and dead-lettered ones are always kept.
+func (m *webhookManager) pruneDelivered(webhookID string) error {
+	delivered, err := m.deliveries(func(delivery models.Delivery) bool {
+		return delivery.WebhookID == webhookID && delivery.State == models.DeliveryDeliveredState
+	})
+	if err != nil || len(delivered) <= maxDeliveryHistory {
+		return err
+	}
+
+	sort.Sort(deliveriesByCreatedAt(delivered))
+	for _, delivery := range delivered[maxDeliveryHistory:] {
+		if err := m.store.Delete(deliveriesCollection, delivery.ID); err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *webhookManager) deliveries(matches func(models.Delivery) bool) ([]models.Delivery, error) {
+	keys, err := m.store.Keys(deliveriesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.Delivery{}
+	for _, key := range keys {
+		delivery := models.Delivery{}
+		err := m.store.Get(deliveriesCollection, key, &delivery)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if matches(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+type webhooksByCreatedAt []models.Webhook
+
+func (webhooks webhooksByCreatedAt) Len() int { return len(webhooks) }
+func (webhooks webhooksByCreatedAt) Less(i, j int) bool {
+	return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+}
+func (webhooks webhooksByCreatedAt) Swap(i, j int) {
+	webhooks[i], webhooks[j] = webhooks[j], webhooks[i]
+}
+
+// deliveriesByCreatedAt sorts deliveries newest first.
+type deliveriesByCreatedAt []models.Delivery
+
+func (deliveries deliveriesByCreatedAt) Len() int { return len(deliveries) }
+func (deliveries deliveriesByCreatedAt) Less(i, j int) bool {
+	return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+}
+func (deliveries deliveriesByCreatedAt) Swap(i, j int) {
+	deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+}
@@ -0,0 +1,91 @@
+package managers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// Download streams a file from the workstation. With archive set the path may
+// also be a directory, which is streamed as a tarball.
+func (m *workstationManager) Download(name, filePath string, archive bool) (io.ReadCloser, error) {
+	log := m.logger.Session("download", lager.Data{"workstation_name": name, "path": filePath, "archive": archive})
+
+	req, err := m.teaFileRequest("GET", name, filePath, archive, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error("request-failed", err)
+		return nil, err
+	}
+
+	if err := teaFileError(res, filePath); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// Upload streams content to a file in the workstation. With archive set the
+// content is a tarball, extracted into the directory at path.
+func (m *workstationManager) Upload(name, filePath string, archive bool, content io.Reader, size int64) error {
+	log := m.logger.Session("upload", lager.Data{"workstation_name": name, "path": filePath, "archive": archive})
+
+	req, err := m.teaFileRequest("PUT", name, filePath, archive, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error("request-failed", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	return teaFileError(res, filePath)
+}
+
+func (m *workstationManager) teaFileRequest(method, name, filePath string, archive bool, body io.Reader) (*http.Request, error) {
+	if !path.IsAbs(filePath) {
+		return nil, models.ValidationError{models.ErrInvalidField{"path"}}
+	}
+
+	teaAddress, err := m.teaAddress(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fileURL := &url.URL{
+		Scheme: "http",
+		Host:   teaAddress,
+		Path:   "/" + path.Join("files", m.teaSecret, path.Clean(filePath)),
+	}
+	if archive {
+		fileURL.RawQuery = "tar=true"
+	}
+
+	return http.NewRequest(method, fileURL.String(), body)
+}
+
+func teaFileError(res *http.Response, filePath string) error {
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return models.ErrNotFound{"file", filePath}
+	case res.StatusCode == http.StatusBadRequest:
+		return models.ValidationError{models.ErrInvalidField{"path"}}
+	case res.StatusCode > 299:
+		return fmt.Errorf("TEA file request failed with status %d", res.StatusCode)
+	}
+	return nil
+}
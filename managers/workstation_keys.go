@@ -0,0 +1,163 @@
+package managers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// teaKeyClient talks to the TEAs, giving up on one that doesn't answer so a
+// hung workstation can't block its caller.
+var teaKeyClient = &http.Client{Timeout: 10 * time.Second}
+
+func (m *workstationManager) AddKey(name string, key []byte) error {
+	sshKey, err := models.ParseSSHKey(string(key))
+	if err != nil {
+		return models.ValidationError{err}
+	}
+
+	err = m.teaKeyRequest(name, "add-key", sshKey)
+	if err != nil {
+		return err
+	}
+
+	err = m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		for _, existing := range annotation.Keys {
+			if existing.Fingerprint == sshKey.Fingerprint {
+				return nil
+			}
+		}
+		annotation.Keys = append(annotation.Keys, sshKey)
+		return nil
+	})
+	if _, ok := err.(models.ValidationError); ok {
+		// The key couldn't be stored, so it would be gone on the next
+		// restart anyway.
+		m.teaKeyRequest(name, "remove-key", sshKey)
+	}
+	return err
+}
+
+func (m *workstationManager) ListKeys(name string) ([]models.SSHKey, error) {
+	annotation, err := m.fetchAnnotation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := annotation.Keys
+	if keys == nil {
+		keys = []models.SSHKey{}
+	}
+	return keys, nil
+}
+
+func (m *workstationManager) RemoveKey(name, fingerprint string) error {
+	annotation, err := m.fetchAnnotation(name)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, key := range annotation.Keys {
+		if key.Fingerprint == fingerprint {
+			index = i
+		}
+	}
+	if index < 0 {
+		return models.ErrNotFound{"key", fingerprint}
+	}
+
+	if m.running(name) {
+		err = m.teaKeyRequest(name, "remove-key", annotation.Keys[index])
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		for i, key := range annotation.Keys {
+			if key.Fingerprint == fingerprint {
+				annotation.Keys = append(annotation.Keys[:i], annotation.Keys[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// PushKeys sends every stored key, along with the owner's profile keys, to
+// the workstation's TEA. Keys live in the container's home directory, so they
+// must be pushed again after a restart.
+func (m *workstationManager) PushKeys(name string) error {
+	log := m.logger.Session("push-keys", lager.Data{"workstation_name": name})
+
+	annotation, err := m.fetchAnnotation(name)
+	if err != nil {
+		return err
+	}
+
+	userKeys, err := m.userManager.ListKeys(annotation.Owner)
+	if err != nil {
+		return err
+	}
+
+	pushed := map[string]bool{}
+	for _, key := range append(annotation.Keys, userKeys...) {
+		if pushed[key.Fingerprint] {
+			continue
+		}
+
+		err := m.teaKeyRequest(name, "add-key", key)
+		if err != nil {
+			log.Error("push-failed", err, lager.Data{"fingerprint": key.Fingerprint})
+			return err
+		}
+		pushed[key.Fingerprint] = true
+	}
+
+	log.Info("pushed", lager.Data{"keys": len(pushed)})
+	return nil
+}
+
+// PushKey sends a single key to the workstation's TEA without storing it, so
+// it is forgotten when the workstation restarts.
+func (m *workstationManager) PushKey(name string, key models.SSHKey) error {
+	return m.teaKeyRequest(name, "add-key", key)
+}
+
+func (m *workstationManager) running(name string) bool {
+	actualLRPs, err := m.receptorClient.ActualLRPsByProcessGuid(name)
+	return err == nil && len(actualLRPs) > 0 && actualLRPs[0].State == receptor.ActualLRPStateRunning
+}
+
+func (m *workstationManager) teaKeyRequest(name, action string, key models.SSHKey) error {
+	route := m.routeProvider.SSHRoute(name)
+	if !strings.HasPrefix(route, "http://") && !strings.HasPrefix(route, "https://") {
+		route = "http://" + route
+	}
+	u, err := url.Parse(route)
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(action, m.teaSecret)
+
+	res, err := teaKeyClient.Post(u.String(), "text/plain", bytes.NewReader([]byte(key.Key)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return fmt.Errorf("TEA %s failed with status %d", action, res.StatusCode)
+	}
+
+	return nil
+}
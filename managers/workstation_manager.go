@@ -1,12 +1,12 @@
 package managers
 
 import (
-	"bytes"
 	"fmt"
-	"net/http"
-	"net/url"
-	"path"
-	"strings"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry-incubator/receptor"
 	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
@@ -15,42 +15,53 @@ import (
 	"github.com/pivotal-golang/lager"
 )
 
-var setupAction = &diego_models.SerialAction{
-	Actions: []diego_models.Action{
-		&diego_models.DownloadAction{
-			From:     "https://tiego-artifacts.s3.amazonaws.com/dropbear/dropbear.tar.gz",
-			To:       "/tmp",
-			CacheKey: "dropbear",
-		},
-		&diego_models.DownloadAction{
-			From:     "https://tiego-artifacts.s3.amazonaws.com/tea-builds/tea-latest.tgz",
-			To:       "/tmp",
-			CacheKey: "tea",
-		},
-		&diego_models.RunAction{
-			Path:      "/tmp/dropbearkey",
-			LogSource: "KEYGEN",
-			Args:      []string{"-t", "rsa", "-f", "/tmp/dropbear_rsa_host_key"},
-		},
-		&diego_models.RunAction{
-			Path:      "/tmp/dropbearkey",
-			LogSource: "KEYGEN",
-			Args:      []string{"-t", "dss", "-f", "/tmp/dropbear_dss_host_key"},
-		},
-		&diego_models.RunAction{
-			Path:      "/tmp/dropbearkey",
-			LogSource: "KEYGEN",
-			Args:      []string{"-t", "ecdsa", "-f", "/tmp/dropbear_ecdsa_host_key"},
-		},
-	},
-}
+const (
+	teaPort   = 8080
+	tiegoPort = 3000
+
+	replacingTimeout = time.Minute
+)
 
 type WorkstationManager interface {
+	Domain() string
 	Create(workstation models.Workstation) error
+	Clone(name string, clone models.Workstation) error
+	Plan(manifest models.Manifest) ([]models.Change, error)
+	ApplyChange(change models.Change, entry models.ManifestWorkstation) error
+	Replace(resized models.Workstation) error
+	ApprovePrivilege(name, approver string) error
+	CheckQuota(resized models.Workstation) error
+	CheckCapacity(resized models.Workstation) error
+	Usage(owners []string) (models.Usage, error)
 	Delete(name string) error
 	Fetch(name string) ([]receptor.ActualLRPResponse, error)
+	Get(name string) (models.Workstation, error)
 	List() ([]models.Workstation, error)
 	AddKey(name string, key []byte) error
+	ListKeys(name string) ([]models.SSHKey, error)
+	RemoveKey(name, fingerprint string) error
+	PushKeys(name string) error
+	PushKey(name string, key models.SSHKey) error
+	Start(name string) error
+	Stop(name string) error
+	Restart(name string) error
+	Reserve(name string) error
+	Release(name string)
+	Replacing(name string) bool
+	Replaced(name string)
+	Tunnel(name string, port uint16) (net.Conn, error)
+	Download(name, path string, archive bool) (io.ReadCloser, error)
+	Upload(name, path string, archive bool, content io.Reader, size int64) error
+	ClearRestoreFrom(name string) error
+
+	ListRoutes(name string) ([]models.Route, error)
+	AddRoute(name string, route models.Route) (models.Route, error)
+	RemoveRoute(name, hostname string) error
+
+	ListSchedules(name string) ([]models.Schedule, error)
+	AddSchedule(name string, schedule models.Schedule) (models.Schedule, error)
+	UpdateSchedule(name, id string, schedule models.Schedule) (models.Schedule, error)
+	DeleteSchedule(name, id string) error
 }
 
 type workstationManager struct {
@@ -58,18 +69,86 @@ type workstationManager struct {
 	logger         lager.Logger
 	teaSecret      string
 	routeProvider  models.RouteProvider
+	userManager    UserManager
+	bootstrap      models.Bootstrap
+	egressPolicies EgressPolicyManager
+	quotas         QuotaManager
+	capacity       CapacityManager
+	updateMutex    sync.Mutex
+
+	// admissionMutex is held from admitting a workstation against the quotas
+	// until it's desired or scaled, so concurrent requests can't both take the
+	// room that's left.
+	admissionMutex sync.Mutex
+
+	reservedLock sync.Mutex
+	reserved     map[string]bool
+
+	replacingLock sync.Mutex
+	replacing     map[string]time.Time
 }
 
-func NewWorkstationManager(receptorClient receptor.Client, routeProvider models.RouteProvider, teaSecret string, logger lager.Logger) WorkstationManager {
+func NewWorkstationManager(receptorClient receptor.Client, routeProvider models.RouteProvider, userManager UserManager, bootstrap models.Bootstrap, egressPolicies EgressPolicyManager, quotas QuotaManager, capacity CapacityManager, teaSecret string, logger lager.Logger) WorkstationManager {
 	return &workstationManager{
 		receptorClient: receptorClient,
 		logger:         logger,
 		teaSecret:      teaSecret,
 		routeProvider:  routeProvider,
+		userManager:    userManager,
+		bootstrap:      bootstrap,
+		egressPolicies: egressPolicies,
+		quotas:         quotas,
+		capacity:       capacity,
+		reserved:       map[string]bool{},
+		replacing:      map[string]time.Time{},
 	}
 }
 
+// Domain is the Diego domain workstations are desired in.
+func (m *workstationManager) Domain() string {
+	return m.bootstrap.Domain
+}
+
 func (m *workstationManager) Create(workstation models.Workstation) error {
+	return m.create(workstation, workstationAnnotation{
+		Owner:       workstation.Owner,
+		Secrets:     workstation.Secrets,
+		Setup:       workstation.Setup,
+		Template:    workstation.Template,
+		RestoreFrom: workstation.RestoreFrom,
+	})
+}
+
+// Clone creates the workstation clone with the image, resources, ports, env,
+// setup scripts, egress policy and keys of the workstation name. Secrets and
+// the privilege mode are left to the caller, since they depend on who clones.
+func (m *workstationManager) Clone(name string, clone models.Workstation) error {
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return err
+	}
+
+	source := workstationFromDesiredLRP(desiredLRP, models.StoppedState)
+	clone.DockerImage = source.DockerImage
+	clone.CPUWeight = source.CPUWeight
+	clone.DiskMB = source.DiskMB
+	clone.MemoryMB = source.MemoryMB
+	clone.Ports = source.Ports
+	clone.Env = source.Env
+	clone.Setup = source.Setup
+	clone.Template = source.Template
+
+	return m.create(clone, workstationAnnotation{
+		Owner:       clone.Owner,
+		Secrets:     clone.Secrets,
+		Setup:       clone.Setup,
+		Template:    clone.Template,
+		RestoreFrom: clone.RestoreFrom,
+		Keys:        parseAnnotation(desiredLRP).Keys,
+	})
+}
+
+func (m *workstationManager) create(workstation models.Workstation, annotation workstationAnnotation) error {
 	log := m.logger.Session("workstation-manager-create", lager.Data{"workstation": workstation})
 
 	if err := workstation.Validate(); err != nil {
@@ -84,63 +163,80 @@ func (m *workstationManager) Create(workstation models.Workstation) error {
 	tiegoRoute := m.routeProvider.TiegoRoute(workstation.Name)
 	sshRoute := m.routeProvider.SSHRoute(workstation.Name)
 	routingInfo := cfroutes.CFRoutes{
-		{Hostnames: []string{tiegoRoute}, Port: 3000},
-		{Hostnames: []string{sshRoute}, Port: 8080},
+		{Hostnames: []string{tiegoRoute}, Port: tiegoPort},
+		{Hostnames: []string{sshRoute}, Port: teaPort},
 	}.RoutingInfo()
 
-	openRules := []diego_models.SecurityGroupRule{
-		{
-			Protocol:     diego_models.AllProtocol,
-			Destinations: []string{"0.0.0.0/0"},
-		},
-	}
+	ports := workstationPorts(workstation.Ports)
 
 	if err != nil {
 		log.Debug("marshalling-route-json-failed", lager.Data{"error": err})
 	}
 
-	mainAction := &diego_models.ParallelAction{
-		Actions: []diego_models.Action{
-			&diego_models.RunAction{
-				Path:      "/bin/bash",
-				LogSource: "SSHD",
-				Args: []string{
-					"-c",
-					`set -e && /tmp/dropbear -p 127.0.0.1:22000 -r /tmp/dropbear_rsa_host_key -r /tmp/dropbear_dss_host_key -r /tmp/dropbear_ecdsa_host_key`,
-				},
-				Privileged: false,
-			},
-			&diego_models.RunAction{
-				Path: "/tmp/tea",
-				Args: []string{
-					"-secret", m.teaSecret,
-				},
-				LogSource:  "TEA",
-				Privileged: false,
-			},
-		},
+	policy, err := m.egressPolicies.Get(workstation.EgressPolicy)
+	if _, ok := err.(models.ErrNotFound); ok {
+		return models.ValidationError{models.ErrInvalidField{"egress_policy"}}
+	}
+	if err != nil {
+		return err
+	}
+	annotation.EgressPolicy = policy.Name
+	annotation.Privilege = workstation.Privilege
+	annotation.PrivilegeApprovedBy = workstation.PrivilegeApprovedBy
+	annotationJSON, err := encodeAnnotation(workstation.Name, annotation)
+	if err != nil {
+		return err
+	}
+
+	// Workstations pending approval aren't run until they're approved.
+	instances := 1
+	if workstation.Privilege == models.PendingApprovalMode {
+		instances = 0
+	}
+
+	privileged := workstation.Privilege == models.PrivilegedMode
+
+	m.admissionMutex.Lock()
+	defer m.admissionMutex.Unlock()
+
+	err = m.admit(workstation.Owner, "", workstationUsage(workstation.MemoryMB, workstation.DiskMB, privileged, instances > 0))
+	if err != nil {
+		return err
+	}
+
+	bootstrap := m.bootstrap.For(workstation.Template)
+	if instances > 0 {
+		err = m.capacity.Place(bootstrap.Stack, workstation.MemoryMB, workstation.DiskMB)
+		if err != nil {
+			return err
+		}
 	}
 
 	lrpRequest := receptor.DesiredLRPCreateRequest{
 		ProcessGuid: workstation.Name,
-		Setup:       setupAction,
-		Domain:      "tiego",
-		Instances:   1,
-		Stack:       "lucid64",
+		Setup:       setupAction(bootstrap, workstation.Setup),
+		Domain:      bootstrap.Domain,
+		Instances:   instances,
+		Stack:       bootstrap.Stack,
 		RootFSPath:  workstation.DockerImage,
 		CPUWeight:   workstation.CPUWeight,
 		DiskMB:      workstation.DiskMB,
 		MemoryMB:    workstation.MemoryMB,
 		LogGuid:     workstation.Name,
 		LogSource:   "TEAPOT-WORKSTATION",
-		Ports:       []uint16{8080, 3000},
+		Ports:       ports,
 		Routes:      routingInfo,
-		Privileged:  true,
-		Action:      mainAction,
-		EgressRules: openRules,
+		Privileged:  privileged,
+		Action:      m.workstationAction(workstation.SecretEnv),
+		EgressRules: policy.SecurityGroupRules(),
+		Annotation:  annotationJSON,
+
+		EnvironmentVariables: environmentVariables(workstation.Env),
 	}
 
-	log.Debug("requesting-lrp", lager.Data{"lrp_request": lrpRequest})
+	loggedRequest := lrpRequest
+	loggedRequest.Action = m.workstationAction(redacted(workstation.SecretEnv))
+	log.Debug("requesting-lrp", lager.Data{"lrp_request": loggedRequest})
 	err = m.receptorClient.CreateDesiredLRP(lrpRequest)
 	if err != nil {
 		log.Debug("request-failed", lager.Data{"error": err})
@@ -151,49 +247,490 @@ func (m *workstationManager) Create(workstation models.Workstation) error {
 	return err
 }
 
+// Replace deletes the workstation and desires it again with the image,
+// resources, ports and env of resized, keeping its routes, setup and
+// annotation.
+// The old workstation is put back when the new one cannot be desired, and it
+// is left alone when resized no longer fits in the owner's quotas.
+func (m *workstationManager) Replace(resized models.Workstation) error {
+	log := m.logger.Session("workstation-manager-replace", lager.Data{"workstation": resized})
+
+	if err := resized.Validate(); err != nil {
+		return err
+	}
+
+	m.updateMutex.Lock()
+	defer m.updateMutex.Unlock()
+	m.admissionMutex.Lock()
+	defer m.admissionMutex.Unlock()
+
+	desiredLRP, err := m.fetchDesiredLRP(resized.Name)
+	if err != nil {
+		return err
+	}
+
+	// The quotas were checked when the replacement was asked for, but other
+	// workstations may have taken the room since.
+	err = m.admit(resized.Owner, resized.Name, workstationUsage(resized.MemoryMB, resized.DiskMB, desiredLRP.Privileged, desiredLRP.Instances > 0))
+	if err != nil {
+		return err
+	}
+
+	annotation := parseAnnotation(desiredLRP)
+	annotation.RestoreFrom = resized.RestoreFrom
+	annotationJSON, err := encodeAnnotation(resized.Name, annotation)
+	if err != nil {
+		return err
+	}
+
+	original := desiredLRPCreateRequest(desiredLRP)
+	replacement := original
+	replacement.RootFSPath = resized.DockerImage
+	replacement.CPUWeight = resized.CPUWeight
+	replacement.DiskMB = resized.DiskMB
+	replacement.MemoryMB = resized.MemoryMB
+	replacement.Ports = workstationPorts(resized.Ports)
+	replacement.EnvironmentVariables = environmentVariables(resized.Env)
+	replacement.Annotation = annotationJSON
+
+	err = m.redesire(log, original, replacement)
+	if err != nil {
+		return err
+	}
+
+	log.Info("replaced")
+	return nil
+}
+
+// CheckCapacity checks that a cell can fit the workstation once resized,
+// unless it is STOPPED, before it is torn down to be replaced.
+func (m *workstationManager) CheckCapacity(resized models.Workstation) error {
+	if resized.State == models.StoppedState {
+		return nil
+	}
+
+	return m.capacity.Place(m.bootstrap.For(resized.Template).Stack, resized.MemoryMB, resized.DiskMB)
+}
+
+// ApprovePrivilege lets a workstation pending approval run privileged,
+// replacing it with a privileged one. Since it never ran, nothing is lost.
+func (m *workstationManager) ApprovePrivilege(name, approver string) error {
+	log := m.logger.Session("workstation-manager-approve-privilege", lager.Data{"name": name, "approver": approver})
+
+	m.updateMutex.Lock()
+	defer m.updateMutex.Unlock()
+	m.admissionMutex.Lock()
+	defer m.admissionMutex.Unlock()
+
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return err
+	}
+
+	annotation := parseAnnotation(desiredLRP)
+	if annotation.Privilege != models.PendingApprovalMode {
+		return models.ValidationError{models.ErrInvalidModification{"privilege"}}
+	}
+
+	err = m.admit(annotation.Owner, name, workstationUsage(desiredLRP.MemoryMB, desiredLRP.DiskMB, true, true))
+	if err != nil {
+		return err
+	}
+
+	err = m.capacity.Place(desiredLRP.Stack, desiredLRP.MemoryMB, desiredLRP.DiskMB)
+	if err != nil {
+		return err
+	}
+	annotation.Privilege = models.PrivilegedMode
+	annotation.PrivilegeApprovedBy = approver
+	annotationJSON, err := encodeAnnotation(name, annotation)
+	if err != nil {
+		return err
+	}
+
+	original := desiredLRPCreateRequest(desiredLRP)
+	approved := original
+	approved.Privileged = true
+	approved.Instances = 1
+	approved.Annotation = annotationJSON
+
+	err = m.redesire(log, original, approved)
+	if err != nil {
+		return err
+	}
+
+	log.Info("approved")
+	return nil
+}
+
+// redesire deletes the workstation's DesiredLRP and desires replacement in
+// its place, desiring the original again if that fails. The workstation is
+// marked as being replaced until Diego reports it desired again, so the
+// removal and creation aren't mistaken for the workstation's.
+func (m *workstationManager) redesire(log lager.Logger, original, replacement receptor.DesiredLRPCreateRequest) error {
+	m.replacingLock.Lock()
+	m.replacing[replacement.ProcessGuid] = time.Now()
+	m.replacingLock.Unlock()
+
+	err := m.receptorClient.DeleteDesiredLRP(replacement.ProcessGuid)
+	if err != nil {
+		log.Error("delete-failed", err)
+		m.Replaced(replacement.ProcessGuid)
+		return err
+	}
+
+	err = m.receptorClient.CreateDesiredLRP(replacement)
+	if err != nil {
+		log.Error("create-failed", err)
+		if restoreErr := m.receptorClient.CreateDesiredLRP(original); restoreErr != nil {
+			log.Error("restore-original-failed", restoreErr)
+			m.Replaced(replacement.ProcessGuid)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Replacing tells whether the workstation's DesiredLRP was deleted to be
+// desired again, and Diego is yet to report it desired. Workstations whose
+// creation isn't reported within replacingTimeout no longer count as being
+// replaced.
+func (m *workstationManager) Replacing(name string) bool {
+	m.replacingLock.Lock()
+	defer m.replacingLock.Unlock()
+
+	since, ok := m.replacing[name]
+	if ok && time.Since(since) > replacingTimeout {
+		delete(m.replacing, name)
+		return false
+	}
+	return ok
+}
+
+// Replaced marks the workstation's replacement as reported by Diego.
+func (m *workstationManager) Replaced(name string) {
+	m.replacingLock.Lock()
+	defer m.replacingLock.Unlock()
+
+	delete(m.replacing, name)
+}
+
 func (m *workstationManager) Delete(name string) error {
 	return m.receptorClient.DeleteDesiredLRP(name)
 }
 
-func (m *workstationManager) Fetch(name string) ([]receptor.ActualLRPResponse, error) {
-	return m.receptorClient.ActualLRPsByProcessGuid(name)
+// Start fails for workstations pending approval, which must not run before
+// they are approved, for reserved ones, and when starting goes over the
+// owner's quotas or the workstation can't be placed.
+func (m *workstationManager) Start(name string) error {
+	if m.isReserved(name) {
+		return models.ErrOperationInProgress{name}
+	}
+
+	m.admissionMutex.Lock()
+	defer m.admissionMutex.Unlock()
+
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return err
+	}
+
+	annotation := parseAnnotation(desiredLRP)
+	if annotation.Privilege == models.PendingApprovalMode {
+		return models.ErrPendingApproval{name}
+	}
+
+	if desiredLRP.Instances == 0 {
+		err = m.admit(annotation.Owner, name, workstationUsage(desiredLRP.MemoryMB, desiredLRP.DiskMB, desiredLRP.Privileged, true))
+		if err != nil {
+			return err
+		}
+
+		err = m.capacity.Place(desiredLRP.Stack, desiredLRP.MemoryMB, desiredLRP.DiskMB)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.scale(name, 1)
 }
 
-func (m *workstationManager) AddKey(name string, key []byte) error {
-	route := m.routeProvider.SSHRoute(name)
-	if !strings.HasPrefix(route, "http://") && !strings.HasPrefix(route, "https://") {
-		route = "http://" + route
+func (m *workstationManager) Stop(name string) error {
+	if m.isReserved(name) {
+		return models.ErrOperationInProgress{name}
 	}
-	u, _ := url.Parse(route)
-	u.Path = path.Join("add-key", m.teaSecret)
 
-	body := bytes.NewReader(key)
-	_, err := http.Post(u.String(), "text/plain", body)
+	return m.scale(name, 0)
+}
+
+// Restart kills the workstation's instance so Diego starts a new one, which
+// is also the way out of CRASHED without waiting for Diego's backoff.
+func (m *workstationManager) Restart(name string) error {
+	if m.isReserved(name) {
+		return models.ErrOperationInProgress{name}
+	}
+
+	actualLRPs, err := m.receptorClient.ActualLRPsByProcessGuid(name)
 	if err != nil {
 		return err
 	}
+	if len(actualLRPs) == 0 {
+		if _, err := m.fetchDesiredLRP(name); err != nil {
+			return err
+		}
+		return models.ErrNotRunning{name}
+	}
+
+	return m.receptorClient.KillActualLRPByProcessGuidAndIndex(name, actualLRPs[0].Index)
+}
 
+// Reserve keeps the workstation from being started, stopped, restarted or
+// reserved again until it is released, while an operation replaces it.
+func (m *workstationManager) Reserve(name string) error {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+
+	if m.reserved[name] {
+		return models.ErrOperationInProgress{name}
+	}
+	m.reserved[name] = true
 	return nil
 }
 
+func (m *workstationManager) Release(name string) {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+
+	delete(m.reserved, name)
+}
+
+func (m *workstationManager) isReserved(name string) bool {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+
+	return m.reserved[name]
+}
+
+func (m *workstationManager) scale(name string, instances int) error {
+	if _, err := m.fetchDesiredLRP(name); err != nil {
+		return err
+	}
+
+	return m.receptorClient.UpdateDesiredLRP(name, receptor.DesiredLRPUpdateRequest{
+		Instances: &instances,
+	})
+}
+
+// ClearRestoreFrom marks the workstation's pending snapshot as restored.
+func (m *workstationManager) ClearRestoreFrom(name string) error {
+	return m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		annotation.RestoreFrom = ""
+		return nil
+	})
+}
+
+func (m *workstationManager) Fetch(name string) ([]receptor.ActualLRPResponse, error) {
+	return m.receptorClient.ActualLRPsByProcessGuid(name)
+}
+
+func (m *workstationManager) Get(name string) (models.Workstation, error) {
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return models.Workstation{}, err
+	}
+
+	state := models.StoppedState
+	placementError := ""
+	actualLRPs, _ := m.receptorClient.ActualLRPsByProcessGuid(name)
+	if len(actualLRPs) > 0 {
+		state = fmt.Sprintf("%v", actualLRPs[0].State)
+		placementError = actualLRPs[0].PlacementError
+	}
+
+	workstation := workstationFromDesiredLRP(desiredLRP, state)
+	workstation.PlacementError = placementError
+	return workstation, nil
+}
+
 func (m *workstationManager) List() ([]models.Workstation, error) {
 	workstations := []models.Workstation{}
 
-	desiredLRPs, _ := m.receptorClient.DesiredLRPsByDomain("tiego")
-	actualLRPs, _ := m.receptorClient.ActualLRPsByDomain("tiego")
+	desiredLRPs, _ := m.receptorClient.DesiredLRPsByDomain(m.bootstrap.Domain)
+	actualLRPs, _ := m.receptorClient.ActualLRPsByDomain(m.bootstrap.Domain)
 
 	for _, desiredLRP := range desiredLRPs {
 		state := models.StoppedState
+		placementError := ""
 		if i := contains(actualLRPs, desiredLRP.ProcessGuid); i >= 0 {
 			state = fmt.Sprintf("%v", actualLRPs[i].State)
+			placementError = actualLRPs[i].PlacementError
 		}
-		workstation := models.Workstation{Name: desiredLRP.ProcessGuid, DockerImage: desiredLRP.RootFSPath, State: state}
+		workstation := workstationFromDesiredLRP(desiredLRP, state)
+		workstation.PlacementError = placementError
 		workstations = append(workstations, workstation)
 	}
 
 	return workstations, nil
 }
 
+func workstationFromDesiredLRP(desiredLRP receptor.DesiredLRPResponse, state string) models.Workstation {
+	annotation := parseAnnotation(desiredLRP)
+
+	var ports []uint16
+	for _, port := range desiredLRP.Ports {
+		if port != teaPort && port != tiegoPort {
+			ports = append(ports, port)
+		}
+	}
+
+	return models.Workstation{
+		Name:        desiredLRP.ProcessGuid,
+		DockerImage: desiredLRP.RootFSPath,
+		State:       state,
+		CPUWeight:   desiredLRP.CPUWeight,
+		DiskMB:      desiredLRP.DiskMB,
+		MemoryMB:    desiredLRP.MemoryMB,
+		Owner:       annotation.Owner,
+		Ports:       ports,
+		Schedules:   annotation.Schedules,
+		Env:         workstationEnv(desiredLRP.EnvironmentVariables),
+		Secrets:     annotation.Secrets,
+		Setup:       annotation.Setup,
+		Template:    annotation.Template,
+		Manifest:    annotation.Manifest,
+		RestoreFrom: annotation.RestoreFrom,
+
+		EgressPolicy: annotation.EgressPolicy,
+		EgressRules:  models.EgressRules(desiredLRP.EgressRules),
+
+		Privilege:           privilegeMode(desiredLRP, annotation),
+		PrivilegeApprovedBy: annotation.PrivilegeApprovedBy,
+	}
+}
+
+// privilegeMode is the recorded mode of the workstation, or the one it runs
+// in for workstations created before modes were recorded.
+func privilegeMode(desiredLRP receptor.DesiredLRPResponse, annotation workstationAnnotation) string {
+	if annotation.Privilege != "" {
+		return annotation.Privilege
+	}
+	if desiredLRP.Privileged {
+		return models.PrivilegedMode
+	}
+	return models.UnprivilegedMode
+}
+
+func desiredLRPCreateRequest(desiredLRP receptor.DesiredLRPResponse) receptor.DesiredLRPCreateRequest {
+	return receptor.DesiredLRPCreateRequest{
+		ProcessGuid:          desiredLRP.ProcessGuid,
+		Domain:               desiredLRP.Domain,
+		RootFSPath:           desiredLRP.RootFSPath,
+		Instances:            desiredLRP.Instances,
+		Stack:                desiredLRP.Stack,
+		EnvironmentVariables: desiredLRP.EnvironmentVariables,
+		Setup:                desiredLRP.Setup,
+		Action:               desiredLRP.Action,
+		Monitor:              desiredLRP.Monitor,
+		StartTimeout:         desiredLRP.StartTimeout,
+		DiskMB:               desiredLRP.DiskMB,
+		MemoryMB:             desiredLRP.MemoryMB,
+		CPUWeight:            desiredLRP.CPUWeight,
+		Privileged:           desiredLRP.Privileged,
+		Ports:                desiredLRP.Ports,
+		Routes:               desiredLRP.Routes,
+		LogGuid:              desiredLRP.LogGuid,
+		LogSource:            desiredLRP.LogSource,
+		MetricsGuid:          desiredLRP.MetricsGuid,
+		Annotation:           desiredLRP.Annotation,
+		EgressRules:          desiredLRP.EgressRules,
+	}
+}
+
+// workstationAction runs sshd and the TEA with the workstation's secrets in
+// their environment, so they reach shells and commands but aren't part of the
+// DesiredLRP's env, which is what workstations are described from.
+func (m *workstationManager) workstationAction(secretEnv map[string]string) diego_models.Action {
+	var env []diego_models.EnvironmentVariable
+	for _, variable := range environmentVariables(secretEnv) {
+		env = append(env, diego_models.EnvironmentVariable{Name: variable.Name, Value: variable.Value})
+	}
+
+	return &diego_models.ParallelAction{
+		Actions: []diego_models.Action{
+			&diego_models.RunAction{
+				Path:      "/bin/bash",
+				LogSource: "SSHD",
+				Args: []string{
+					"-c",
+					`set -e && /tmp/dropbear -p 127.0.0.1:22000 -r /tmp/dropbear_rsa_host_key -r /tmp/dropbear_dss_host_key -r /tmp/dropbear_ecdsa_host_key`,
+				},
+				Env:        env,
+				Privileged: false,
+			},
+			&diego_models.RunAction{
+				Path: "/tmp/tea",
+				Args: []string{
+					"-secret", m.teaSecret,
+				},
+				Env:        env,
+				LogSource:  "TEA",
+				Privileged: false,
+			},
+		},
+	}
+}
+
+func redacted(secretEnv map[string]string) map[string]string {
+	if len(secretEnv) == 0 {
+		return nil
+	}
+
+	env := map[string]string{}
+	for name := range secretEnv {
+		env[name] = "[REDACTED]"
+	}
+	return env
+}
+
+// workstationPorts are the ports a workstation exposes, the TEA's and
+// tiego's along with its own.
+func workstationPorts(own []uint16) []uint16 {
+	ports := []uint16{teaPort, tiegoPort}
+	for _, port := range own {
+		if !containsPort(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+func environmentVariables(env map[string]string) []receptor.EnvironmentVariable {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var variables []receptor.EnvironmentVariable
+	for _, name := range names {
+		variables = append(variables, receptor.EnvironmentVariable{Name: name, Value: env[name]})
+	}
+	return variables
+}
+
+func workstationEnv(variables []receptor.EnvironmentVariable) map[string]string {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	env := map[string]string{}
+	for _, variable := range variables {
+		env[variable.Name] = variable.Value
+	}
+	return env
+}
+
 func contains(s []receptor.ActualLRPResponse, e string) int {
 	for i, a := range s {
 		if a.ProcessGuid == e {
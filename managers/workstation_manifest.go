@@ -0,0 +1,354 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
+	"github.com/luan/teapot/models"
+	"github.com/nu7hatch/gouuid"
+	"github.com/pivotal-golang/lager"
+)
+
+// Plan lists the changes needed for the workstations labelled with the
+// manifest's name to match it. Workstations in the manifest that exist
+// without the label are left alone and fail the plan.
+func (m *workstationManager) Plan(manifest models.Manifest) ([]models.Change, error) {
+	manifest = m.generateHostnames(manifest)
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+
+	desiredLRPs, err := m.receptorClient.DesiredLRPsByDomain(m.bootstrap.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]receptor.DesiredLRPResponse{}
+	for _, desiredLRP := range desiredLRPs {
+		existing[desiredLRP.ProcessGuid] = desiredLRP
+	}
+
+	changes := []models.Change{}
+	declared := map[string]bool{}
+	for _, entry := range manifest.Workstations {
+		declared[entry.Name] = true
+
+		for _, route := range entry.Routes {
+			if route.Port != teaPort && route.Port != tiegoPort && !containsPort(entry.Ports, route.Port) {
+				return nil, models.ValidationError{models.ErrInvalidField{"routes"}}
+			}
+			if route.ValidateDomain(m.routeProvider.AppsDomain()) != nil {
+				return nil, models.ValidationError{models.ErrInvalidField{"routes"}}
+			}
+		}
+
+		desiredLRP, ok := existing[entry.Name]
+		if !ok {
+			changes = append(changes, models.Change{Workstation: entry.Name, Action: models.ChangeCreate})
+			continue
+		}
+
+		if parseAnnotation(desiredLRP).Manifest != manifest.Name {
+			return nil, models.ValidationError{models.ErrUnmanaged{entry.Name, manifest.Name}}
+		}
+
+		change, err := m.diff(entry, desiredLRP)
+		if err != nil {
+			return nil, err
+		}
+		if len(change.Fields) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	for _, desiredLRP := range desiredLRPs {
+		if !declared[desiredLRP.ProcessGuid] && parseAnnotation(desiredLRP).Manifest == manifest.Name {
+			changes = append(changes, models.Change{Workstation: desiredLRP.ProcessGuid, Action: models.ChangeDelete})
+		}
+	}
+
+	return changes, nil
+}
+
+// ApplyChange makes a change of a manifest's plan, entry being the
+// workstation it declares. Replacements only get what can be updated in
+// place, their image, resources, ports and env must be replaced with Replace.
+func (m *workstationManager) ApplyChange(change models.Change, entry models.ManifestWorkstation) error {
+	log := m.logger.Session("apply-change", lager.Data{"workstation_name": change.Workstation, "action": change.Action})
+	entry = m.generateEntryHostnames(entry)
+
+	var err error
+	switch change.Action {
+	case models.ChangeCreate:
+		err = m.createFromManifest(entry)
+	case models.ChangeUpdate, models.ChangeReplace:
+		err = m.updateFromManifest(entry)
+	case models.ChangeDelete:
+		err = m.Delete(change.Workstation)
+	}
+
+	if err != nil {
+		log.Error("failed", err)
+		return err
+	}
+	log.Info("changed")
+	return nil
+}
+
+// generateHostnames fills in the hostnames left out of the manifest's routes,
+// the way AddRoute does.
+func (m *workstationManager) generateHostnames(manifest models.Manifest) models.Manifest {
+	entries := make([]models.ManifestWorkstation, len(manifest.Workstations))
+	for i, entry := range manifest.Workstations {
+		entries[i] = m.generateEntryHostnames(entry)
+	}
+
+	manifest.Workstations = entries
+	return manifest
+}
+
+func (m *workstationManager) generateEntryHostnames(entry models.ManifestWorkstation) models.ManifestWorkstation {
+	routes := make([]models.Route, len(entry.Routes))
+	for i, route := range entry.Routes {
+		if route.Hostname == "" {
+			route.Hostname = m.routeProvider.PortRoute(entry.Name, route.Port)
+		}
+		routes[i] = route
+	}
+	entry.Routes = routes
+	return entry
+}
+
+// diff compares a manifest entry with its workstation. Fields the receptor
+// cannot update in place make it a replacement.
+func (m *workstationManager) diff(entry models.ManifestWorkstation, desiredLRP receptor.DesiredLRPResponse) (models.Change, error) {
+	current := workstationFromDesiredLRP(desiredLRP, models.StoppedState)
+	annotation := parseAnnotation(desiredLRP)
+	change := models.Change{Workstation: entry.Name, Action: models.ChangeUpdate}
+
+	compare := func(field, from, to string) {
+		if from != to {
+			change.Fields = append(change.Fields, models.FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	compare("docker_image", current.DockerImage, entry.DockerImage)
+	compare("cpu_weight", fmt.Sprint(current.CPUWeight), fmt.Sprint(entry.CPUWeight))
+	compare("disk_mb", fmt.Sprint(current.DiskMB), fmt.Sprint(entry.DiskMB))
+	compare("memory_mb", fmt.Sprint(current.MemoryMB), fmt.Sprint(entry.MemoryMB))
+	compare("ports", describePorts(current.Ports), describePorts(entry.Ports))
+	compare("env", describeEnv(current.Env), describeEnv(entry.Env))
+	if len(change.Fields) > 0 {
+		change.Action = models.ChangeReplace
+	}
+
+	routes, err := m.extraRoutes(desiredLRP)
+	if err != nil {
+		return change, err
+	}
+
+	compare("owner", current.Owner, entry.Owner)
+	compare("keys", describeKeys(annotation.Keys), describeKeys(manifestKeys(entry)))
+	compare("routes", describeRoutes(routes), describeRoutes(entry.Routes))
+	compare("schedules", describeSchedules(current.Schedules), describeSchedules(entry.Schedules))
+
+	return change, nil
+}
+
+func (m *workstationManager) createFromManifest(entry models.ManifestWorkstation) error {
+	err := m.create(entry.Workstation, workstationAnnotation{
+		Owner:     entry.Owner,
+		Manifest:  entry.Manifest,
+		Keys:      manifestKeys(entry),
+		Schedules: withScheduleIDs(entry.Schedules, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, route := range entry.Routes {
+		if _, err := m.AddRoute(entry.Name, route); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *workstationManager) updateFromManifest(entry models.ManifestWorkstation) error {
+	keys := manifestKeys(entry)
+	var added, removed []models.SSHKey
+
+	err := m.updateAnnotation(entry.Name, func(annotation *workstationAnnotation) error {
+		added = keysMissingFrom(keys, annotation.Keys)
+		removed = keysMissingFrom(annotation.Keys, keys)
+
+		annotation.Owner = entry.Owner
+		annotation.Keys = keys
+		annotation.Schedules = withScheduleIDs(entry.Schedules, annotation.Schedules)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if m.running(entry.Name) {
+		for _, key := range added {
+			if err := m.teaKeyRequest(entry.Name, "add-key", key); err != nil {
+				return err
+			}
+		}
+		for _, key := range removed {
+			if err := m.teaKeyRequest(entry.Name, "remove-key", key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.updateRoutes(entry.Name, func(desiredLRP receptor.DesiredLRPResponse, _ cfroutes.CFRoutes) (cfroutes.CFRoutes, error) {
+		cfRoutes := cfroutes.CFRoutes{
+			{Hostnames: []string{m.routeProvider.TiegoRoute(entry.Name)}, Port: tiegoPort},
+			{Hostnames: []string{m.routeProvider.SSHRoute(entry.Name)}, Port: teaPort},
+		}
+
+		for _, route := range entry.Routes {
+			if !containsPort(desiredLRP.Ports, route.Port) {
+				return nil, models.ValidationError{models.ErrInvalidField{"routes"}}
+			}
+
+			if err := m.checkHostnameFree(entry.Name, route.Hostname); err != nil {
+				return nil, err
+			}
+
+			added := false
+			for i, cfRoute := range cfRoutes {
+				if cfRoute.Port == route.Port {
+					cfRoutes[i].Hostnames = append(cfRoute.Hostnames, route.Hostname)
+					added = true
+					break
+				}
+			}
+			if !added {
+				cfRoutes = append(cfRoutes, cfroutes.CFRoute{Hostnames: []string{route.Hostname}, Port: route.Port})
+			}
+		}
+
+		return cfRoutes, nil
+	})
+}
+
+func manifestKeys(entry models.ManifestWorkstation) []models.SSHKey {
+	var keys []models.SSHKey
+	for _, line := range entry.Keys {
+		key, err := models.ParseSSHKey(line)
+		if err == nil && len(keysMissingFrom([]models.SSHKey{key}, keys)) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func keysMissingFrom(keys, from []models.SSHKey) []models.SSHKey {
+	var missing []models.SSHKey
+	for _, key := range keys {
+		found := false
+		for _, other := range from {
+			if other.Fingerprint == key.Fingerprint {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// withScheduleIDs keeps the IDs of the schedules that did not change, so only
+// new schedules get new ones.
+func withScheduleIDs(schedules, existing []models.Schedule) []models.Schedule {
+	used := map[string]bool{}
+	var result []models.Schedule
+
+	for _, schedule := range schedules {
+		for _, other := range existing {
+			if !used[other.ID] && describeSchedule(other) == describeSchedule(schedule) {
+				schedule.ID = other.ID
+				break
+			}
+		}
+
+		if schedule.ID == "" {
+			guid, err := uuid.NewV4()
+			if err == nil {
+				schedule.ID = guid.String()
+			}
+		}
+
+		used[schedule.ID] = true
+		result = append(result, schedule)
+	}
+
+	return result
+}
+
+func describePorts(ports []uint16) string {
+	var described []string
+	for _, port := range ports {
+		described = append(described, fmt.Sprint(port))
+	}
+	return describeSorted(described)
+}
+
+func describeEnv(env map[string]string) string {
+	var described []string
+	for name, value := range env {
+		described = append(described, name+"="+value)
+	}
+	return describeSorted(described)
+}
+
+func describeKeys(keys []models.SSHKey) string {
+	var described []string
+	for _, key := range keys {
+		described = append(described, key.Fingerprint)
+	}
+	return describeSorted(described)
+}
+
+func describeRoutes(routes []models.Route) string {
+	var described []string
+	for _, route := range routes {
+		described = append(described, fmt.Sprintf("%s:%d", route.Hostname, route.Port))
+	}
+	return describeSorted(described)
+}
+
+func describeSchedules(schedules []models.Schedule) string {
+	var described []string
+	for _, schedule := range schedules {
+		described = append(described, describeSchedule(schedule))
+	}
+	return describeSorted(described)
+}
+
+func describeSchedule(schedule models.Schedule) string {
+	described := schedule.Cron + " " + schedule.Action
+	if schedule.Command != "" {
+		described += " " + schedule.Command
+	}
+	if schedule.Location != "" {
+		described += " (" + schedule.Location + ")"
+	}
+	return described
+}
+
+func describeSorted(described []string) string {
+	sort.Strings(described)
+	return strings.Join(described, ", ")
+}
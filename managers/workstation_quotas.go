@@ -0,0 +1,128 @@
+package managers
+
+import (
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/models"
+)
+
+// Usage sums up the workstations owned by any of owners.
+func (m *workstationManager) Usage(owners []string) (models.Usage, error) {
+	desiredLRPs, err := m.receptorClient.DesiredLRPsByDomain(m.bootstrap.Domain)
+	if err != nil {
+		return models.Usage{}, err
+	}
+
+	return usageOf(desiredLRPs, owners, ""), nil
+}
+
+// CheckQuota checks that the owner of the workstation and their teams have
+// room for it to be resized, as it is started or not.
+func (m *workstationManager) CheckQuota(resized models.Workstation) error {
+	if len(m.quotaScopes(resized.Owner)) == 0 {
+		return nil
+	}
+
+	desiredLRP, err := m.fetchDesiredLRP(resized.Name)
+	if err != nil {
+		return err
+	}
+
+	return m.admit(resized.Owner, resized.Name, workstationUsage(resized.MemoryMB, resized.DiskMB, desiredLRP.Privileged, desiredLRP.Instances > 0))
+}
+
+type quotaScope struct {
+	kind, name string
+	members    []string
+	quota      models.Quota
+}
+
+// quotaScopes lists the quotas owner's workstations count against, leaving
+// out those without limits.
+func (m *workstationManager) quotaScopes(owner string) []quotaScope {
+	var scopes []quotaScope
+	if quota := m.quotas.UserQuota(owner); quota != (models.Quota{}) {
+		scopes = append(scopes, quotaScope{"user", owner, []string{owner}, quota})
+	}
+	for _, team := range m.quotas.Teams(owner) {
+		if team.Quota != (models.Quota{}) {
+			scopes = append(scopes, quotaScope{"team", team.Name, team.Members, team.Quota})
+		}
+	}
+	return scopes
+}
+
+// admit fails with ErrQuotaExceeded when a workstation of owner using usage,
+// in place of the workstation name if it exists, takes the owner or one of
+// their teams over quota.
+func (m *workstationManager) admit(owner, name string, usage models.Usage) error {
+	scopes := m.quotaScopes(owner)
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	desiredLRPs, err := m.receptorClient.DesiredLRPsByDomain(m.bootstrap.Domain)
+	if err != nil {
+		return err
+	}
+
+	// Only what grows is held against the quotas.
+	added := usage
+	for _, desiredLRP := range desiredLRPs {
+		if desiredLRP.ProcessGuid == name {
+			added = usage.Sub(desiredUsage(desiredLRP))
+		}
+	}
+
+	for _, scope := range scopes {
+		total := usageOf(desiredLRPs, scope.members, name).Add(usage)
+		if limits := scope.quota.Exceeded(total, added); len(limits) > 0 {
+			return models.ErrQuotaExceeded{
+				Scope:  scope.kind,
+				Name:   scope.name,
+				Limits: limits,
+				Quota:  scope.quota,
+				Usage:  total,
+			}
+		}
+	}
+
+	return nil
+}
+
+// usageOf sums up the workstations owned by any of owners, leaving out the
+// workstation named except.
+func usageOf(desiredLRPs []receptor.DesiredLRPResponse, owners []string, except string) models.Usage {
+	usage := models.Usage{}
+	for _, desiredLRP := range desiredLRPs {
+		if desiredLRP.ProcessGuid == except || !containsString(owners, parseAnnotation(desiredLRP).Owner) {
+			continue
+		}
+		usage = usage.Add(desiredUsage(desiredLRP))
+	}
+	return usage
+}
+
+func desiredUsage(desiredLRP receptor.DesiredLRPResponse) models.Usage {
+	return workstationUsage(desiredLRP.MemoryMB, desiredLRP.DiskMB, desiredLRP.Privileged, desiredLRP.Instances > 0)
+}
+
+func workstationUsage(memoryMB, diskMB int, privileged, started bool) models.Usage {
+	usage := models.Usage{Workstations: 1}
+	if started {
+		usage.MemoryMB = memoryMB
+		usage.DiskMB = diskMB
+		if privileged {
+			usage.Privileged = 1
+		}
+	}
+	return usage
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package managers
+
+import (
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/route-emitter/cfroutes"
+	"github.com/luan/teapot/models"
+)
+
+// ListRoutes returns the routes added to the workstation, leaving out the
+// tiego and TEA routes every workstation has.
+func (m *workstationManager) ListRoutes(name string) ([]models.Route, error) {
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.extraRoutes(desiredLRP)
+}
+
+func (m *workstationManager) extraRoutes(desiredLRP receptor.DesiredLRPResponse) ([]models.Route, error) {
+	cfRoutes, err := cfroutes.CFRoutesFromRoutingInfo(desiredLRP.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	builtIn := m.builtInHostnames(desiredLRP.ProcessGuid)
+	routes := []models.Route{}
+	for _, cfRoute := range cfRoutes {
+		for _, hostname := range cfRoute.Hostnames {
+			if !builtIn[hostname] {
+				routes = append(routes, models.Route{Hostname: hostname, Port: cfRoute.Port})
+			}
+		}
+	}
+
+	return routes, nil
+}
+
+// AddRoute routes a hostname to one of the workstation's ports, generating
+// the hostname if none is given. The receptor cannot change the ports of an
+// existing DesiredLRP, so only ports declared when the workstation was
+// created can be routed to.
+func (m *workstationManager) AddRoute(name string, route models.Route) (models.Route, error) {
+	if route.Hostname == "" {
+		route.Hostname = m.routeProvider.PortRoute(name, route.Port)
+	}
+
+	if err := route.Validate(); err != nil {
+		return route, err
+	}
+
+	if err := route.ValidateDomain(m.routeProvider.AppsDomain()); err != nil {
+		return route, err
+	}
+
+	err := m.updateRoutes(name, func(desiredLRP receptor.DesiredLRPResponse, cfRoutes cfroutes.CFRoutes) (cfroutes.CFRoutes, error) {
+		if !containsPort(desiredLRP.Ports, route.Port) {
+			return nil, models.ValidationError{models.ErrInvalidField{"port"}}
+		}
+
+		if err := m.checkHostnameFree(name, route.Hostname); err != nil {
+			return nil, err
+		}
+
+		for _, cfRoute := range cfRoutes {
+			for _, hostname := range cfRoute.Hostnames {
+				if hostname == route.Hostname {
+					return nil, models.ValidationError{models.ErrDuplicateField{"hostname"}}
+				}
+			}
+		}
+
+		for i, cfRoute := range cfRoutes {
+			if cfRoute.Port == route.Port {
+				cfRoutes[i].Hostnames = append(cfRoute.Hostnames, route.Hostname)
+				return cfRoutes, nil
+			}
+		}
+
+		return append(cfRoutes, cfroutes.CFRoute{Hostnames: []string{route.Hostname}, Port: route.Port}), nil
+	})
+
+	return route, err
+}
+
+func (m *workstationManager) RemoveRoute(name, hostname string) error {
+	if m.builtInHostnames(name)[hostname] {
+		return models.ValidationError{models.ErrInvalidModification{"hostname"}}
+	}
+
+	return m.updateRoutes(name, func(desiredLRP receptor.DesiredLRPResponse, cfRoutes cfroutes.CFRoutes) (cfroutes.CFRoutes, error) {
+		for i, cfRoute := range cfRoutes {
+			for j, existing := range cfRoute.Hostnames {
+				if existing != hostname {
+					continue
+				}
+
+				cfRoutes[i].Hostnames = append(cfRoute.Hostnames[:j], cfRoute.Hostnames[j+1:]...)
+				if len(cfRoutes[i].Hostnames) == 0 {
+					cfRoutes = append(cfRoutes[:i], cfRoutes[i+1:]...)
+				}
+				return cfRoutes, nil
+			}
+		}
+
+		return nil, models.ErrNotFound{"route", hostname}
+	})
+}
+
+func (m *workstationManager) updateRoutes(name string, update func(receptor.DesiredLRPResponse, cfroutes.CFRoutes) (cfroutes.CFRoutes, error)) error {
+	m.updateMutex.Lock()
+	defer m.updateMutex.Unlock()
+
+	desiredLRP, err := m.fetchDesiredLRP(name)
+	if err != nil {
+		return err
+	}
+
+	cfRoutes, err := cfroutes.CFRoutesFromRoutingInfo(desiredLRP.Routes)
+	if err != nil {
+		return err
+	}
+
+	cfRoutes, err = update(desiredLRP, cfRoutes)
+	if err != nil {
+		return err
+	}
+
+	return m.receptorClient.UpdateDesiredLRP(name, receptor.DesiredLRPUpdateRequest{
+		Routes: cfRoutes.RoutingInfo(),
+	})
+}
+
+// checkHostnameFree makes sure no other workstation in the domain routes the
+// hostname, or has it as one of its own routes.
+func (m *workstationManager) checkHostnameFree(name, hostname string) error {
+	desiredLRPs, err := m.receptorClient.DesiredLRPsByDomain(m.bootstrap.Domain)
+	if err != nil {
+		return err
+	}
+
+	for _, desiredLRP := range desiredLRPs {
+		if desiredLRP.ProcessGuid == name {
+			continue
+		}
+
+		if m.builtInHostnames(desiredLRP.ProcessGuid)[hostname] {
+			return models.ValidationError{models.ErrDuplicateField{"hostname"}}
+		}
+
+		cfRoutes, err := cfroutes.CFRoutesFromRoutingInfo(desiredLRP.Routes)
+		if err != nil {
+			return err
+		}
+
+		for _, cfRoute := range cfRoutes {
+			for _, existing := range cfRoute.Hostnames {
+				if existing == hostname {
+					return models.ValidationError{models.ErrDuplicateField{"hostname"}}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *workstationManager) builtInHostnames(name string) map[string]bool {
+	return map[string]bool{
+		m.routeProvider.TiegoRoute(name): true,
+		m.routeProvider.SSHRoute(name):   true,
+	}
+}
+
+func containsPort(ports []uint16, port uint16) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package managers
+
+import (
+	"github.com/luan/teapot/models"
+	"github.com/nu7hatch/gouuid"
+)
+
+func (m *workstationManager) ListSchedules(name string) ([]models.Schedule, error) {
+	annotation, err := m.fetchAnnotation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := annotation.Schedules
+	if schedules == nil {
+		schedules = []models.Schedule{}
+	}
+	return schedules, nil
+}
+
+func (m *workstationManager) AddSchedule(name string, schedule models.Schedule) (models.Schedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return schedule, err
+	}
+
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return schedule, err
+	}
+	schedule.ID = guid.String()
+
+	err = m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		annotation.Schedules = append(annotation.Schedules, schedule)
+		return nil
+	})
+
+	return schedule, err
+}
+
+func (m *workstationManager) UpdateSchedule(name, id string, schedule models.Schedule) (models.Schedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return schedule, err
+	}
+	schedule.ID = id
+
+	err := m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		for i := range annotation.Schedules {
+			if annotation.Schedules[i].ID == id {
+				annotation.Schedules[i] = schedule
+				return nil
+			}
+		}
+		return models.ErrNotFound{"schedule", id}
+	})
+
+	return schedule, err
+}
+
+func (m *workstationManager) DeleteSchedule(name, id string) error {
+	return m.updateAnnotation(name, func(annotation *workstationAnnotation) error {
+		for i, schedule := range annotation.Schedules {
+			if schedule.ID == id {
+				annotation.Schedules = append(annotation.Schedules[:i], annotation.Schedules[i+1:]...)
+				return nil
+			}
+		}
+		return models.ErrNotFound{"schedule", id}
+	})
+}
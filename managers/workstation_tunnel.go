@@ -0,0 +1,120 @@
+package managers
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/gorilla/websocket"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// Tunnel opens a connection to a port inside the workstation. Only the TEA's
+// port is reachable from the outside, so the TEA relays the connection over a
+// websocket.
+func (m *workstationManager) Tunnel(name string, port uint16) (net.Conn, error) {
+	log := m.logger.Session("tunnel", lager.Data{"workstation_name": name, "port": port})
+
+	teaAddress, err := m.teaAddress(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnelURL := &url.URL{
+		Scheme: "ws",
+		Host:   teaAddress,
+		Path:   "/" + path.Join("tunnel", m.teaSecret, strconv.Itoa(int(port))),
+	}
+
+	conn, err := net.Dial("tcp", tunnelURL.Host)
+	if err != nil {
+		log.Error("dial-failed", err)
+		return nil, err
+	}
+
+	wsConn, _, err := websocket.NewClient(conn, tunnelURL, http.Header{"Origin": {tunnelURL.String()}}, 1024, 1024)
+	if err != nil {
+		log.Error("handshake-failed", err)
+		conn.Close()
+		return nil, err
+	}
+
+	log.Debug("opened")
+	return &websocketConn{Conn: wsConn}, nil
+}
+
+// teaAddress returns the host:port the TEA of a running workstation can be
+// reached at directly, without going through the router.
+func (m *workstationManager) teaAddress(name string) (string, error) {
+	actualLRPs, err := m.receptorClient.ActualLRPsByProcessGuid(name)
+	if err != nil || len(actualLRPs) == 0 {
+		return "", models.ErrNotFound{"workstation", name}
+	}
+
+	actualLRP := actualLRPs[0]
+	hostPort := teaHostPort(actualLRP)
+	if actualLRP.State != receptor.ActualLRPStateRunning || hostPort == 0 {
+		return "", models.ErrNotRunning{name}
+	}
+
+	return fmt.Sprintf("%s:%d", actualLRP.Address, hostPort), nil
+}
+
+func teaHostPort(actualLRP receptor.ActualLRPResponse) uint16 {
+	for _, mapping := range actualLRP.Ports {
+		if mapping.ContainerPort == teaPort {
+			return mapping.HostPort
+		}
+	}
+	return 0
+}
+
+// websocketConn exposes the binary messages of a websocket as a stream.
+type websocketConn struct {
+	*websocket.Conn
+	reader io.Reader
+}
+
+func (c *websocketConn) Read(p []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			_, reader, err := c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			c.reader = reader
+		}
+
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *websocketConn) Write(p []byte) (int, error) {
+	err := c.WriteMessage(websocket.BinaryMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *websocketConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
@@ -0,0 +1,69 @@
+package teapot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ParseWorkstationManifest reads a manifest written either in JSON or in
+// YAML. YAML manifests use the same field names as JSON ones.
+func ParseWorkstationManifest(contents []byte) (WorkstationManifest, error) {
+	manifest := WorkstationManifest{}
+
+	trimmed := bytes.TrimSpace(contents)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		var document interface{}
+		if err := yaml.Unmarshal(contents, &document); err != nil {
+			return manifest, err
+		}
+
+		document, err := jsonValue(document)
+		if err != nil {
+			return manifest, err
+		}
+
+		contents, err = json.Marshal(document)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	err := json.Unmarshal(contents, &manifest)
+	return manifest, err
+}
+
+// jsonValue turns the maps YAML decodes into ones JSON can encode.
+func jsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, element := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key %v: keys must be strings", key)
+			}
+
+			converted, err := jsonValue(element)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = converted
+		}
+		return object, nil
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			converted, err := jsonValue(element)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = converted
+		}
+		return array, nil
+	default:
+		return value, nil
+	}
+}
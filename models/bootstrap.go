@@ -0,0 +1,193 @@
+package models
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultStack  = "lucid64"
+	DefaultDomain = "tiego"
+)
+
+var checksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Bootstrap describes how workstations are placed on Diego and what is
+// downloaded and run in them before sshd and the TEA start.
+type Bootstrap struct {
+	Stack  string `json:"stack,omitempty"`
+	Domain string `json:"domain,omitempty"`
+
+	Dropbear Artifact        `json:"dropbear"`
+	Tea      Artifact        `json:"tea"`
+	Steps    []BootstrapStep `json:"steps,omitempty"`
+
+	// Dotfiles is a git repository cloned into the home directory, its
+	// install.sh is run if there is one.
+	Dotfiles string `json:"dotfiles,omitempty"`
+
+	// Templates overrides parts of the bootstrap for workstations created
+	// from the named templates.
+	Templates map[string]BootstrapOverride `json:"templates,omitempty"`
+}
+
+// BootstrapOverride replaces the artifacts and dotfiles it sets, its steps run
+// after the default ones.
+type BootstrapOverride struct {
+	Dropbear *Artifact       `json:"dropbear,omitempty"`
+	Tea      *Artifact       `json:"tea,omitempty"`
+	Steps    []BootstrapStep `json:"steps,omitempty"`
+	Dotfiles string          `json:"dotfiles,omitempty"`
+}
+
+// Artifact is a tarball extracted into the workstation. Checksum is the hex
+// SHA-256 of the tarball.
+type Artifact struct {
+	URL      string `json:"url"`
+	CacheKey string `json:"cache_key,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// BootstrapStep either extracts Download into To or runs Run with bash.
+type BootstrapStep struct {
+	Download *Artifact `json:"download,omitempty"`
+	To       string    `json:"to,omitempty"`
+	Run      string    `json:"run,omitempty"`
+}
+
+func DefaultBootstrap() Bootstrap {
+	return Bootstrap{
+		Stack:  DefaultStack,
+		Domain: DefaultDomain,
+		Dropbear: Artifact{
+			URL:      "https://tiego-artifacts.s3.amazonaws.com/dropbear/dropbear.tar.gz",
+			CacheKey: "dropbear",
+		},
+		Tea: Artifact{
+			URL:      "https://tiego-artifacts.s3.amazonaws.com/tea-builds/tea-latest.tgz",
+			CacheKey: "tea",
+		},
+	}
+}
+
+// For returns the bootstrap of workstations created from template.
+func (bootstrap Bootstrap) For(template string) Bootstrap {
+	override, ok := bootstrap.Templates[template]
+	if !ok {
+		return bootstrap
+	}
+
+	if override.Dropbear != nil {
+		bootstrap.Dropbear = *override.Dropbear
+	}
+	if override.Tea != nil {
+		bootstrap.Tea = *override.Tea
+	}
+	if override.Dotfiles != "" {
+		bootstrap.Dotfiles = override.Dotfiles
+	}
+	bootstrap.Steps = append(append([]BootstrapStep{}, bootstrap.Steps...), override.Steps...)
+	bootstrap.Templates = nil
+
+	return bootstrap
+}
+
+// Artifacts lists every artifact downloaded by the bootstrap or one of its
+// template overrides.
+func (bootstrap Bootstrap) Artifacts() []Artifact {
+	artifacts := []Artifact{bootstrap.Dropbear, bootstrap.Tea}
+	artifacts = append(artifacts, stepArtifacts(bootstrap.Steps)...)
+
+	for _, override := range bootstrap.Templates {
+		if override.Dropbear != nil {
+			artifacts = append(artifacts, *override.Dropbear)
+		}
+		if override.Tea != nil {
+			artifacts = append(artifacts, *override.Tea)
+		}
+		artifacts = append(artifacts, stepArtifacts(override.Steps)...)
+	}
+
+	return artifacts
+}
+
+func (bootstrap Bootstrap) Validate() error {
+	var validationError ValidationError
+
+	if bootstrap.Stack == "" {
+		validationError = append(validationError, ErrInvalidField{"stack"})
+	}
+
+	if bootstrap.Domain == "" {
+		validationError = append(validationError, ErrInvalidField{"domain"})
+	}
+
+	if !validArtifact(bootstrap.Dropbear) {
+		validationError = append(validationError, ErrInvalidField{"dropbear"})
+	}
+
+	if !validArtifact(bootstrap.Tea) {
+		validationError = append(validationError, ErrInvalidField{"tea"})
+	}
+
+	if !validSteps(bootstrap.Steps) {
+		validationError = append(validationError, ErrInvalidField{"steps"})
+	}
+
+	if !validRepository(bootstrap.Dotfiles) {
+		validationError = append(validationError, ErrInvalidField{"dotfiles"})
+	}
+
+	for _, override := range bootstrap.Templates {
+		if (override.Dropbear != nil && !validArtifact(*override.Dropbear)) ||
+			(override.Tea != nil && !validArtifact(*override.Tea)) ||
+			!validSteps(override.Steps) || !validRepository(override.Dotfiles) {
+			validationError = append(validationError, ErrInvalidField{"templates"})
+			break
+		}
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+func stepArtifacts(steps []BootstrapStep) []Artifact {
+	var artifacts []Artifact
+	for _, step := range steps {
+		if step.Download != nil {
+			artifacts = append(artifacts, *step.Download)
+		}
+	}
+	return artifacts
+}
+
+func validArtifact(artifact Artifact) bool {
+	u, err := url.Parse(artifact.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return artifact.Checksum == "" || checksumPattern.MatchString(artifact.Checksum)
+}
+
+func validSteps(steps []BootstrapStep) bool {
+	for _, step := range steps {
+		if (step.Download == nil) == (step.Run == "") {
+			return false
+		}
+		if step.Download != nil && (!validArtifact(*step.Download) || !strings.HasPrefix(step.To, "/")) {
+			return false
+		}
+	}
+	return true
+}
+
+func validRepository(repository string) bool {
+	if repository == "" {
+		return true
+	}
+	u, err := url.Parse(repository)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
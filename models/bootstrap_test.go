@@ -0,0 +1,83 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Bootstrap", func() {
+	const checksum = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	var bootstrap Bootstrap
+
+	BeforeEach(func() {
+		bootstrap = DefaultBootstrap()
+		bootstrap.Steps = []BootstrapStep{{Run: "apt-get install -y git"}}
+		bootstrap.Templates = map[string]BootstrapOverride{
+			"go-large": {
+				Tea:      &Artifact{URL: "http://mirror.internal/tea.tgz", Checksum: checksum},
+				Steps:    []BootstrapStep{{Download: &Artifact{URL: "http://mirror.internal/go.tgz"}, To: "/usr/local"}},
+				Dotfiles: "https://git.internal/dotfiles.git",
+			},
+		}
+	})
+
+	Describe("For", func() {
+		It("applies the template's overrides", func() {
+			forTemplate := bootstrap.For("go-large")
+			Expect(forTemplate.Dropbear).To(Equal(DefaultBootstrap().Dropbear))
+			Expect(forTemplate.Tea.URL).To(Equal("http://mirror.internal/tea.tgz"))
+			Expect(forTemplate.Dotfiles).To(Equal("https://git.internal/dotfiles.git"))
+			Expect(forTemplate.Steps).To(HaveLen(2))
+			Expect(forTemplate.Steps[1].To).To(Equal("/usr/local"))
+		})
+
+		It("leaves the bootstrap alone for other templates", func() {
+			Expect(bootstrap.For("")).To(Equal(bootstrap))
+			Expect(bootstrap.Steps).To(HaveLen(1))
+		})
+	})
+
+	Describe("Artifacts", func() {
+		It("lists the artifacts of the bootstrap and its overrides", func() {
+			Expect(bootstrap.Artifacts()).To(HaveLen(4))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("is valid", func() {
+			Expect(bootstrap.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"stack",
+				Bootstrap{Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: DefaultBootstrap().Tea},
+			},
+			{"dropbear",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: Artifact{URL: "/dropbear.tgz"}, Tea: DefaultBootstrap().Tea},
+			},
+			{"tea",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: Artifact{URL: "http://mirror/tea.tgz", Checksum: "md5:abc"}},
+			},
+			{"steps",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: DefaultBootstrap().Tea,
+					Steps: []BootstrapStep{{Download: &Artifact{URL: "http://mirror/go.tgz"}, To: "relative"}}},
+			},
+			{"steps",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: DefaultBootstrap().Tea,
+					Steps: []BootstrapStep{{}}},
+			},
+			{"dotfiles",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: DefaultBootstrap().Tea, Dotfiles: "dotfiles"},
+			},
+			{"templates",
+				Bootstrap{Stack: "lucid64", Domain: "tiego", Dropbear: DefaultBootstrap().Dropbear, Tea: DefaultBootstrap().Tea,
+					Templates: map[string]BootstrapOverride{"t": {Tea: &Artifact{}}}},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+})
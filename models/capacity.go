@@ -0,0 +1,51 @@
+package models
+
+import "fmt"
+
+// Capacity is what a Diego cell, or all those of a stack, has left for new
+// containers.
+type Capacity struct {
+	MemoryMB   int `json:"memory_mb"`
+	DiskMB     int `json:"disk_mb"`
+	Containers int `json:"containers"`
+}
+
+type Cell struct {
+	ID    string   `json:"id"`
+	Stack string   `json:"stack"`
+	Free  Capacity `json:"free"`
+}
+
+// StackCapacity sums up the free capacity of the cells of a stack. Since a
+// container has to fit on a single cell, LargestFree is what the cell with
+// the most free memory has left.
+type StackCapacity struct {
+	Stack       string   `json:"stack"`
+	Cells       int      `json:"cells"`
+	Free        Capacity `json:"free"`
+	LargestFree Capacity `json:"largest_free"`
+}
+
+// Fits tells whether a container using memoryMB and diskMB can be placed in
+// the capacity.
+func (capacity Capacity) Fits(memoryMB, diskMB int) bool {
+	return capacity.Containers > 0 && capacity.MemoryMB >= memoryMB && capacity.DiskMB >= diskMB
+}
+
+func (capacity Capacity) Add(other Capacity) Capacity {
+	return Capacity{
+		MemoryMB:   capacity.MemoryMB + other.MemoryMB,
+		DiskMB:     capacity.DiskMB + other.DiskMB,
+		Containers: capacity.Containers + other.Containers,
+	}
+}
+
+type ErrInsufficientCapacity struct {
+	Stack    string
+	MemoryMB int
+	DiskMB   int
+}
+
+func (err ErrInsufficientCapacity) Error() string {
+	return fmt.Sprintf("no cell of stack %s can fit a workstation with %d MB of memory and %d MB of disk", err.Stack, err.MemoryMB, err.DiskMB)
+}
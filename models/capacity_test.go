@@ -0,0 +1,33 @@
+package models_test
+
+import (
+	. "github.com/luan/teapot/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capacity", func() {
+	capacity := Capacity{MemoryMB: 2048, DiskMB: 4096, Containers: 1}
+
+	Describe("Fits", func() {
+		It("fits containers within the free memory and disk", func() {
+			Expect(capacity.Fits(2048, 4096)).To(BeTrue())
+		})
+
+		It("doesn't fit containers using more memory or disk than is free", func() {
+			Expect(capacity.Fits(2049, 0)).To(BeFalse())
+			Expect(capacity.Fits(0, 4097)).To(BeFalse())
+		})
+
+		It("doesn't fit anything without free containers", func() {
+			Expect(Capacity{MemoryMB: 2048, DiskMB: 4096}.Fits(0, 0)).To(BeFalse())
+		})
+	})
+
+	Describe("Add", func() {
+		It("sums up the capacities", func() {
+			Expect(capacity.Add(capacity)).To(Equal(Capacity{MemoryMB: 4096, DiskMB: 8192, Containers: 2}))
+		})
+	})
+})
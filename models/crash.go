@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	CrashedWhileStartingReason = "crashed while starting: the setup failed or sshd and the TEA could not start"
+	CrashedWhileRunningReason  = "crashed while running: sshd or the TEA exited, e.g. when running out of memory"
+)
+
+// CrashHistory is what teapot saw of a workstation's crashes and restarts in
+// the ActualLRP events. CrashCount is Diego's, which resets once the
+// workstation keeps running.
+type CrashHistory struct {
+	CrashCount      int        `json:"crash_count"`
+	LastCrashReason string     `json:"last_crash_reason,omitempty"`
+	LastCrashedAt   *time.Time `json:"last_crashed_at,omitempty"`
+	Restarts        []Restart  `json:"restarts,omitempty"`
+}
+
+// Restart is a new instance of the workstation replacing a crashed one, or
+// the one killed at RequestedBy's request.
+type Restart struct {
+	At          time.Time `json:"at"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+}
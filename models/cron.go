@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression is a parsed standard five-field cron expression:
+// minute, hour, day of month, month and day of week.
+type CronExpression struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}},
+}
+
+func ParseCron(expression string) (CronExpression, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return CronExpression{}, errors.New("cron expression must have 5 fields")
+	}
+
+	values := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		parsed, err := cronFields[i].parse(field)
+		if err != nil {
+			return CronExpression{}, err
+		}
+		values[i] = parsed
+	}
+
+	// Sunday may be written as either 0 or 7.
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return CronExpression{
+		minutes:       values[0],
+		hours:         values[1],
+		daysOfMonth:   values[2],
+		months:        values[3],
+		daysOfWeek:    values[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+// Matches reports whether the expression fires during the minute of t.
+func (c CronExpression) Matches(t time.Time) bool {
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+
+	// As in cron(8), when both day fields are restricted either may match.
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+func (f cronField) parse(field string) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, f.invalid(field)
+			}
+			part = part[:i]
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return nil, f.invalid(field)
+			}
+			if end, err = f.value(bounds[1]); err != nil {
+				return nil, f.invalid(field)
+			}
+		default:
+			value, err := f.value(part)
+			if err != nil {
+				return nil, f.invalid(field)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start > end {
+			return nil, f.invalid(field)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, f.invalid(s)
+	}
+	return v, nil
+}
+
+func (f cronField) invalid(s string) error {
+	return fmt.Errorf("invalid %s in cron expression: %s", f.name, s)
+}
@@ -0,0 +1,77 @@
+package models_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("CronExpression", func() {
+	// 2015-03-02 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2015, time.March, day, hour, minute, 30, 0, time.UTC)
+	}
+
+	Describe("ParseCron", func() {
+		for _, expression := range []string{
+			"* * * * *",
+			"0 8 * * 1-5",
+			"*/15 9-17 * * MON-FRI",
+			"0,30 * 1 JAN,jul *",
+			"0 0 * * 7",
+		} {
+			expression := expression
+			It("parses '"+expression+"'", func() {
+				_, err := ParseCron(expression)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		}
+
+		for _, expression := range []string{
+			"",
+			"* * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"5-1 * * * *",
+			"*/0 * * * *",
+			"a * * * *",
+		} {
+			expression := expression
+			It("rejects '"+expression+"'", func() {
+				_, err := ParseCron(expression)
+				Expect(err).To(HaveOccurred())
+			})
+		}
+	})
+
+	Describe("Matches", func() {
+		It("matches weekday mornings", func() {
+			cron, _ := ParseCron("0 8 * * 1-5")
+			Expect(cron.Matches(at(2, 8, 0))).To(BeTrue())
+			Expect(cron.Matches(at(2, 8, 1))).To(BeFalse())
+			Expect(cron.Matches(at(1, 8, 0))).To(BeFalse())
+		})
+
+		It("supports steps", func() {
+			cron, _ := ParseCron("*/20 * * * *")
+			Expect(cron.Matches(at(2, 3, 40))).To(BeTrue())
+			Expect(cron.Matches(at(2, 3, 50))).To(BeFalse())
+		})
+
+		It("treats 7 as Sunday", func() {
+			cron, _ := ParseCron("0 0 * * 7")
+			Expect(cron.Matches(at(1, 0, 0))).To(BeTrue())
+		})
+
+		It("matches either day field when both are restricted", func() {
+			cron, _ := ParseCron("0 0 15 * MON")
+			Expect(cron.Matches(at(2, 0, 0))).To(BeTrue())
+			Expect(cron.Matches(at(15, 0, 0))).To(BeTrue())
+			Expect(cron.Matches(at(3, 0, 0))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package models
+
+import (
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot"
+)
+
+// OpenEgressPolicy allows all outbound traffic. It's the default policy
+// unless the configuration says otherwise.
+const OpenEgressPolicy = "open"
+
+// EgressPolicy is a named list of rules allowing outbound traffic from
+// workstations, anything not allowed is blocked.
+type EgressPolicy struct {
+	Name  string              `json:"name"`
+	Rules []teapot.EgressRule `json:"rules"`
+}
+
+func NewOpenEgressPolicy() EgressPolicy {
+	return EgressPolicy{
+		Name: OpenEgressPolicy,
+		Rules: []teapot.EgressRule{
+			{Protocol: string(diego_models.AllProtocol), Destinations: []string{"0.0.0.0/0"}},
+		},
+	}
+}
+
+func (policy EgressPolicy) Validate() error {
+	var validationError ValidationError
+
+	if !templateNamePattern.MatchString(policy.Name) {
+		validationError = append(validationError, ErrInvalidField{"name"})
+	}
+
+	for _, rule := range policy.SecurityGroupRules() {
+		if err := rule.Validate(); err != nil {
+			validationError = append(validationError, ErrInvalidField{"rules [ " + err.Error() + " ]"})
+			break
+		}
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// SecurityGroupRules translates the policy into the egress rules of Diego.
+func (policy EgressPolicy) SecurityGroupRules() []diego_models.SecurityGroupRule {
+	rules := []diego_models.SecurityGroupRule{}
+	for _, rule := range policy.Rules {
+		securityGroupRule := diego_models.SecurityGroupRule{
+			Protocol:     diego_models.ProtocolName(rule.Protocol),
+			Destinations: rule.Destinations,
+			Ports:        rule.Ports,
+			Log:          rule.Log,
+		}
+		if rule.PortRange != nil {
+			securityGroupRule.PortRange = &diego_models.PortRange{Start: rule.PortRange.Start, End: rule.PortRange.End}
+		}
+		if rule.ICMP != nil {
+			securityGroupRule.IcmpInfo = &diego_models.ICMPInfo{Type: rule.ICMP.Type, Code: rule.ICMP.Code}
+		}
+		rules = append(rules, securityGroupRule)
+	}
+	return rules
+}
+
+// EgressRules translates Diego's egress rules back, to show the rules a
+// workstation was actually desired with.
+func EgressRules(securityGroupRules []diego_models.SecurityGroupRule) []teapot.EgressRule {
+	rules := []teapot.EgressRule{}
+	for _, securityGroupRule := range securityGroupRules {
+		rule := teapot.EgressRule{
+			Protocol:     string(securityGroupRule.Protocol),
+			Destinations: securityGroupRule.Destinations,
+			Ports:        securityGroupRule.Ports,
+			Log:          securityGroupRule.Log,
+		}
+		if securityGroupRule.PortRange != nil {
+			rule.PortRange = &teapot.PortRange{Start: securityGroupRule.PortRange.Start, End: securityGroupRule.PortRange.End}
+		}
+		if securityGroupRule.IcmpInfo != nil {
+			rule.ICMP = &teapot.ICMPInfo{Type: securityGroupRule.IcmpInfo.Type, Code: securityGroupRule.IcmpInfo.Code}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
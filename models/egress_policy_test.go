@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	diego_models "github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressPolicy", func() {
+	policy := EgressPolicy{
+		Name: "internet-only",
+		Rules: []teapot.EgressRule{
+			{Protocol: "tcp", Destinations: []string{"0.0.0.0-9.255.255.255", "11.0.0.0-255.255.255.255"}, Ports: []uint16{80, 443}},
+			{Protocol: "udp", Destinations: []string{"8.8.8.8"}, PortRange: &teapot.PortRange{Start: 53, End: 53}},
+			{Protocol: "icmp", Destinations: []string{"0.0.0.0/0"}, ICMP: &teapot.ICMPInfo{Type: 8, Code: 0}},
+		},
+	}
+
+	Describe("Validate", func() {
+		It("is valid", func() {
+			Expect(policy.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("is valid without rules, blocking all egress", func() {
+			Expect(EgressPolicy{Name: "closed"}.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("is valid when open", func() {
+			Expect(NewOpenEgressPolicy().Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"name",
+				EgressPolicy{Name: "a b"},
+			},
+			{"rules",
+				EgressPolicy{Name: "a", Rules: []teapot.EgressRule{{Protocol: "sctp", Destinations: []string{"10.0.0.0/8"}}}},
+			},
+			{"rules",
+				EgressPolicy{Name: "a", Rules: []teapot.EgressRule{{Protocol: "tcp", Destinations: []string{"10.0.0.0/8"}}}},
+			},
+			{"rules",
+				EgressPolicy{Name: "a", Rules: []teapot.EgressRule{{Protocol: "icmp", Destinations: []string{"10.0.0.0/8"}}}},
+			},
+			{"rules",
+				EgressPolicy{Name: "a", Rules: []teapot.EgressRule{{Protocol: "all", Destinations: []string{"10.0.0.0/33"}}}},
+			},
+			{"rules",
+				EgressPolicy{Name: "a", Rules: []teapot.EgressRule{{Protocol: "all"}}},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+
+	Describe("SecurityGroupRules", func() {
+		It("translates the rules for Diego", func() {
+			Expect(policy.SecurityGroupRules()).To(Equal([]diego_models.SecurityGroupRule{
+				{Protocol: diego_models.TCPProtocol, Destinations: []string{"0.0.0.0-9.255.255.255", "11.0.0.0-255.255.255.255"}, Ports: []uint16{80, 443}},
+				{Protocol: diego_models.UDPProtocol, Destinations: []string{"8.8.8.8"}, PortRange: &diego_models.PortRange{Start: 53, End: 53}},
+				{Protocol: diego_models.ICMPProtocol, Destinations: []string{"0.0.0.0/0"}, IcmpInfo: &diego_models.ICMPInfo{Type: 8, Code: 0}},
+			}))
+		})
+
+		It("translates back", func() {
+			Expect(EgressRules(policy.SecurityGroupRules())).To(Equal(policy.Rules))
+		})
+	})
+})
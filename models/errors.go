@@ -26,6 +26,14 @@ func (err ErrInvalidModification) Error() string {
 	return "attempt to make invalid change to field: " + err.InvalidField
 }
 
+type ErrAnnotationTooLarge struct {
+	Name string
+}
+
+func (err ErrAnnotationTooLarge) Error() string {
+	return "too many keys and schedules to store for workstation: " + err.Name
+}
+
 type Validator interface {
 	Validate() error
 }
@@ -47,3 +55,36 @@ func (ve ValidationError) Error() string {
 
 	return buffer.String()
 }
+
+type ErrNotFound struct {
+	Resource string
+	Name     string
+}
+
+func (err ErrNotFound) Error() string {
+	return err.Resource + " not found: " + err.Name
+}
+
+type ErrNotRunning struct {
+	Name string
+}
+
+func (err ErrNotRunning) Error() string {
+	return "workstation is not running: " + err.Name
+}
+
+type ErrPendingApproval struct {
+	Name string
+}
+
+func (err ErrPendingApproval) Error() string {
+	return "workstation is pending privilege approval: " + err.Name
+}
+
+type ErrOperationInProgress struct {
+	Name string
+}
+
+func (err ErrOperationInProgress) Error() string {
+	return "workstation is being replaced: " + err.Name
+}
@@ -8,6 +8,12 @@ import (
 )
 
 type FakeRouteProvider struct {
+	AppsDomainStub        func() string
+	appsDomainMutex       sync.RWMutex
+	appsDomainArgsForCall []struct{}
+	appsDomainReturns     struct {
+		result1 string
+	}
 	TiegoRouteStub        func(name string) string
 	tiegoRouteMutex       sync.RWMutex
 	tiegoRouteArgsForCall []struct {
@@ -24,6 +30,39 @@ type FakeRouteProvider struct {
 	sSHRouteReturns struct {
 		result1 string
 	}
+	PortRouteStub        func(name string, port uint16) string
+	portRouteMutex       sync.RWMutex
+	portRouteArgsForCall []struct {
+		name string
+		port uint16
+	}
+	portRouteReturns struct {
+		result1 string
+	}
+}
+
+func (fake *FakeRouteProvider) AppsDomain() string {
+	fake.appsDomainMutex.Lock()
+	fake.appsDomainArgsForCall = append(fake.appsDomainArgsForCall, struct{}{})
+	fake.appsDomainMutex.Unlock()
+	if fake.AppsDomainStub != nil {
+		return fake.AppsDomainStub()
+	} else {
+		return fake.appsDomainReturns.result1
+	}
+}
+
+func (fake *FakeRouteProvider) AppsDomainCallCount() int {
+	fake.appsDomainMutex.RLock()
+	defer fake.appsDomainMutex.RUnlock()
+	return len(fake.appsDomainArgsForCall)
+}
+
+func (fake *FakeRouteProvider) AppsDomainReturns(result1 string) {
+	fake.AppsDomainStub = nil
+	fake.appsDomainReturns = struct {
+		result1 string
+	}{result1}
 }
 
 func (fake *FakeRouteProvider) TiegoRoute(name string) string {
@@ -90,4 +129,37 @@ func (fake *FakeRouteProvider) SSHRouteReturns(result1 string) {
 	}{result1}
 }
 
+func (fake *FakeRouteProvider) PortRoute(name string, port uint16) string {
+	fake.portRouteMutex.Lock()
+	fake.portRouteArgsForCall = append(fake.portRouteArgsForCall, struct {
+		name string
+		port uint16
+	}{name, port})
+	fake.portRouteMutex.Unlock()
+	if fake.PortRouteStub != nil {
+		return fake.PortRouteStub(name, port)
+	} else {
+		return fake.portRouteReturns.result1
+	}
+}
+
+func (fake *FakeRouteProvider) PortRouteCallCount() int {
+	fake.portRouteMutex.RLock()
+	defer fake.portRouteMutex.RUnlock()
+	return len(fake.portRouteArgsForCall)
+}
+
+func (fake *FakeRouteProvider) PortRouteArgsForCall(i int) (string, uint16) {
+	fake.portRouteMutex.RLock()
+	defer fake.portRouteMutex.RUnlock()
+	return fake.portRouteArgsForCall[i].name, fake.portRouteArgsForCall[i].port
+}
+
+func (fake *FakeRouteProvider) PortRouteReturns(result1 string) {
+	fake.PortRouteStub = nil
+	fake.portRouteReturns = struct {
+		result1 string
+	}{result1}
+}
+
 var _ models.RouteProvider = new(FakeRouteProvider)
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/luan/teapot"
+)
+
+type Job struct {
+	ID            string `json:"id"`
+	Workstation   string `json:"workstation,omitempty"`
+	DockerImage   string `json:"docker_image"`
+	Command       string `json:"command"`
+	CPUWeight     uint   `json:"cpu_weight"`
+	DiskMB        int    `json:"disk_mb"`
+	MemoryMB      int    `json:"memory_mb"`
+	Privileged    bool   `json:"privileged"`
+	State         string `json:"state"`
+	Failed        bool   `json:"failed"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	ExitCode      *int   `json:"exit_code,omitempty"`
+	Output        string `json:"output"`
+}
+
+func NewJob(request teapot.JobCreateRequest) Job {
+	if len(request.DockerImage) == 0 {
+		request.DockerImage = DefaultDockerImage
+	}
+
+	return Job{
+		DockerImage: request.DockerImage,
+		Command:     request.Command,
+		CPUWeight:   request.CPUWeight,
+		DiskMB:      request.DiskMB,
+		MemoryMB:    request.MemoryMB,
+		Privileged:  request.Privileged,
+	}
+}
+
+func (job Job) Validate() error {
+	var validationError ValidationError
+
+	if len(strings.TrimSpace(job.Command)) == 0 {
+		validationError = append(validationError, ErrInvalidField{"command"})
+	}
+
+	if !validDockerImage(job.DockerImage) {
+		validationError = append(validationError, ErrInvalidField{"docker_image"})
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Job", func() {
+	Describe("NewJob", func() {
+		It("defaults dockerImage to the workstation default", func() {
+			job := NewJob(teapot.JobCreateRequest{})
+			Expect(job.DockerImage).To(Equal(DefaultDockerImage))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("is valid with a command and docker_image", func() {
+			job := Job{Command: "ls", DockerImage: "docker:///busybox"}
+			Expect(job.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"command",
+				Job{DockerImage: "docker:///busybox"},
+			},
+			{"command",
+				Job{Command: "  ", DockerImage: "docker:///busybox"},
+			},
+			{"docker_image",
+				Job{Command: "ls", DockerImage: "busybox"},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+})
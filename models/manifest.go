@@ -0,0 +1,147 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/luan/teapot"
+)
+
+var manifestNamePattern = regexp.MustCompile(`^[\w-.]+$`)
+
+const (
+	ChangeCreate  = "create"
+	ChangeUpdate  = "update"
+	ChangeReplace = "replace"
+	ChangeDelete  = "delete"
+)
+
+// Manifest declares every workstation labelled with its name. Applying it
+// creates, updates or deletes those workstations to match.
+type Manifest struct {
+	Name         string
+	Workstations []ManifestWorkstation
+}
+
+type ManifestWorkstation struct {
+	Workstation
+
+	// Keys are authorized_keys lines.
+	Keys   []string
+	Routes []Route
+}
+
+// Change is a step of applying a manifest. Updates are made in place, while
+// replacing a workstation deletes and recreates it in the background, keeping
+// its home directory, tracked by the operation OperationID.
+type Change struct {
+	Workstation string        `json:"workstation"`
+	Action      string        `json:"action"`
+	Fields      []FieldChange `json:"fields,omitempty"`
+	OperationID string        `json:"operation_id,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type ErrUnmanaged struct {
+	Name     string
+	Manifest string
+}
+
+func (err ErrUnmanaged) Error() string {
+	return fmt.Sprintf("workstation %s exists but is not managed by manifest %s", err.Name, err.Manifest)
+}
+
+func NewManifest(request teapot.WorkstationManifest) Manifest {
+	manifest := Manifest{Name: request.Name}
+
+	for _, spec := range request.Workstations {
+		workstation := NewWorkstation(teapot.WorkstationCreateRequest{
+			Name:        spec.Name,
+			DockerImage: spec.DockerImage,
+			CPUWeight:   spec.CPUWeight,
+			DiskMB:      spec.DiskMB,
+			MemoryMB:    spec.MemoryMB,
+			Ports:       spec.Ports,
+			Env:         spec.Env,
+		})
+		workstation.Owner = spec.Owner
+		workstation.Manifest = request.Name
+
+		for _, scheduleRequest := range spec.Schedules {
+			workstation.Schedules = append(workstation.Schedules, NewSchedule(scheduleRequest))
+		}
+
+		entry := ManifestWorkstation{Workstation: workstation, Keys: spec.Keys}
+		for _, routeRequest := range spec.Routes {
+			entry.Routes = append(entry.Routes, NewRoute(routeRequest))
+		}
+
+		manifest.Workstations = append(manifest.Workstations, entry)
+	}
+
+	return manifest
+}
+
+func (manifest Manifest) Validate() error {
+	var validationError ValidationError
+
+	if !manifestNamePattern.MatchString(manifest.Name) {
+		validationError = append(validationError, ErrInvalidField{"name"})
+	}
+
+	names := map[string]bool{}
+	for _, entry := range manifest.Workstations {
+		if names[entry.Name] {
+			validationError = append(validationError, ErrDuplicateField{"workstations.name"})
+		}
+		names[entry.Name] = true
+
+		if err := entry.Validate(); err != nil {
+			validationError = append(validationError, err)
+		}
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+func (entry ManifestWorkstation) Validate() error {
+	var validationError ValidationError
+
+	if err := entry.Workstation.Validate(); err != nil {
+		validationError = append(validationError, err.(ValidationError)...)
+	}
+
+	for _, key := range entry.Keys {
+		if _, err := ParseSSHKey(key); err != nil {
+			validationError = append(validationError, ErrInvalidField{"keys"})
+			break
+		}
+	}
+
+	for _, route := range entry.Routes {
+		if err := route.Validate(); err != nil {
+			validationError = append(validationError, ErrInvalidField{"routes"})
+			break
+		}
+	}
+
+	for _, schedule := range entry.Schedules {
+		if err := schedule.Validate(); err != nil {
+			validationError = append(validationError, ErrInvalidField{"schedules"})
+			break
+		}
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Manifest", func() {
+	Describe("NewManifest", func() {
+		It("labels the workstations with the manifest name", func() {
+			manifest := NewManifest(teapot.WorkstationManifest{
+				Name: "lab",
+				Workstations: []teapot.WorkstationSpec{{
+					Name:      "lab-01",
+					Owner:     "alice",
+					Routes:    []teapot.RouteCreateRequest{{Hostname: "Lab-01.example.com", Port: 3000}},
+					Schedules: []teapot.ScheduleCreateRequest{{Cron: "0 19 * * *", Action: "stop"}},
+				}},
+			})
+
+			entry := manifest.Workstations[0]
+			Expect(entry.Manifest).To(Equal("lab"))
+			Expect(entry.Owner).To(Equal("alice"))
+			Expect(entry.DockerImage).To(Equal(DefaultDockerImage))
+			Expect(entry.Routes).To(Equal([]Route{{Hostname: "lab-01.example.com", Port: 3000}}))
+			Expect(entry.Schedules).To(Equal([]Schedule{{Cron: "0 19 * * *", Action: "stop"}}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("is valid with a name and valid workstations", func() {
+			manifest := Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+				{Workstation: Workstation{Name: "lab-01", DockerImage: DefaultDockerImage}},
+			}}
+			Expect(manifest.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"name",
+				Manifest{},
+			},
+			{"Unique constraint failed for: workstations.name",
+				Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+					{Workstation: Workstation{Name: "a", DockerImage: DefaultDockerImage}},
+					{Workstation: Workstation{Name: "a", DockerImage: DefaultDockerImage}},
+				}},
+			},
+			{"docker_image",
+				Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+					{Workstation: Workstation{Name: "a", DockerImage: "ubuntu"}},
+				}},
+			},
+			{"keys",
+				Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+					{Workstation: Workstation{Name: "a", DockerImage: DefaultDockerImage}, Keys: []string{"not a key"}},
+				}},
+			},
+			{"routes",
+				Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+					{Workstation: Workstation{Name: "a", DockerImage: DefaultDockerImage}, Routes: []Route{{Hostname: "a.example.com"}}},
+				}},
+			},
+			{"schedules",
+				Manifest{Name: "lab", Workstations: []ManifestWorkstation{
+					{Workstation: Workstation{Name: "a", DockerImage: DefaultDockerImage, Schedules: []Schedule{{Cron: "whenever", Action: "start"}}}},
+				}},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+})
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	OperationResize  = "resize"
+	OperationCreate  = "create"
+	OperationReplace = "replace"
+
+	OperationInProgressState      = "IN_PROGRESS"
+	OperationPendingApprovalState = "PENDING_APPROVAL"
+	OperationSucceededState       = "SUCCEEDED"
+	OperationFailedState          = "FAILED"
+
+	OperationBackingUpStep        = "backing-up"
+	OperationReplacingStep        = "replacing"
+	OperationRequestingLRPStep    = "requesting-lrp"
+	OperationDownloadingSetupStep = "downloading-setup"
+	OperationStartingStep         = "starting"
+	OperationPendingApprovalStep  = "pending-approval"
+	OperationReadyStep            = "ready"
+	OperationDoneStep             = "done"
+)
+
+// Operation tracks a change to a workstation that takes longer than a request,
+// such as creating it or replacing it with a resized one.
+type Operation struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Workstation   string    `json:"workstation"`
+	State         string    `json:"state"`
+	Step          string    `json:"step"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
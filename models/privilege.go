@@ -0,0 +1,50 @@
+package models
+
+// Privilege policies decide whether workstations run in privileged
+// containers. They are set for the whole deployment and can be overridden by
+// templates.
+const (
+	PrivilegedPolicy    = "privileged"
+	UnprivilegedPolicy  = "unprivileged"
+	AdminApprovedPolicy = "admin-approved"
+)
+
+// Privilege modes of workstations. A workstation pending approval is
+// desired stopped and unprivileged until an admin approves it.
+const (
+	PrivilegedMode      = "privileged"
+	UnprivilegedMode    = "unprivileged"
+	PendingApprovalMode = "pending-approval"
+)
+
+func ValidPrivilegePolicy(policy string) bool {
+	switch policy {
+	case PrivilegedPolicy, UnprivilegedPolicy, AdminApprovedPolicy:
+		return true
+	}
+	return false
+}
+
+// PrivilegeMode is the mode of a workstation under policy. Asking for a
+// privileged workstation fails under the unprivileged policy, and needs an
+// admin's approval under the admin-approved one.
+func PrivilegeMode(policy string, privileged, admin bool) (string, error) {
+	switch policy {
+	case PrivilegedPolicy:
+		return PrivilegedMode, nil
+	case UnprivilegedPolicy:
+		if privileged {
+			return "", ValidationError{ErrInvalidField{"privileged"}}
+		}
+		return UnprivilegedMode, nil
+	case AdminApprovedPolicy:
+		if !privileged {
+			return UnprivilegedMode, nil
+		}
+		if admin {
+			return PrivilegedMode, nil
+		}
+		return PendingApprovalMode, nil
+	}
+	return "", ValidationError{ErrInvalidField{"privilege"}}
+}
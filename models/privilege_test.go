@@ -0,0 +1,42 @@
+package models_test
+
+import (
+	. "github.com/luan/teapot/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrivilegeMode", func() {
+	type modeCase struct {
+		policy     string
+		privileged bool
+		admin      bool
+		mode       string
+	}
+
+	for _, testCase := range []modeCase{
+		{PrivilegedPolicy, false, false, PrivilegedMode},
+		{UnprivilegedPolicy, false, true, UnprivilegedMode},
+		{AdminApprovedPolicy, false, false, UnprivilegedMode},
+		{AdminApprovedPolicy, true, true, PrivilegedMode},
+		{AdminApprovedPolicy, true, false, PendingApprovalMode},
+	} {
+		testCase := testCase
+		It("is "+testCase.mode+" under the "+testCase.policy+" policy", func() {
+			mode, err := PrivilegeMode(testCase.policy, testCase.privileged, testCase.admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(testCase.mode))
+		})
+	}
+
+	It("refuses privilege under the unprivileged policy, even to admins", func() {
+		_, err := PrivilegeMode(UnprivilegedPolicy, true, true)
+		Expect(err).To(Equal(ValidationError{ErrInvalidField{"privileged"}}))
+	})
+
+	It("fails for unknown policies", func() {
+		_, err := PrivilegeMode("root", false, false)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Quota limits the workstations of a user, or of all the members of a team
+// together. Limits left at zero aren't enforced. Memory, disk and privileged
+// workstations only count while workstations are started.
+type Quota struct {
+	MaxWorkstations int `json:"max_workstations,omitempty"`
+	MemoryMB        int `json:"memory_mb,omitempty"`
+	DiskMB          int `json:"disk_mb,omitempty"`
+	MaxPrivileged   int `json:"max_privileged,omitempty"`
+}
+
+type Usage struct {
+	Workstations int `json:"workstations"`
+	MemoryMB     int `json:"memory_mb"`
+	DiskMB       int `json:"disk_mb"`
+	Privileged   int `json:"privileged"`
+}
+
+// Team shares a quota between its members, on top of their own.
+type Team struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	Quota   Quota    `json:"quota"`
+}
+
+// QuotaConfig gives every user the Default quota unless Users has one for
+// them.
+type QuotaConfig struct {
+	Default Quota            `json:"default"`
+	Users   map[string]Quota `json:"users,omitempty"`
+	Teams   []Team           `json:"teams,omitempty"`
+}
+
+func (usage Usage) Add(other Usage) Usage {
+	return Usage{
+		Workstations: usage.Workstations + other.Workstations,
+		MemoryMB:     usage.MemoryMB + other.MemoryMB,
+		DiskMB:       usage.DiskMB + other.DiskMB,
+		Privileged:   usage.Privileged + other.Privileged,
+	}
+}
+
+func (usage Usage) Sub(other Usage) Usage {
+	return usage.Add(Usage{
+		Workstations: -other.Workstations,
+		MemoryMB:     -other.MemoryMB,
+		DiskMB:       -other.DiskMB,
+		Privileged:   -other.Privileged,
+	})
+}
+
+// Exceeded lists the limits usage is over, only looking at those added counts
+// towards so a quota lowered below its usage doesn't block everything else.
+func (quota Quota) Exceeded(usage, added Usage) []string {
+	var limits []string
+	if quota.MaxWorkstations > 0 && added.Workstations > 0 && usage.Workstations > quota.MaxWorkstations {
+		limits = append(limits, "max_workstations")
+	}
+	if quota.MemoryMB > 0 && added.MemoryMB > 0 && usage.MemoryMB > quota.MemoryMB {
+		limits = append(limits, "memory_mb")
+	}
+	if quota.DiskMB > 0 && added.DiskMB > 0 && usage.DiskMB > quota.DiskMB {
+		limits = append(limits, "disk_mb")
+	}
+	if quota.MaxPrivileged > 0 && added.Privileged > 0 && usage.Privileged > quota.MaxPrivileged {
+		limits = append(limits, "max_privileged")
+	}
+	return limits
+}
+
+func (quota Quota) valid() bool {
+	return quota.MaxWorkstations >= 0 && quota.MemoryMB >= 0 && quota.DiskMB >= 0 && quota.MaxPrivileged >= 0
+}
+
+func (config QuotaConfig) Validate() error {
+	var validationError ValidationError
+
+	if !config.Default.valid() {
+		validationError = append(validationError, ErrInvalidField{"default"})
+	}
+
+	for _, quota := range config.Users {
+		if !quota.valid() {
+			validationError = append(validationError, ErrInvalidField{"users"})
+			break
+		}
+	}
+
+	teams := map[string]bool{}
+	for _, team := range config.Teams {
+		if !templateNamePattern.MatchString(team.Name) || !team.Quota.valid() {
+			validationError = append(validationError, ErrInvalidField{"teams"})
+			break
+		}
+		if teams[team.Name] {
+			validationError = append(validationError, ErrDuplicateField{"teams"})
+			break
+		}
+		teams[team.Name] = true
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// ErrQuotaExceeded tells which limits of the quota of a user or team would be
+// exceeded, along with what the usage would be.
+type ErrQuotaExceeded struct {
+	Scope  string
+	Name   string
+	Limits []string
+	Quota  Quota
+	Usage  Usage
+}
+
+func (err ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf(
+		"quota of %s %s exceeded for %s: %d workstations, %d MB of memory, %d MB of disk and %d privileged workstations would be in use",
+		err.Scope, err.Name, strings.Join(err.Limits, ", "),
+		err.Usage.Workstations, err.Usage.MemoryMB, err.Usage.DiskMB, err.Usage.Privileged,
+	)
+}
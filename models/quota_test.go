@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	. "github.com/luan/teapot/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quota", func() {
+	quota := Quota{MaxWorkstations: 2, MemoryMB: 4096, DiskMB: 8192, MaxPrivileged: 1}
+
+	Describe("Exceeded", func() {
+		It("lists the limits the usage is over", func() {
+			usage := Usage{Workstations: 3, MemoryMB: 5120, DiskMB: 8192, Privileged: 2}
+			added := Usage{Workstations: 1, MemoryMB: 1024, DiskMB: 1024, Privileged: 1}
+			Expect(quota.Exceeded(usage, added)).To(Equal([]string{"max_workstations", "memory_mb", "max_privileged"}))
+		})
+
+		It("ignores the limits nothing was added to", func() {
+			usage := Usage{Workstations: 3, MemoryMB: 5120}
+			Expect(quota.Exceeded(usage, Usage{MemoryMB: -1024})).To(BeEmpty())
+		})
+
+		It("doesn't enforce limits of zero", func() {
+			usage := Usage{Workstations: 30, MemoryMB: 50000}
+			Expect(Quota{}.Exceeded(usage, usage)).To(BeEmpty())
+		})
+	})
+
+	Describe("QuotaConfig", func() {
+		It("is valid", func() {
+			config := QuotaConfig{
+				Default: quota,
+				Users:   map[string]Quota{"alice": {MaxWorkstations: 5}},
+				Teams:   []Team{{Name: "platform", Members: []string{"alice"}, Quota: quota}},
+			}
+			Expect(config.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"default",
+				QuotaConfig{Default: Quota{MemoryMB: -1}},
+			},
+			{"users",
+				QuotaConfig{Users: map[string]Quota{"alice": {MaxWorkstations: -1}}},
+			},
+			{"teams",
+				QuotaConfig{Teams: []Team{{Name: "a b"}}},
+			},
+			{"teams",
+				QuotaConfig{Teams: []Team{{Name: "a"}, {Name: "a"}}},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+})
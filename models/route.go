@@ -0,0 +1,48 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/luan/teapot"
+)
+
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+type Route struct {
+	Hostname string `json:"hostname"`
+	Port     uint16 `json:"port"`
+}
+
+func NewRoute(request teapot.RouteCreateRequest) Route {
+	return Route{
+		Hostname: strings.ToLower(request.Hostname),
+		Port:     request.Port,
+	}
+}
+
+func (route Route) Validate() error {
+	var validationError ValidationError
+
+	if len(route.Hostname) > 253 || !hostnamePattern.MatchString(route.Hostname) {
+		validationError = append(validationError, ErrInvalidField{"hostname"})
+	}
+
+	if route.Port == 0 {
+		validationError = append(validationError, ErrInvalidField{"port"})
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// ValidateDomain checks that the hostname is under the apps domain, the only
+// one teapot may hand out hostnames in.
+func (route Route) ValidateDomain(appsDomain string) error {
+	if !strings.HasSuffix(route.Hostname, "."+appsDomain) {
+		return ValidationError{ErrInvalidField{"hostname"}}
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package models
 
+import "strconv"
+
 //go:generate counterfeiter -o fakes/fake_route_provider.go . RouteProvider
 type RouteProvider interface {
+	AppsDomain() string
 	TiegoRoute(name string) string
 	SSHRoute(name string) string
+	PortRoute(name string, port uint16) string
 }
 
 type routeProvider struct {
@@ -16,6 +20,10 @@ func NewRouteProvider(appsDomain string) *routeProvider {
 	}
 }
 
+func (rp *routeProvider) AppsDomain() string {
+	return rp.appsDomain
+}
+
 func (rp *routeProvider) TiegoRoute(name string) string {
 	return "tiego-" + name + "." + rp.appsDomain
 }
@@ -23,3 +31,7 @@ func (rp *routeProvider) TiegoRoute(name string) string {
 func (rp *routeProvider) SSHRoute(name string) string {
 	return "ssh-" + name + "." + rp.appsDomain
 }
+
+func (rp *routeProvider) PortRoute(name string, port uint16) string {
+	return strconv.Itoa(int(port)) + "-" + name + "." + rp.appsDomain
+}
@@ -0,0 +1,56 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Route", func() {
+	Describe("NewRoute", func() {
+		It("lowercases the hostname", func() {
+			route := NewRoute(teapot.RouteCreateRequest{Hostname: "Preview.Example.com", Port: 4000})
+			Expect(route).To(Equal(Route{Hostname: "preview.example.com", Port: 4000}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("is valid with a hostname and a port", func() {
+			Expect(Route{Hostname: "my-app.example.com", Port: 4000}.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"hostname",
+				Route{Port: 4000},
+			},
+			{"hostname",
+				Route{Hostname: "-app.example.com", Port: 4000},
+			},
+			{"hostname",
+				Route{Hostname: "app..example.com", Port: 4000},
+			},
+			{"hostname",
+				Route{Hostname: "app.example.com/path", Port: 4000},
+			},
+			{"port",
+				Route{Hostname: "app.example.com"},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+
+	Describe("ValidateDomain", func() {
+		It("is valid with a hostname under the apps domain", func() {
+			Expect(Route{Hostname: "my-app.example.com", Port: 4000}.ValidateDomain("example.com")).NotTo(HaveOccurred())
+		})
+
+		It("is invalid with a hostname outside the apps domain", func() {
+			Expect(Route{Hostname: "my-app.example.org", Port: 4000}.ValidateDomain("example.com")).To(HaveOccurred())
+			Expect(Route{Hostname: "example.com", Port: 4000}.ValidateDomain("example.com")).To(HaveOccurred())
+			Expect(Route{Hostname: "notexample.com", Port: 4000}.ValidateDomain("example.com")).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/luan/teapot"
+)
+
+const (
+	ScheduleStartAction  = "start"
+	ScheduleStopAction   = "stop"
+	ScheduleExecAction   = "exec"
+	ScheduleBackupAction = "backup"
+)
+
+type Schedule struct {
+	ID       string `json:"id"`
+	Cron     string `json:"cron"`
+	Action   string `json:"action"`
+	Command  string `json:"command,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+func NewSchedule(request teapot.ScheduleCreateRequest) Schedule {
+	return Schedule{
+		Cron:     request.Cron,
+		Action:   request.Action,
+		Command:  request.Command,
+		Location: request.Location,
+	}
+}
+
+func (schedule Schedule) Validate() error {
+	var validationError ValidationError
+
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		validationError = append(validationError, ErrInvalidField{"cron"})
+	}
+
+	switch schedule.Action {
+	case ScheduleStartAction, ScheduleStopAction, ScheduleBackupAction:
+	case ScheduleExecAction:
+		if len(strings.TrimSpace(schedule.Command)) == 0 {
+			validationError = append(validationError, ErrInvalidField{"command"})
+		}
+	default:
+		validationError = append(validationError, ErrInvalidField{"action"})
+	}
+
+	if _, err := time.LoadLocation(schedule.Location); err != nil {
+		validationError = append(validationError, ErrInvalidField{"location"})
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// Due reports whether the schedule fires during the minute of t, evaluated in
+// the schedule's location (UTC when unset).
+func (schedule Schedule) Due(t time.Time) bool {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return false
+	}
+
+	location, err := time.LoadLocation(schedule.Location)
+	if err != nil {
+		return false
+	}
+
+	return cron.Matches(t.In(location))
+}
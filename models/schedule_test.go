@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Schedule", func() {
+	Describe("Validate", func() {
+		It("is valid for start and stop schedules", func() {
+			Expect(Schedule{Cron: "0 8 * * 1-5", Action: ScheduleStartAction}.Validate()).NotTo(HaveOccurred())
+			Expect(Schedule{Cron: "0 19 * * 1-5", Action: ScheduleStopAction}.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("is valid for exec schedules with a command", func() {
+			schedule := Schedule{Cron: "0 * * * *", Action: ScheduleExecAction, Command: "make"}
+			Expect(schedule.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"cron",
+				Schedule{Cron: "daily", Action: ScheduleStartAction},
+			},
+			{"action",
+				Schedule{Cron: "* * * * *", Action: "reboot"},
+			},
+			{"command",
+				Schedule{Cron: "* * * * *", Action: ScheduleExecAction},
+			},
+			{"location",
+				Schedule{Cron: "* * * * *", Action: ScheduleStopAction, Location: "Mars/Olympus_Mons"},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+
+	Describe("Due", func() {
+		It("evaluates the cron expression in UTC by default", func() {
+			schedule := Schedule{Cron: "0 8 * * *", Action: ScheduleStartAction}
+			Expect(schedule.Due(time.Date(2015, 3, 2, 8, 0, 0, 0, time.UTC))).To(BeTrue())
+			Expect(schedule.Due(time.Date(2015, 3, 2, 9, 0, 0, 0, time.UTC))).To(BeFalse())
+		})
+
+		It("evaluates the cron expression in the schedule's location", func() {
+			location, err := time.LoadLocation("America/Sao_Paulo")
+			Expect(err).NotTo(HaveOccurred())
+
+			schedule := Schedule{Cron: "0 8 * * *", Action: ScheduleStartAction, Location: "America/Sao_Paulo"}
+			Expect(schedule.Due(time.Date(2015, 3, 2, 8, 0, 0, 0, location))).To(BeTrue())
+			Expect(schedule.Due(time.Date(2015, 3, 2, 8, 0, 0, 0, time.UTC))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/luan/teapot"
+)
+
+// Secret is a value kept encrypted by teapot and given to the workstations
+// that reference it as the environment variable Name. Value is never
+// serialized, so it can't end up in responses or logs.
+type Secret struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner,omitempty"`
+	Value     string    `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewSecret(name, owner string, request teapot.SecretRequest) Secret {
+	return Secret{
+		Name:  name,
+		Owner: owner,
+		Value: request.Value,
+	}
+}
+
+func (secret Secret) Validate() error {
+	var validationError ValidationError
+
+	if !envNamePattern.MatchString(secret.Name) {
+		validationError = append(validationError, ErrInvalidField{"name"})
+	}
+
+	if secret.Value == "" {
+		validationError = append(validationError, ErrInvalidField{"value"})
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
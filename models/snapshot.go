@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	SnapshotPendingState  = "PENDING"
+	SnapshotCompleteState = "COMPLETE"
+	SnapshotFailedState   = "FAILED"
+)
+
+// Snapshot is a tarball of a workstation's home directory kept in the backup
+// blobstore.
+type Snapshot struct {
+	ID            string    `json:"id"`
+	Workstation   string    `json:"workstation"`
+	Owner         string    `json:"owner,omitempty"`
+	State         string    `json:"state"`
+	Size          int64     `json:"size"`
+	CreatedAt     time.Time `json:"created_at"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+}
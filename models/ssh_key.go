@@ -0,0 +1,83 @@
+package models
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+var sshKeyTypes = map[string]bool{
+	"ssh-rsa":             true,
+	"ssh-dss":             true,
+	"ssh-ed25519":         true,
+	"ecdsa-sha2-nistp256": true,
+	"ecdsa-sha2-nistp384": true,
+	"ecdsa-sha2-nistp521": true,
+}
+
+type SSHKey struct {
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type"`
+	Comment     string `json:"comment,omitempty"`
+	Key         string `json:"key"`
+}
+
+// ParseSSHKey parses a single OpenSSH authorized_keys line, optionally
+// prefixed with options, and computes its MD5 fingerprint in the colon
+// separated hex format printed by `ssh-keygen -l -E md5`.
+func ParseSSHKey(line string) (SSHKey, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.ContainsAny(line, "\r\n") {
+		return SSHKey{}, ErrInvalidField{"key"}
+	}
+
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if !sshKeyTypes[field] || i+1 >= len(fields) {
+			continue
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil || sshKeyBlobType(blob) != field {
+			return SSHKey{}, ErrInvalidField{"key"}
+		}
+
+		return SSHKey{
+			Fingerprint: md5Fingerprint(blob),
+			Type:        field,
+			Comment:     strings.Join(fields[i+2:], " "),
+			Key:         line,
+		}, nil
+	}
+
+	return SSHKey{}, ErrInvalidField{"key"}
+}
+
+func sshKeyBlobType(blob []byte) string {
+	if len(blob) < 4 {
+		return ""
+	}
+
+	length := binary.BigEndian.Uint32(blob)
+	if uint32(len(blob)-4) < length {
+		return ""
+	}
+
+	return string(blob[4 : 4+length])
+}
+
+func md5Fingerprint(blob []byte) string {
+	sum := md5.Sum(blob)
+
+	var buffer bytes.Buffer
+	for i, b := range sum {
+		if i > 0 {
+			buffer.WriteString(":")
+		}
+		fmt.Fprintf(&buffer, "%02x", b)
+	}
+	return buffer.String()
+}
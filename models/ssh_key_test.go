@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("SSHKey", func() {
+	const ed25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHWEf4+56yLRUuMjH57WdqKF6pgB0cB+oAyLag6FheTj user@example.com"
+	const rsaKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDE7ZwhGIsL9ILBhIevpaApaXSB/rgyZHTC466PHKF+6B4zbO3DTxfdHrz7CVjFcgkv9NC/UEimQmrZOKDhMkAv+EnUP2JyMpGBpxZnuq2/5aZeBgdQuCxbwRx6AzrtyYW7M6FVGGyKPOiWJ6D+a2RQgaHw62K7hwXpoxv1pgMvyw=="
+
+	Describe("ParseSSHKey", func() {
+		It("parses the type, comment and fingerprint", func() {
+			key, err := ParseSSHKey(ed25519Key + "\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(Equal(SSHKey{
+				Fingerprint: "21:07:6a:ee:20:2a:fa:2b:4e:87:ba:57:10:ed:35:c9",
+				Type:        "ssh-ed25519",
+				Comment:     "user@example.com",
+				Key:         ed25519Key,
+			}))
+		})
+
+		It("parses keys without a comment", func() {
+			key, err := ParseSSHKey(rsaKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.Fingerprint).To(Equal("53:c8:41:a2:eb:cb:18:53:4e:82:ec:00:bc:a5:42:bb"))
+			Expect(key.Comment).To(BeEmpty())
+		})
+
+		It("accepts authorized_keys options", func() {
+			key, err := ParseSSHKey(`no-port-forwarding,command="ls" ` + rsaKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.Type).To(Equal("ssh-rsa"))
+		})
+
+		for _, invalid := range []string{
+			"",
+			"a-key",
+			"ssh-rsa",
+			"ssh-rsa not-base64!",
+			"ssh-dss AAAAC3NzaC1lZDI1NTE5AAAAIHWEf4+56yLRUuMjH57WdqKF6pgB0cB+oAyLag6FheTj",
+			ed25519Key + "\n" + rsaKey,
+		} {
+			invalid := invalid
+			It("rejects '"+invalid+"'", func() {
+				_, err := ParseSSHKey(invalid)
+				Expect(err).To(MatchError(ErrInvalidField{"key"}))
+			})
+		}
+	})
+})
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"regexp"
+
+	"github.com/luan/teapot"
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[\w-.]+$`)
+	envNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Template is a named preset of workstation fields. Fields left empty are up
+// to the create request.
+type Template struct {
+	Name        string            `json:"name"`
+	DockerImage string            `json:"docker_image,omitempty"`
+	CPUWeight   uint              `json:"cpu_weight,omitempty"`
+	DiskMB      int               `json:"disk_mb,omitempty"`
+	MemoryMB    int               `json:"memory_mb,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Ports       []uint16          `json:"ports,omitempty"`
+	Setup       []string          `json:"setup,omitempty"`
+
+	EgressPolicy string `json:"egress_policy,omitempty"`
+
+	// Privilege is the privilege policy of workstations created from the
+	// template, overriding the deployment's.
+	Privilege string `json:"privilege,omitempty"`
+}
+
+func NewTemplate(name string, request teapot.TemplateRequest) Template {
+	return Template{
+		Name:        name,
+		DockerImage: request.DockerImage,
+		CPUWeight:   request.CPUWeight,
+		DiskMB:      request.DiskMB,
+		MemoryMB:    request.MemoryMB,
+		Env:         request.Env,
+		Ports:       request.Ports,
+		Setup:       request.Setup,
+
+		EgressPolicy: request.EgressPolicy,
+		Privilege:    request.Privilege,
+	}
+}
+
+func (template Template) Validate() error {
+	var validationError ValidationError
+
+	if !templateNamePattern.MatchString(template.Name) {
+		validationError = append(validationError, ErrInvalidField{"name"})
+	}
+
+	if template.DockerImage != "" && !validDockerImage(template.DockerImage) {
+		validationError = append(validationError, ErrInvalidField{"docker_image"})
+	}
+
+	if !validEnv(template.Env) {
+		validationError = append(validationError, ErrInvalidField{"env"})
+	}
+
+	if template.EgressPolicy != "" && !templateNamePattern.MatchString(template.EgressPolicy) {
+		validationError = append(validationError, ErrInvalidField{"egress_policy"})
+	}
+
+	if template.Privilege != "" && !ValidPrivilegePolicy(template.Privilege) {
+		validationError = append(validationError, ErrInvalidField{"privilege"})
+	}
+
+	for _, port := range template.Ports {
+		if port == 0 {
+			validationError = append(validationError, ErrInvalidField{"ports"})
+			break
+		}
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// Apply fills in the fields the request leaves empty from the template. Env
+// variables of the request override the template's, and its setup scripts
+// run after the template's.
+func (template Template) Apply(request teapot.WorkstationCreateRequest) teapot.WorkstationCreateRequest {
+	if request.DockerImage == "" {
+		request.DockerImage = template.DockerImage
+	}
+	if request.CPUWeight == 0 {
+		request.CPUWeight = template.CPUWeight
+	}
+	if request.DiskMB == 0 {
+		request.DiskMB = template.DiskMB
+	}
+	if request.MemoryMB == 0 {
+		request.MemoryMB = template.MemoryMB
+	}
+	if len(request.Ports) == 0 {
+		request.Ports = template.Ports
+	}
+	if request.EgressPolicy == "" {
+		request.EgressPolicy = template.EgressPolicy
+	}
+
+	env := map[string]string{}
+	for name, value := range template.Env {
+		env[name] = value
+	}
+	for name, value := range request.Env {
+		env[name] = value
+	}
+	if len(env) > 0 {
+		request.Env = env
+	}
+
+	request.Setup = append(append([]string{}, template.Setup...), request.Setup...)
+	if len(request.Setup) == 0 {
+		request.Setup = nil
+	}
+
+	return request
+}
+
+func validEnv(env map[string]string) bool {
+	for name := range env {
+		if !envNamePattern.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
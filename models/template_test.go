@@ -0,0 +1,92 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/luan/teapot"
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Template", func() {
+	template := Template{
+		Name:        "go-large",
+		DockerImage: "docker:///golang#1.3.3",
+		CPUWeight:   4,
+		DiskMB:      4096,
+		MemoryMB:    2048,
+		Env:         map[string]string{"GOPATH": "/home/vcap/go", "EDITOR": "vim"},
+		Ports:       []uint16{4000},
+		Setup:       []string{"go get github.com/tools/godep"},
+
+		EgressPolicy: "internet-only",
+	}
+
+	Describe("Validate", func() {
+		It("is valid with only a name", func() {
+			Expect(Template{Name: "empty"}.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("is valid with every field set", func() {
+			Expect(template.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"name",
+				Template{},
+			},
+			{"name",
+				Template{Name: "a b"},
+			},
+			{"docker_image",
+				Template{Name: "a", DockerImage: "ubuntu"},
+			},
+			{"env",
+				Template{Name: "a", Env: map[string]string{"1ABC": "x"}},
+			},
+			{"ports",
+				Template{Name: "a", Ports: []uint16{0}},
+			},
+			{"egress_policy",
+				Template{Name: "a", EgressPolicy: "no policy"},
+			},
+			{"privilege",
+				Template{Name: "a", Privilege: "root"},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+
+	Describe("Apply", func() {
+		It("fills in the fields the request leaves empty", func() {
+			request := template.Apply(teapot.WorkstationCreateRequest{Name: "w1", Template: "go-large"})
+			Expect(request).To(Equal(teapot.WorkstationCreateRequest{
+				Name:        "w1",
+				Template:    "go-large",
+				DockerImage: "docker:///golang#1.3.3",
+				CPUWeight:   4,
+				DiskMB:      4096,
+				MemoryMB:    2048,
+				Env:         map[string]string{"GOPATH": "/home/vcap/go", "EDITOR": "vim"},
+				Ports:       []uint16{4000},
+				Setup:       []string{"go get github.com/tools/godep"},
+
+				EgressPolicy: "internet-only",
+			}))
+		})
+
+		It("keeps the fields set in the request", func() {
+			request := template.Apply(teapot.WorkstationCreateRequest{
+				MemoryMB: 512,
+				Env:      map[string]string{"EDITOR": "emacs"},
+				Setup:    []string{"make"},
+			})
+
+			Expect(request.MemoryMB).To(Equal(512))
+			Expect(request.DiskMB).To(Equal(4096))
+			Expect(request.Env).To(Equal(map[string]string{"GOPATH": "/home/vcap/go", "EDITOR": "emacs"}))
+			Expect(request.Setup).To(Equal([]string{"go get github.com/tools/godep", "make"}))
+		})
+	})
+})
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	UsageByUser  = "user"
+	UsageByTeam  = "team"
+	UsageByImage = "image"
+
+	UsageDayFormat = "2006-01-02"
+)
+
+// RunningInterval is a stretch of time a workstation ran for, with what it
+// was allocated meanwhile. Team is the owner's when it started, the first one
+// they are a member of. StoppedAt is nil while it's still running.
+type RunningInterval struct {
+	Workstation string     `json:"workstation"`
+	Owner       string     `json:"owner,omitempty"`
+	Team        string     `json:"team,omitempty"`
+	DockerImage string     `json:"docker_image"`
+	CPUWeight   uint       `json:"cpu_weight"`
+	DiskMB      int        `json:"disk_mb"`
+	MemoryMB    int        `json:"memory_mb"`
+	StartedAt   time.Time  `json:"started_at"`
+	StoppedAt   *time.Time `json:"stopped_at,omitempty"`
+}
+
+func NewRunningInterval(workstation Workstation, team string, startedAt time.Time) RunningInterval {
+	return RunningInterval{
+		Workstation: workstation.Name,
+		Owner:       workstation.Owner,
+		Team:        team,
+		DockerImage: workstation.DockerImage,
+		CPUWeight:   workstation.CPUWeight,
+		DiskMB:      workstation.DiskMB,
+		MemoryMB:    workstation.MemoryMB,
+		StartedAt:   startedAt,
+	}
+}
+
+// UsageRecord is what a group of workstations used during a day, in UTC.
+// Hours are workstation-hours, and the resource hours are the allocations
+// multiplied by the hours they were held for.
+type UsageRecord struct {
+	Day            string  `json:"day"`
+	GroupBy        string  `json:"group_by"`
+	Group          string  `json:"group"`
+	Workstations   int     `json:"workstations"`
+	Hours          float64 `json:"hours"`
+	MemoryMBHours  float64 `json:"memory_mb_hours"`
+	DiskMBHours    float64 `json:"disk_mb_hours"`
+	CPUWeightHours float64 `json:"cpu_weight_hours"`
+}
+
+func ValidUsageGroupBy(groupBy string) bool {
+	return groupBy == UsageByUser || groupBy == UsageByTeam || groupBy == UsageByImage
+}
+
+// DailyUsage is what a group of workstations used during a UTC day, summed
+// up in seconds so days rolled up at different times can be added together.
+// Workstations are the names of the ones that ran, counted once.
+type DailyUsage struct {
+	Day              string   `json:"day"`
+	GroupBy          string   `json:"group_by"`
+	Group            string   `json:"group"`
+	Workstations     []string `json:"workstations"`
+	Seconds          float64  `json:"seconds"`
+	MemoryMBSeconds  float64  `json:"memory_mb_seconds"`
+	DiskMBSeconds    float64  `json:"disk_mb_seconds"`
+	CPUWeightSeconds float64  `json:"cpu_weight_seconds"`
+}
+
+// Add sums other, which must be of the same day and group, into usage.
+func (usage *DailyUsage) Add(other DailyUsage) {
+	for _, name := range other.Workstations {
+		if !containsString(usage.Workstations, name) {
+			usage.Workstations = append(usage.Workstations, name)
+		}
+	}
+	usage.Seconds += other.Seconds
+	usage.MemoryMBSeconds += other.MemoryMBSeconds
+	usage.DiskMBSeconds += other.DiskMBSeconds
+	usage.CPUWeightSeconds += other.CPUWeightSeconds
+}
+
+func (usage DailyUsage) Record() UsageRecord {
+	return UsageRecord{
+		Day:            usage.Day,
+		GroupBy:        usage.GroupBy,
+		Group:          usage.Group,
+		Workstations:   len(usage.Workstations),
+		Hours:          hours(usage.Seconds),
+		MemoryMBHours:  hours(usage.MemoryMBSeconds),
+		DiskMBHours:    hours(usage.DiskMBSeconds),
+		CPUWeightHours: hours(usage.CPUWeightSeconds),
+	}
+}
+
+// SplitUsage splits the intervals into UTC days between from and to and sums
+// them up per day and group. Intervals still running are counted up to now.
+func SplitUsage(intervals []RunningInterval, groupBy string, from, to, now time.Time) []DailyUsage {
+	type key struct{ day, group string }
+
+	totals := map[key]*DailyUsage{}
+	for _, interval := range intervals {
+		start := interval.StartedAt
+		end := now
+		if interval.StoppedAt != nil {
+			end = *interval.StoppedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		for start.Before(end) {
+			day := start.UTC().Truncate(24 * time.Hour)
+			dayEnd := day.Add(24 * time.Hour)
+			if dayEnd.After(end) {
+				dayEnd = end
+			}
+
+			k := key{day.Format(UsageDayFormat), interval.group(groupBy)}
+			usage, ok := totals[k]
+			if !ok {
+				usage = &DailyUsage{Day: k.day, GroupBy: groupBy, Group: k.group}
+				totals[k] = usage
+			}
+
+			seconds := dayEnd.Sub(start).Seconds()
+			usage.Add(DailyUsage{
+				Workstations:     []string{interval.Workstation},
+				Seconds:          seconds,
+				MemoryMBSeconds:  seconds * float64(interval.MemoryMB),
+				DiskMBSeconds:    seconds * float64(interval.DiskMB),
+				CPUWeightSeconds: seconds * float64(interval.CPUWeight),
+			})
+
+			start = dayEnd
+		}
+	}
+
+	usages := []DailyUsage{}
+	for _, usage := range totals {
+		usages = append(usages, *usage)
+	}
+	return usages
+}
+
+// UsageRecords adds up the daily usages of the same day and group into
+// records, sorted by day then group.
+func UsageRecords(usages []DailyUsage) []UsageRecord {
+	type key struct{ day, group string }
+
+	totals := map[key]*DailyUsage{}
+	for _, usage := range usages {
+		k := key{usage.Day, usage.Group}
+		total, ok := totals[k]
+		if !ok {
+			total = &DailyUsage{Day: usage.Day, GroupBy: usage.GroupBy, Group: usage.Group}
+			totals[k] = total
+		}
+		total.Add(usage)
+	}
+
+	records := []UsageRecord{}
+	for _, total := range totals {
+		records = append(records, total.Record())
+	}
+
+	sort.Sort(usageRecordsByDay(records))
+	return records
+}
+
+// AggregateUsage splits the intervals into UTC days between from and to,
+// which should be midnights, and sums them up per day and group. Intervals
+// still running are counted up to now.
+func AggregateUsage(intervals []RunningInterval, groupBy string, from, to, now time.Time) []UsageRecord {
+	return UsageRecords(SplitUsage(intervals, groupBy, from, to, now))
+}
+
+func (interval RunningInterval) group(groupBy string) string {
+	switch groupBy {
+	case UsageByTeam:
+		return interval.Team
+	case UsageByImage:
+		return interval.DockerImage
+	default:
+		return interval.Owner
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hours converts seconds to hours, rounded to four decimals.
+func hours(seconds float64) float64 {
+	return math.Floor(seconds/3600*10000+0.5) / 10000
+}
+
+type usageRecordsByDay []UsageRecord
+
+func (records usageRecordsByDay) Len() int { return len(records) }
+func (records usageRecordsByDay) Less(i, j int) bool {
+	if records[i].Day != records[j].Day {
+		return records[i].Day < records[j].Day
+	}
+	return records[i].Group < records[j].Group
+}
+func (records usageRecordsByDay) Swap(i, j int) { records[i], records[j] = records[j], records[i] }
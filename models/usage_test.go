@@ -0,0 +1,73 @@
+package models_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Usage", func() {
+	Describe("AggregateUsage", func() {
+		var (
+			intervals []RunningInterval
+			from, to  time.Time
+			now       time.Time
+		)
+
+		at := func(day, hour int) time.Time {
+			return time.Date(2015, time.March, day, hour, 0, 0, 0, time.UTC)
+		}
+
+		stopped := func(t time.Time) *time.Time {
+			return &t
+		}
+
+		BeforeEach(func() {
+			from = at(1, 0)
+			to = at(4, 0)
+			now = at(3, 12)
+
+			intervals = []RunningInterval{
+				{Workstation: "w1", Owner: "alice", Team: "core", DockerImage: "docker:///ubuntu", MemoryMB: 1024, DiskMB: 2048, CPUWeight: 2, StartedAt: at(1, 20), StoppedAt: stopped(at(2, 2))},
+				{Workstation: "w1", Owner: "alice", Team: "core", DockerImage: "docker:///ubuntu", MemoryMB: 2048, DiskMB: 2048, CPUWeight: 2, StartedAt: at(2, 8), StoppedAt: stopped(at(2, 9))},
+				{Workstation: "w2", Owner: "bob", Team: "core", DockerImage: "docker:///golang", MemoryMB: 512, DiskMB: 1024, CPUWeight: 1, StartedAt: at(3, 6)},
+			}
+		})
+
+		It("splits the intervals into days, by user", func() {
+			Expect(AggregateUsage(intervals, UsageByUser, from, to, now)).To(Equal([]UsageRecord{
+				{Day: "2015-03-01", GroupBy: "user", Group: "alice", Workstations: 1, Hours: 4, MemoryMBHours: 4096, DiskMBHours: 8192, CPUWeightHours: 8},
+				{Day: "2015-03-02", GroupBy: "user", Group: "alice", Workstations: 1, Hours: 3, MemoryMBHours: 4096, DiskMBHours: 6144, CPUWeightHours: 6},
+				{Day: "2015-03-03", GroupBy: "user", Group: "bob", Workstations: 1, Hours: 6, MemoryMBHours: 3072, DiskMBHours: 6144, CPUWeightHours: 6},
+			}))
+		})
+
+		It("groups by team", func() {
+			records := AggregateUsage(intervals, UsageByTeam, from, to, now)
+			Expect(records).To(HaveLen(3))
+			Expect(records[2].Group).To(Equal("core"))
+		})
+
+		It("groups by image", func() {
+			records := AggregateUsage(intervals, UsageByImage, at(3, 0), to, now)
+			Expect(records).To(Equal([]UsageRecord{
+				{Day: "2015-03-03", GroupBy: "image", Group: "docker:///golang", Workstations: 1, Hours: 6, MemoryMBHours: 3072, DiskMBHours: 6144, CPUWeightHours: 6},
+			}))
+		})
+
+		It("only counts the time between from and to", func() {
+			records := AggregateUsage(intervals, UsageByUser, at(2, 0), at(3, 0), now)
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Day).To(Equal("2015-03-02"))
+			Expect(records[0].Hours).To(Equal(3.0))
+		})
+
+		It("counts the workstations that ran during the day once", func() {
+			records := AggregateUsage(intervals, UsageByTeam, at(2, 0), at(3, 0), now)
+			Expect(records[0].Workstations).To(Equal(1))
+		})
+	})
+})
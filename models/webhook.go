@@ -0,0 +1,133 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	"github.com/luan/teapot"
+)
+
+const (
+	WorkstationCreatedEvent = "created"
+	WorkstationRunningEvent = "running"
+	WorkstationCrashedEvent = "crashed"
+	WorkstationStoppedEvent = "stopped"
+	WorkstationDeletedEvent = "deleted"
+
+	DeliveryPendingState   = "PENDING"
+	DeliveryDeliveredState = "DELIVERED"
+	DeliveryFailedState    = "FAILED"
+)
+
+var WorkstationEvents = []string{
+	WorkstationCreatedEvent,
+	WorkstationRunningEvent,
+	WorkstationCrashedEvent,
+	WorkstationStoppedEvent,
+	WorkstationDeletedEvent,
+}
+
+// Webhook subscribes URL to the workstation events named in Events, or to all
+// of them when it's empty. Payloads are signed with Secret, which is never
+// sent back.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events,omitempty"`
+	Secret    string    `json:"-"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhook(createdBy string, request teapot.WebhookRequest) Webhook {
+	return Webhook{
+		URL:       request.URL,
+		Events:    request.Events,
+		Secret:    request.Secret,
+		CreatedBy: createdBy,
+	}
+}
+
+func (webhook Webhook) Validate() error {
+	var validationError ValidationError
+
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		validationError = append(validationError, ErrInvalidField{"url"})
+	}
+
+	for _, event := range webhook.Events {
+		if !validWorkstationEvent(event) {
+			validationError = append(validationError, ErrInvalidField{"events"})
+			break
+		}
+	}
+
+	if webhook.Secret == "" {
+		validationError = append(validationError, ErrInvalidField{"secret"})
+	}
+
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+func (webhook Webhook) Subscribes(event string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex HMAC-SHA256 of payload keyed with the secret, which
+// receivers compute again to check a delivery came from teapot.
+func (webhook Webhook) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validWorkstationEvent(event string) bool {
+	for _, valid := range WorkstationEvents {
+		if event == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkstationEvent is the payload POSTed to webhooks. CrashCount is only set
+// on crashed events.
+type WorkstationEvent struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"event"`
+	Workstation string    `json:"workstation"`
+	CrashCount  int       `json:"crash_count,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// Delivery is an event being sent to a webhook. Failed attempts are retried
+// at NextAttemptAt, backing off exponentially, until the delivery is given up
+// on and dead-lettered in the FAILED state.
+type Delivery struct {
+	ID             string           `json:"id"`
+	WebhookID      string           `json:"webhook_id"`
+	Event          WorkstationEvent `json:"event"`
+	State          string           `json:"state"`
+	Attempts       int              `json:"attempts"`
+	ResponseStatus int              `json:"response_status,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	LastAttemptAt  *time.Time       `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/luan/teapot/models"
+)
+
+var _ = Describe("Webhook", func() {
+	webhook := Webhook{
+		URL:    "https://billing.example.com/teapot",
+		Events: []string{WorkstationRunningEvent, WorkstationStoppedEvent},
+		Secret: "s3cret",
+	}
+
+	Describe("Validate", func() {
+		It("is valid with every field set", func() {
+			Expect(webhook.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("is valid without events", func() {
+			Expect(Webhook{URL: "http://slack-bot:8080/hook", Secret: "s3cret"}.Validate()).NotTo(HaveOccurred())
+		})
+
+		for _, testCase := range []ValidatorErrorCase{
+			{"url",
+				Webhook{Secret: "s3cret"},
+			},
+			{"url",
+				Webhook{URL: "ftp://example.com", Secret: "s3cret"},
+			},
+			{"url",
+				Webhook{URL: "/hook", Secret: "s3cret"},
+			},
+			{"events",
+				Webhook{URL: "http://example.com", Events: []string{"exploded"}, Secret: "s3cret"},
+			},
+			{"secret",
+				Webhook{URL: "http://example.com"},
+			},
+		} {
+			testValidatorErrorCase(testCase)
+		}
+	})
+
+	Describe("Subscribes", func() {
+		It("subscribes to the events listed", func() {
+			Expect(webhook.Subscribes(WorkstationRunningEvent)).To(BeTrue())
+			Expect(webhook.Subscribes(WorkstationCrashedEvent)).To(BeFalse())
+		})
+
+		It("subscribes to every event when none are listed", func() {
+			for _, event := range WorkstationEvents {
+				Expect(Webhook{}.Subscribes(event)).To(BeTrue())
+			}
+		})
+	})
+
+	Describe("Sign", func() {
+		It("is the hex HMAC-SHA256 of the payload", func() {
+			Expect(Webhook{Secret: "key"}.Sign([]byte("The quick brown fox jumps over the lazy dog"))).To(Equal("f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"))
+		})
+	})
+})
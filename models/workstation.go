@@ -19,11 +19,57 @@ type Workstation struct {
 	CPUWeight   uint   `json:"cpu_weight"`
 	DiskMB      int    `json:"disk_mb"`
 	MemoryMB    int    `json:"memory_mb"`
+	Owner       string `json:"owner,omitempty"`
+
+	// Ports are exposed in addition to the TEA's and the tiego ports, so they
+	// can be routed to later on.
+	Ports     []uint16   `json:"ports,omitempty"`
+	Schedules []Schedule `json:"schedules,omitempty"`
+
+	Env map[string]string `json:"env,omitempty"`
+
+	// Secrets names the owner's secrets set as environment variables, and
+	// SecretEnv holds their values while the workstation is being created.
+	Secrets   []string          `json:"secrets,omitempty"`
+	SecretEnv map[string]string `json:"-"`
+
+	// Setup scripts run with bash before the workstation starts.
+	Setup []string `json:"setup,omitempty"`
+
+	// Template is the preset the workstation was created from, if any.
+	Template string `json:"template,omitempty"`
+
+	// Manifest names the manifest managing the workstation, if any.
+	Manifest string `json:"manifest,omitempty"`
+
+	// RestoreFrom is the snapshot still waiting to be restored, if any.
+	RestoreFrom string `json:"restore_from,omitempty"`
+
+	// EgressPolicy names the policy the workstation's egress rules come from,
+	// the default one when empty. EgressRules are the rules it was desired
+	// with.
+	EgressPolicy string              `json:"egress_policy,omitempty"`
+	EgressRules  []teapot.EgressRule `json:"-"`
+
+	// Privilege is the workstation's privilege mode and PrivilegeApprovedBy
+	// the admin who let it run privileged, if one had to.
+	Privilege           string `json:"privilege,omitempty"`
+	PrivilegeApprovedBy string `json:"privilege_approved_by,omitempty"`
+
+	// PlacementError tells why Diego couldn't place the workstation on a
+	// cell, while it's UNCLAIMED.
+	PlacementError string `json:"placement_error,omitempty"`
+
+	// Crashes is what's known of the workstation's crashes and restarts, only
+	// filled in when getting a single workstation.
+	Crashes *CrashHistory `json:"crashes,omitempty"`
 }
 
+const DefaultDockerImage = "docker:///ubuntu#trusty"
+
 func NewWorkstation(request teapot.WorkstationCreateRequest) Workstation {
 	if len(request.DockerImage) == 0 {
-		request.DockerImage = "docker:///ubuntu#trusty"
+		request.DockerImage = DefaultDockerImage
 	}
 
 	return Workstation{
@@ -32,10 +78,41 @@ func NewWorkstation(request teapot.WorkstationCreateRequest) Workstation {
 		CPUWeight:   request.CPUWeight,
 		DiskMB:      request.DiskMB,
 		MemoryMB:    request.MemoryMB,
+		Ports:       request.Ports,
+		Env:         request.Env,
+		Secrets:     request.Secrets,
+		Setup:       request.Setup,
+		Template:    request.Template,
+		RestoreFrom: request.RestoreFrom,
 		State:       StoppedState,
+
+		EgressPolicy: request.EgressPolicy,
 	}
 }
 
+// Resize returns the workstation with the image and resources set in request,
+// failing if request doesn't change anything.
+func (workstation Workstation) Resize(request teapot.WorkstationUpdateRequest) (Workstation, error) {
+	if request == (teapot.WorkstationUpdateRequest{}) {
+		return workstation, ValidationError{ErrInvalidModification{"docker_image, cpu_weight, disk_mb or memory_mb"}}
+	}
+
+	if request.DockerImage != "" {
+		workstation.DockerImage = request.DockerImage
+	}
+	if request.CPUWeight != 0 {
+		workstation.CPUWeight = request.CPUWeight
+	}
+	if request.DiskMB != 0 {
+		workstation.DiskMB = request.DiskMB
+	}
+	if request.MemoryMB != 0 {
+		workstation.MemoryMB = request.MemoryMB
+	}
+
+	return workstation, workstation.Validate()
+}
+
 func (workstation Workstation) Validate() error {
 	var validationError ValidationError
 
@@ -44,13 +121,39 @@ func (workstation Workstation) Validate() error {
 		validationError = append(validationError, ErrInvalidField{"name"})
 	}
 
-	matched, err = regexp.MatchString("^docker:///[\\w-.]+(/[\\w-.]+)?#?[\\w-.]*$", workstation.DockerImage)
-	if err != nil || !matched {
+	if !validDockerImage(workstation.DockerImage) {
 		validationError = append(validationError, ErrInvalidField{"docker_image"})
 	}
 
+	if !validEnv(workstation.Env) {
+		validationError = append(validationError, ErrInvalidField{"env"})
+	}
+
+	for _, name := range workstation.Secrets {
+		if !envNamePattern.MatchString(name) {
+			validationError = append(validationError, ErrInvalidField{"secrets"})
+			break
+		}
+	}
+
+	if workstation.EgressPolicy != "" && !templateNamePattern.MatchString(workstation.EgressPolicy) {
+		validationError = append(validationError, ErrInvalidField{"egress_policy"})
+	}
+
+	for _, port := range workstation.Ports {
+		if port == 0 {
+			validationError = append(validationError, ErrInvalidField{"ports"})
+			break
+		}
+	}
+
 	if len(validationError) > 0 {
 		return validationError
 	}
 	return nil
 }
+
+func validDockerImage(dockerImage string) bool {
+	matched, err := regexp.MatchString("^docker:///[\\w-.]+(/[\\w-.]+)?#?[\\w-.]*$", dockerImage)
+	return err == nil && matched
+}
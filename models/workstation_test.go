@@ -65,8 +65,46 @@ var _ = Describe("Workstation", func() {
 			{"docker_image",
 				Workstation{Name: "a", DockerImage: "docker:///ubuntu:trusty"},
 			},
+			{"ports",
+				Workstation{Name: "a", DockerImage: DefaultDockerImage, Ports: []uint16{4000, 0}},
+			},
+			{"env",
+				Workstation{Name: "a", DockerImage: DefaultDockerImage, Env: map[string]string{"NOT-VALID": "x"}},
+			},
 		} {
 			testValidatorErrorCase(testCase)
 		}
 	})
+
+	Describe("Resize", func() {
+		BeforeEach(func() {
+			workstation = Workstation{
+				Name:        "w1",
+				DockerImage: DefaultDockerImage,
+				CPUWeight:   1,
+				DiskMB:      1024,
+				MemoryMB:    256,
+			}
+		})
+
+		It("changes only the fields that are set", func() {
+			resized, err := workstation.Resize(teapot.WorkstationUpdateRequest{MemoryMB: 1024})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resized.MemoryMB).To(Equal(1024))
+			Expect(resized.DiskMB).To(Equal(1024))
+			Expect(resized.CPUWeight).To(Equal(uint(1)))
+			Expect(resized.DockerImage).To(Equal(DefaultDockerImage))
+		})
+
+		It("fails when nothing would change", func() {
+			_, err := workstation.Resize(teapot.WorkstationUpdateRequest{})
+			Expect(err).To(BeAssignableToTypeOf(ValidationError{}))
+		})
+
+		It("validates the new docker image", func() {
+			_, err := workstation.Resize(teapot.WorkstationUpdateRequest{DockerImage: "blah"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("docker_image"))
+		})
+	})
 })
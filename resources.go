@@ -1,15 +1,371 @@
 package teapot
 
+import "time"
+
 type WorkstationCreateRequest struct {
 	Name        string `json:"name"`
 	DockerImage string `json:"docker_image"`
 	CPUWeight   uint   `json:"cpu_weight"`
 	DiskMB      int    `json:"disk_mb"`
 	MemoryMB    int    `json:"memory_mb"`
+
+	Ports []uint16          `json:"ports,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+
+	// Secrets names secrets of the user that are set as environment
+	// variables of the same name.
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Setup scripts run with bash before the workstation starts.
+	Setup []string `json:"setup,omitempty"`
+
+	// Template names a preset the fields left empty are taken from.
+	Template string `json:"template,omitempty"`
+
+	// RestoreFrom is the ID of a snapshot that is extracted into the home
+	// directory once the workstation first runs.
+	RestoreFrom string `json:"restore_from,omitempty"`
+
+	// EgressPolicy names the policy limiting the workstation's outbound
+	// traffic, only admins can choose one.
+	EgressPolicy string `json:"egress_policy,omitempty"`
+
+	// Privileged asks for a privileged container, which the privilege policy
+	// may refuse or leave to an admin's approval.
+	Privileged bool `json:"privileged,omitempty"`
+}
+
+// WorkstationManifest declares every workstation managed by the manifest
+// Name, see PUT /workstations.
+type WorkstationManifest struct {
+	Name         string            `json:"name"`
+	Workstations []WorkstationSpec `json:"workstations"`
+}
+
+type WorkstationSpec struct {
+	Name        string            `json:"name"`
+	DockerImage string            `json:"docker_image,omitempty"`
+	CPUWeight   uint              `json:"cpu_weight,omitempty"`
+	DiskMB      int               `json:"disk_mb,omitempty"`
+	MemoryMB    int               `json:"memory_mb,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Ports       []uint16          `json:"ports,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+
+	Keys      []string                `json:"keys,omitempty"`
+	Routes    []RouteCreateRequest    `json:"routes,omitempty"`
+	Schedules []ScheduleCreateRequest `json:"schedules,omitempty"`
+}
+
+type WorkstationChange struct {
+	Workstation string        `json:"workstation"`
+	Action      string        `json:"action"`
+	Fields      []FieldChange `json:"fields,omitempty"`
+	OperationID string        `json:"operation_id,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// WorkstationUpdateRequest resizes a workstation, fields left out keep their
+// current value.
+type WorkstationUpdateRequest struct {
+	DockerImage string `json:"docker_image,omitempty"`
+	CPUWeight   uint   `json:"cpu_weight,omitempty"`
+	DiskMB      int    `json:"disk_mb,omitempty"`
+	MemoryMB    int    `json:"memory_mb,omitempty"`
+}
+
+type WorkstationCloneRequest struct {
+	Name string `json:"name"`
+
+	// RestoreSnapshot restores the latest complete snapshot of the original
+	// workstation into the clone.
+	RestoreSnapshot bool `json:"restore_snapshot,omitempty"`
 }
 
 type WorkstationResponse struct {
-	Name        string `json:"name"`
+	Name        string   `json:"name"`
+	DockerImage string   `json:"docker_image"`
+	State       string   `json:"state"`
+	CPUWeight   uint     `json:"cpu_weight"`
+	DiskMB      int      `json:"disk_mb"`
+	MemoryMB    int      `json:"memory_mb"`
+	Owner       string   `json:"owner,omitempty"`
+	Ports       []uint16 `json:"ports,omitempty"`
+	Template    string   `json:"template,omitempty"`
+	Manifest    string   `json:"manifest,omitempty"`
+	RestoreFrom string   `json:"restore_from,omitempty"`
+
+	EgressPolicy string `json:"egress_policy,omitempty"`
+
+	Privilege           string `json:"privilege,omitempty"`
+	PrivilegeApprovedBy string `json:"privilege_approved_by,omitempty"`
+	PlacementError      string `json:"placement_error,omitempty"`
+
+	Crashes *CrashHistoryResponse `json:"crashes,omitempty"`
+
+	Env     map[string]string `json:"env,omitempty"`
+	Secrets []string          `json:"secrets,omitempty"`
+	Setup   []string          `json:"setup,omitempty"`
+}
+
+type CrashHistoryResponse struct {
+	CrashCount      int               `json:"crash_count"`
+	LastCrashReason string            `json:"last_crash_reason,omitempty"`
+	LastCrashedAt   *time.Time        `json:"last_crashed_at,omitempty"`
+	Restarts        []RestartResponse `json:"restarts,omitempty"`
+}
+
+type RestartResponse struct {
+	At          time.Time `json:"at"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+}
+
+type LogLineResponse struct {
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Instance  string    `json:"instance,omitempty"`
+	Stream    string    `json:"stream"`
+	Message   string    `json:"message"`
+}
+
+type TemplateRequest struct {
+	DockerImage string            `json:"docker_image,omitempty"`
+	CPUWeight   uint              `json:"cpu_weight,omitempty"`
+	DiskMB      int               `json:"disk_mb,omitempty"`
+	MemoryMB    int               `json:"memory_mb,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Ports       []uint16          `json:"ports,omitempty"`
+	Setup       []string          `json:"setup,omitempty"`
+
+	EgressPolicy string `json:"egress_policy,omitempty"`
+	Privilege    string `json:"privilege,omitempty"`
+}
+
+type TemplateResponse struct {
+	Name        string            `json:"name"`
+	DockerImage string            `json:"docker_image,omitempty"`
+	CPUWeight   uint              `json:"cpu_weight,omitempty"`
+	DiskMB      int               `json:"disk_mb,omitempty"`
+	MemoryMB    int               `json:"memory_mb,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Ports       []uint16          `json:"ports,omitempty"`
+	Setup       []string          `json:"setup,omitempty"`
+
+	EgressPolicy string `json:"egress_policy,omitempty"`
+	Privilege    string `json:"privilege,omitempty"`
+}
+
+// EgressRule allows outbound traffic to the destinations, which are IPs,
+// CIDR ranges or IP ranges like 10.0.0.1-10.0.0.9. TCP and UDP rules need
+// ports or a port range, ICMP rules the ICMP type and code.
+type EgressRule struct {
+	Protocol     string     `json:"protocol"`
+	Destinations []string   `json:"destinations"`
+	Ports        []uint16   `json:"ports,omitempty"`
+	PortRange    *PortRange `json:"port_range,omitempty"`
+	ICMP         *ICMPInfo  `json:"icmp,omitempty"`
+	Log          bool       `json:"log,omitempty"`
+}
+
+type PortRange struct {
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+}
+
+type ICMPInfo struct {
+	Type int32 `json:"type"`
+	Code int32 `json:"code"`
+}
+
+// QuotaResponse is a quota of the user or of one of their teams, with what
+// counts against it. Limits of zero aren't enforced.
+type QuotaResponse struct {
+	Scope  string      `json:"scope"`
+	Name   string      `json:"name"`
+	Limits QuotaLimits `json:"limits"`
+	Usage  QuotaUsage  `json:"usage"`
+}
+
+type QuotaLimits struct {
+	MaxWorkstations int `json:"max_workstations"`
+	MemoryMB        int `json:"memory_mb"`
+	DiskMB          int `json:"disk_mb"`
+	MaxPrivileged   int `json:"max_privileged"`
+}
+
+type QuotaUsage struct {
+	Workstations int `json:"workstations"`
+	MemoryMB     int `json:"memory_mb"`
+	DiskMB       int `json:"disk_mb"`
+	Privileged   int `json:"privileged"`
+}
+
+// CapacityResponse sums up what the Diego cells of a stack have left. A
+// workstation has to fit on a single cell, LargestFree is what the cell with
+// the most free memory has left.
+type CapacityResponse struct {
+	Stack       string        `json:"stack"`
+	Cells       int           `json:"cells"`
+	Free        CellResources `json:"free"`
+	LargestFree CellResources `json:"largest_free"`
+}
+
+type CellResources struct {
+	MemoryMB   int `json:"memory_mb"`
+	DiskMB     int `json:"disk_mb"`
+	Containers int `json:"containers"`
+}
+
+// UsageRecordResponse is what a group of workstations used during a day, in
+// UTC. Group is the user, team or image named by GroupBy.
+type UsageRecordResponse struct {
+	Day            string  `json:"day"`
+	GroupBy        string  `json:"group_by"`
+	Group          string  `json:"group"`
+	Workstations   int     `json:"workstations"`
+	Hours          float64 `json:"hours"`
+	MemoryMBHours  float64 `json:"memory_mb_hours"`
+	DiskMBHours    float64 `json:"disk_mb_hours"`
+	CPUWeightHours float64 `json:"cpu_weight_hours"`
+}
+
+type EgressPolicyResponse struct {
+	Name  string       `json:"name"`
+	Rules []EgressRule `json:"rules"`
+}
+
+type RouteCreateRequest struct {
+	Hostname string `json:"hostname,omitempty"`
+	Port     uint16 `json:"port"`
+}
+
+type RouteResponse struct {
+	Hostname string `json:"hostname"`
+	Port     uint16 `json:"port"`
+}
+
+type SnapshotResponse struct {
+	ID            string    `json:"id"`
+	Workstation   string    `json:"workstation"`
+	Owner         string    `json:"owner,omitempty"`
+	State         string    `json:"state"`
+	Size          int64     `json:"size"`
+	CreatedAt     time.Time `json:"created_at"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+}
+
+type OperationResponse struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Workstation   string    `json:"workstation"`
+	State         string    `json:"state"`
+	Step          string    `json:"step"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type SecretRequest struct {
+	Value string `json:"value"`
+}
+
+// SecretResponse describes a secret, its value is never sent back.
+type SecretResponse struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookRequest subscribes URL to the given workstation events, all of them
+// when Events is empty. Secret signs every payload sent to it.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	Secret string   `json:"secret"`
+}
+
+// WebhookResponse describes a webhook, its secret is never sent back.
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WorkstationEventResponse struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"event"`
+	Workstation string    `json:"workstation"`
+	CrashCount  int       `json:"crash_count,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type DeliveryResponse struct {
+	ID             string                   `json:"id"`
+	WebhookID      string                   `json:"webhook_id"`
+	Event          WorkstationEventResponse `json:"event"`
+	State          string                   `json:"state"`
+	Attempts       int                      `json:"attempts"`
+	ResponseStatus int                      `json:"response_status,omitempty"`
+	LastError      string                   `json:"last_error,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	LastAttemptAt  *time.Time               `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+}
+
+type UserKeyCreateRequest struct {
+	Key string `json:"key"`
+}
+
+type SSHKeyResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type"`
+	Comment     string `json:"comment,omitempty"`
+	Key         string `json:"key"`
+}
+
+type JobCreateRequest struct {
 	DockerImage string `json:"docker_image"`
-	State       string `json:"state"`
+	CPUWeight   uint   `json:"cpu_weight"`
+	DiskMB      int    `json:"disk_mb"`
+	MemoryMB    int    `json:"memory_mb"`
+	Command     string `json:"command"`
+	Privileged  bool   `json:"privileged,omitempty"`
+}
+
+type WorkstationExecRequest struct {
+	Command string `json:"command"`
+}
+
+type JobResponse struct {
+	ID            string `json:"id"`
+	Workstation   string `json:"workstation,omitempty"`
+	DockerImage   string `json:"docker_image"`
+	Command       string `json:"command"`
+	State         string `json:"state"`
+	Failed        bool   `json:"failed"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	ExitCode      *int   `json:"exit_code,omitempty"`
+	Output        string `json:"output"`
+}
+
+type ScheduleCreateRequest struct {
+	Cron     string `json:"cron"`
+	Action   string `json:"action"`
+	Command  string `json:"command,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+type ScheduleResponse struct {
+	ID       string `json:"id"`
+	Cron     string `json:"cron"`
+	Action   string `json:"action"`
+	Command  string `json:"command,omitempty"`
+	Location string `json:"location,omitempty"`
 }
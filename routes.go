@@ -5,17 +5,175 @@ import "github.com/tedsuo/rata"
 const (
 	// Workstations
 	CreateWorkstationRoute   = "CreateWorkstation"
+	GetWorkstationRoute      = "GetWorkstation"
 	DeleteWorkstationRoute   = "DeleteWorkstation"
 	AttachWorkstationRoute   = "AttachWorkstation"
 	ListWorkstationsRoute    = "ListWorkstations"
 	AddKeyToWorkstationRoute = "AddKeyToWorkstationRoute"
+	ExecWorkstationRoute     = "ExecWorkstation"
+	ForwardWorkstationRoute  = "ForwardWorkstation"
+	CloneWorkstationRoute    = "CloneWorkstation"
+	ApplyWorkstationsRoute   = "ApplyWorkstations"
+	UpdateWorkstationRoute   = "UpdateWorkstation"
+	ApprovePrivilegeRoute    = "ApprovePrivilege"
+	RestartWorkstationRoute  = "RestartWorkstation"
+	StreamLogsRoute          = "StreamLogs"
+
+	// Operations
+	GetOperationRoute = "GetOperation"
+
+	// Keys
+	ListKeysRoute  = "ListKeys"
+	GetKeyRoute    = "GetKey"
+	RemoveKeyRoute = "RemoveKey"
+
+	// Schedules
+	ListSchedulesRoute  = "ListSchedules"
+	CreateScheduleRoute = "CreateSchedule"
+	UpdateScheduleRoute = "UpdateSchedule"
+	DeleteScheduleRoute = "DeleteSchedule"
+
+	// Files
+	DownloadFileRoute = "DownloadFile"
+	UploadFileRoute   = "UploadFile"
+
+	// Routes
+	ListRoutesRoute  = "ListRoutes"
+	CreateRouteRoute = "CreateRoute"
+	DeleteRouteRoute = "DeleteRoute"
+
+	// Snapshots
+	ListSnapshotsRoute  = "ListSnapshots"
+	CreateSnapshotRoute = "CreateSnapshot"
+	GetSnapshotRoute    = "GetSnapshot"
+	DeleteSnapshotRoute = "DeleteSnapshot"
+
+	// Templates
+	ListTemplatesRoute  = "ListTemplates"
+	GetTemplateRoute    = "GetTemplate"
+	SaveTemplateRoute   = "SaveTemplate"
+	DeleteTemplateRoute = "DeleteTemplate"
+
+	// Egress policies
+	ListEgressPoliciesRoute   = "ListEgressPolicies"
+	GetWorkstationEgressRoute = "GetWorkstationEgress"
+
+	// Quotas
+	GetUserQuotasRoute = "GetUserQuotas"
+
+	// Capacity
+	GetCapacityRoute = "GetCapacity"
+
+	// Usage
+	GetUsageRoute = "GetUsage"
+
+	// Users
+	ListUserKeysRoute  = "ListUserKeys"
+	AddUserKeyRoute    = "AddUserKey"
+	RemoveUserKeyRoute = "RemoveUserKey"
+	ListSecretsRoute   = "ListSecrets"
+	SaveSecretRoute    = "SaveSecret"
+	DeleteSecretRoute  = "DeleteSecret"
+
+	// Webhooks
+	CreateWebhookRoute         = "CreateWebhook"
+	ListWebhooksRoute          = "ListWebhooks"
+	GetWebhookRoute            = "GetWebhook"
+	DeleteWebhookRoute         = "DeleteWebhook"
+	ListWebhookDeliveriesRoute = "ListWebhookDeliveries"
+	ListDeadLettersRoute       = "ListDeadLetters"
+	RedeliverRoute             = "Redeliver"
+
+	// Jobs
+	CreateJobRoute = "CreateJob"
+	GetJobRoute    = "GetJob"
+	CancelJobRoute = "CancelJob"
 )
 
 var Routes = rata.Routes{
 	// Workstations
 	{Path: "/workstations", Method: "POST", Name: CreateWorkstationRoute},
+	{Path: "/workstations/:name", Method: "GET", Name: GetWorkstationRoute},
 	{Path: "/workstations/:name", Method: "DELETE", Name: DeleteWorkstationRoute},
+	{Path: "/workstations/:name", Method: "PATCH", Name: UpdateWorkstationRoute},
 	{Path: "/workstations/:name/attach", Method: "GET", Name: AttachWorkstationRoute},
 	{Path: "/workstations", Method: "GET", Name: ListWorkstationsRoute},
+	{Path: "/workstations", Method: "PUT", Name: ApplyWorkstationsRoute},
 	{Path: "/workstations/:name/add-key", Method: "Post", Name: AddKeyToWorkstationRoute},
+	{Path: "/workstations/:name/exec", Method: "POST", Name: ExecWorkstationRoute},
+	{Path: "/workstations/:name/forward/:port", Method: "GET", Name: ForwardWorkstationRoute},
+	{Path: "/workstations/:name/clone", Method: "POST", Name: CloneWorkstationRoute},
+	{Path: "/workstations/:name/approve-privilege", Method: "POST", Name: ApprovePrivilegeRoute},
+	{Path: "/workstations/:name/restart", Method: "POST", Name: RestartWorkstationRoute},
+	{Path: "/workstations/:name/logs", Method: "GET", Name: StreamLogsRoute},
+
+	// Operations
+	{Path: "/operations/:id", Method: "GET", Name: GetOperationRoute},
+
+	// Keys
+	{Path: "/workstations/:name/keys", Method: "GET", Name: ListKeysRoute},
+	{Path: "/workstations/:name/keys/:fingerprint", Method: "GET", Name: GetKeyRoute},
+	{Path: "/workstations/:name/keys/:fingerprint", Method: "DELETE", Name: RemoveKeyRoute},
+
+	// Schedules
+	{Path: "/workstations/:name/schedules", Method: "GET", Name: ListSchedulesRoute},
+	{Path: "/workstations/:name/schedules", Method: "POST", Name: CreateScheduleRoute},
+	{Path: "/workstations/:name/schedules/:id", Method: "PUT", Name: UpdateScheduleRoute},
+	{Path: "/workstations/:name/schedules/:id", Method: "DELETE", Name: DeleteScheduleRoute},
+
+	// Files, the path inside the workstation follows the trailing slash
+	{Path: "/workstations/:name/files/", Method: "GET", Name: DownloadFileRoute},
+	{Path: "/workstations/:name/files/", Method: "PUT", Name: UploadFileRoute},
+
+	// Routes
+	{Path: "/workstations/:name/routes", Method: "GET", Name: ListRoutesRoute},
+	{Path: "/workstations/:name/routes", Method: "POST", Name: CreateRouteRoute},
+	{Path: "/workstations/:name/routes/:hostname", Method: "DELETE", Name: DeleteRouteRoute},
+
+	// Snapshots
+	{Path: "/workstations/:name/snapshots", Method: "GET", Name: ListSnapshotsRoute},
+	{Path: "/workstations/:name/snapshots", Method: "POST", Name: CreateSnapshotRoute},
+	{Path: "/snapshots/:id", Method: "GET", Name: GetSnapshotRoute},
+	{Path: "/snapshots/:id", Method: "DELETE", Name: DeleteSnapshotRoute},
+
+	// Templates
+	{Path: "/templates", Method: "GET", Name: ListTemplatesRoute},
+	{Path: "/templates/:name", Method: "GET", Name: GetTemplateRoute},
+	{Path: "/templates/:name", Method: "PUT", Name: SaveTemplateRoute},
+	{Path: "/templates/:name", Method: "DELETE", Name: DeleteTemplateRoute},
+
+	// Egress policies
+	{Path: "/egress_policies", Method: "GET", Name: ListEgressPoliciesRoute},
+	{Path: "/workstations/:name/egress", Method: "GET", Name: GetWorkstationEgressRoute},
+
+	// Quotas
+	{Path: "/quotas/me", Method: "GET", Name: GetUserQuotasRoute},
+
+	// Capacity
+	{Path: "/capacity", Method: "GET", Name: GetCapacityRoute},
+
+	// Usage
+	{Path: "/usage", Method: "GET", Name: GetUsageRoute},
+
+	// Users
+	{Path: "/users/me/keys", Method: "GET", Name: ListUserKeysRoute},
+	{Path: "/users/me/keys", Method: "POST", Name: AddUserKeyRoute},
+	{Path: "/users/me/keys/:fingerprint", Method: "DELETE", Name: RemoveUserKeyRoute},
+	{Path: "/users/me/secrets", Method: "GET", Name: ListSecretsRoute},
+	{Path: "/users/me/secrets/:name", Method: "PUT", Name: SaveSecretRoute},
+	{Path: "/users/me/secrets/:name", Method: "DELETE", Name: DeleteSecretRoute},
+
+	// Webhooks
+	{Path: "/webhooks", Method: "POST", Name: CreateWebhookRoute},
+	{Path: "/webhooks", Method: "GET", Name: ListWebhooksRoute},
+	{Path: "/webhooks/:id", Method: "GET", Name: GetWebhookRoute},
+	{Path: "/webhooks/:id", Method: "DELETE", Name: DeleteWebhookRoute},
+	{Path: "/webhooks/:id/deliveries", Method: "GET", Name: ListWebhookDeliveriesRoute},
+	{Path: "/dead-letters", Method: "GET", Name: ListDeadLettersRoute},
+	{Path: "/dead-letters/:id/redeliver", Method: "POST", Name: RedeliverRoute},
+
+	// Jobs
+	{Path: "/jobs", Method: "POST", Name: CreateJobRoute},
+	{Path: "/jobs/:id", Method: "GET", Name: GetJobRoute},
+	{Path: "/jobs/:id/cancel", Method: "POST", Name: CancelJobRoute},
 }
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// Scheduler fires the cron schedules stored on each workstation. It is meant
+// to be run as an ifrit member.
+type Scheduler struct {
+	workstationManager managers.WorkstationManager
+	jobManager         managers.JobManager
+	backupManager      managers.BackupManager
+	interval           time.Duration
+	logger             lager.Logger
+
+	lastRun time.Time
+
+	firingLock sync.Mutex
+	firing     map[string]bool
+}
+
+func New(workstationManager managers.WorkstationManager, jobManager managers.JobManager, backupManager managers.BackupManager, interval time.Duration, logger lager.Logger) *Scheduler {
+	return &Scheduler{
+		workstationManager: workstationManager,
+		jobManager:         jobManager,
+		backupManager:      backupManager,
+		interval:           interval,
+		logger:             logger.Session("scheduler"),
+		firing:             map[string]bool{},
+	}
+}
+
+func (s *Scheduler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case now := <-ticker.C:
+			s.Tick(now)
+		}
+	}
+}
+
+// Tick runs every schedule due in the minutes since the last tick, up to the
+// minute of now, so minutes skipped by a late tick are caught up on. Each
+// minute is only processed once, no matter how often Tick is called.
+// Schedules are fired in the background, as backups can take a while, and a
+// schedule still running from a previous minute is skipped.
+func (s *Scheduler) Tick(now time.Time) {
+	minute := now.Truncate(time.Minute)
+	if !minute.After(s.lastRun) {
+		return
+	}
+
+	first := minute
+	if !s.lastRun.IsZero() {
+		first = s.lastRun.Add(time.Minute)
+	}
+
+	workstations, err := s.workstationManager.List()
+	if err != nil {
+		s.logger.Error("list-workstations-failed", err)
+		return
+	}
+	s.lastRun = minute
+
+	for t := first; !t.After(minute); t = t.Add(time.Minute) {
+		for _, workstation := range workstations {
+			for _, schedule := range workstation.Schedules {
+				if schedule.Due(t) {
+					s.goFire(workstation.Name, schedule)
+				}
+			}
+		}
+	}
+}
+
+func (s *Scheduler) goFire(name string, schedule models.Schedule) {
+	key := name + "/" + schedule.ID
+
+	s.firingLock.Lock()
+	defer s.firingLock.Unlock()
+
+	if s.firing[key] {
+		s.logger.Info("still-firing", lager.Data{"workstation_name": name, "schedule": schedule})
+		return
+	}
+	s.firing[key] = true
+
+	go func() {
+		s.fire(name, schedule)
+
+		s.firingLock.Lock()
+		delete(s.firing, key)
+		s.firingLock.Unlock()
+	}()
+}
+
+func (s *Scheduler) fire(name string, schedule models.Schedule) {
+	log := s.logger.Session("fire", lager.Data{"workstation_name": name, "schedule": schedule})
+
+	var err error
+	switch schedule.Action {
+	case models.ScheduleStartAction:
+		err = s.workstationManager.Start(name)
+	case models.ScheduleStopAction:
+		err = s.stop(name)
+	case models.ScheduleExecAction:
+		_, err = s.jobManager.Exec(name, schedule.Command)
+	case models.ScheduleBackupAction:
+		_, err = s.backupManager.Backup(name)
+	}
+
+	if err != nil {
+		log.Error("failed", err)
+		return
+	}
+
+	log.Info("fired")
+}
+
+// stop backs up the home directory before stopping the workstation, and keeps
+// it running if the backup fails so no work is lost.
+func (s *Scheduler) stop(name string) error {
+	_, err := s.backupManager.Backup(name)
+	if err != nil && err != managers.ErrBackupsDisabled {
+		return err
+	}
+
+	return s.workstationManager.Stop(name)
+}
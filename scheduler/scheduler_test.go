@@ -0,0 +1,129 @@
+package scheduler_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	blob_fakes "github.com/luan/teapot/blobstore/fakes"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	. "github.com/luan/teapot/scheduler"
+	"github.com/luan/teapot/store"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	var (
+		fakeReceptorClient *fake_receptor.FakeClient
+		sched              *Scheduler
+		newScheduler       func(*blob_fakes.FakeBlobstore) *Scheduler
+		monday8am          time.Time
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		dataStore, _ := store.NewStore("")
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "something", logger)
+		jobManager := managers.NewJobManager(fakeReceptorClient, models.DefaultBootstrap(), egressPolicyManager, logger)
+		newScheduler = func(blobstore *blob_fakes.FakeBlobstore) *Scheduler {
+			backupManager := managers.NewBackupManager(workstationManager, nil, dataStore, logger)
+			if blobstore != nil {
+				backupManager = managers.NewBackupManager(workstationManager, blobstore, dataStore, logger)
+			}
+			return New(workstationManager, jobManager, backupManager, time.Second, logger)
+		}
+		sched = newScheduler(nil)
+
+		monday8am = time.Date(2015, time.March, 2, 8, 0, 0, 0, time.UTC)
+
+		desiredLRP := receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			RootFSPath:  "docker:///ubuntu#trusty",
+			Annotation: `{"schedules":[
+				{"id":"a","cron":"0 8 * * 1-5","action":"start"},
+				{"id":"b","cron":"0 19 * * 1-5","action":"stop"},
+				{"id":"c","cron":"0 8 * * *","action":"exec","command":"apt-get update"}
+			]}`,
+		}
+		fakeReceptorClient.DesiredLRPsByDomainReturns([]receptor.DesiredLRPResponse{desiredLRP}, nil)
+		fakeReceptorClient.GetDesiredLRPReturns(desiredLRP, nil)
+	})
+
+	Describe("Tick", func() {
+		It("starts workstations whose start schedule is due", func() {
+			sched.Tick(monday8am)
+
+			Eventually(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+			name, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+			Expect(name).To(Equal("w1"))
+			Expect(*update.Instances).To(Equal(1))
+		})
+
+		It("stops workstations whose stop schedule is due", func() {
+			sched.Tick(monday8am.Add(11 * time.Hour))
+
+			Eventually(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+			_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+			Expect(*update.Instances).To(Equal(0))
+		})
+
+		Context("when backups are configured", func() {
+			var fakeBlobstore *blob_fakes.FakeBlobstore
+
+			BeforeEach(func() {
+				fakeBlobstore = new(blob_fakes.FakeBlobstore)
+				sched = newScheduler(fakeBlobstore)
+			})
+
+			It("keeps the workstation running when its home directory can't be backed up", func() {
+				sched.Tick(monday8am.Add(11 * time.Hour))
+
+				Consistently(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(0))
+				Expect(fakeBlobstore.PutCallCount()).To(Equal(0))
+			})
+		})
+
+		It("runs recurring commands as tasks", func() {
+			sched.Tick(monday8am)
+
+			Eventually(fakeReceptorClient.CreateTaskCallCount).Should(Equal(1))
+			task := fakeReceptorClient.CreateTaskArgsForCall(0)
+			Expect(task.Action).NotTo(BeNil())
+			Expect(task.Annotation).To(ContainSubstring("apt-get update"))
+		})
+
+		It("fires each minute only once", func() {
+			sched.Tick(monday8am)
+			sched.Tick(monday8am.Add(15 * time.Second))
+
+			Eventually(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+			Consistently(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+		})
+
+		It("catches up on the minutes missed since the last tick", func() {
+			sched.Tick(monday8am.Add(-2 * time.Minute))
+			sched.Tick(monday8am.Add(time.Minute))
+
+			Eventually(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+			Eventually(fakeReceptorClient.CreateTaskCallCount).Should(Equal(1))
+		})
+
+		It("does nothing when no schedule is due", func() {
+			sched.Tick(monday8am.Add(time.Minute))
+
+			Consistently(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(0))
+			Expect(fakeReceptorClient.CreateTaskCallCount()).To(Equal(0))
+		})
+	})
+})
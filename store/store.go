@@ -0,0 +1,140 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Store keeps teapot's own state: everything that does not belong to a single
+// workstation and therefore cannot live in its DesiredLRP annotation. Values
+// are stored as JSON, grouped into collections.
+type Store interface {
+	Get(collection, key string, value interface{}) error
+	Put(collection, key string, value interface{}) error
+	Delete(collection, key string) error
+	Keys(collection string) ([]string, error)
+}
+
+type store struct {
+	path        string
+	collections map[string]map[string]json.RawMessage
+	mutex       sync.RWMutex
+}
+
+// NewStore returns a Store persisted to the JSON file at path. When path is
+// empty the store only lives in memory.
+func NewStore(path string) (Store, error) {
+	s := &store{
+		path:        path,
+		collections: map[string]map[string]json.RawMessage{},
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contents) > 0 {
+		err = json.Unmarshal(contents, &s.collections)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *store) Get(collection, key string, value interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	raw, ok := s.collections[collection][key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+func (s *store) Put(collection, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.collections[collection] == nil {
+		s.collections[collection] = map[string]json.RawMessage{}
+	}
+	s.collections[collection][key] = raw
+
+	return s.save()
+}
+
+func (s *store) Delete(collection, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.collections[collection][key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.collections[collection], key)
+
+	return s.save()
+}
+
+func (s *store) Keys(collection string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := []string{}
+	for key := range s.collections[collection] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	contents, err := json.Marshal(s.collections)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,89 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/luan/teapot/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+	type thing struct {
+		Name string `json:"name"`
+	}
+
+	var (
+		tmpDir string
+		path   string
+		s      Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "teapot-store")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "teapot.json")
+
+		s, err = NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("stores and retrieves values", func() {
+		Expect(s.Put("things", "a", thing{Name: "a"})).To(Succeed())
+
+		var value thing
+		Expect(s.Get("things", "a", &value)).To(Succeed())
+		Expect(value.Name).To(Equal("a"))
+	})
+
+	It("returns ErrNotFound for missing keys", func() {
+		var value thing
+		Expect(s.Get("things", "missing", &value)).To(Equal(ErrNotFound))
+		Expect(s.Delete("things", "missing")).To(Equal(ErrNotFound))
+	})
+
+	It("lists the keys of a collection in order", func() {
+		s.Put("things", "b", thing{})
+		s.Put("things", "a", thing{})
+		s.Put("others", "c", thing{})
+
+		Expect(s.Keys("things")).To(Equal([]string{"a", "b"}))
+		Expect(s.Keys("nothing")).To(BeEmpty())
+	})
+
+	It("deletes values", func() {
+		s.Put("things", "a", thing{})
+		Expect(s.Delete("things", "a")).To(Succeed())
+		Expect(s.Keys("things")).To(BeEmpty())
+	})
+
+	It("persists values across instances", func() {
+		s.Put("things", "a", thing{Name: "persisted"})
+
+		reopened, err := NewStore(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		var value thing
+		Expect(reopened.Get("things", "a", &value)).To(Succeed())
+		Expect(value.Name).To(Equal("persisted"))
+	})
+
+	Context("without a path", func() {
+		It("keeps values in memory", func() {
+			memory, err := NewStore("")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(memory.Put("things", "a", thing{Name: "a"})).To(Succeed())
+			var value thing
+			Expect(memory.Get("things", "a", &value)).To(Succeed())
+		})
+	})
+})
@@ -24,10 +24,25 @@ var filename = flag.String(
 	"the filename to download from the bucket",
 )
 
+var artifacts = flag.String(
+	"artifacts",
+	"",
+	"url of a teapot artifact server to download teapot from instead of the bucket",
+)
+
+var version = flag.String(
+	"version",
+	"latest",
+	"the version of teapot to download from the artifact server",
+)
+
 var receptorAddr string
 
 func DockerTeapot(client receptor.Client, routeRoot string) error {
 	teapotDownloadURL := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", *bucket, *filename)
+	if *artifacts != "" {
+		teapotDownloadURL = fmt.Sprintf("%s/artifacts/teapot/%s", strings.TrimRight(*artifacts, "/"), *version)
+	}
 	fmt.Println(teapotDownloadURL)
 	client.DeleteDesiredLRP("teapot")
 	route := fmt.Sprintf("teapot.%s", routeRoot)
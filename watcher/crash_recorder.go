@@ -0,0 +1,41 @@
+package watcher
+
+import (
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// NewCrashRecorder returns a Listener that records every time a workstation's
+// instance crashes, and every time Diego restarts a crashed one. Diego doesn't
+// say why an instance crashed, so the reason is worked out from the state it
+// crashed in.
+func NewCrashRecorder(manager managers.WorkstationManager, crashManager managers.CrashManager, logger lager.Logger) Listener {
+	log := logger.Session("crash-recorder")
+
+	return ListenerFunc(func(event receptor.Event) {
+		changed, ok := event.(receptor.ActualLRPChangedEvent)
+		if !ok || changed.After.Domain != manager.Domain() {
+			return
+		}
+
+		name := changed.After.ProcessGuid
+		var err error
+		switch {
+		case changed.Before.State != receptor.ActualLRPStateCrashed && changed.After.State == receptor.ActualLRPStateCrashed:
+			reason := models.CrashedWhileStartingReason
+			if changed.Before.State == receptor.ActualLRPStateRunning {
+				reason = models.CrashedWhileRunningReason
+			}
+			log.Info("crashed", lager.Data{"workstation_name": name, "crash_count": changed.After.CrashCount})
+			err = crashManager.RecordCrash(name, reason, changed.After.CrashCount)
+		case changed.Before.State == receptor.ActualLRPStateCrashed && changed.After.State != receptor.ActualLRPStateCrashed:
+			err = crashManager.RecordRestart(name, "")
+		}
+
+		if err != nil {
+			log.Error("record-failed", err, lager.Data{"workstation_name": name})
+		}
+	})
+}
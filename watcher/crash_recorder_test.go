@@ -0,0 +1,84 @@
+package watcher_test
+
+import (
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CrashRecorder", func() {
+	var (
+		listener     Listener
+		crashManager managers.CrashManager
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		fakeReceptorClient := new(fake_receptor.FakeClient)
+		dataStore, _ := store.NewStore("")
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+		crashManager = managers.NewCrashManager(dataStore)
+		listener = NewCrashRecorder(manager, crashManager, logger)
+	})
+
+	changed := func(before, after receptor.ActualLRPState, crashCount int) receptor.Event {
+		return receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: before},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: after, CrashCount: crashCount},
+		)
+	}
+
+	It("records crashes while starting", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateCrashed, 1))
+
+		history, err := crashManager.Get("w1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.CrashCount).To(Equal(1))
+		Expect(history.LastCrashReason).To(Equal(models.CrashedWhileStartingReason))
+		Expect(history.LastCrashedAt).NotTo(BeNil())
+	})
+
+	It("records crashes while running", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateRunning, receptor.ActualLRPStateCrashed, 3))
+
+		history, _ := crashManager.Get("w1")
+		Expect(history.CrashCount).To(Equal(3))
+		Expect(history.LastCrashReason).To(Equal(models.CrashedWhileRunningReason))
+	})
+
+	It("records Diego restarting crashed instances", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateCrashed, receptor.ActualLRPStateUnclaimed, 1))
+
+		history, _ := crashManager.Get("w1")
+		Expect(history.Restarts).To(HaveLen(1))
+		Expect(history.Restarts[0].RequestedBy).To(BeEmpty())
+	})
+
+	It("ignores other transitions", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning, 0))
+
+		history, _ := crashManager.Get("w1")
+		Expect(history).To(Equal(models.CrashHistory{}))
+	})
+
+	It("ignores LRPs of other domains", func() {
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "cf-apps", State: receptor.ActualLRPStateRunning},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "cf-apps", State: receptor.ActualLRPStateCrashed},
+		))
+
+		history, _ := crashManager.Get("w1")
+		Expect(history.CrashCount).To(Equal(0))
+	})
+})
@@ -0,0 +1,70 @@
+package watcher
+
+import (
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+// NewEventPublisher returns a Listener that publishes the lifecycle events of
+// workstations to the webhooks: created and deleted along with their
+// DesiredLRP, stopped when it's scaled down, running and crashed along with
+// its instance. The DesiredLRP teapot deletes and desires again to replace a
+// workstation isn't published as deleted and created.
+func NewEventPublisher(manager managers.WorkstationManager, webhookManager managers.WebhookManager, logger lager.Logger) Listener {
+	log := logger.Session("event-publisher")
+
+	return ListenerFunc(func(receptorEvent receptor.Event) {
+		event, ok := workstationEvent(receptorEvent, manager.Domain())
+		if !ok {
+			return
+		}
+
+		switch event.Type {
+		case models.WorkstationDeletedEvent:
+			if manager.Replacing(event.Workstation) {
+				return
+			}
+		case models.WorkstationCreatedEvent:
+			if manager.Replacing(event.Workstation) {
+				manager.Replaced(event.Workstation)
+				return
+			}
+		}
+
+		log.Info("publishing", lager.Data{"workstation_name": event.Workstation, "event": event.Type})
+		if err := webhookManager.Publish(event); err != nil {
+			log.Error("publish-failed", err, lager.Data{"workstation_name": event.Workstation, "event": event.Type})
+		}
+	})
+}
+
+func workstationEvent(receptorEvent receptor.Event, domain string) (models.WorkstationEvent, bool) {
+	switch e := receptorEvent.(type) {
+	case receptor.DesiredLRPCreatedEvent:
+		if e.DesiredLRPResponse.Domain == domain {
+			return models.WorkstationEvent{Type: models.WorkstationCreatedEvent, Workstation: e.DesiredLRPResponse.ProcessGuid}, true
+		}
+	case receptor.DesiredLRPRemovedEvent:
+		if e.DesiredLRPResponse.Domain == domain {
+			return models.WorkstationEvent{Type: models.WorkstationDeletedEvent, Workstation: e.DesiredLRPResponse.ProcessGuid}, true
+		}
+	case receptor.DesiredLRPChangedEvent:
+		if e.After.Domain == domain && e.Before.Instances > 0 && e.After.Instances == 0 {
+			return models.WorkstationEvent{Type: models.WorkstationStoppedEvent, Workstation: e.After.ProcessGuid}, true
+		}
+	case receptor.ActualLRPChangedEvent:
+		if e.After.Domain != domain || e.Before.State == e.After.State {
+			break
+		}
+		switch e.After.State {
+		case receptor.ActualLRPStateRunning:
+			return models.WorkstationEvent{Type: models.WorkstationRunningEvent, Workstation: e.After.ProcessGuid}, true
+		case receptor.ActualLRPStateCrashed:
+			return models.WorkstationEvent{Type: models.WorkstationCrashedEvent, Workstation: e.After.ProcessGuid, CrashCount: e.After.CrashCount}, true
+		}
+	}
+
+	return models.WorkstationEvent{}, false
+}
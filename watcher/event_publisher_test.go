@@ -0,0 +1,128 @@
+package watcher_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventPublisher", func() {
+	var (
+		listener           Listener
+		fakeReceptorClient *fake_receptor.FakeClient
+		manager            managers.WorkstationManager
+		webhookManager     managers.WebhookManager
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		dataStore, _ := store.NewStore("")
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager = managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+		webhookManager = managers.NewWebhookManager(dataStore, time.Second, 3)
+		listener = NewEventPublisher(manager, webhookManager, logger)
+
+		_, err := webhookManager.Create(models.Webhook{URL: "http://example.com", Secret: "s3cret"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	published := func() []models.WorkstationEvent {
+		deliveries, err := webhookManager.Due(time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		events := []models.WorkstationEvent{}
+		for _, delivery := range deliveries {
+			events = append(events, delivery.Event)
+		}
+		return events
+	}
+
+	changed := func(before, after receptor.ActualLRPState) receptor.Event {
+		return receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: before},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: after, CrashCount: 2},
+		)
+	}
+
+	It("publishes workstations being created and deleted", func() {
+		listener.HandleEvent(receptor.NewDesiredLRPCreatedEvent(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+		listener.HandleEvent(receptor.NewDesiredLRPRemovedEvent(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+
+		events := published()
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal(models.WorkstationCreatedEvent))
+		Expect(events[0].Workstation).To(Equal("w1"))
+		Expect(events[0].ID).NotTo(BeEmpty())
+		Expect(events[1].Type).To(Equal(models.WorkstationDeletedEvent))
+	})
+
+	scaled := func(before, after int) receptor.Event {
+		return receptor.NewDesiredLRPChangedEvent(
+			receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego", Instances: before},
+			receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego", Instances: after},
+		)
+	}
+
+	It("publishes workstations starting to run, crashing and stopping", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning))
+		listener.HandleEvent(changed(receptor.ActualLRPStateRunning, receptor.ActualLRPStateCrashed))
+		listener.HandleEvent(scaled(1, 0))
+
+		events := published()
+		Expect(events).To(HaveLen(3))
+		Expect(events[0].Type).To(Equal(models.WorkstationRunningEvent))
+		Expect(events[1].Type).To(Equal(models.WorkstationCrashedEvent))
+		Expect(events[1].CrashCount).To(Equal(2))
+		Expect(events[2].Type).To(Equal(models.WorkstationStoppedEvent))
+	})
+
+	It("doesn't publish workstations whose instance is restarted or removed along with them as stopped", func() {
+		listener.HandleEvent(receptor.NewActualLRPRemovedEvent(receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+		listener.HandleEvent(scaled(0, 1))
+		listener.HandleEvent(scaled(0, 0))
+
+		Expect(published()).To(BeEmpty())
+	})
+
+	It("doesn't publish workstations replaced by teapot as deleted and created", func() {
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego", RootFSPath: "docker:///ubuntu", MemoryMB: 256, DiskMB: 1024}, nil)
+
+		err := manager.Replace(models.Workstation{Name: "w1", DockerImage: "docker:///debian", MemoryMB: 512, DiskMB: 1024})
+		Expect(err).NotTo(HaveOccurred())
+
+		listener.HandleEvent(receptor.NewDesiredLRPRemovedEvent(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+		listener.HandleEvent(receptor.NewDesiredLRPCreatedEvent(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+		Expect(published()).To(BeEmpty())
+
+		listener.HandleEvent(receptor.NewDesiredLRPRemovedEvent(receptor.DesiredLRPResponse{ProcessGuid: "w1", Domain: "tiego"}))
+		events := published()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Type).To(Equal(models.WorkstationDeletedEvent))
+	})
+
+	It("ignores changes that aren't lifecycle events", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateUnclaimed, receptor.ActualLRPStateClaimed))
+		listener.HandleEvent(changed(receptor.ActualLRPStateRunning, receptor.ActualLRPStateRunning))
+
+		Expect(published()).To(BeEmpty())
+	})
+
+	It("ignores LRPs of other domains", func() {
+		listener.HandleEvent(receptor.NewDesiredLRPCreatedEvent(receptor.DesiredLRPResponse{ProcessGuid: "app", Domain: "cf-apps"}))
+
+		Expect(published()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,54 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	pushKeysAttempts = 5
+	pushKeysBackoff  = time.Second
+)
+
+// NewKeyRestorer returns a Listener that pushes a workstation's stored keys
+// to its TEA every time an instance transitions to RUNNING. The TEA may not
+// be listening yet, so failed pushes are retried, backing off exponentially,
+// away from the watcher so other listeners aren't held up.
+func NewKeyRestorer(manager managers.WorkstationManager, logger lager.Logger) Listener {
+	log := logger.Session("key-restorer")
+
+	return ListenerFunc(func(event receptor.Event) {
+		changed, ok := event.(receptor.ActualLRPChangedEvent)
+		if !ok || changed.After.Domain != manager.Domain() {
+			return
+		}
+
+		if changed.Before.State == receptor.ActualLRPStateRunning || changed.After.State != receptor.ActualLRPStateRunning {
+			return
+		}
+
+		go pushKeys(manager, changed.After.ProcessGuid, log)
+	})
+}
+
+func pushKeys(manager managers.WorkstationManager, name string, log lager.Logger) {
+	backoff := pushKeysBackoff
+	for attempt := 1; ; attempt++ {
+		err := manager.PushKeys(name)
+		if err == nil {
+			return
+		}
+
+		if attempt == pushKeysAttempts {
+			log.Error("push-keys-failed", err, lager.Data{"workstation_name": name, "attempts": attempt})
+			return
+		}
+
+		log.Info("push-keys-will-retry", lager.Data{"workstation_name": name, "attempt": attempt, "error": err.Error()})
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
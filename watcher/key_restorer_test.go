@@ -0,0 +1,127 @@
+package watcher_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("KeyRestorer", func() {
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHWEf4+56yLRUuMjH57WdqKF6pgB0cB+oAyLag6FheTj user@example.com"
+
+	var (
+		teaServer   *ghttp.Server
+		listener    Listener
+		userManager managers.UserManager
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		teaServer = ghttp.NewServer()
+		fakeRouteProvider := &model_fakes.FakeRouteProvider{}
+		fakeRouteProvider.SSHRouteReturns(teaServer.URL())
+		fakeReceptorClient := new(fake_receptor.FakeClient)
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			Annotation:  `{"owner":"alice","keys":[{"fingerprint":"21:07","type":"ssh-ed25519","key":"` + key + `"}]}`,
+		}, nil)
+
+		dataStore, _ := store.NewStore("")
+		userManager = managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, fakeRouteProvider, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+		listener = NewKeyRestorer(manager, logger)
+	})
+
+	AfterEach(func() {
+		teaServer.Close()
+	})
+
+	It("pushes the stored keys when an instance becomes RUNNING", func() {
+		teaServer.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/add-key/s3cret"),
+			ghttp.RespondWith(http.StatusCreated, ""),
+		))
+
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateClaimed},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+		))
+
+		Eventually(teaServer.ReceivedRequests).Should(HaveLen(1))
+	})
+
+	It("retries when the TEA can't take the keys yet", func() {
+		teaServer.AppendHandlers(
+			ghttp.RespondWith(http.StatusBadGateway, ""),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/add-key/s3cret"),
+				ghttp.RespondWith(http.StatusCreated, ""),
+			),
+		)
+
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateClaimed},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+		))
+
+		Eventually(teaServer.ReceivedRequests, 3*time.Second).Should(HaveLen(2))
+	})
+
+	It("also pushes the owner's profile keys", func() {
+		const profileKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDE7ZwhGIsL9ILBhIevpaApaXSB/rgyZHTC466PHKF+6B4zbO3DTxfdHrz7CVjFcgkv9NC/UEimQmrZOKDhMkAv+EnUP2JyMpGBpxZnuq2/5aZeBgdQuCxbwRx6AzrtyYW7M6FVGGyKPOiWJ6D+a2RQgaHw62K7hwXpoxv1pgMvyw=="
+		_, err := userManager.AddKey("alice", []byte(profileKey))
+		Expect(err).NotTo(HaveOccurred())
+
+		teaServer.AppendHandlers(
+			ghttp.RespondWith(http.StatusCreated, ""),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/add-key/s3cret"),
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					Expect(string(body)).To(Equal(profileKey))
+				},
+			),
+		)
+
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateClaimed},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+		))
+
+		Eventually(teaServer.ReceivedRequests).Should(HaveLen(2))
+	})
+
+	It("ignores instances that were already RUNNING", func() {
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+		))
+
+		Consistently(teaServer.ReceivedRequests).Should(BeEmpty())
+	})
+
+	It("ignores LRPs from other domains", func() {
+		listener.HandleEvent(receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "teapot", Domain: "teapot", State: receptor.ActualLRPStateClaimed},
+			receptor.ActualLRPResponse{ProcessGuid: "teapot", Domain: "teapot", State: receptor.ActualLRPStateRunning},
+		))
+
+		Consistently(teaServer.ReceivedRequests).Should(BeEmpty())
+	})
+})
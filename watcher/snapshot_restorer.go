@@ -0,0 +1,66 @@
+package watcher
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/pivotal-golang/lager"
+)
+
+// NewSnapshotRestorer returns a Listener that extracts the snapshot a
+// workstation was created from into its home directory the first time it
+// transitions to RUNNING. Restores run in the background so the other
+// listeners aren't held up, one at a time per workstation.
+func NewSnapshotRestorer(workstationManager managers.WorkstationManager, backupManager managers.BackupManager, logger lager.Logger) Listener {
+	log := logger.Session("snapshot-restorer")
+
+	restoringLock := sync.Mutex{}
+	restoring := map[string]bool{}
+
+	restore := func(name, snapshotID string) {
+		defer func() {
+			restoringLock.Lock()
+			delete(restoring, name)
+			restoringLock.Unlock()
+		}()
+
+		err := backupManager.Restore(name, snapshotID)
+		if err != nil {
+			log.Error("restore-failed", err, lager.Data{"workstation_name": name, "snapshot_id": snapshotID})
+			return
+		}
+
+		err = workstationManager.ClearRestoreFrom(name)
+		if err != nil {
+			log.Error("clear-restore-from-failed", err, lager.Data{"workstation_name": name})
+		}
+	}
+
+	return ListenerFunc(func(event receptor.Event) {
+		changed, ok := event.(receptor.ActualLRPChangedEvent)
+		if !ok || changed.After.Domain != workstationManager.Domain() {
+			return
+		}
+
+		if changed.Before.State == receptor.ActualLRPStateRunning || changed.After.State != receptor.ActualLRPStateRunning {
+			return
+		}
+
+		name := changed.After.ProcessGuid
+		workstation, err := workstationManager.Get(name)
+		if err != nil || workstation.RestoreFrom == "" {
+			return
+		}
+
+		restoringLock.Lock()
+		defer restoringLock.Unlock()
+		if restoring[name] {
+			log.Info("already-restoring", lager.Data{"workstation_name": name})
+			return
+		}
+		restoring[name] = true
+
+		go restore(name, workstation.RestoreFrom)
+	})
+}
@@ -0,0 +1,146 @@
+package watcher_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	blob_fakes "github.com/luan/teapot/blobstore/fakes"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("SnapshotRestorer", func() {
+	var (
+		teaServer          *ghttp.Server
+		fakeReceptorClient *fake_receptor.FakeClient
+		fakeBlobstore      *blob_fakes.FakeBlobstore
+		listener           Listener
+	)
+
+	running := receptor.NewActualLRPChangedEvent(
+		receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateClaimed},
+		receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning},
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		teaServer = ghttp.NewServer()
+		host, port, _ := net.SplitHostPort(strings.TrimPrefix(teaServer.URL(), "http://"))
+		hostPort, _ := strconv.Atoi(port)
+
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			Annotation:  `{"owner":"alice","restore_from":"snap"}`,
+		}, nil)
+		fakeReceptorClient.ActualLRPsByProcessGuidReturns([]receptor.ActualLRPResponse{{
+			ProcessGuid: "w1",
+			State:       receptor.ActualLRPStateRunning,
+			Address:     host,
+			Ports:       []receptor.PortMapping{{ContainerPort: 8080, HostPort: uint16(hostPort)}},
+		}}, nil)
+
+		fakeBlobstore = new(blob_fakes.FakeBlobstore)
+		fakeBlobstore.GetReturns(ioutil.NopCloser(strings.NewReader("tarball")), nil)
+
+		dataStore, _ := store.NewStore("")
+		dataStore.Put("snapshots", "snap", models.Snapshot{ID: "snap", Workstation: "w0", State: models.SnapshotCompleteState, Size: 7})
+
+		userManager := managers.NewUserManager(dataStore)
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		workstationManager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, userManager, models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+		backupManager := managers.NewBackupManager(workstationManager, fakeBlobstore, dataStore, logger)
+		listener = NewSnapshotRestorer(workstationManager, backupManager, logger)
+	})
+
+	AfterEach(func() {
+		teaServer.Close()
+	})
+
+	Context("when the workstation has a snapshot to restore", func() {
+		BeforeEach(func() {
+			teaServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/files/s3cret/home/vcap", "tar=true"),
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					Expect(string(body)).To(Equal("tarball"))
+				},
+			))
+
+			listener.HandleEvent(running)
+		})
+
+		It("extracts the snapshot into the home directory", func() {
+			Eventually(teaServer.ReceivedRequests).Should(HaveLen(1))
+			Expect(fakeBlobstore.GetArgsForCall(0)).To(Equal("snapshots/snap.tar"))
+		})
+
+		It("clears the pending restore", func() {
+			Eventually(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(1))
+			_, update := fakeReceptorClient.UpdateDesiredLRPArgsForCall(0)
+			Expect(*update.Annotation).To(Equal(`{"owner":"alice"}`))
+		})
+	})
+
+	Context("when the restore fails", func() {
+		BeforeEach(func() {
+			teaServer.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+			listener.HandleEvent(running)
+		})
+
+		It("keeps the restore pending", func() {
+			Eventually(teaServer.ReceivedRequests).Should(HaveLen(1))
+			Consistently(fakeReceptorClient.UpdateDesiredLRPCallCount).Should(Equal(0))
+		})
+	})
+
+	Context("when the restore takes a while", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			teaServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			})
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("doesn't hold up the watcher, nor restore twice", func() {
+			listener.HandleEvent(running)
+			Eventually(teaServer.ReceivedRequests).Should(HaveLen(1))
+
+			listener.HandleEvent(running)
+			Consistently(teaServer.ReceivedRequests).Should(HaveLen(1))
+		})
+	})
+
+	Context("when the workstation has nothing to restore", func() {
+		BeforeEach(func() {
+			fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{ProcessGuid: "w1"}, nil)
+			listener.HandleEvent(running)
+		})
+
+		It("does nothing", func() {
+			Consistently(teaServer.ReceivedRequests).Should(BeEmpty())
+			Expect(fakeBlobstore.GetCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/pivotal-golang/lager"
+)
+
+type usageMeter struct {
+	receptorClient receptor.Client
+	manager        managers.WorkstationManager
+	usageManager   managers.UsageManager
+	logger         lager.Logger
+}
+
+// NewUsageMeter returns a Listener that meters workstations from the moment
+// their instance is RUNNING until it leaves that state or is removed, along
+// with the resources they run with. It syncs the running intervals with the
+// instances Diego has whenever the watcher subscribes, since workstations may
+// have started or stopped while teapot wasn't watching.
+func NewUsageMeter(receptorClient receptor.Client, manager managers.WorkstationManager, usageManager managers.UsageManager, logger lager.Logger) Listener {
+	return &usageMeter{
+		receptorClient: receptorClient,
+		manager:        manager,
+		usageManager:   usageManager,
+		logger:         logger.Session("usage-meter"),
+	}
+}
+
+func (meter *usageMeter) HandleEvent(event receptor.Event) {
+	now := time.Now()
+
+	switch e := event.(type) {
+	case receptor.ActualLRPChangedEvent:
+		if e.After.Domain != meter.manager.Domain() || e.Before.State == e.After.State {
+			return
+		}
+
+		name := e.After.ProcessGuid
+		if e.After.State == receptor.ActualLRPStateRunning {
+			meter.start(name, now)
+		} else if e.Before.State == receptor.ActualLRPStateRunning {
+			meter.stop(name, now)
+		}
+	case receptor.ActualLRPRemovedEvent:
+		if e.ActualLRPResponse.Domain != meter.manager.Domain() {
+			return
+		}
+
+		meter.stop(e.ActualLRPResponse.ProcessGuid, now)
+	}
+}
+
+// Sync closes the intervals of the workstations that are no longer running,
+// as of now, and opens one for the running workstations that have none, from
+// when their instance started running.
+func (meter *usageMeter) Sync() {
+	now := time.Now()
+
+	actualLRPs, err := meter.receptorClient.ActualLRPsByDomain(meter.manager.Domain())
+	if err != nil {
+		meter.logger.Error("fetch-actual-lrps-failed", err)
+		return
+	}
+
+	metered, err := meter.usageManager.Running()
+	if err != nil {
+		meter.logger.Error("list-running-failed", err)
+		return
+	}
+
+	running := map[string]receptor.ActualLRPResponse{}
+	for _, actualLRP := range actualLRPs {
+		if actualLRP.State == receptor.ActualLRPStateRunning {
+			running[actualLRP.ProcessGuid] = actualLRP
+		}
+	}
+
+	for _, name := range metered {
+		if _, ok := running[name]; ok {
+			delete(running, name)
+			continue
+		}
+		meter.stop(name, now)
+	}
+
+	for name, actualLRP := range running {
+		meter.start(name, time.Unix(0, actualLRP.Since))
+	}
+}
+
+func (meter *usageMeter) start(name string, at time.Time) {
+	workstation, err := meter.manager.Get(name)
+	if err != nil {
+		meter.logger.Error("get-workstation-failed", err, lager.Data{"workstation_name": name})
+		return
+	}
+	if err := meter.usageManager.Start(workstation, at); err != nil {
+		meter.logger.Error("start-failed", err, lager.Data{"workstation_name": name})
+	}
+}
+
+func (meter *usageMeter) stop(name string, at time.Time) {
+	if err := meter.usageManager.Stop(name, at); err != nil {
+		meter.logger.Error("stop-failed", err, lager.Data{"workstation_name": name})
+	}
+}
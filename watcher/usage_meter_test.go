@@ -0,0 +1,166 @@
+package watcher_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	model_fakes "github.com/luan/teapot/models/fakes"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UsageMeter", func() {
+	var (
+		listener           Listener
+		fakeReceptorClient *fake_receptor.FakeClient
+		usageManager       managers.UsageManager
+		dataStore          store.Store
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		dataStore, _ = store.NewStore("")
+		egressPolicyManager, _ := managers.NewEgressPolicyManager("", models.OpenEgressPolicy)
+		quotaManager, _ := managers.NewQuotaManager("")
+		capacityManager := managers.NewCapacityManager(fakeReceptorClient, false)
+		manager := managers.NewWorkstationManager(fakeReceptorClient, &model_fakes.FakeRouteProvider{}, managers.NewUserManager(dataStore), models.DefaultBootstrap(), egressPolicyManager, quotaManager, capacityManager, "s3cret", logger)
+		usageManager = managers.NewUsageManager(dataStore, quotaManager)
+		listener = NewUsageMeter(fakeReceptorClient, manager, usageManager, logger)
+
+		fakeReceptorClient.GetDesiredLRPReturns(receptor.DesiredLRPResponse{
+			ProcessGuid: "w1",
+			RootFSPath:  "docker:///ubuntu#trusty",
+			MemoryMB:    1024,
+			DiskMB:      2048,
+			CPUWeight:   2,
+			Annotation:  `{"owner":"alice"}`,
+		}, nil)
+	})
+
+	changed := func(before, after receptor.ActualLRPState) receptor.Event {
+		return receptor.NewActualLRPChangedEvent(
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: before},
+			receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: after},
+		)
+	}
+
+	running := func() []string {
+		keys, _ := dataStore.Keys("usage-running")
+		return keys
+	}
+
+	report := func() []models.UsageRecord {
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		records, err := usageManager.Report(models.UsageByUser, today, today.Add(24*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		return records
+	}
+
+	It("meters workstations while they are running", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning))
+
+		records := report()
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Group).To(Equal("alice"))
+		Expect(records[0].Workstations).To(Equal(1))
+
+		Expect(running()).To(Equal([]string{"w1"}))
+
+		listener.HandleEvent(changed(receptor.ActualLRPStateRunning, receptor.ActualLRPStateCrashed))
+		Expect(running()).To(BeEmpty())
+		Expect(report()).To(HaveLen(1))
+	})
+
+	It("stops metering workstations whose instance is removed", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning))
+		listener.HandleEvent(receptor.NewActualLRPRemovedEvent(receptor.ActualLRPResponse{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning}))
+
+		Expect(running()).To(BeEmpty())
+		Expect(report()).To(HaveLen(1))
+	})
+
+	It("ignores workstations that never ran", func() {
+		listener.HandleEvent(changed(receptor.ActualLRPStateUnclaimed, receptor.ActualLRPStateClaimed))
+		listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateCrashed))
+
+		Expect(report()).To(BeEmpty())
+	})
+
+	It("keeps the days before today as daily records only", func() {
+		yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-12 * time.Hour)
+		workstation := models.Workstation{Name: "w2", Owner: "bob", DockerImage: "docker:///ubuntu", MemoryMB: 512}
+		Expect(usageManager.Start(workstation, yesterday.Add(-time.Hour))).To(Succeed())
+		Expect(usageManager.Stop("w2", yesterday)).To(Succeed())
+		Expect(usageManager.Start(workstation, yesterday.Add(time.Hour))).To(Succeed())
+
+		day := yesterday.Truncate(24 * time.Hour)
+		records, err := usageManager.Report(models.UsageByUser, day, day.Add(48*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Workstations).To(Equal(1))
+		Expect(records[0].Hours).To(Equal(12.0))
+
+		intervals, _ := dataStore.Keys("usage-intervals")
+		Expect(intervals).To(BeEmpty())
+		Expect(running()).To(Equal([]string{"w2"}))
+
+		daily, _ := dataStore.Keys("usage-daily")
+		Expect(daily).To(HaveLen(3))
+	})
+
+	Describe("syncing", func() {
+		var syncer Syncer
+
+		BeforeEach(func() {
+			syncer = listener.(Syncer)
+		})
+
+		It("stops metering workstations that stopped meanwhile", func() {
+			listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning))
+
+			syncer.Sync()
+
+			Expect(fakeReceptorClient.ActualLRPsByDomainArgsForCall(0)).To(Equal("tiego"))
+			Expect(running()).To(BeEmpty())
+			Expect(report()).To(HaveLen(1))
+		})
+
+		It("meters workstations running without being metered from when they started", func() {
+			since := time.Now().Add(-time.Hour)
+			fakeReceptorClient.ActualLRPsByDomainReturns([]receptor.ActualLRPResponse{
+				{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning, Since: since.UnixNano()},
+				{ProcessGuid: "w2", Domain: "tiego", State: receptor.ActualLRPStateClaimed},
+			}, nil)
+
+			syncer.Sync()
+
+			Expect(running()).To(Equal([]string{"w1"}))
+			interval := models.RunningInterval{}
+			Expect(dataStore.Get("usage-running", "w1", &interval)).To(Succeed())
+			Expect(interval.StartedAt.UnixNano()).To(Equal(since.UnixNano()))
+		})
+
+		It("keeps metering workstations still running", func() {
+			listener.HandleEvent(changed(receptor.ActualLRPStateClaimed, receptor.ActualLRPStateRunning))
+			interval := models.RunningInterval{}
+			Expect(dataStore.Get("usage-running", "w1", &interval)).To(Succeed())
+
+			fakeReceptorClient.ActualLRPsByDomainReturns([]receptor.ActualLRPResponse{
+				{ProcessGuid: "w1", Domain: "tiego", State: receptor.ActualLRPStateRunning, Since: time.Now().UnixNano()},
+			}, nil)
+			syncer.Sync()
+
+			synced := models.RunningInterval{}
+			Expect(dataStore.Get("usage-running", "w1", &synced)).To(Succeed())
+			Expect(synced.StartedAt).To(Equal(interval.StartedAt))
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package watcher
+
+import (
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/pivotal-golang/lager"
+)
+
+type Listener interface {
+	HandleEvent(event receptor.Event)
+}
+
+// Syncer is a Listener that catches up with what it may have missed while the
+// watcher wasn't subscribed, every time it subscribes.
+type Syncer interface {
+	Sync()
+}
+
+type ListenerFunc func(event receptor.Event)
+
+func (f ListenerFunc) HandleEvent(event receptor.Event) {
+	f(event)
+}
+
+// Watcher subscribes to the receptor event stream and dispatches every event
+// to its listeners. It is meant to be run as an ifrit member.
+type Watcher struct {
+	receptorClient receptor.Client
+	retryInterval  time.Duration
+	listeners      []Listener
+	logger         lager.Logger
+}
+
+func New(receptorClient receptor.Client, retryInterval time.Duration, logger lager.Logger, listeners ...Listener) *Watcher {
+	return &Watcher{
+		receptorClient: receptorClient,
+		retryInterval:  retryInterval,
+		listeners:      listeners,
+		logger:         logger.Session("watcher"),
+	}
+}
+
+func (w *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		source, err := w.receptorClient.SubscribeToEvents()
+		if err != nil {
+			w.logger.Error("subscribe-failed", err)
+		} else {
+			w.sync()
+			if w.consume(source, signals) {
+				return nil
+			}
+		}
+
+		select {
+		case <-signals:
+			return nil
+		case <-time.After(w.retryInterval):
+		}
+	}
+}
+
+func (w *Watcher) sync() {
+	for _, listener := range w.listeners {
+		if syncer, ok := listener.(Syncer); ok {
+			syncer.Sync()
+		}
+	}
+}
+
+func (w *Watcher) consume(source receptor.EventSource, signals <-chan os.Signal) bool {
+	defer source.Close()
+
+	events := make(chan receptor.Event)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			event, err := source.Next()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-signals:
+			return true
+		case event := <-events:
+			for _, listener := range w.listeners {
+				listener.HandleEvent(event)
+			}
+		case err := <-errs:
+			w.logger.Error("event-source-failed", err)
+			return false
+		}
+	}
+}
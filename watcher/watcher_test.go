@@ -0,0 +1,109 @@
+package watcher_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/receptor"
+	"github.com/cloudfoundry-incubator/receptor/fake_receptor"
+	. "github.com/luan/teapot/watcher"
+	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		fakeReceptorClient *fake_receptor.FakeClient
+		fakeEventSource    *fake_receptor.FakeEventSource
+		events             chan receptor.Event
+		received           chan receptor.Event
+		process            ifrit.Process
+		listener           Listener
+		logger             lager.Logger
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("test")
+		fakeReceptorClient = new(fake_receptor.FakeClient)
+		fakeEventSource = new(fake_receptor.FakeEventSource)
+		events = make(chan receptor.Event, 10)
+		received = make(chan receptor.Event, 10)
+
+		stream := events
+		fakeEventSource.NextStub = func() (receptor.Event, error) {
+			event, ok := <-stream
+			if !ok {
+				return nil, receptor.ErrSourceClosed
+			}
+			return event, nil
+		}
+		fakeEventSource.CloseStub = func() error {
+			defer func() { recover() }()
+			close(stream)
+			return nil
+		}
+		fakeReceptorClient.SubscribeToEventsReturns(fakeEventSource, nil)
+
+		listener = ListenerFunc(func(event receptor.Event) {
+			received <- event
+		})
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Invoke(New(fakeReceptorClient, 10*time.Millisecond, logger, listener))
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("dispatches events to the listeners", func() {
+		event := receptor.NewActualLRPRemovedEvent(receptor.ActualLRPResponse{ProcessGuid: "w1"})
+		events <- event
+		Eventually(received).Should(Receive(Equal(event)))
+	})
+
+	Context("when a listener is a syncer", func() {
+		var synced chan struct{}
+
+		BeforeEach(func() {
+			synced = make(chan struct{}, 10)
+			listener = &syncingListener{Listener: listener, synced: synced}
+		})
+
+		It("syncs it once subscribed", func() {
+			Eventually(synced).Should(Receive())
+			Expect(fakeReceptorClient.SubscribeToEventsCallCount()).To(Equal(1))
+		})
+	})
+
+	It("closes the event source when signalled", func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		Expect(fakeEventSource.CloseCallCount()).To(Equal(1))
+	})
+
+	Context("when subscribing fails", func() {
+		BeforeEach(func() {
+			fakeReceptorClient.SubscribeToEventsReturns(nil, errors.New("boom"))
+		})
+
+		It("keeps retrying", func() {
+			Eventually(fakeReceptorClient.SubscribeToEventsCallCount).Should(BeNumerically(">", 1))
+		})
+	})
+})
+
+type syncingListener struct {
+	Listener
+	synced chan struct{}
+}
+
+func (l *syncingListener) Sync() {
+	l.synced <- struct{}{}
+}
@@ -0,0 +1,133 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	EventHeader     = "X-Teapot-Event"
+	DeliveryHeader  = "X-Teapot-Delivery"
+	SignatureHeader = "X-Teapot-Signature"
+)
+
+// Deliverer POSTs the deliveries queued by the webhook manager as they become
+// due. It is meant to be run as an ifrit member.
+type Deliverer struct {
+	manager    managers.WebhookManager
+	interval   time.Duration
+	httpClient *http.Client
+	logger     lager.Logger
+}
+
+// New returns a Deliverer looking for due deliveries every interval, giving
+// webhooks timeout to answer each.
+func New(manager managers.WebhookManager, interval, timeout time.Duration, logger lager.Logger) *Deliverer {
+	return &Deliverer{
+		manager:    manager,
+		interval:   interval,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logger.Session("webhooks"),
+	}
+}
+
+func (d *Deliverer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case now := <-ticker.C:
+			d.Tick(now)
+		}
+	}
+}
+
+// Tick sends every delivery due at now, all at once, and returns when they
+// have all been attempted.
+func (d *Deliverer) Tick(now time.Time) {
+	deliveries, err := d.manager.Due(now)
+	if err != nil {
+		d.logger.Error("list-due-deliveries-failed", err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery models.Delivery) {
+			defer wg.Done()
+			d.deliver(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Deliverer) deliver(delivery models.Delivery) {
+	log := d.logger.Session("deliver", lager.Data{
+		"delivery_id": delivery.ID,
+		"webhook_id":  delivery.WebhookID,
+		"event":       delivery.Event.Type,
+	})
+
+	webhook, err := d.manager.Get(delivery.WebhookID)
+	if err != nil {
+		log.Error("webhook-not-found", err)
+		return
+	}
+
+	status, err := d.post(webhook, delivery)
+	delivery, recordErr := d.manager.RecordAttempt(delivery.ID, status, err)
+	if recordErr != nil {
+		log.Error("record-attempt-failed", recordErr)
+		return
+	}
+
+	switch delivery.State {
+	case models.DeliveryDeliveredState:
+		log.Info("delivered")
+	case models.DeliveryFailedState:
+		log.Info("dead-lettered", lager.Data{"attempts": delivery.Attempts, "error": delivery.LastError})
+	default:
+		log.Info("will-retry", lager.Data{"attempts": delivery.Attempts, "error": delivery.LastError, "next_attempt_at": delivery.NextAttemptAt})
+	}
+}
+
+// post sends the event signed with the webhook's secret, the signature being
+// the hex HMAC-SHA256 of the body prefixed with "sha256=".
+func (d *Deliverer) post(webhook models.Webhook, delivery models.Delivery) (int, error) {
+	payload, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "teapot")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, "sha256="+webhook.Sign(payload))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,141 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/luan/teapot/managers"
+	"github.com/luan/teapot/models"
+	"github.com/luan/teapot/store"
+	. "github.com/luan/teapot/webhooks"
+	"github.com/pivotal-golang/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Deliverer", func() {
+	var (
+		server    *ghttp.Server
+		manager   managers.WebhookManager
+		deliverer *Deliverer
+		webhook   models.Webhook
+	)
+
+	BeforeEach(func() {
+		logger := lager.NewLogger("test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+		server = ghttp.NewServer()
+		dataStore, _ := store.NewStore("")
+		manager = managers.NewWebhookManager(dataStore, time.Minute, 2)
+		deliverer = New(manager, time.Second, time.Second, logger)
+
+		var err error
+		webhook, err = manager.Create(models.Webhook{URL: server.URL() + "/hook", Secret: "s3cret"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manager.Publish(models.WorkstationEvent{Type: models.WorkstationRunningEvent, Workstation: "w1"})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("POSTs the event signed with the webhook's secret", func() {
+		server.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal("POST"))
+			Expect(r.URL.Path).To(Equal("/hook"))
+			Expect(r.Header.Get(EventHeader)).To(Equal("running"))
+			Expect(r.Header.Get(DeliveryHeader)).NotTo(BeEmpty())
+
+			body, _ := ioutil.ReadAll(r.Body)
+			Expect(r.Header.Get(SignatureHeader)).To(Equal("sha256=" + webhook.Sign(body)))
+
+			var event models.WorkstationEvent
+			Expect(json.Unmarshal(body, &event)).To(Succeed())
+			Expect(event.Type).To(Equal(models.WorkstationRunningEvent))
+			Expect(event.Workstation).To(Equal("w1"))
+		})
+
+		deliverer.Tick(time.Now())
+
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+		deliveries, _ := manager.Deliveries(webhook.ID)
+		Expect(deliveries).To(HaveLen(1))
+		Expect(deliveries[0].State).To(Equal(models.DeliveryDeliveredState))
+		Expect(deliveries[0].ResponseStatus).To(Equal(http.StatusOK))
+		Expect(deliveries[0].Attempts).To(Equal(1))
+
+		deliverer.Tick(time.Now().Add(time.Hour))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	Context("when the webhook fails", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.RespondWith(http.StatusOK, ""),
+			)
+		})
+
+		It("retries with exponential backoff, then dead-letters the delivery", func() {
+			deliverer.Tick(time.Now())
+
+			deliveries, _ := manager.Deliveries(webhook.ID)
+			Expect(deliveries[0].State).To(Equal(models.DeliveryPendingState))
+			Expect(deliveries[0].LastError).To(Equal("webhook responded with status 500"))
+			Expect(*deliveries[0].NextAttemptAt).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+
+			deliverer.Tick(time.Now().Add(30 * time.Second))
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+
+			deliverer.Tick(time.Now().Add(time.Minute))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+
+			deadLetters, _ := manager.DeadLetters()
+			Expect(deadLetters).To(HaveLen(1))
+			Expect(deadLetters[0].State).To(Equal(models.DeliveryFailedState))
+			Expect(deadLetters[0].Attempts).To(Equal(2))
+			Expect(deadLetters[0].LastError).To(Equal("webhook responded with status 502"))
+
+			deliverer.Tick(time.Now().Add(time.Hour))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+
+			_, err := manager.Redeliver(deadLetters[0].ID)
+			Expect(err).NotTo(HaveOccurred())
+
+			deliverer.Tick(time.Now())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+			deadLetters, _ = manager.DeadLetters()
+			Expect(deadLetters).To(BeEmpty())
+		})
+	})
+
+	It("backs off twice as long after every failure", func() {
+		dataStore, _ := store.NewStore("")
+		manager = managers.NewWebhookManager(dataStore, time.Minute, 5)
+		_, err := manager.Create(models.Webhook{URL: server.URL(), Secret: "s3cret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manager.Publish(models.WorkstationEvent{Type: models.WorkstationCrashedEvent, Workstation: "w1"})).To(Succeed())
+
+		due, _ := manager.Due(time.Now())
+		Expect(due).To(HaveLen(1))
+
+		for _, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute} {
+			delivery, err := manager.RecordAttempt(due[0].ID, 0, errors.New("connection refused"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delivery.State).To(Equal(models.DeliveryPendingState))
+			Expect(*delivery.NextAttemptAt).To(BeTemporally("~", time.Now().Add(backoff), time.Second))
+		}
+
+		delivery, _ := manager.RecordAttempt(due[0].ID, 0, errors.New("connection refused"))
+		Expect(delivery.State).To(Equal(models.DeliveryFailedState))
+		Expect(delivery.NextAttemptAt).To(BeNil())
+		Expect(delivery.LastError).To(Equal("connection refused"))
+	})
+})